@@ -4,6 +4,12 @@ go 1.22
 
 toolchain go1.22.5
 
+require (
+	github.com/ethereum/go-ethereum v1.14.7
+	github.com/lmittmann/w3 v0.16.8
+	github.com/onmetahq/meta-http v0.0.4-alpha
+)
+
 require (
 	github.com/Microsoft/go-winio v0.6.2 // indirect
 	github.com/StackExchange/wmi v1.2.1 // indirect
@@ -15,13 +21,10 @@ require (
 	github.com/deckarep/golang-set/v2 v2.6.0 // indirect
 	github.com/decred/dcrd/dcrec/secp256k1/v4 v4.0.1 // indirect
 	github.com/ethereum/c-kzg-4844 v1.0.0 // indirect
-	github.com/ethereum/go-ethereum v1.14.7 // indirect
 	github.com/go-ole/go-ole v1.3.0 // indirect
 	github.com/gorilla/websocket v1.5.0 // indirect
 	github.com/holiman/uint256 v1.3.0 // indirect
-	github.com/lmittmann/w3 v0.16.8 // indirect
 	github.com/mmcloughlin/addchain v0.4.0 // indirect
-	github.com/onmetahq/meta-http v0.0.4-alpha // indirect
 	github.com/shirou/gopsutil v3.21.4-0.20210419000835-c7a38de76ee5+incompatible // indirect
 	github.com/supranational/blst v0.3.11 // indirect
 	github.com/tklauser/go-sysconf v0.3.12 // indirect
@@ -0,0 +1,110 @@
+// Package allowance checks ERC-20 allowances on chain and builds the approve
+// transaction a swap needs, independently of the aggregator used.
+package allowance
+
+import (
+	"context"
+	"fmt"
+	"math/big"
+
+	ethcommon "github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/lmittmann/w3"
+	w3eth "github.com/lmittmann/w3/module/eth"
+	"github.com/onmetahq/go-evm/internal/http/common"
+)
+
+var (
+	funcAllowance = w3.MustNewFunc("allowance(address,address)", "uint256")
+	funcApprove   = w3.MustNewFunc("approve(address,uint256)", "bool")
+)
+
+type AllowanceReq struct {
+	ChainId uint64
+	Token   string
+	Owner   string
+	// Spender is the contract pulling the tokens, e.g. the AllowanceTarget
+	// of a SwapTx or the 1inch spender.
+	Spender string
+	// Amount is the allowance the swap needs.
+	Amount *big.Int
+	// Cap is approved instead of Amount when set, so later swaps up to Cap
+	// do not need a new approval. It must not be below Amount.
+	Cap *big.Int
+}
+
+// Allowance reads the allowance of owner to spender for token.
+func Allowance(ctx context.Context, client *w3.Client, token, owner, spender string) (*big.Int, error) {
+	var allowance big.Int
+	if err := client.CallCtx(ctx,
+		w3eth.CallFunc(ethcommon.HexToAddress(token), funcAllowance,
+			ethcommon.HexToAddress(owner), ethcommon.HexToAddress(spender)).Returns(&allowance),
+	); err != nil {
+		return nil, fmt.Errorf("failed to get allowance: %w", err)
+	}
+	return &allowance, nil
+}
+
+// EnsureAllowance returns the approve transactions to send in order before
+// spending req.Amount, or nil when the on-chain allowance is already
+// sufficient or the token is native. A nonzero allowance below req.Amount
+// is reset to zero first, tokens such as USDT revert when an allowance is
+// changed from a nonzero value to another.
+func EnsureAllowance(ctx context.Context, client *w3.Client, req AllowanceReq) ([]common.ApproveTx, error) {
+	if req.Amount == nil || req.Amount.Sign() < 0 {
+		return nil, fmt.Errorf("invalid allowance amount: %v", req.Amount)
+	}
+
+	approve := req.Amount
+	if req.Cap != nil {
+		if req.Cap.Cmp(req.Amount) < 0 {
+			return nil, fmt.Errorf("allowance cap %s below amount %s", req.Cap, req.Amount)
+		}
+		approve = req.Cap
+	}
+
+	if common.IsNativeToken(req.Token) {
+		return nil, nil
+	}
+
+	allowance, err := Allowance(ctx, client, req.Token, req.Owner, req.Spender)
+	if err != nil {
+		return nil, err
+	}
+
+	if allowance.Cmp(req.Amount) >= 0 {
+		return nil, nil
+	}
+
+	var txs []common.ApproveTx
+	if allowance.Sign() > 0 {
+		reset, err := approveTx(req, big.NewInt(0))
+		if err != nil {
+			return nil, err
+		}
+		txs = append(txs, reset)
+	}
+
+	tx, err := approveTx(req, approve)
+	if err != nil {
+		return nil, err
+	}
+	return append(txs, tx), nil
+}
+
+func approveTx(req AllowanceReq, amount *big.Int) (common.ApproveTx, error) {
+	data, err := funcApprove.EncodeArgs(ethcommon.HexToAddress(req.Spender), amount)
+	if err != nil {
+		return common.ApproveTx{}, fmt.Errorf("failed to encode approve: %w", err)
+	}
+
+	return common.ApproveTx{
+		ChainId: req.ChainId,
+		Token:   req.Token,
+		Spender: req.Spender,
+		Amount:  amount,
+		To:      req.Token,
+		Data:    hexutil.Encode(data),
+		Value:   big.NewInt(0),
+	}, nil
+}
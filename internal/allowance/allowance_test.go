@@ -0,0 +1,157 @@
+package allowance
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"math/big"
+	"testing"
+
+	ethcommon "github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/lmittmann/w3"
+	"github.com/onmetahq/go-evm/internal/http/common"
+	"github.com/onmetahq/go-evm/internal/http/fake"
+)
+
+const (
+	token   = "0x2791bca1f2de4661ed88a30c99a7a9449aa84174"
+	owner   = "0x15Ba05723b04785C3E21157171810892A4FB795c"
+	spender = "0x1111111254eeb25477b68fb85ed929f73a960582"
+)
+
+func newNode(t *testing.T, allowance *big.Int) (*fake.RPC, *w3.Client) {
+	node := fake.NewRPC()
+	t.Cleanup(node.Close)
+
+	node.Handle("eth_call", func(params []json.RawMessage) (any, error) {
+		var msg struct {
+			To    string        `json:"to"`
+			Input hexutil.Bytes `json:"input"`
+			Data  hexutil.Bytes `json:"data"`
+		}
+		if err := json.Unmarshal(params[0], &msg); err != nil {
+			return nil, err
+		}
+
+		input := msg.Input
+		if len(input) == 0 {
+			input = msg.Data
+		}
+
+		var o, s ethcommon.Address
+		if err := funcAllowance.DecodeArgs(input, &o, &s); err != nil {
+			return nil, err
+		}
+		if o != ethcommon.HexToAddress(owner) || s != ethcommon.HexToAddress(spender) {
+			return nil, fmt.Errorf("unexpected allowance call, owner: %s, spender: %s", o, s)
+		}
+		return hexutil.Encode(ethcommon.LeftPadBytes(allowance.Bytes(), 32)), nil
+	})
+
+	client, err := w3.Dial(node.URL)
+	if err != nil {
+		t.Fatalf("dial err: %v", err)
+	}
+	t.Cleanup(func() { client.Close() })
+	return node, client
+}
+
+func TestEnsureAllowanceSufficient(t *testing.T) {
+	_, client := newNode(t, big.NewInt(1_000_000))
+
+	txs, err := EnsureAllowance(context.Background(), client, AllowanceReq{
+		ChainId: 137,
+		Token:   token,
+		Owner:   owner,
+		Spender: spender,
+		Amount:  big.NewInt(1_000_000),
+	})
+	if err != nil {
+		t.Fatalf("ensure allowance err: %v", err)
+	}
+
+	if txs != nil {
+		t.Fatalf("expected no approval, txs: %+v", txs)
+	}
+}
+
+func TestEnsureAllowanceInsufficient(t *testing.T) {
+	req := AllowanceReq{
+		ChainId: 137,
+		Token:   token,
+		Owner:   owner,
+		Spender: spender,
+		Amount:  big.NewInt(1_000_000),
+	}
+
+	for _, c := range []struct {
+		name      string
+		allowance *big.Int
+		cap       *big.Int
+		want      []*big.Int
+	}{
+		{name: "exact", allowance: big.NewInt(0), want: []*big.Int{big.NewInt(1_000_000)}},
+		{name: "capped", allowance: big.NewInt(0), cap: big.NewInt(50_000_000), want: []*big.Int{big.NewInt(50_000_000)}},
+		// USDT reverts on approve from a nonzero allowance, it is reset first.
+		{name: "reset", allowance: big.NewInt(999_999), want: []*big.Int{big.NewInt(0), big.NewInt(1_000_000)}},
+	} {
+		_, client := newNode(t, c.allowance)
+		req.Cap = c.cap
+		txs, err := EnsureAllowance(context.Background(), client, req)
+		if err != nil {
+			t.Fatalf("%s: ensure allowance err: %v", c.name, err)
+		}
+
+		if len(txs) != len(c.want) {
+			t.Fatalf("%s: invalid approvals, txs: %+v", c.name, txs)
+		}
+
+		for i, tx := range txs {
+			if tx.To != token || tx.Spender != spender || tx.Amount.Cmp(c.want[i]) != 0 {
+				t.Fatalf("%s: invalid approval %d, tx: %+v", c.name, i, tx)
+			}
+
+			var (
+				gotSpender ethcommon.Address
+				gotAmount  big.Int
+			)
+			if err := funcApprove.DecodeArgs(hexutil.MustDecode(tx.Data), &gotSpender, &gotAmount); err != nil {
+				t.Fatalf("%s: decode approve err: %v", c.name, err)
+			}
+
+			if gotSpender != ethcommon.HexToAddress(spender) || gotAmount.Cmp(c.want[i]) != 0 {
+				t.Fatalf("%s: invalid approve args, spender: %s, amount: %s", c.name, gotSpender, &gotAmount)
+			}
+		}
+	}
+}
+
+func TestEnsureAllowanceInvalid(t *testing.T) {
+	node, client := newNode(t, big.NewInt(0))
+
+	_, err := EnsureAllowance(context.Background(), client, AllowanceReq{
+		Token:   token,
+		Owner:   owner,
+		Spender: spender,
+		Amount:  big.NewInt(1_000_000),
+		Cap:     big.NewInt(1),
+	})
+	if err == nil {
+		t.Fatalf("expected cap below amount to fail")
+	}
+
+	txs, err := EnsureAllowance(context.Background(), client, AllowanceReq{
+		Token:   common.NativeToken,
+		Owner:   owner,
+		Spender: spender,
+		Amount:  big.NewInt(1_000_000),
+	})
+	if err != nil || txs != nil {
+		t.Fatalf("expected no approval for the native token, txs: %+v, err: %v", txs, err)
+	}
+
+	if calls := node.Calls("eth_call"); calls != 0 {
+		t.Fatalf("expected no eth_call, calls: %d", calls)
+	}
+}
@@ -1 +1,126 @@
+// Package dex builds swaps against DEX contracts directly, without an
+// aggregator API, from routes quoted on chain.
 package dex
+
+import (
+	"math/big"
+
+	ethcommon "github.com/ethereum/go-ethereum/common"
+)
+
+// Permit2Address is the Permit2 contract, the same on every chain.
+const Permit2Address = "0x000000000022D473030F116dDEE9F6B43aC78BA3"
+
+// UniswapDeployment holds the Uniswap contracts of a chain. V2Router is
+// optional, V2 pools are not quoted without it.
+type UniswapDeployment struct {
+	UniversalRouter string
+	QuoterV2        string
+	V2Router        string
+}
+
+// DefaultUniswapDeployments maps chain ids to the Uniswap deployment of
+// each chain.
+var DefaultUniswapDeployments = map[uint64]UniswapDeployment{
+	1: {
+		UniversalRouter: "0x3fC91A3afd70395Cd496C647d5a6CC9D4B2b7FAD",
+		QuoterV2:        "0x61fFE014bA17989E743c5F6cB21bF9697530B21e",
+		V2Router:        "0x7a250d5630B4cF539739dF2C5dAcb4c659F2488D",
+	},
+	10: {
+		UniversalRouter: "0xCb1355ff08Ab38bBCE60111F1bb2B784bE25D7e8",
+		QuoterV2:        "0x61fFE014bA17989E743c5F6cB21bF9697530B21e",
+		V2Router:        "0x4A7b5Da61326A6379179b40d00F57E5bbDC962c2",
+	},
+	137: {
+		UniversalRouter: "0xec7BE89e9d109e7e3Fec59c222CF297125FEFda2",
+		QuoterV2:        "0x61fFE014bA17989E743c5F6cB21bF9697530B21e",
+		V2Router:        "0xedf6066a2b290C185783862C7F4776A2C8077AD1",
+	},
+	8453: {
+		UniversalRouter: "0x3fC91A3afd70395Cd496C647d5a6CC9D4B2b7FAD",
+		QuoterV2:        "0x3d4e44Eb1374240CE5F1B871ab261CD16335B76a",
+		V2Router:        "0x4752ba5DBc23f44D87826276BF6Fd6b1C372aD24",
+	},
+	42161: {
+		UniversalRouter: "0x5E325eDA8064b456f4781070C0738d849c824258",
+		QuoterV2:        "0x61fFE014bA17989E743c5F6cB21bF9697530B21e",
+		V2Router:        "0x4752ba5DBc23f44D87826276BF6Fd6b1C372aD24",
+	},
+}
+
+// V3FeeTiers are the fee tiers quoted for single hop V3 routes, in
+// hundredths of a bip.
+var V3FeeTiers = []uint32{100, 500, 3000, 10000}
+
+// v3HopFeeTiers are the fee tiers quoted for each hop of two hop V3 routes
+// through the wrapped native token.
+var v3HopFeeTiers = []uint32{500, 3000}
+
+// Universal Router commands, see the Commands library of the router.
+const (
+	cmdV3SwapExactIn  byte = 0x00
+	cmdV3SwapExactOut byte = 0x01
+	cmdSweep          byte = 0x04
+	cmdPayPortion     byte = 0x06
+	cmdV2SwapExactIn  byte = 0x08
+	cmdV2SwapExactOut byte = 0x09
+	cmdPermit2Permit  byte = 0x0a
+	cmdWrapEth        byte = 0x0b
+	cmdUnwrapWeth     byte = 0x0c
+)
+
+// Universal Router recipient placeholders.
+var (
+	msgSender   = ethcommon.HexToAddress("0x0000000000000000000000000000000000000001")
+	addressThis = ethcommon.HexToAddress("0x0000000000000000000000000000000000000002")
+)
+
+// Gas charged on top of the quoted swap gas for the router and token
+// transfers, and per hop of a V2 route which the V2 router does not
+// estimate.
+const (
+	routerGasOverhead = 60_000
+	v2HopGas          = 90_000
+)
+
+// UniswapOptions are the QuoteReq.Options only understood by the Universal
+// Router.
+type UniswapOptions struct {
+	// Permit2 lets the router pull Src through Permit2 without a prior
+	// Permit2 approval transaction, it is executed before the swap.
+	Permit2 *Permit2Permit
+}
+
+func (UniswapOptions) Provider() string {
+	return "uniswap"
+}
+
+// Permit2Permit is a Permit2 PermitSingle signed by the token owner,
+// allowing Spender to transfer up to Amount of Token until Expiration.
+type Permit2Permit struct {
+	Token       string
+	Amount      *big.Int
+	Expiration  uint64
+	Nonce       uint64
+	Spender     string
+	SigDeadline *big.Int
+	Signature   []byte
+}
+
+// UniswapRoute is a route through Uniswap pools, Fees holds the fee tier of
+// each V3 hop and is empty for V2 routes.
+type UniswapRoute struct {
+	Tokens []ethcommon.Address
+	Fees   []uint32
+	V2     bool
+}
+
+// UniswapQuote is the best route found for a swap, AmountIn and AmountOut
+// are the quoted amounts before slippage.
+type UniswapQuote struct {
+	Route     UniswapRoute
+	AmountIn  *big.Int
+	AmountOut *big.Int
+	Gas       *big.Int
+}
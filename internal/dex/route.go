@@ -0,0 +1,177 @@
+package dex
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math/big"
+
+	ethcommon "github.com/ethereum/go-ethereum/common"
+	"github.com/lmittmann/w3"
+	w3eth "github.com/lmittmann/w3/module/eth"
+	"github.com/lmittmann/w3/w3types"
+	"github.com/onmetahq/go-evm/internal/http/common"
+)
+
+var (
+	funcQuoteExactInput  = w3.MustNewFunc("quoteExactInput(bytes path, uint256 amountIn)", "uint256 amountOut, uint160[] sqrtPriceX96AfterList, uint32[] initializedTicksCrossedList, uint256 gasEstimate")
+	funcQuoteExactOutput = w3.MustNewFunc("quoteExactOutput(bytes path, uint256 amountOut)", "uint256 amountIn, uint160[] sqrtPriceX96AfterList, uint32[] initializedTicksCrossedList, uint256 gasEstimate")
+	funcGetAmountsOut    = w3.MustNewFunc("getAmountsOut(uint256 amountIn, address[] path)", "uint256[] amounts")
+	funcGetAmountsIn     = w3.MustNewFunc("getAmountsIn(uint256 amountOut, address[] path)", "uint256[] amounts")
+)
+
+// Path encodes a V3 route as read by the quoter and the router: each token
+// followed by the fee tier of the next hop as a uint24. Exact out paths
+// start from the output token.
+func (r UniswapRoute) Path(exactOut bool) []byte {
+	tokens, fees := r.Tokens, r.Fees
+	if exactOut {
+		tokens, fees = reversed(tokens), reversed(fees)
+	}
+
+	path := make([]byte, 0, len(tokens)*20+len(fees)*3)
+	for i, token := range tokens {
+		path = append(path, token.Bytes()...)
+		if i < len(fees) {
+			path = append(path, byte(fees[i]>>16), byte(fees[i]>>8), byte(fees[i]))
+		}
+	}
+	return path
+}
+
+// Routes converts r into the provider agnostic route, one hop per pool.
+func (r UniswapRoute) Routes() []common.Route {
+	hops := make([][]common.RoutePart, 0, len(r.Tokens)-1)
+	for i := 0; i+1 < len(r.Tokens); i++ {
+		protocol := "UNISWAP_V2"
+		if !r.V2 {
+			protocol = fmt.Sprintf("UNISWAP_V3_%d", r.Fees[i])
+		}
+		hops = append(hops, []common.RoutePart{{
+			Protocol:  protocol,
+			Part:      100,
+			FromToken: r.Tokens[i].Hex(),
+			ToToken:   r.Tokens[i+1].Hex(),
+		}})
+	}
+	return []common.Route{{Hops: hops}}
+}
+
+// candidateRoutes lists the routes quoted from tokenIn to tokenOut: direct
+// V3 pools of every fee tier, two hop V3 routes through the wrapped native
+// token and, with a V2 router, the same through V2 pools.
+func candidateRoutes(tokenIn, tokenOut, wrapped ethcommon.Address, v2 bool) []UniswapRoute {
+	var routes []UniswapRoute
+	for _, fee := range V3FeeTiers {
+		routes = append(routes, UniswapRoute{Tokens: []ethcommon.Address{tokenIn, tokenOut}, Fees: []uint32{fee}})
+	}
+
+	hop := tokenIn != wrapped && tokenOut != wrapped
+	if hop {
+		for _, feeIn := range v3HopFeeTiers {
+			for _, feeOut := range v3HopFeeTiers {
+				routes = append(routes, UniswapRoute{Tokens: []ethcommon.Address{tokenIn, wrapped, tokenOut}, Fees: []uint32{feeIn, feeOut}})
+			}
+		}
+	}
+
+	if v2 {
+		routes = append(routes, UniswapRoute{Tokens: []ethcommon.Address{tokenIn, tokenOut}, V2: true})
+		if hop {
+			routes = append(routes, UniswapRoute{Tokens: []ethcommon.Address{tokenIn, wrapped, tokenOut}, V2: true})
+		}
+	}
+	return routes
+}
+
+// bestRoute quotes every candidate route in a single batch and returns the
+// one with the highest output, or the lowest input when exactOut is set.
+// Routes without a pool revert and are skipped.
+func bestRoute(ctx context.Context, client *w3.Client, deployment UniswapDeployment, routes []UniswapRoute, amount *big.Int, exactOut bool) (UniswapQuote, error) {
+	quoter := ethcommon.HexToAddress(deployment.QuoterV2)
+	v2Router := ethcommon.HexToAddress(deployment.V2Router)
+
+	type result struct {
+		amount   big.Int
+		gas      big.Int
+		amounts  []*big.Int
+		sqrt     []*big.Int
+		ticks    []uint32
+		resolved bool
+	}
+
+	results := make([]result, len(routes))
+	calls := make([]w3types.RPCCaller, len(routes))
+	for i, route := range routes {
+		r := &results[i]
+		switch {
+		case route.V2 && exactOut:
+			calls[i] = w3eth.CallFunc(v2Router, funcGetAmountsIn, amount, route.Tokens).Returns(&r.amounts)
+		case route.V2:
+			calls[i] = w3eth.CallFunc(v2Router, funcGetAmountsOut, amount, route.Tokens).Returns(&r.amounts)
+		case exactOut:
+			calls[i] = w3eth.CallFunc(quoter, funcQuoteExactOutput, route.Path(true), amount).Returns(&r.amount, &r.sqrt, &r.ticks, &r.gas)
+		default:
+			calls[i] = w3eth.CallFunc(quoter, funcQuoteExactInput, route.Path(false), amount).Returns(&r.amount, &r.sqrt, &r.ticks, &r.gas)
+		}
+	}
+
+	err := client.CallCtx(ctx, calls...)
+	var errs w3.CallErrors
+	if err != nil && !errors.As(err, &errs) {
+		return UniswapQuote{}, fmt.Errorf("unable to quote uniswap routes, err: %w", err)
+	}
+
+	var best *UniswapQuote
+	for i, route := range routes {
+		if len(errs) > i && errs[i] != nil {
+			continue
+		}
+
+		r := &results[i]
+		quoted, gas := &r.amount, new(big.Int).Add(&r.gas, big.NewInt(routerGasOverhead))
+		if route.V2 {
+			if len(r.amounts) != len(route.Tokens) {
+				continue
+			}
+			quoted = r.amounts[len(r.amounts)-1]
+			if exactOut {
+				quoted = r.amounts[0]
+			}
+			gas = big.NewInt(routerGasOverhead + v2HopGas*int64(len(route.Tokens)-1))
+		}
+
+		if quoted.Sign() == 0 {
+			continue
+		}
+
+		better := best == nil
+		if best != nil && exactOut {
+			better = quoted.Cmp(best.AmountIn) < 0
+		} else if best != nil {
+			better = quoted.Cmp(best.AmountOut) > 0
+		}
+		if !better {
+			continue
+		}
+
+		quote := UniswapQuote{Route: route, AmountIn: amount, AmountOut: quoted, Gas: gas}
+		if exactOut {
+			quote.AmountIn, quote.AmountOut = quoted, amount
+		}
+		best = &quote
+	}
+
+	if best == nil {
+		return UniswapQuote{}, fmt.Errorf("no uniswap pool for the pair, err: %w", common.ErrInsufficientLiquidity)
+	}
+	return *best, nil
+}
+
+func reversed[T any](s []T) []T {
+	out := make([]T, len(s))
+	for i, v := range s {
+		out[len(s)-1-i] = v
+	}
+	return out
+}
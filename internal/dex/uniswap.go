@@ -0,0 +1,505 @@
+package dex
+
+import (
+	"context"
+	"fmt"
+	"math/big"
+	"strconv"
+	"time"
+
+	ethcommon "github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/common/math"
+	"github.com/ethereum/go-ethereum/signer/core/apitypes"
+	"github.com/lmittmann/w3"
+	w3eth "github.com/lmittmann/w3/module/eth"
+	"github.com/onmetahq/go-evm/internal/gas"
+	"github.com/onmetahq/go-evm/internal/http/common"
+)
+
+var (
+	funcExecute = w3.MustNewFunc("execute(bytes commands, bytes[] inputs, uint256 deadline)", "")
+
+	// Universal Router command inputs, the selector is dropped when encoded.
+	inputV3Swap   = w3.MustNewFunc("v3Swap(address recipient, uint256 amount, uint256 limit, bytes path, bool payerIsUser)", "")
+	inputV2Swap   = w3.MustNewFunc("v2Swap(address recipient, uint256 amount, uint256 limit, address[] path, bool payerIsUser)", "")
+	inputTransfer = w3.MustNewFunc("transfer(address token, address recipient, uint256 value)", "")
+	inputWeth     = w3.MustNewFunc("weth(address recipient, uint256 amount)", "")
+	// The PermitSingle tuple only holds static fields, so it is encoded in
+	// place like its flattened fields.
+	inputPermit2Permit = w3.MustNewFunc("permit(address token, uint160 amount, uint48 expiration, uint48 nonce, address spender, uint256 sigDeadline, bytes signature)", "")
+
+	funcPermit2Allowance = w3.MustNewFunc("allowance(address owner, address token, address spender)", "uint160 amount, uint48 expiration, uint48 nonce")
+)
+
+// DefaultDeadline is how long the router accepts a swap after it is built.
+const DefaultDeadline = 20 * time.Minute
+
+type uniswap struct {
+	rpcs        map[uint64]*w3.Client
+	deployments map[uint64]UniswapDeployment
+	deadline    time.Duration
+	oracle      gas.Oracle
+}
+
+type Option func(*uniswap)
+
+// WithDeadline sets how long the router accepts a swap after it is built,
+// DefaultDeadline otherwise.
+func WithDeadline(deadline time.Duration) Option {
+	return func(u *uniswap) {
+		u.deadline = deadline
+	}
+}
+
+// WithGasOracle prices requests without a GasPrice with oracle instead of
+// the eth_gasPrice of rpcs.
+func WithGasOracle(oracle gas.Oracle) Option {
+	return func(u *uniswap) {
+		u.oracle = oracle
+	}
+}
+
+// NewUniswap returns a provider quoting Uniswap pools on chain and swapping
+// through the Universal Router, without any aggregator API. rpcs maps a chain
+// id to the node used for quotes, deployments the chain id to its Uniswap
+// contracts, DefaultUniswapDeployments when nil.
+func NewUniswap(rpcs map[uint64]*w3.Client, deployments map[uint64]UniswapDeployment, opts ...Option) *uniswap {
+	if deployments == nil {
+		deployments = DefaultUniswapDeployments
+	}
+
+	u := &uniswap{
+		rpcs:        rpcs,
+		deployments: deployments,
+		deadline:    DefaultDeadline,
+		oracle:      gas.NodeOracle(rpcs),
+	}
+	for _, opt := range opts {
+		opt(u)
+	}
+	return u
+}
+
+var _ common.Aggregator = (*uniswap)(nil)
+
+func (u *uniswap) FetchSupportedTokens(ctx context.Context, chainId uint64) ([]common.Token, error) {
+	return []common.Token{}, fmt.Errorf("operation token list is not supported by uniswap, err: %w", common.ErrUnsupportedParameter)
+}
+
+func (u *uniswap) FetchExactInQuote(ctx context.Context, req common.QuoteReq) (common.QuoteRes, error) {
+	slippage, err := req.Slippage()
+	if err != nil {
+		return common.QuoteRes{}, err
+	}
+
+	quote, err := u.Quote(ctx, req, false)
+	if err != nil {
+		return common.QuoteRes{}, fmt.Errorf("unable to fetch exact in quote from uniswap, err: %w", err)
+	}
+
+	gasPrice, err := u.gasPrice(ctx, req)
+	if err != nil {
+		return common.QuoteRes{}, err
+	}
+
+	out := common.MinReceived(quote.AmountOut, req.FeeBps)
+	return common.QuoteRes{
+		ChainId:     req.ChainId,
+		Src:         req.Src,
+		Dst:         req.Dst,
+		FromAmount:  quote.AmountIn,
+		ToAmount:    out,
+		Gas:         quote.Gas,
+		GasPrice:    gasPrice,
+		MinToAmount: common.MinReceived(out, slippage),
+		Routes:      quote.Route.Routes(),
+	}, nil
+}
+
+func (u *uniswap) FetchExactOutQuote(ctx context.Context, req common.QuoteReq) (common.QuoteRes, error) {
+	if req.FeeBps > 0 {
+		return common.QuoteRes{}, fmt.Errorf("uniswap exact out swaps do not support fees, err: %w", common.ErrUnsupportedParameter)
+	}
+
+	quote, err := u.Quote(ctx, req, true)
+	if err != nil {
+		return common.QuoteRes{}, fmt.Errorf("unable to fetch exact out quote from uniswap, err: %w", err)
+	}
+
+	gasPrice, err := u.gasPrice(ctx, req)
+	if err != nil {
+		return common.QuoteRes{}, err
+	}
+
+	return common.QuoteRes{
+		ChainId:     req.ChainId,
+		Src:         req.Src,
+		Dst:         req.Dst,
+		FromAmount:  req.Amount,
+		ToAmount:    quote.AmountIn,
+		Gas:         quote.Gas,
+		GasPrice:    gasPrice,
+		MinToAmount: req.Amount,
+		Routes:      quote.Route.Routes(),
+	}, nil
+}
+
+func (u *uniswap) FetchExactInSwapCallData(ctx context.Context, req common.QuoteReq) (common.SwapTx, error) {
+	slippage, err := req.Slippage()
+	if err != nil {
+		return common.SwapTx{}, err
+	}
+
+	if req.FeeBps > 0 && req.Referrer == "" {
+		return common.SwapTx{}, fmt.Errorf("uniswap fee requires a referrer, feeBps: %d", req.FeeBps)
+	}
+
+	quote, err := u.Quote(ctx, req, false)
+	if err != nil {
+		return common.SwapTx{}, fmt.Errorf("unable to fetch exact in swap from uniswap, err: %w", err)
+	}
+
+	gasPrice, err := u.gasPrice(ctx, req)
+	if err != nil {
+		return common.SwapTx{}, err
+	}
+
+	var (
+		srcNative = common.IsNativeToken(req.Src)
+		dstNative = common.IsNativeToken(req.Dst)
+		recipient = recipient(req)
+		tokenOut  = quote.Route.Tokens[len(quote.Route.Tokens)-1]
+		minSwap   = common.MinReceived(quote.AmountOut, slippage)
+		minOut    = common.MinReceived(minSwap, req.FeeBps)
+		cmds      commands
+	)
+
+	if err := cmds.permit2(req); err != nil {
+		return common.SwapTx{}, err
+	}
+	if srcNative {
+		cmds.add(cmdWrapEth, inputWeth, addressThis, quote.AmountIn)
+	}
+
+	swapRecipient := recipient
+	if dstNative || req.FeeBps > 0 {
+		swapRecipient = addressThis
+	}
+	cmds.swap(quote.Route, false, swapRecipient, quote.AmountIn, minSwap, !srcNative)
+
+	if req.FeeBps > 0 {
+		cmds.add(cmdPayPortion, inputTransfer, tokenOut, ethcommon.HexToAddress(req.Referrer), big.NewInt(int64(req.FeeBps)))
+		if !dstNative {
+			cmds.add(cmdSweep, inputTransfer, tokenOut, recipient, minOut)
+		}
+	}
+	if dstNative {
+		cmds.add(cmdUnwrapWeth, inputWeth, recipient, minOut)
+	}
+
+	value := big.NewInt(0)
+	if srcNative {
+		value = quote.AmountIn
+	}
+
+	return u.swapTx(req, quote, cmds, common.SwapTx{
+		FromAmount:  quote.AmountIn,
+		ToAmount:    common.MinReceived(quote.AmountOut, req.FeeBps),
+		MinToAmount: minOut,
+		Value:       value,
+		GasPrice:    gasPrice,
+	})
+}
+
+func (u *uniswap) FetchExactOutSwapCallData(ctx context.Context, req common.QuoteReq) (common.SwapTx, error) {
+	slippage, err := req.Slippage()
+	if err != nil {
+		return common.SwapTx{}, err
+	}
+
+	if req.FeeBps > 0 {
+		return common.SwapTx{}, fmt.Errorf("uniswap exact out swaps do not support fees, err: %w", common.ErrUnsupportedParameter)
+	}
+
+	quote, err := u.Quote(ctx, req, true)
+	if err != nil {
+		return common.SwapTx{}, fmt.Errorf("unable to fetch exact out swap from uniswap, err: %w", err)
+	}
+
+	gasPrice, err := u.gasPrice(ctx, req)
+	if err != nil {
+		return common.SwapTx{}, err
+	}
+
+	var (
+		srcNative   = common.IsNativeToken(req.Src)
+		dstNative   = common.IsNativeToken(req.Dst)
+		recipient   = recipient(req)
+		amountInMax = new(big.Int).Mul(quote.AmountIn, big.NewInt(int64(10_000+slippage)))
+		cmds        commands
+	)
+	amountInMax.Div(amountInMax, big.NewInt(10_000))
+
+	if err := cmds.permit2(req); err != nil {
+		return common.SwapTx{}, err
+	}
+	if srcNative {
+		cmds.add(cmdWrapEth, inputWeth, addressThis, amountInMax)
+	}
+
+	swapRecipient := recipient
+	if dstNative {
+		swapRecipient = addressThis
+	}
+	cmds.swap(quote.Route, true, swapRecipient, quote.AmountOut, amountInMax, !srcNative)
+
+	if dstNative {
+		cmds.add(cmdUnwrapWeth, inputWeth, recipient, quote.AmountOut)
+	}
+	if srcNative {
+		// Refund the wrapped input the swap did not use.
+		cmds.add(cmdUnwrapWeth, inputWeth, msgSender, big.NewInt(0))
+	}
+
+	value := big.NewInt(0)
+	if srcNative {
+		value = amountInMax
+	}
+
+	return u.swapTx(req, quote, cmds, common.SwapTx{
+		FromAmount:  amountInMax,
+		ToAmount:    quote.AmountOut,
+		MinToAmount: quote.AmountOut,
+		Value:       value,
+		GasPrice:    gasPrice,
+	})
+}
+
+// Quote returns the best route for req among direct and wrapped native token
+// routes through V3 and V2 pools, quoted with a single batched call.
+func (u *uniswap) Quote(ctx context.Context, req common.QuoteReq, exactOut bool) (UniswapQuote, error) {
+	if req.Amount == nil || req.Amount.Sign() <= 0 {
+		return UniswapQuote{}, fmt.Errorf("invalid amount: %v", req.Amount)
+	}
+
+	client, deployment, err := u.chain(req.ChainId)
+	if err != nil {
+		return UniswapQuote{}, err
+	}
+
+	wrapped := ethcommon.HexToAddress(common.WrappedNativeTokens[req.ChainId])
+	tokenIn, tokenOut := poolToken(req.Src, wrapped), poolToken(req.Dst, wrapped)
+	if tokenIn == tokenOut {
+		return UniswapQuote{}, fmt.Errorf("uniswap cannot swap a token to itself, src: %s, dst: %s, err: %w", req.Src, req.Dst, common.ErrUnsupportedToken)
+	}
+
+	routes := candidateRoutes(tokenIn, tokenOut, wrapped, deployment.V2Router != "")
+	return bestRoute(ctx, client, deployment, routes, req.Amount, exactOut)
+}
+
+// FetchPermit2Permit returns an unsigned permit letting the Universal Router
+// pull amount of token from owner through Permit2 until expiration, using the
+// owner's current Permit2 nonce.
+func (u *uniswap) FetchPermit2Permit(ctx context.Context, chainId uint64, owner, token string, amount *big.Int, expiration time.Time) (Permit2Permit, error) {
+	client, deployment, err := u.chain(chainId)
+	if err != nil {
+		return Permit2Permit{}, err
+	}
+
+	var allowed, expires, nonce big.Int
+	if err := client.CallCtx(ctx,
+		w3eth.CallFunc(ethcommon.HexToAddress(Permit2Address), funcPermit2Allowance,
+			ethcommon.HexToAddress(owner), ethcommon.HexToAddress(token),
+			ethcommon.HexToAddress(deployment.UniversalRouter)).Returns(&allowed, &expires, &nonce),
+	); err != nil {
+		return Permit2Permit{}, fmt.Errorf("unable to fetch permit2 nonce, err: %w", err)
+	}
+
+	return Permit2Permit{
+		Token:       token,
+		Amount:      amount,
+		Expiration:  uint64(expiration.Unix()),
+		Nonce:       nonce.Uint64(),
+		Spender:     deployment.UniversalRouter,
+		SigDeadline: big.NewInt(time.Now().Add(u.deadline).Unix()),
+	}, nil
+}
+
+// Permit2TypedData returns the EIP-712 PermitSingle payload of permit to
+// sign.
+func Permit2TypedData(chainId uint64, permit Permit2Permit) apitypes.TypedData {
+	return apitypes.TypedData{
+		Types: apitypes.Types{
+			"EIP712Domain": {
+				{Name: "name", Type: "string"},
+				{Name: "chainId", Type: "uint256"},
+				{Name: "verifyingContract", Type: "address"},
+			},
+			"PermitSingle": {
+				{Name: "details", Type: "PermitDetails"},
+				{Name: "spender", Type: "address"},
+				{Name: "sigDeadline", Type: "uint256"},
+			},
+			"PermitDetails": {
+				{Name: "token", Type: "address"},
+				{Name: "amount", Type: "uint160"},
+				{Name: "expiration", Type: "uint48"},
+				{Name: "nonce", Type: "uint48"},
+			},
+		},
+		PrimaryType: "PermitSingle",
+		Domain: apitypes.TypedDataDomain{
+			Name:              "Permit2",
+			ChainId:           math.NewHexOrDecimal256(int64(chainId)),
+			VerifyingContract: Permit2Address,
+		},
+		Message: apitypes.TypedDataMessage{
+			"details": map[string]any{
+				"token":      permit.Token,
+				"amount":     permit.Amount.String(),
+				"expiration": strconv.FormatUint(permit.Expiration, 10),
+				"nonce":      strconv.FormatUint(permit.Nonce, 10),
+			},
+			"spender":     permit.Spender,
+			"sigDeadline": permit.SigDeadline.String(),
+		},
+	}
+}
+
+// SignPermit2Permit returns permit with the signature of signer, which must
+// be the token owner.
+func SignPermit2Permit(ctx context.Context, signer common.Signer, chainId uint64, permit Permit2Permit) (Permit2Permit, error) {
+	signature, err := signer.SignTypedData(ctx, Permit2TypedData(chainId, permit))
+	if err != nil {
+		return Permit2Permit{}, fmt.Errorf("unable to sign permit2 permit, err: %w", err)
+	}
+	permit.Signature = signature
+	return permit, nil
+}
+
+func (u *uniswap) swapTx(req common.QuoteReq, quote UniswapQuote, cmds commands, tx common.SwapTx) (common.SwapTx, error) {
+	if cmds.err != nil {
+		return common.SwapTx{}, cmds.err
+	}
+
+	deadline := big.NewInt(time.Now().Add(u.deadline).Unix())
+	data, err := funcExecute.EncodeArgs(cmds.commands, cmds.inputs, deadline)
+	if err != nil {
+		return common.SwapTx{}, fmt.Errorf("unable to encode uniswap execute, err: %w", err)
+	}
+
+	tx.ChainId = req.ChainId
+	tx.Src = req.Src
+	tx.Dst = req.Dst
+	tx.From = req.From
+	tx.To = u.deployments[req.ChainId].UniversalRouter
+	tx.Data = hexutil.Encode(data)
+	tx.Gas = quote.Gas
+	tx.AllowanceTarget = Permit2Address
+	tx.Routes = quote.Route.Routes()
+	return tx, nil
+}
+
+func (u *uniswap) chain(chainId uint64) (*w3.Client, UniswapDeployment, error) {
+	deployment, ok := u.deployments[chainId]
+	if !ok {
+		return nil, UniswapDeployment{}, fmt.Errorf("no uniswap deployment on chainId %d, err: %w", chainId, common.ErrUnsupportedChain)
+	}
+
+	if _, ok := common.WrappedNativeTokens[chainId]; !ok {
+		return nil, UniswapDeployment{}, fmt.Errorf("no wrapped native token on chainId %d, err: %w", chainId, common.ErrUnsupportedChain)
+	}
+
+	client, ok := u.rpcs[chainId]
+	if !ok {
+		return nil, UniswapDeployment{}, fmt.Errorf("no rpc to quote uniswap on chainId %d, err: %w", chainId, common.ErrUnsupportedChain)
+	}
+	return client, deployment, nil
+}
+
+// gasPrice returns the gas price of req or the one of the gas oracle.
+func (u *uniswap) gasPrice(ctx context.Context, req common.QuoteReq) (*big.Int, error) {
+	if req.GasPrice != nil {
+		return req.GasPrice, nil
+	}
+
+	gasPrice, err := u.oracle.GasPrice(ctx, req.ChainId)
+	if err != nil {
+		return nil, fmt.Errorf("unable to estimate uniswap gas price, err: %w", err)
+	}
+	return gasPrice, nil
+}
+
+// token returns the pool token of an aggregator token address, the wrapped
+// native token for the native placeholder.
+func poolToken(token string, wrapped ethcommon.Address) ethcommon.Address {
+	if common.IsNativeToken(token) {
+		return wrapped
+	}
+	return ethcommon.HexToAddress(token)
+}
+
+func recipient(req common.QuoteReq) ethcommon.Address {
+	if req.Receiver != "" {
+		return ethcommon.HexToAddress(req.Receiver)
+	}
+	return msgSender
+}
+
+// commands accumulates the commands and inputs of an execute call.
+// The first encoding error is kept and returned by swapTx.
+type commands struct {
+	commands []byte
+	inputs   [][]byte
+	err      error
+}
+
+func (c *commands) add(command byte, input *w3.Func, args ...any) {
+	encoded, err := input.EncodeArgs(args...)
+	if err != nil {
+		if c.err == nil {
+			c.err = fmt.Errorf("unable to encode uniswap command %#x, err: %w", command, err)
+		}
+		return
+	}
+	c.commands = append(c.commands, command)
+	c.inputs = append(c.inputs, encoded[4:])
+}
+
+func (c *commands) swap(route UniswapRoute, exactOut bool, recipient ethcommon.Address, amount, limit *big.Int, payerIsUser bool) {
+	switch {
+	case route.V2 && exactOut:
+		c.add(cmdV2SwapExactOut, inputV2Swap, recipient, amount, limit, route.Tokens, payerIsUser)
+	case route.V2:
+		c.add(cmdV2SwapExactIn, inputV2Swap, recipient, amount, limit, route.Tokens, payerIsUser)
+	case exactOut:
+		c.add(cmdV3SwapExactOut, inputV3Swap, recipient, amount, limit, route.Path(true), payerIsUser)
+	default:
+		c.add(cmdV3SwapExactIn, inputV3Swap, recipient, amount, limit, route.Path(false), payerIsUser)
+	}
+}
+
+// permit2 adds the PERMIT2_PERMIT command of req when it carries a signed
+// permit.
+func (c *commands) permit2(req common.QuoteReq) error {
+	opts, err := common.OptionsOf[UniswapOptions](req)
+	if err != nil {
+		return err
+	}
+
+	if opts.Permit2 == nil {
+		return nil
+	}
+
+	permit := opts.Permit2
+	if len(permit.Signature) == 0 || permit.Amount == nil || permit.SigDeadline == nil {
+		return fmt.Errorf("invalid permit2 permit, signature, amount and sigDeadline are required")
+	}
+
+	c.add(cmdPermit2Permit, inputPermit2Permit,
+		ethcommon.HexToAddress(permit.Token), permit.Amount,
+		new(big.Int).SetUint64(permit.Expiration), new(big.Int).SetUint64(permit.Nonce),
+		ethcommon.HexToAddress(permit.Spender), permit.SigDeadline, permit.Signature)
+	return nil
+}
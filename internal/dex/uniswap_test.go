@@ -0,0 +1,431 @@
+package dex
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/big"
+	"testing"
+	"time"
+
+	ethcommon "github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/signer/core/apitypes"
+	"github.com/lmittmann/w3"
+	"github.com/onmetahq/go-evm/internal/http/common"
+	"github.com/onmetahq/go-evm/internal/http/fake"
+)
+
+const (
+	NATIVE = "0xeeeeeeeeeeeeeeeeeeeeeeeeeeeeeeeeeeeeeeee"
+	USDC   = "0x2791bca1f2de4661ed88a30c99a7a9449aa84174"
+	USDT   = "0xc2132D05D31c914a87C6611C10748AEb04B58e8F"
+	FROM   = "0x15Ba05723b04785C3E21157171810892A4FB795c"
+)
+
+// pools maps the pool of a hop, "v2" or the V3 fee tier, to the output per
+// unit of input, hops without a pool revert.
+var pools = map[string]int64{
+	"500":  2,
+	"3000": 1,
+	"v2":   1,
+}
+
+func newNode(t *testing.T) *w3.Client {
+	node := fake.NewRPC()
+	t.Cleanup(node.Close)
+
+	node.Handle("eth_chainId", func(params []json.RawMessage) (any, error) {
+		return "0x89", nil
+	})
+	node.Handle("eth_gasPrice", func(params []json.RawMessage) (any, error) {
+		return "0x6fc23ac00", nil
+	})
+	node.Handle("eth_call", func(params []json.RawMessage) (any, error) {
+		var msg struct {
+			Input hexutil.Bytes `json:"input"`
+			Data  hexutil.Bytes `json:"data"`
+		}
+		if err := json.Unmarshal(params[0], &msg); err != nil {
+			return nil, err
+		}
+
+		input := msg.Input
+		if len(input) == 0 {
+			input = msg.Data
+		}
+		return quote(input)
+	})
+
+	client, err := w3.Dial(node.URL)
+	if err != nil {
+		t.Fatalf("dial err: %v", err)
+	}
+	t.Cleanup(func() { client.Close() })
+	return client
+}
+
+// quote answers the quoter and V2 router calls with the rates of pools,
+// routes through the wrapped native token revert.
+func quote(input []byte) (any, error) {
+	var (
+		amount big.Int
+		path   []byte
+		tokens []ethcommon.Address
+		out    []byte
+		err    error
+	)
+
+	revert := &fake.RevertError{Message: "execution reverted"}
+	switch {
+	case funcQuoteExactInput.DecodeArgs(input, &path, &amount) == nil:
+		rate, ok := pools[fmt.Sprint(uint32(path[20])<<16|uint32(path[21])<<8|uint32(path[22]))]
+		if !ok || len(path) != 43 {
+			return nil, revert
+		}
+		out, err = funcQuoteExactInput.Returns.Pack(new(big.Int).Mul(&amount, big.NewInt(rate)), []*big.Int{big.NewInt(0)}, []uint32{1}, big.NewInt(100_000))
+	case funcQuoteExactOutput.DecodeArgs(input, &path, &amount) == nil:
+		rate, ok := pools[fmt.Sprint(uint32(path[20])<<16|uint32(path[21])<<8|uint32(path[22]))]
+		if !ok || len(path) != 43 {
+			return nil, revert
+		}
+		out, err = funcQuoteExactOutput.Returns.Pack(new(big.Int).Div(&amount, big.NewInt(rate)), []*big.Int{big.NewInt(0)}, []uint32{1}, big.NewInt(100_000))
+	case funcGetAmountsOut.DecodeArgs(input, &amount, &tokens) == nil:
+		if len(tokens) != 2 {
+			return nil, revert
+		}
+		out, err = funcGetAmountsOut.Returns.Pack([]*big.Int{&amount, new(big.Int).Mul(&amount, big.NewInt(pools["v2"]))})
+	case funcGetAmountsIn.DecodeArgs(input, &amount, &tokens) == nil:
+		if len(tokens) != 2 {
+			return nil, revert
+		}
+		out, err = funcGetAmountsIn.Returns.Pack([]*big.Int{new(big.Int).Div(&amount, big.NewInt(pools["v2"])), &amount})
+	case funcPermit2Allowance.DecodeArgs(input, new(ethcommon.Address), new(ethcommon.Address), new(ethcommon.Address)) == nil:
+		out, err = funcPermit2Allowance.Returns.Pack(big.NewInt(0), big.NewInt(0), big.NewInt(7))
+	default:
+		return nil, fmt.Errorf("unexpected call %x", input[:4])
+	}
+	if err != nil {
+		return nil, err
+	}
+	return hexutil.Encode(out), nil
+}
+
+func newUniswap(t *testing.T) *uniswap {
+	return NewUniswap(map[uint64]*w3.Client{137: newNode(t)}, nil)
+}
+
+// decodeExecute returns the commands and inputs of an execute calldata.
+func decodeExecute(t *testing.T, data string) ([]byte, [][]byte) {
+	input, err := hexutil.Decode(data)
+	if err != nil {
+		t.Fatalf("decode calldata err: %v", err)
+	}
+
+	var (
+		commands []byte
+		inputs   [][]byte
+		deadline big.Int
+	)
+	if err := funcExecute.DecodeArgs(input, &commands, &inputs, &deadline); err != nil {
+		t.Fatalf("decode execute err: %v", err)
+	}
+
+	if deadline.Int64() <= time.Now().Unix() {
+		t.Fatalf("deadline in the past: %s", &deadline)
+	}
+	return commands, inputs
+}
+
+func decodeInput(t *testing.T, fn *w3.Func, input []byte, args ...any) {
+	if err := fn.DecodeArgs(append(fn.Selector[:], input...), args...); err != nil {
+		t.Fatalf("decode %s err: %v", fn.Signature, err)
+	}
+}
+
+func TestExactInQuote(t *testing.T) {
+	u := newUniswap(t)
+
+	res, err := u.FetchExactInQuote(context.Background(), common.QuoteReq{
+		ChainId:     137,
+		Src:         USDC,
+		Dst:         USDT,
+		Amount:      big.NewInt(1_000_000),
+		SlippageBps: common.Bps(100),
+	})
+	if err != nil {
+		t.Fatalf("quote err: %v", err)
+	}
+
+	if res.ToAmount.Cmp(big.NewInt(2_000_000)) != 0 || res.MinToAmount.Cmp(big.NewInt(1_980_000)) != 0 {
+		t.Fatalf("unexpected amounts, to: %s, min: %s", res.ToAmount, res.MinToAmount)
+	}
+
+	if res.Gas.Cmp(big.NewInt(100_000+routerGasOverhead)) != 0 || res.GasPrice.Cmp(big.NewInt(30_000_000_000)) != 0 {
+		t.Fatalf("unexpected gas, gas: %s, gasPrice: %s", res.Gas, res.GasPrice)
+	}
+
+	if len(res.Routes) != 1 || len(res.Routes[0].Hops) != 1 || res.Routes[0].Hops[0][0].Protocol != "UNISWAP_V3_500" {
+		t.Fatalf("unexpected routes: %+v", res.Routes)
+	}
+}
+
+func TestExactOutQuote(t *testing.T) {
+	u := newUniswap(t)
+
+	req := common.QuoteReq{
+		ChainId: 137,
+		Src:     USDC,
+		Dst:     USDT,
+		Amount:  big.NewInt(2_000_000),
+	}
+	res, err := u.FetchExactOutQuote(context.Background(), req)
+	if err != nil {
+		t.Fatalf("quote err: %v", err)
+	}
+
+	if res.FromAmount.Cmp(big.NewInt(2_000_000)) != 0 || res.ToAmount.Cmp(big.NewInt(1_000_000)) != 0 {
+		t.Fatalf("unexpected amounts, from: %s, to: %s", res.FromAmount, res.ToAmount)
+	}
+
+	req.FeeBps = 10
+	if _, err := u.FetchExactOutQuote(context.Background(), req); !errors.Is(err, common.ErrUnsupportedParameter) {
+		t.Fatalf("expected exact out fees to be unsupported, err: %v", err)
+	}
+
+	if _, err := u.FetchSupportedTokens(context.Background(), 137); !errors.Is(err, common.ErrUnsupportedParameter) {
+		t.Fatalf("expected the token list to be unsupported, err: %v", err)
+	}
+}
+
+func TestNoPool(t *testing.T) {
+	defer func(p map[string]int64) { pools = p }(pools)
+	pools = map[string]int64{}
+
+	_, err := newUniswap(t).FetchExactInQuote(context.Background(), common.QuoteReq{
+		ChainId: 137,
+		Src:     USDC,
+		Dst:     USDT,
+		Amount:  big.NewInt(1_000_000),
+	})
+	if !errors.Is(err, common.ErrInsufficientLiquidity) {
+		t.Fatalf("expected insufficient liquidity, err: %v", err)
+	}
+}
+
+func TestV2Route(t *testing.T) {
+	defer func(p map[string]int64) { pools = p }(pools)
+	pools = map[string]int64{"v2": 3}
+
+	tx, err := newUniswap(t).FetchExactInSwapCallData(context.Background(), common.QuoteReq{
+		ChainId: 137,
+		Src:     USDC,
+		Dst:     USDT,
+		Amount:  big.NewInt(1_000_000),
+		From:    FROM,
+	})
+	if err != nil {
+		t.Fatalf("swap err: %v", err)
+	}
+
+	commands, inputs := decodeExecute(t, tx.Data)
+	if string(commands) != string([]byte{cmdV2SwapExactIn}) {
+		t.Fatalf("unexpected commands: %x", commands)
+	}
+
+	var (
+		recipient   ethcommon.Address
+		amount      big.Int
+		min         big.Int
+		path        []ethcommon.Address
+		payerIsUser bool
+	)
+	decodeInput(t, inputV2Swap, inputs[0], &recipient, &amount, &min, &path, &payerIsUser)
+	if recipient != msgSender || min.Cmp(big.NewInt(2_970_000)) != 0 || len(path) != 2 || !payerIsUser {
+		t.Fatalf("unexpected v2 swap, recipient: %s, min: %s, path: %v", recipient, &min, path)
+	}
+
+	if tx.Gas.Cmp(big.NewInt(routerGasOverhead+v2HopGas)) != 0 || tx.Routes[0].Hops[0][0].Protocol != "UNISWAP_V2" {
+		t.Fatalf("unexpected gas or routes, gas: %s, routes: %+v", tx.Gas, tx.Routes)
+	}
+}
+
+func TestExactInSwapNativeIn(t *testing.T) {
+	tx, err := newUniswap(t).FetchExactInSwapCallData(context.Background(), common.QuoteReq{
+		ChainId:     137,
+		Src:         NATIVE,
+		Dst:         USDC,
+		Amount:      big.NewInt(1_000_000),
+		From:        FROM,
+		SlippageBps: common.Bps(50),
+	})
+	if err != nil {
+		t.Fatalf("swap err: %v", err)
+	}
+
+	if tx.To != DefaultUniswapDeployments[137].UniversalRouter || tx.AllowanceTarget != Permit2Address || tx.Value.Cmp(big.NewInt(1_000_000)) != 0 {
+		t.Fatalf("unexpected tx, to: %s, allowanceTarget: %s, value: %s", tx.To, tx.AllowanceTarget, tx.Value)
+	}
+
+	commands, inputs := decodeExecute(t, tx.Data)
+	if string(commands) != string([]byte{cmdWrapEth, cmdV3SwapExactIn}) {
+		t.Fatalf("unexpected commands: %x", commands)
+	}
+
+	var (
+		recipient   ethcommon.Address
+		amount      big.Int
+		min         big.Int
+		path        []byte
+		payerIsUser bool
+	)
+	decodeInput(t, inputWeth, inputs[0], &recipient, &amount)
+	if recipient != addressThis || amount.Cmp(big.NewInt(1_000_000)) != 0 {
+		t.Fatalf("unexpected wrap, recipient: %s, amount: %s", recipient, &amount)
+	}
+
+	decodeInput(t, inputV3Swap, inputs[1], &recipient, &amount, &min, &path, &payerIsUser)
+	wmatic := ethcommon.HexToAddress(common.WrappedNativeTokens[137])
+	want := UniswapRoute{Tokens: []ethcommon.Address{wmatic, ethcommon.HexToAddress(USDC)}, Fees: []uint32{500}}.Path(false)
+	if recipient != msgSender || min.Cmp(big.NewInt(1_990_000)) != 0 || string(path) != string(want) || payerIsUser {
+		t.Fatalf("unexpected swap, recipient: %s, min: %s, path: %x, payerIsUser: %v", recipient, &min, path, payerIsUser)
+	}
+}
+
+func TestExactInSwapFee(t *testing.T) {
+	referrer := "0x1111111111111111111111111111111111111111"
+	tx, err := newUniswap(t).FetchExactInSwapCallData(context.Background(), common.QuoteReq{
+		ChainId:  137,
+		Src:      USDC,
+		Dst:      NATIVE,
+		Amount:   big.NewInt(1_000_000),
+		From:     FROM,
+		Receiver: USDT,
+		Referrer: referrer,
+		FeeBps:   100,
+	})
+	if err != nil {
+		t.Fatalf("swap err: %v", err)
+	}
+
+	commands, inputs := decodeExecute(t, tx.Data)
+	if string(commands) != string([]byte{cmdV3SwapExactIn, cmdPayPortion, cmdUnwrapWeth}) {
+		t.Fatalf("unexpected commands: %x", commands)
+	}
+
+	var (
+		token, recipient ethcommon.Address
+		bips, min        big.Int
+	)
+	decodeInput(t, inputTransfer, inputs[1], &token, &recipient, &bips)
+	if token != ethcommon.HexToAddress(common.WrappedNativeTokens[137]) || recipient != ethcommon.HexToAddress(referrer) || bips.Int64() != 100 {
+		t.Fatalf("unexpected fee, token: %s, recipient: %s, bips: %s", token, recipient, &bips)
+	}
+
+	decodeInput(t, inputWeth, inputs[2], &recipient, &min)
+	if recipient != ethcommon.HexToAddress(USDT) || min.Cmp(tx.MinToAmount) != 0 || min.Cmp(big.NewInt(1_960_200)) != 0 {
+		t.Fatalf("unexpected unwrap, recipient: %s, min: %s", recipient, &min)
+	}
+}
+
+func TestExactOutSwapNativeIn(t *testing.T) {
+	tx, err := newUniswap(t).FetchExactOutSwapCallData(context.Background(), common.QuoteReq{
+		ChainId:     137,
+		Src:         NATIVE,
+		Dst:         USDC,
+		Amount:      big.NewInt(2_000_000),
+		From:        FROM,
+		SlippageBps: common.Bps(100),
+	})
+	if err != nil {
+		t.Fatalf("swap err: %v", err)
+	}
+
+	if tx.FromAmount.Cmp(big.NewInt(1_010_000)) != 0 || tx.Value.Cmp(tx.FromAmount) != 0 {
+		t.Fatalf("unexpected amounts, from: %s, value: %s", tx.FromAmount, tx.Value)
+	}
+
+	commands, inputs := decodeExecute(t, tx.Data)
+	if string(commands) != string([]byte{cmdWrapEth, cmdV3SwapExactOut, cmdUnwrapWeth}) {
+		t.Fatalf("unexpected commands: %x", commands)
+	}
+
+	var (
+		recipient   ethcommon.Address
+		amount, max big.Int
+		path        []byte
+		payerIsUser bool
+	)
+	decodeInput(t, inputV3Swap, inputs[1], &recipient, &amount, &max, &path, &payerIsUser)
+	if amount.Cmp(big.NewInt(2_000_000)) != 0 || max.Cmp(big.NewInt(1_010_000)) != 0 || ethcommon.BytesToAddress(path[:20]) != ethcommon.HexToAddress(USDC) {
+		t.Fatalf("unexpected swap, amount: %s, max: %s, path: %x", &amount, &max, path)
+	}
+
+	decodeInput(t, inputWeth, inputs[2], &recipient, &amount)
+	if recipient != msgSender || amount.Sign() != 0 {
+		t.Fatalf("unexpected refund, recipient: %s, amount: %s", recipient, &amount)
+	}
+}
+
+func TestPermit2(t *testing.T) {
+	key, err := crypto.GenerateKey()
+	if err != nil {
+		t.Fatalf("generate key err: %v", err)
+	}
+	signer := common.PrivateKeySigner(key)
+
+	u := newUniswap(t)
+	permit, err := u.FetchPermit2Permit(context.Background(), 137, signer.Address(), USDC, big.NewInt(1_000_000), time.Now().Add(time.Hour))
+	if err != nil {
+		t.Fatalf("fetch permit err: %v", err)
+	}
+
+	if permit.Nonce != 7 || permit.Spender != DefaultUniswapDeployments[137].UniversalRouter {
+		t.Fatalf("unexpected permit: %+v", permit)
+	}
+
+	permit, err = SignPermit2Permit(context.Background(), signer, 137, permit)
+	if err != nil {
+		t.Fatalf("sign permit err: %v", err)
+	}
+
+	hash, _, err := apitypes.TypedDataAndHash(Permit2TypedData(137, permit))
+	if err != nil {
+		t.Fatalf("hash permit err: %v", err)
+	}
+	sig := append([]byte{}, permit.Signature...)
+	sig[64] -= 27
+	pub, err := crypto.SigToPub(hash, sig)
+	if err != nil || crypto.PubkeyToAddress(*pub).Hex() != signer.Address() {
+		t.Fatalf("permit not signed by owner, err: %v", err)
+	}
+
+	tx, err := u.FetchExactInSwapCallData(context.Background(), common.QuoteReq{
+		ChainId: 137,
+		Src:     USDC,
+		Dst:     USDT,
+		Amount:  big.NewInt(1_000_000),
+		From:    signer.Address(),
+		Options: []common.ProviderOptions{UniswapOptions{Permit2: &permit}},
+	})
+	if err != nil {
+		t.Fatalf("swap err: %v", err)
+	}
+
+	commands, inputs := decodeExecute(t, tx.Data)
+	if string(commands) != string([]byte{cmdPermit2Permit, cmdV3SwapExactIn}) {
+		t.Fatalf("unexpected commands: %x", commands)
+	}
+
+	var (
+		token, spender        ethcommon.Address
+		amount, exp, nonce, d big.Int
+		signature             []byte
+	)
+	decodeInput(t, inputPermit2Permit, inputs[0], &token, &amount, &exp, &nonce, &spender, &d, &signature)
+	if token != ethcommon.HexToAddress(USDC) || nonce.Int64() != 7 || string(signature) != string(permit.Signature) {
+		t.Fatalf("unexpected permit input, token: %s, nonce: %s", token, &nonce)
+	}
+}
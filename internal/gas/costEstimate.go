@@ -0,0 +1,274 @@
+package gas
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math/big"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/lmittmann/w3"
+	w3eth "github.com/lmittmann/w3/module/eth"
+	"github.com/lmittmann/w3/w3types"
+)
+
+var (
+	// GasPriceOracleAddress is the OP Stack predeploy pricing the L1 data fee.
+	GasPriceOracleAddress = common.HexToAddress("0x420000000000000000000000000000000000000F")
+	// NodeInterfaceAddress is the Arbitrum virtual contract estimating the L1
+	// component of a transaction, it only answers eth_call.
+	NodeInterfaceAddress = common.HexToAddress("0x00000000000000000000000000000000000000C8")
+)
+
+// OPStackChains and ArbitrumChains list the chains whose L1 data fee is
+// added by CostEstimate.
+var (
+	OPStackChains = map[uint64]bool{
+		10:       true,
+		8453:     true,
+		34443:    true,
+		7777777:  true,
+		11155420: true,
+		84532:    true,
+	}
+	ArbitrumChains = map[uint64]bool{
+		42161:  true,
+		42170:  true,
+		421614: true,
+	}
+)
+
+var (
+	funcGetL1Fee              = w3.MustNewFunc("getL1Fee(bytes)", "uint256")
+	funcIsEcotone             = w3.MustNewFunc("isEcotone()", "bool")
+	funcIsFjord               = w3.MustNewFunc("isFjord()", "bool")
+	funcL1BaseFee             = w3.MustNewFunc("l1BaseFee()", "uint256")
+	funcBlobBaseFee           = w3.MustNewFunc("blobBaseFee()", "uint256")
+	funcBaseFeeScalar         = w3.MustNewFunc("baseFeeScalar()", "uint32")
+	funcBlobBaseFeeScalar     = w3.MustNewFunc("blobBaseFeeScalar()", "uint32")
+	funcGasEstimateComponents = w3.MustNewFunc("gasEstimateComponents(address to, bool contractCreation, bytes data)",
+		"uint64 gasEstimate, uint64 gasEstimateForL1, uint256 baseFee, uint256 l1BaseFeeEstimate")
+)
+
+// CostReq is the transaction priced by CostEstimate.
+type CostReq struct {
+	ChainId uint64
+	From    string
+	To      string
+	Data    []byte
+	Value   *big.Int
+	// Gas is the L2 execution gas, estimated by the node when zero.
+	Gas uint64
+	// GasPrice is the L2 gas price, eth_gasPrice of the node when nil.
+	GasPrice *big.Int
+}
+
+// Cost is the total cost in wei of a transaction, the L2 execution fee plus
+// the L1 data fee charged by rollups.
+type Cost struct {
+	L2Gas      uint64
+	L2GasPrice *big.Int
+	// L2Fee is L2Gas × L2GasPrice.
+	L2Fee *big.Int
+	// L1Fee is the L1 data fee, zero on other chains.
+	L1Fee *big.Int
+	Total *big.Int
+	// L1BaseFee is the L1 base fee the data fee is priced with.
+	L1BaseFee *big.Int
+	// OPStack is set when L1Fee comes from the GasPriceOracle.
+	OPStack *OPStackFee
+	// ArbitrumL1Gas is L1Fee expressed in L2 gas, as returned by the
+	// NodeInterface.
+	ArbitrumL1Gas uint64
+}
+
+// OPStackFee holds the GasPriceOracle fields of the L1 data fee, the blob
+// fields are zero before Ecotone.
+type OPStackFee struct {
+	Ecotone           bool
+	Fjord             bool
+	BlobBaseFee       *big.Int
+	BaseFeeScalar     uint32
+	BlobBaseFeeScalar uint32
+}
+
+// CostEstimate returns the cost of req, adding the L1 data fee read from the
+// GasPriceOracle on OP Stack chains and from the NodeInterface on Arbitrum.
+// The L2 gas and gas price are requested in the same batch when unset.
+func CostEstimate(ctx context.Context, client *w3.Client, req CostReq) (Cost, error) {
+	if req.Value == nil {
+		req.Value = big.NewInt(0)
+	}
+	if req.To == "" {
+		return Cost{}, fmt.Errorf("cost estimate needs a recipient")
+	}
+
+	if ArbitrumChains[req.ChainId] {
+		return arbitrumCost(ctx, client, req)
+	}
+
+	var (
+		gas      = req.Gas
+		gasPrice big.Int
+		calls    []w3types.RPCCaller
+	)
+	if gas == 0 {
+		calls = append(calls, w3eth.EstimateGas(req.message(), nil).Returns(&gas))
+	}
+	if req.GasPrice == nil {
+		calls = append(calls, w3eth.GasPrice().Returns(&gasPrice))
+	} else {
+		gasPrice.Set(req.GasPrice)
+	}
+
+	var op *opStackCalls
+	if OPStackChains[req.ChainId] {
+		tx, err := unsignedTx(req, gas, &gasPrice)
+		if err != nil {
+			return Cost{}, err
+		}
+		op = &opStackCalls{tx: tx}
+		calls = append(calls, op.calls()...)
+	}
+
+	if len(calls) > 0 {
+		err := client.CallCtx(ctx, calls...)
+		var errs w3.CallErrors
+		if err != nil && !errors.As(err, &errs) {
+			return Cost{}, fmt.Errorf("failed RPC request: %s", err)
+		}
+
+		// Only the OP Stack fields introduced by upgrades may fail.
+		optional := 0
+		if op != nil {
+			optional = opStackOptional
+		}
+		for i, err := range errs {
+			if err != nil && i < len(calls)-optional {
+				return Cost{}, fmt.Errorf("failed to estimate cost: %s", err)
+			}
+		}
+	}
+
+	cost := Cost{
+		L2Gas:      gas,
+		L2GasPrice: &gasPrice,
+		L2Fee:      new(big.Int).Mul(new(big.Int).SetUint64(gas), &gasPrice),
+		L1Fee:      big.NewInt(0),
+		L1BaseFee:  big.NewInt(0),
+	}
+	if op != nil {
+		cost.L1Fee, cost.L1BaseFee, cost.OPStack = &op.l1Fee, &op.l1BaseFee, op.fee()
+	}
+	cost.Total = new(big.Int).Add(cost.L2Fee, cost.L1Fee)
+	return cost, nil
+}
+
+func arbitrumCost(ctx context.Context, client *w3.Client, req CostReq) (Cost, error) {
+	var output []byte
+	msg := req.message()
+	msg.To, msg.Input = &NodeInterfaceAddress, nil
+	msg.Func, msg.Args = funcGasEstimateComponents, []any{common.HexToAddress(req.To), false, req.Data}
+
+	if err := client.CallCtx(ctx, w3eth.Call(msg, nil, nil).Returns(&output)); err != nil {
+		return Cost{}, fmt.Errorf("failed to estimate arbitrum gas components: %s", err)
+	}
+
+	var (
+		gasEstimate, l1Gas uint64
+		baseFee, l1BaseFee big.Int
+	)
+	if err := funcGasEstimateComponents.DecodeReturns(output, &gasEstimate, &l1Gas, &baseFee, &l1BaseFee); err != nil {
+		return Cost{}, fmt.Errorf("failed to decode arbitrum gas components: %s", err)
+	}
+
+	// The estimate includes the L1 component, without L2 gas left over the
+	// cost would only cover the L1 data.
+	l2Gas := req.Gas
+	if l2Gas == 0 {
+		if gasEstimate <= l1Gas {
+			return Cost{}, fmt.Errorf("invalid arbitrum gas estimate %d, not above the l1 gas %d", gasEstimate, l1Gas)
+		}
+		l2Gas = gasEstimate - l1Gas
+	}
+	gasPrice := new(big.Int).Set(&baseFee)
+	if req.GasPrice != nil {
+		gasPrice.Set(req.GasPrice)
+	}
+
+	// The L1 component is quoted in L2 gas at the L2 base fee.
+	l1Fee := new(big.Int).Mul(new(big.Int).SetUint64(l1Gas), &baseFee)
+	l2Fee := new(big.Int).Mul(new(big.Int).SetUint64(l2Gas), gasPrice)
+	return Cost{
+		L2Gas:         l2Gas,
+		L2GasPrice:    gasPrice,
+		L2Fee:         l2Fee,
+		L1Fee:         l1Fee,
+		Total:         new(big.Int).Add(l2Fee, l1Fee),
+		L1BaseFee:     &l1BaseFee,
+		ArbitrumL1Gas: l1Gas,
+	}, nil
+}
+
+// opStackOptional is the number of GasPriceOracle getters missing before
+// Ecotone or Fjord, they are requested last so their failures can be
+// ignored.
+const opStackOptional = 5
+
+type opStackCalls struct {
+	tx                []byte
+	l1Fee, l1BaseFee  big.Int
+	ecotone, fjord    bool
+	blobBaseFee       big.Int
+	baseFeeScalar     uint32
+	blobBaseFeeScalar uint32
+}
+
+func (c *opStackCalls) calls() []w3types.RPCCaller {
+	return []w3types.RPCCaller{
+		w3eth.CallFunc(GasPriceOracleAddress, funcGetL1Fee, c.tx).Returns(&c.l1Fee),
+		w3eth.CallFunc(GasPriceOracleAddress, funcL1BaseFee).Returns(&c.l1BaseFee),
+		w3eth.CallFunc(GasPriceOracleAddress, funcIsEcotone).Returns(&c.ecotone),
+		w3eth.CallFunc(GasPriceOracleAddress, funcIsFjord).Returns(&c.fjord),
+		w3eth.CallFunc(GasPriceOracleAddress, funcBlobBaseFee).Returns(&c.blobBaseFee),
+		w3eth.CallFunc(GasPriceOracleAddress, funcBaseFeeScalar).Returns(&c.baseFeeScalar),
+		w3eth.CallFunc(GasPriceOracleAddress, funcBlobBaseFeeScalar).Returns(&c.blobBaseFeeScalar),
+	}
+}
+
+func (c *opStackCalls) fee() *OPStackFee {
+	return &OPStackFee{
+		Ecotone:           c.ecotone,
+		Fjord:             c.fjord,
+		BlobBaseFee:       &c.blobBaseFee,
+		BaseFeeScalar:     c.baseFeeScalar,
+		BlobBaseFeeScalar: c.blobBaseFeeScalar,
+	}
+}
+
+func (r CostReq) message() *w3types.Message {
+	return LimitReq{ChainId: r.ChainId, From: r.From, To: r.To, Data: r.Data, Value: r.Value}.message()
+}
+
+// unsignedTx serializes req as the GasPriceOracle expects it, an unsigned
+// EIP-1559 transaction whose signature padding the oracle accounts for.
+// gas and gasPrice only change a few bytes and may still be unknown.
+func unsignedTx(req CostReq, gas uint64, gasPrice *big.Int) ([]byte, error) {
+	to := common.HexToAddress(req.To)
+	tx := types.NewTx(&types.DynamicFeeTx{
+		ChainID:   new(big.Int).SetUint64(req.ChainId),
+		GasTipCap: gasPrice,
+		GasFeeCap: gasPrice,
+		Gas:       gas,
+		To:        &to,
+		Value:     req.Value,
+		Data:      req.Data,
+	})
+
+	data, err := tx.MarshalBinary()
+	if err != nil {
+		return nil, fmt.Errorf("failed to serialize transaction: %s", err)
+	}
+	return data, nil
+}
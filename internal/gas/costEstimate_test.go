@@ -0,0 +1,207 @@
+package gas
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"math/big"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/lmittmann/w3"
+	"github.com/onmetahq/go-evm/internal/http/fake"
+)
+
+const (
+	from   = "0x15Ba05723b04785C3E21157171810892A4FB795c"
+	router = "0x1111111254eeb25477b68fb85ed929f73a960582"
+)
+
+var swapData = hexutil.MustDecode("0x12aa3caf0000000000000000000000000000000000000000000000000000000000000001")
+
+type callMsg struct {
+	From  common.Address `json:"from"`
+	To    common.Address `json:"to"`
+	Value *hexutil.Big   `json:"value"`
+	Input hexutil.Bytes  `json:"input"`
+	Data  hexutil.Bytes  `json:"data"`
+}
+
+func newCostNode(t *testing.T, call func(msg callMsg) ([]byte, error)) (*fake.RPC, *w3.Client) {
+	node := fake.NewRPC()
+	t.Cleanup(node.Close)
+
+	node.Handle("eth_gasPrice", func(params []json.RawMessage) (any, error) {
+		return hexBig(big.NewInt(1_000_000)), nil
+	})
+	node.Handle("eth_estimateGas", func(params []json.RawMessage) (any, error) {
+		return hexutil.Uint64(200_000), nil
+	})
+	node.Handle("eth_call", func(params []json.RawMessage) (any, error) {
+		var msg callMsg
+		if err := json.Unmarshal(params[0], &msg); err != nil {
+			return nil, err
+		}
+		if len(msg.Input) == 0 {
+			msg.Input = msg.Data
+		}
+
+		out, err := call(msg)
+		if err != nil {
+			return nil, err
+		}
+		return hexutil.Encode(out), nil
+	})
+
+	client, err := w3.Dial(node.URL)
+	if err != nil {
+		t.Fatalf("dial err: %v", err)
+	}
+	t.Cleanup(func() { client.Close() })
+	return node, client
+}
+
+func word(n uint64) []byte {
+	return common.LeftPadBytes(new(big.Int).SetUint64(n).Bytes(), 32)
+}
+
+func TestCostEstimateOPStack(t *testing.T) {
+	_, client := newCostNode(t, func(msg callMsg) ([]byte, error) {
+		if msg.To != GasPriceOracleAddress {
+			return nil, fmt.Errorf("unexpected call to %s", msg.To)
+		}
+
+		switch {
+		case bytes.Equal(msg.Input[:4], funcGetL1Fee.Selector[:]):
+			var serialized []byte
+			if err := funcGetL1Fee.DecodeArgs(msg.Input, &serialized); err != nil {
+				return nil, err
+			}
+
+			var tx types.Transaction
+			if err := tx.UnmarshalBinary(serialized); err != nil {
+				return nil, err
+			}
+			if tx.ChainId().Uint64() != 10 || !bytes.Equal(tx.Data(), swapData) || *tx.To() != common.HexToAddress(router) {
+				return nil, fmt.Errorf("unexpected transaction: %x", serialized)
+			}
+			return word(5_000_000_000), nil
+		case bytes.Equal(msg.Input, funcL1BaseFee.Selector[:]):
+			return word(20_000_000_000), nil
+		case bytes.Equal(msg.Input, funcIsEcotone.Selector[:]):
+			return word(1), nil
+		case bytes.Equal(msg.Input, funcBlobBaseFee.Selector[:]):
+			return word(1), nil
+		case bytes.Equal(msg.Input, funcBaseFeeScalar.Selector[:]):
+			return word(1368), nil
+		case bytes.Equal(msg.Input, funcBlobBaseFeeScalar.Selector[:]):
+			return word(810949), nil
+		}
+		// isFjord is missing before Fjord.
+		return nil, &fake.RevertError{Message: "execution reverted"}
+	})
+
+	cost, err := CostEstimate(context.Background(), client, CostReq{ChainId: 10, From: from, To: router, Data: swapData})
+	if err != nil {
+		t.Fatalf("cost err: %v", err)
+	}
+
+	if cost.L2Gas != 200_000 || cost.L2Fee.Cmp(big.NewInt(200_000_000_000)) != 0 || cost.L1Fee.Cmp(big.NewInt(5_000_000_000)) != 0 {
+		t.Fatalf("unexpected cost, l2Gas: %d, l2Fee: %s, l1Fee: %s", cost.L2Gas, cost.L2Fee, cost.L1Fee)
+	}
+
+	if cost.Total.Cmp(big.NewInt(205_000_000_000)) != 0 || cost.L1BaseFee.Cmp(big.NewInt(20_000_000_000)) != 0 {
+		t.Fatalf("unexpected total: %s, l1BaseFee: %s", cost.Total, cost.L1BaseFee)
+	}
+
+	if op := cost.OPStack; op == nil || !op.Ecotone || op.Fjord || op.BaseFeeScalar != 1368 || op.BlobBaseFeeScalar != 810949 {
+		t.Fatalf("unexpected op stack fields: %+v", cost.OPStack)
+	}
+}
+
+func TestCostEstimateArbitrum(t *testing.T) {
+	node, client := newCostNode(t, func(msg callMsg) ([]byte, error) {
+		var (
+			to       common.Address
+			creation bool
+			data     []byte
+		)
+		if msg.To != NodeInterfaceAddress {
+			return nil, fmt.Errorf("unexpected call to %s", msg.To)
+		}
+		if err := funcGasEstimateComponents.DecodeArgs(msg.Input, &to, &creation, &data); err != nil {
+			return nil, err
+		}
+		if to != common.HexToAddress(router) || creation || !bytes.Equal(data, swapData) {
+			return nil, fmt.Errorf("unexpected components call, to: %s, data: %x", to, data)
+		}
+		if msg.From != common.HexToAddress(from) || msg.Value.ToInt().Cmp(big.NewInt(1e18)) != 0 {
+			return nil, fmt.Errorf("unexpected sender or value, from: %s, value: %s", msg.From, msg.Value)
+		}
+
+		out := append(word(900_000), word(300_000)...)
+		out = append(out, word(10_000_000)...)
+		return append(out, word(15_000_000_000)...), nil
+	})
+
+	cost, err := CostEstimate(context.Background(), client, CostReq{ChainId: 42161, From: from, To: router, Data: swapData, Value: big.NewInt(1e18)})
+	if err != nil {
+		t.Fatalf("cost err: %v", err)
+	}
+
+	if cost.L2Gas != 600_000 || cost.ArbitrumL1Gas != 300_000 || cost.L1Fee.Cmp(big.NewInt(3_000_000_000_000)) != 0 {
+		t.Fatalf("unexpected cost, l2Gas: %d, l1Gas: %d, l1Fee: %s", cost.L2Gas, cost.ArbitrumL1Gas, cost.L1Fee)
+	}
+
+	if cost.Total.Cmp(big.NewInt(9_000_000_000_000)) != 0 || node.Calls("eth_estimateGas") != 0 {
+		t.Fatalf("unexpected total: %s", cost.Total)
+	}
+}
+
+func TestCostEstimateArbitrumErrors(t *testing.T) {
+	_, client := newCostNode(t, func(msg callMsg) ([]byte, error) {
+		out := append(word(300_000), word(300_000)...)
+		out = append(out, word(10_000_000)...)
+		return append(out, word(15_000_000_000)...), nil
+	})
+
+	req := CostReq{ChainId: 42161, From: from, To: router, Data: swapData}
+	if _, err := CostEstimate(context.Background(), client, req); err == nil {
+		t.Fatalf("expected an error for an estimate without l2 gas")
+	}
+
+	// The caller's gas limit and price are used as is, the returned price is
+	// a copy.
+	req.Gas, req.GasPrice = 500_000, big.NewInt(20_000_000)
+	cost, err := CostEstimate(context.Background(), client, req)
+	if err != nil {
+		t.Fatalf("cost err: %v", err)
+	}
+
+	cost.L2GasPrice.SetUint64(1)
+	if cost.L2Gas != 500_000 || req.GasPrice.Cmp(big.NewInt(20_000_000)) != 0 {
+		t.Fatalf("unexpected cost, l2Gas: %d, gasPrice: %s", cost.L2Gas, req.GasPrice)
+	}
+}
+
+func TestCostEstimateL1(t *testing.T) {
+	node, client := newCostNode(t, func(msg callMsg) ([]byte, error) {
+		return nil, fmt.Errorf("unexpected call to %s", msg.To)
+	})
+
+	cost, err := CostEstimate(context.Background(), client, CostReq{ChainId: 137, From: from, To: router, Data: swapData, Gas: 150_000})
+	if err != nil {
+		t.Fatalf("cost err: %v", err)
+	}
+
+	if cost.L1Fee.Sign() != 0 || cost.Total.Cmp(big.NewInt(150_000_000_000)) != 0 || cost.OPStack != nil {
+		t.Fatalf("unexpected cost, l1Fee: %s, total: %s", cost.L1Fee, cost.Total)
+	}
+
+	if node.Calls("eth_estimateGas") != 0 || node.Calls("eth_call") != 0 {
+		t.Fatalf("expected only the gas price to be requested")
+	}
+}
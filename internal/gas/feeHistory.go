@@ -0,0 +1,240 @@
+package gas
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/big"
+	"sort"
+
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/rpc"
+	"github.com/lmittmann/w3"
+	w3eth "github.com/lmittmann/w3/module/eth"
+	"github.com/lmittmann/w3/w3types"
+)
+
+const (
+	// DefaultFeeHistoryBlocks is the number of blocks read by
+	// FeeHistoryEstimate.
+	DefaultFeeHistoryBlocks = 20
+	// baseFeeChangeDenominator bounds the base fee change between two blocks
+	// to 1/8, see EIP-1559.
+	baseFeeChangeDenominator = 8
+	// baseFeeHeadroom multiplies the projected base fee in maxFeePerGas so a
+	// transaction stays valid through several full blocks.
+	baseFeeHeadroom = 2
+)
+
+// DefaultFeePercentiles are the reward percentiles of the slow, standard and
+// fast tiers.
+var DefaultFeePercentiles = [3]float64{10, 50, 90}
+
+// FeeHistory is the result of eth_feeHistory. BaseFeePerGas holds one more
+// entry than the blocks read, the base fee of the next block.
+type FeeHistory struct {
+	OldestBlock   *hexutil.Big     `json:"oldestBlock"`
+	BaseFeePerGas []*hexutil.Big   `json:"baseFeePerGas"`
+	GasUsedRatio  []float64        `json:"gasUsedRatio"`
+	Reward        [][]*hexutil.Big `json:"reward"`
+}
+
+type feeHistoryFactory struct {
+	blocks      uint64
+	newest      string
+	percentiles []float64
+	ret         *FeeHistory
+}
+
+// FeeHistoryCall requests eth_feeHistory over the last blocks up to newest,
+// e.g. "latest", with the priority fee at each reward percentile. w3 has no
+// eth_feeHistory caller.
+func FeeHistoryCall(blocks uint64, newest string, percentiles []float64) w3types.RPCCallerFactory[FeeHistory] {
+	return &feeHistoryFactory{blocks: blocks, newest: newest, percentiles: percentiles}
+}
+
+func (f *feeHistoryFactory) Returns(ret *FeeHistory) w3types.RPCCaller {
+	f.ret = ret
+	return f
+}
+
+func (f *feeHistoryFactory) CreateRequest() (rpc.BatchElem, error) {
+	if f.ret == nil {
+		return rpc.BatchElem{}, fmt.Errorf("fee history must be returned into a non-nil pointer")
+	}
+
+	return rpc.BatchElem{
+		Method: "eth_feeHistory",
+		Args:   []any{hexutil.Uint64(f.blocks), f.newest, f.percentiles},
+		Result: &json.RawMessage{},
+	}, nil
+}
+
+func (f *feeHistoryFactory) HandleResponse(elem rpc.BatchElem) error {
+	if elem.Error != nil {
+		return elem.Error
+	}
+	return json.Unmarshal(*elem.Result.(*json.RawMessage), f.ret)
+}
+
+// FeeEstimate is the EIP-1559 fee of a transaction, both fees equal the gas
+// price on chains without EIP-1559.
+type FeeEstimate struct {
+	MaxFeePerGas         *big.Int
+	MaxPriorityFeePerGas *big.Int
+}
+
+// FeeEstimates holds the fees of each tier, a faster tier pays a higher
+// priority fee.
+type FeeEstimates struct {
+	// BaseFee is the projected base fee of the next block, zero when Legacy.
+	BaseFee *big.Int
+	// Legacy is set on chains without EIP-1559, the fees are then the
+	// eth_gasPrice of the node and should be sent as a legacy gas price.
+	Legacy   bool
+	Slow     FeeEstimate
+	Standard FeeEstimate
+	Fast     FeeEstimate
+}
+
+type feeHistoryConfig struct {
+	blocks      uint64
+	percentiles [3]float64
+}
+
+type FeeHistoryOption func(*feeHistoryConfig)
+
+// WithFeeHistoryBlocks sets the number of blocks read, DefaultFeeHistoryBlocks
+// otherwise.
+func WithFeeHistoryBlocks(blocks uint64) FeeHistoryOption {
+	return func(c *feeHistoryConfig) {
+		c.blocks = blocks
+	}
+}
+
+// WithFeePercentiles sets the reward percentiles of the slow, standard and
+// fast tiers, DefaultFeePercentiles otherwise.
+func WithFeePercentiles(slow, standard, fast float64) FeeHistoryOption {
+	return func(c *feeHistoryConfig) {
+		c.percentiles = [3]float64{slow, standard, fast}
+	}
+}
+
+// FeeHistoryEstimate estimates the fees of the next block from
+// eth_feeHistory. The priority fee of each tier is the median over the blocks
+// read of the reward at the tier's percentile and maxFeePerGas allows for the
+// projected base fee to double. Chains without EIP-1559 fall back to
+// eth_gasPrice, requested in the same batch.
+func FeeHistoryEstimate(ctx context.Context, client *w3.Client, opts ...FeeHistoryOption) (FeeEstimates, error) {
+	cfg := feeHistoryConfig{blocks: DefaultFeeHistoryBlocks, percentiles: DefaultFeePercentiles}
+	for _, opt := range opts {
+		opt(&cfg)
+	}
+
+	if cfg.blocks == 0 {
+		return FeeEstimates{}, fmt.Errorf("fee history needs at least one block")
+	}
+	if p := cfg.percentiles; p[0] < 0 || p[0] > p[1] || p[1] > p[2] || p[2] > 100 {
+		return FeeEstimates{}, fmt.Errorf("invalid fee percentiles: %v", p)
+	}
+
+	var (
+		history  FeeHistory
+		gasPrice big.Int
+		errs     w3.CallErrors
+	)
+	err := client.CallCtx(ctx,
+		FeeHistoryCall(cfg.blocks, "latest", cfg.percentiles[:]).Returns(&history),
+		w3eth.GasPrice().Returns(&gasPrice),
+	)
+	if err != nil && !errors.As(err, &errs) {
+		return FeeEstimates{}, fmt.Errorf("failed RPC request: %s", err)
+	}
+
+	if errs == nil || errs[0] == nil {
+		if estimates, ok := feeHistoryEstimates(history); ok {
+			return estimates, nil
+		}
+	}
+
+	if errs != nil && errs[1] != nil {
+		return FeeEstimates{}, fmt.Errorf("failed to get gas price: %s", errs[1])
+	}
+	return legacyEstimates(&gasPrice), nil
+}
+
+// feeHistoryEstimates returns the tiers of history, false when the chain
+// has no base fee.
+func feeHistoryEstimates(history FeeHistory) (FeeEstimates, bool) {
+	if len(history.BaseFeePerGas) == 0 {
+		return FeeEstimates{}, false
+	}
+
+	baseFee := history.BaseFeePerGas[len(history.BaseFeePerGas)-1].ToInt()
+	if len(history.BaseFeePerGas) == len(history.GasUsedRatio) {
+		// The node did not include the next block, project it from the last.
+		baseFee = nextBaseFee(baseFee, history.GasUsedRatio[len(history.GasUsedRatio)-1])
+	}
+	if baseFee == nil || baseFee.Sign() == 0 {
+		return FeeEstimates{}, false
+	}
+
+	var tiers [3]FeeEstimate
+	for i := range tiers {
+		tip := medianReward(history, i)
+		maxFee := new(big.Int).Mul(baseFee, big.NewInt(baseFeeHeadroom))
+		tiers[i] = FeeEstimate{
+			MaxFeePerGas:         maxFee.Add(maxFee, tip),
+			MaxPriorityFeePerGas: tip,
+		}
+	}
+
+	return FeeEstimates{
+		BaseFee:  new(big.Int).Set(baseFee),
+		Slow:     tiers[0],
+		Standard: tiers[1],
+		Fast:     tiers[2],
+	}, true
+}
+
+// nextBaseFee applies the EIP-1559 update rule to the base fee of a block
+// that used ratio of its gas limit.
+func nextBaseFee(baseFee *big.Int, ratio float64) *big.Int {
+	// Work in basis points of the gas target to stay in integers.
+	delta := new(big.Int).Mul(baseFee, big.NewInt(int64((ratio-0.5)*2*10_000)))
+	delta.Quo(delta, big.NewInt(10_000*baseFeeChangeDenominator))
+	return delta.Add(delta, baseFee)
+}
+
+// medianReward returns the median reward at percentile index i over the
+// blocks of history, empty blocks are skipped as they carry no reward.
+func medianReward(history FeeHistory, i int) *big.Int {
+	var rewards []*big.Int
+	for block, reward := range history.Reward {
+		if block < len(history.GasUsedRatio) && history.GasUsedRatio[block] == 0 {
+			continue
+		}
+		if i < len(reward) && reward[i] != nil {
+			rewards = append(rewards, reward[i].ToInt())
+		}
+	}
+
+	if len(rewards) == 0 {
+		return big.NewInt(0)
+	}
+
+	sort.Slice(rewards, func(a, b int) bool { return rewards[a].Cmp(rewards[b]) < 0 })
+	return new(big.Int).Set(rewards[len(rewards)/2])
+}
+
+func legacyEstimates(gasPrice *big.Int) FeeEstimates {
+	tier := FeeEstimate{MaxFeePerGas: gasPrice, MaxPriorityFeePerGas: gasPrice}
+	return FeeEstimates{
+		BaseFee:  big.NewInt(0),
+		Legacy:   true,
+		Slow:     tier,
+		Standard: tier,
+		Fast:     tier,
+	}
+}
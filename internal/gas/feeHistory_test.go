@@ -0,0 +1,159 @@
+package gas
+
+import (
+	"context"
+	"encoding/json"
+	"math/big"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/lmittmann/w3"
+	"github.com/onmetahq/go-evm/internal/http/fake"
+)
+
+func gwei(n int64) *big.Int {
+	return new(big.Int).Mul(big.NewInt(n), big.NewInt(1_000_000_000))
+}
+
+func hexBig(n *big.Int) *hexutil.Big {
+	return (*hexutil.Big)(n)
+}
+
+func newNode(t *testing.T, history *FeeHistory) *w3.Client {
+	node := fake.NewRPC()
+	t.Cleanup(node.Close)
+
+	node.Handle("eth_chainId", func(params []json.RawMessage) (any, error) {
+		return "0x89", nil
+	})
+	node.Handle("eth_gasPrice", func(params []json.RawMessage) (any, error) {
+		return hexBig(gwei(40)), nil
+	})
+	if history != nil {
+		node.Handle("eth_feeHistory", func(params []json.RawMessage) (any, error) {
+			var percentiles []float64
+			if err := json.Unmarshal(params[2], &percentiles); err != nil {
+				return nil, err
+			}
+			if len(percentiles) != 3 {
+				t.Errorf("unexpected percentiles: %v", percentiles)
+			}
+			return history, nil
+		})
+	}
+
+	client, err := w3.Dial(node.URL)
+	if err != nil {
+		t.Fatalf("dial err: %v", err)
+	}
+	t.Cleanup(func() { client.Close() })
+	return client
+}
+
+func rewards(tips ...[3]int64) [][]*hexutil.Big {
+	out := make([][]*hexutil.Big, 0, len(tips))
+	for _, tip := range tips {
+		out = append(out, []*hexutil.Big{hexBig(gwei(tip[0])), hexBig(gwei(tip[1])), hexBig(gwei(tip[2]))})
+	}
+	return out
+}
+
+func TestFeeHistoryEstimate(t *testing.T) {
+	client := newNode(t, &FeeHistory{
+		OldestBlock:   hexBig(big.NewInt(100)),
+		BaseFeePerGas: []*hexutil.Big{hexBig(gwei(20)), hexBig(gwei(22)), hexBig(gwei(21)), hexBig(gwei(25))},
+		GasUsedRatio:  []float64{0.9, 0.4, 0},
+		Reward:        rewards([3]int64{1, 2, 5}, [3]int64{1, 3, 4}, [3]int64{0, 0, 0}),
+	})
+
+	estimates, err := FeeHistoryEstimate(context.Background(), client)
+	if err != nil {
+		t.Fatalf("estimate err: %v", err)
+	}
+
+	if estimates.Legacy || estimates.BaseFee.Cmp(gwei(25)) != 0 {
+		t.Fatalf("unexpected base fee: %s, legacy: %v", estimates.BaseFee, estimates.Legacy)
+	}
+
+	for _, tc := range []struct {
+		name string
+		fee  FeeEstimate
+		tip  int64
+	}{
+		{name: "slow", fee: estimates.Slow, tip: 1},
+		{name: "standard", fee: estimates.Standard, tip: 3},
+		{name: "fast", fee: estimates.Fast, tip: 5},
+	} {
+		if tc.fee.MaxPriorityFeePerGas.Cmp(gwei(tc.tip)) != 0 || tc.fee.MaxFeePerGas.Cmp(gwei(50+tc.tip)) != 0 {
+			t.Errorf("unexpected %s fee, maxFee: %s, tip: %s", tc.name, tc.fee.MaxFeePerGas, tc.fee.MaxPriorityFeePerGas)
+		}
+	}
+}
+
+func TestFeeHistoryProjectsBaseFee(t *testing.T) {
+	client := newNode(t, &FeeHistory{
+		OldestBlock:   hexBig(big.NewInt(100)),
+		BaseFeePerGas: []*hexutil.Big{hexBig(gwei(16))},
+		GasUsedRatio:  []float64{1},
+		Reward:        rewards([3]int64{1, 2, 3}),
+	})
+
+	estimates, err := FeeHistoryEstimate(context.Background(), client)
+	if err != nil {
+		t.Fatalf("estimate err: %v", err)
+	}
+
+	if estimates.BaseFee.Cmp(gwei(18)) != 0 {
+		t.Fatalf("expected a full block to raise the base fee by 1/8, baseFee: %s", estimates.BaseFee)
+	}
+}
+
+func TestFeeHistoryLegacy(t *testing.T) {
+	for _, tc := range []struct {
+		name    string
+		history *FeeHistory
+	}{
+		{name: "unsupported", history: nil},
+		{name: "zero base fee", history: &FeeHistory{
+			OldestBlock:   hexBig(big.NewInt(100)),
+			BaseFeePerGas: []*hexutil.Big{hexBig(big.NewInt(0)), hexBig(big.NewInt(0))},
+			GasUsedRatio:  []float64{0.5},
+		}},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			estimates, err := FeeHistoryEstimate(context.Background(), newNode(t, tc.history))
+			if err != nil {
+				t.Fatalf("estimate err: %v", err)
+			}
+
+			if !estimates.Legacy || estimates.Fast.MaxFeePerGas.Cmp(gwei(40)) != 0 || estimates.Slow.MaxPriorityFeePerGas.Cmp(gwei(40)) != 0 {
+				t.Fatalf("expected legacy gas price, estimates: %+v", estimates)
+			}
+		})
+	}
+}
+
+func TestFeeHistoryInvalidPercentiles(t *testing.T) {
+	_, err := FeeHistoryEstimate(context.Background(), newNode(t, nil), WithFeePercentiles(50, 10, 90))
+	if err == nil {
+		t.Fatalf("expected an error for unordered percentiles")
+	}
+}
+
+func TestEIP1559Estimate(t *testing.T) {
+	client := newNode(t, &FeeHistory{
+		OldestBlock:   hexBig(big.NewInt(100)),
+		BaseFeePerGas: []*hexutil.Big{hexBig(gwei(30)), hexBig(gwei(30))},
+		GasUsedRatio:  []float64{0.5},
+		Reward:        rewards([3]int64{1, 2, 3}),
+	})
+
+	baseFee, tip, err := EIP1559Estimate(context.Background(), client)
+	if err != nil {
+		t.Fatalf("estimate err: %v", err)
+	}
+
+	if baseFee.Cmp(gwei(30)) != 0 || tip.Cmp(gwei(2)) != 0 {
+		t.Fatalf("unexpected fees, baseFee: %s, tip: %s", baseFee, tip)
+	}
+}
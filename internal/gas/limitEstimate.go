@@ -2,60 +2,218 @@ package gas
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"math/big"
 	"strings"
 
+	"github.com/ethereum/go-ethereum/accounts/abi"
 	"github.com/ethereum/go-ethereum/common"
 	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/rpc"
 	"github.com/lmittmann/w3"
-	w3eth "github.com/lmittmann/w3/module/eth"
 	"github.com/lmittmann/w3/w3types"
-	hex "github.com/onmetahq/go-evm/internal/utils"
 )
 
-func LimitEstimate(ctx context.Context, client *w3.Client, from, to, data string, value uint64) (uint64, error) {
-	var (
-		fromAddr  = common.HexToAddress(from)
-		toAddr    = common.HexToAddress(to)
-		amount    = new(big.Int).SetUint64(value)
-		bytesData []byte
-	)
-
-	var hexData string
-	var err error
-
-	if data != "" {
-		// Convert data to hex if it is not
-		if !hex.WithOrWithout0xPrefix(data) {
-			hexData = hexutil.Encode([]byte(data))
-		} else if strings.HasPrefix(data, "0x") {
-			hexData = data
-		} else {
-			hexData = "0x" + data
-		}
+// ErrLimitAboveCap is returned when the estimated gas exceeds the cap of the
+// chain, the transaction could not be sent with a capped limit.
+var ErrLimitAboveCap = errors.New("gas limit above cap")
 
-		bytesData, err = hexutil.Decode(hexData)
-		if err != nil {
-			return 0, fmt.Errorf("failed to decode data: %s", err)
-		}
+// LimitReq is the transaction whose gas limit is estimated.
+type LimitReq struct {
+	ChainId uint64
+	From    string
+	// To is empty for a contract creation.
+	To    string
+	Data  []byte
+	Value *big.Int
+}
+
+func (r LimitReq) message() *w3types.Message {
+	msg := &w3types.Message{
+		From:  common.HexToAddress(r.From),
+		Value: r.Value,
+		Input: r.Data,
+	}
+	if r.To != "" {
+		to := common.HexToAddress(r.To)
+		msg.To = &to
+	}
+	if msg.Value == nil {
+		msg.Value = big.NewInt(0)
+	}
+	return msg
+}
+
+type limitConfig struct {
+	bufferBps uint64
+	bufferGas uint64
+	caps      map[uint64]uint64
+	block     rpc.BlockNumber
+	overrides w3types.State
+}
+
+func newLimitConfig(opts []LimitOption) limitConfig {
+	cfg := limitConfig{block: rpc.LatestBlockNumber}
+	for _, opt := range opts {
+		opt(&cfg)
+	}
+	return cfg
+}
+
+type LimitOption func(*limitConfig)
+
+// WithBufferBps raises the estimate by bps basis points, e.g. 2000 for 20%,
+// covering state changes between the estimate and the transaction.
+func WithBufferBps(bps uint64) LimitOption {
+	return func(c *limitConfig) {
+		c.bufferBps = bps
+	}
+}
+
+// WithBufferGas adds gas to the estimate after WithBufferBps is applied.
+func WithBufferGas(gas uint64) LimitOption {
+	return func(c *limitConfig) {
+		c.bufferGas = gas
+	}
+}
+
+// WithLimitCaps bounds the limit of each chain, e.g. to the block gas limit.
+// Buffers are trimmed to the cap and an estimate above it fails with
+// ErrLimitAboveCap.
+func WithLimitCaps(caps map[uint64]uint64) LimitOption {
+	return func(c *limitConfig) {
+		c.caps = caps
+	}
+}
+
+// WithStateOverrides estimates against state modified by overrides, e.g. to
+// fund the sender or fake an allowance that is not mined yet, see
+// BalanceOverride and ERC20Override.
+func WithStateOverrides(overrides w3types.State) LimitOption {
+	return func(c *limitConfig) {
+		c.overrides = overrides
+	}
+}
+
+// WithBlock estimates at block, e.g. rpc.PendingBlockNumber or a block
+// number, instead of the latest block.
+func WithBlock(block rpc.BlockNumber) LimitOption {
+	return func(c *limitConfig) {
+		c.block = block
+	}
+}
+
+func (c limitConfig) apply(chainId uint64, gas uint64) (uint64, error) {
+	limit := gas + gas*c.bufferBps/10_000 + c.bufferGas
+
+	max, ok := c.caps[chainId]
+	switch {
+	case !ok:
+	case gas > max:
+		return 0, fmt.Errorf("estimated gas %d on chainId %d, cap: %d, err: %w", gas, chainId, max, ErrLimitAboveCap)
+	case limit > max:
+		limit = max
+	}
+	return limit, nil
+}
+
+// RevertError is returned when the estimated transaction reverts, Reason is
+// decoded from Error(string) and Panic(uint256) revert data.
+type RevertError struct {
+	Reason string
+	Data   []byte
+}
+
+func (e *RevertError) Error() string {
+	switch {
+	case e.Reason != "":
+		return "execution reverted: " + e.Reason
+	case len(e.Data) > 0:
+		return "execution reverted, data: " + hexutil.Encode(e.Data)
 	}
+	return "execution reverted"
+}
 
-	var gas uint64
-	msg := w3types.Message{
-		From:  fromAddr,
-		To:    &toAddr,
-		Gas:   0,
-		Value: amount,
-		Input: bytesData,
+// LimitEstimate returns the gas limit of req, the node estimate with the
+// buffers and caps of opts applied. A reverting transaction fails with a
+// *RevertError.
+func LimitEstimate(ctx context.Context, client *w3.Client, req LimitReq, opts ...LimitOption) (uint64, error) {
+	cfg := newLimitConfig(opts)
+
+	var gas hexutil.Uint64
+	if err := client.CallCtx(ctx,
+		&messageCall{method: "eth_estimateGas", msg: req.message(), block: cfg.block, overrides: cfg.overrides, ret: &gas},
+	); err != nil {
+		return 0, fmt.Errorf("failed estimate gas: %w", estimateError(err))
 	}
 
-	if err := client.CallCtx(
-		context.Background(),
-		w3eth.EstimateGas(&msg, nil).Returns(&gas),
+	return cfg.apply(req.ChainId, uint64(gas))
+}
+
+// SimulateCall executes req with eth_call at the block and with the state
+// overrides of opts, and returns its output. A reverting transaction fails
+// with a *RevertError, which nodes often report in more detail than for
+// eth_estimateGas. Buffers and caps are ignored.
+func SimulateCall(ctx context.Context, client *w3.Client, req LimitReq, opts ...LimitOption) ([]byte, error) {
+	cfg := newLimitConfig(opts)
+
+	var output hexutil.Bytes
+	if err := client.CallCtx(ctx,
+		&messageCall{method: "eth_call", msg: req.message(), block: cfg.block, overrides: cfg.overrides, ret: &output},
 	); err != nil {
-		return 0, fmt.Errorf("failed estimate gas: %s", err)
+		return nil, fmt.Errorf("failed call: %w", estimateError(err))
+	}
+	return output, nil
+}
+
+// messageCall requests method, eth_call or eth_estimateGas, for msg at block
+// with the state overrides, nil for none, decoding the result into ret. w3
+// takes no block tags besides pending and no overrides for eth_estimateGas.
+type messageCall struct {
+	method    string
+	msg       *w3types.Message
+	block     rpc.BlockNumber
+	overrides w3types.State
+	ret       any
+}
+
+func (c *messageCall) CreateRequest() (rpc.BatchElem, error) {
+	args := []any{c.msg, c.block}
+	if len(c.overrides) > 0 {
+		args = append(args, c.overrides)
+	}
+	return rpc.BatchElem{
+		Method: c.method,
+		Args:   args,
+		Result: c.ret,
+	}, nil
+}
+
+func (c *messageCall) HandleResponse(elem rpc.BatchElem) error {
+	return elem.Error
+}
+
+// estimateError returns the error of a single call batch, as a *RevertError
+// when the node reports a revert.
+func estimateError(err error) error {
+	var errs w3.CallErrors
+	if errors.As(err, &errs) && len(errs) == 1 {
+		err = errs[0]
 	}
 
-	return gas, nil
+	var dataErr rpc.DataError
+	if errors.As(err, &dataErr) {
+		if data, ok := dataErr.ErrorData().(string); ok {
+			if raw, decodeErr := hexutil.Decode(data); decodeErr == nil && len(raw) > 0 {
+				reason, _ := abi.UnpackRevert(raw)
+				return &RevertError{Reason: reason, Data: raw}
+			}
+		}
+	}
+
+	if msg, ok := strings.CutPrefix(err.Error(), "execution reverted"); ok {
+		return &RevertError{Reason: strings.TrimPrefix(msg, ": ")}
+	}
+	return err
 }
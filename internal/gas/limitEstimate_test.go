@@ -0,0 +1,205 @@
+package gas
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"math/big"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/rpc"
+	"github.com/lmittmann/w3"
+	"github.com/onmetahq/go-evm/internal/http/fake"
+)
+
+var (
+	funcError = w3.MustNewFunc("Error(string)", "")
+	funcPanic = w3.MustNewFunc("Panic(uint256)", "")
+)
+
+func newEstimateNode(t *testing.T, estimate func(msg callMsg) (uint64, error)) *w3.Client {
+	node := fake.NewRPC()
+	t.Cleanup(node.Close)
+
+	node.Handle("eth_estimateGas", func(params []json.RawMessage) (any, error) {
+		var msg callMsg
+		if err := json.Unmarshal(params[0], &msg); err != nil {
+			return nil, err
+		}
+		if len(msg.Input) == 0 {
+			msg.Input = msg.Data
+		}
+
+		gas, err := estimate(msg)
+		if err != nil {
+			return nil, err
+		}
+		return hexutil.Uint64(gas), nil
+	})
+
+	client, err := w3.Dial(node.URL)
+	if err != nil {
+		t.Fatalf("dial err: %v", err)
+	}
+	t.Cleanup(func() { client.Close() })
+	return client
+}
+
+func TestLimitEstimate(t *testing.T) {
+	value, _ := new(big.Int).SetString("100000000000000000000", 10)
+	client := newEstimateNode(t, func(msg callMsg) (uint64, error) {
+		if msg.Value.ToInt().Cmp(value) != 0 || string(msg.Input) != string(swapData) {
+			t.Errorf("unexpected estimate, value: %s, input: %x", msg.Value, msg.Input)
+		}
+		return 100_000, nil
+	})
+	req := LimitReq{ChainId: 1, From: from, To: router, Data: swapData, Value: value}
+
+	for _, tc := range []struct {
+		name string
+		opts []LimitOption
+		want uint64
+	}{
+		{name: "no buffer", want: 100_000},
+		{name: "buffers", opts: []LimitOption{WithBufferBps(2000), WithBufferGas(5_000)}, want: 125_000},
+		{name: "capped buffer", opts: []LimitOption{WithBufferBps(2000), WithLimitCaps(map[uint64]uint64{1: 110_000})}, want: 110_000},
+		{name: "other chain cap", opts: []LimitOption{WithBufferBps(2000), WithLimitCaps(map[uint64]uint64{137: 110_000})}, want: 120_000},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			gas, err := LimitEstimate(context.Background(), client, req, tc.opts...)
+			if err != nil || gas != tc.want {
+				t.Fatalf("unexpected limit: %d, err: %v", gas, err)
+			}
+		})
+	}
+
+	_, err := LimitEstimate(context.Background(), client, req, WithLimitCaps(map[uint64]uint64{1: 90_000}))
+	if !errors.Is(err, ErrLimitAboveCap) {
+		t.Fatalf("expected limit above cap, err: %v", err)
+	}
+}
+
+func TestLimitEstimateRevert(t *testing.T) {
+	errorData, _ := funcError.EncodeArgs("STF")
+	panicData, _ := funcPanic.EncodeArgs(big.NewInt(0x11))
+	customData := hexutil.MustDecode("0x8baa579f")
+
+	for _, tc := range []struct {
+		name   string
+		err    error
+		reason string
+		data   []byte
+	}{
+		{name: "error string", err: &fake.RevertError{Message: "execution reverted: STF", Data: hexutil.Encode(errorData)}, reason: "STF", data: errorData},
+		{name: "panic", err: &fake.RevertError{Message: "execution reverted", Data: hexutil.Encode(panicData)}, reason: "arithmetic underflow or overflow", data: panicData},
+		{name: "custom error", err: &fake.RevertError{Message: "execution reverted", Data: hexutil.Encode(customData)}, data: customData},
+		{name: "message only", err: errors.New("execution reverted: TRANSFER_FAILED"), reason: "TRANSFER_FAILED"},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			client := newEstimateNode(t, func(msg callMsg) (uint64, error) {
+				return 0, tc.err
+			})
+
+			_, err := LimitEstimate(context.Background(), client, LimitReq{ChainId: 1, From: from, To: router, Data: swapData})
+			var revert *RevertError
+			if !errors.As(err, &revert) {
+				t.Fatalf("expected a revert error, err: %v", err)
+			}
+
+			if revert.Reason != tc.reason || string(revert.Data) != string(tc.data) {
+				t.Fatalf("unexpected revert, reason: %q, data: %x", revert.Reason, revert.Data)
+			}
+		})
+	}
+}
+
+func TestLimitEstimateContext(t *testing.T) {
+	client := newEstimateNode(t, func(msg callMsg) (uint64, error) {
+		return 21_000, nil
+	})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	_, err := LimitEstimate(ctx, client, LimitReq{ChainId: 1, From: from, To: router})
+	if !errors.Is(err, context.Canceled) {
+		t.Fatalf("expected the cancelled context to be honored, err: %v", err)
+	}
+}
+
+func TestLimitEstimateOverrides(t *testing.T) {
+	const (
+		token   = "0x2791bca1f2de4661ed88a30c99a7a9449aa84174"
+		spender = "0x1111111254eeb25477b68fb85ed929f73a960582"
+	)
+
+	var params []json.RawMessage
+	node := fake.NewRPC()
+	t.Cleanup(node.Close)
+	for _, method := range []string{"eth_estimateGas", "eth_call"} {
+		node.Handle(method, func(p []json.RawMessage) (any, error) {
+			params = p
+			if method == "eth_call" {
+				return "0x01", nil
+			}
+			return hexutil.Uint64(150_000), nil
+		})
+	}
+
+	client, err := w3.Dial(node.URL)
+	if err != nil {
+		t.Fatalf("dial err: %v", err)
+	}
+	t.Cleanup(func() { client.Close() })
+
+	amount := big.NewInt(1_000_000)
+	overrides := BalanceOverride(from, big.NewInt(1e18)).Merge(ERC20Override(token, from, spender, amount, 9, 10))
+	req := LimitReq{ChainId: 137, From: from, To: router, Data: swapData}
+
+	gas, err := LimitEstimate(context.Background(), client, req, WithStateOverrides(overrides), WithBlock(rpc.PendingBlockNumber))
+	if err != nil || gas != 150_000 {
+		t.Fatalf("unexpected limit: %d, err: %v", gas, err)
+	}
+
+	if len(params) != 3 || string(params[1]) != `"pending"` {
+		t.Fatalf("expected the block tag and overrides, params: %s", params)
+	}
+
+	var state map[common.Address]struct {
+		Balance   *hexutil.Big                `json:"balance"`
+		StateDiff map[common.Hash]common.Hash `json:"stateDiff"`
+	}
+	if err := json.Unmarshal(params[2], &state); err != nil {
+		t.Fatalf("decode overrides err: %v", err)
+	}
+
+	if state[common.HexToAddress(from)].Balance.ToInt().Cmp(big.NewInt(1e18)) != 0 {
+		t.Fatalf("expected the sender to be funded, overrides: %s", params[2])
+	}
+
+	owner := common.HexToAddress(from)
+	balanceSlot := MappingSlot(owner, common.BigToHash(big.NewInt(9)))
+	allowanceSlot := MappingSlot(common.HexToAddress(spender), MappingSlot(owner, common.BigToHash(big.NewInt(10))))
+	diff := state[common.HexToAddress(token)].StateDiff
+	if diff[balanceSlot] != common.BigToHash(amount) || diff[allowanceSlot] != common.BigToHash(amount) {
+		t.Fatalf("expected the token balance and allowance, overrides: %s", params[2])
+	}
+
+	output, err := SimulateCall(context.Background(), client, req, WithStateOverrides(overrides), WithBlock(rpc.SafeBlockNumber))
+	if err != nil || string(output) != "\x01" || string(params[1]) != `"safe"` {
+		t.Fatalf("unexpected call, output: %x, params: %s, err: %v", output, params, err)
+	}
+
+	if _, err := LimitEstimate(context.Background(), client, req); err != nil || len(params) != 2 || string(params[1]) != `"latest"` {
+		t.Fatalf("expected the latest block without overrides, params: %s, err: %v", params, err)
+	}
+}
+
+func TestMappingSlot(t *testing.T) {
+	slot := MappingSlot(common.Address{}, common.Hash{})
+	if slot != common.HexToHash("0xad3228b676f7d3cd4284a5443f17f1962b36e491b30a40b2405849e597ba5fb5") {
+		t.Fatalf("unexpected slot: %s", slot)
+	}
+}
@@ -0,0 +1,289 @@
+package gas
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math/big"
+	"sort"
+	"sync"
+
+	"github.com/lmittmann/w3"
+	"github.com/onmetahq/go-evm/internal/http/common"
+)
+
+// Oracle prices the gas of a chain, it is injected wherever a swap needs a
+// gas price instead of calling PriceEstimate on a node.
+type Oracle interface {
+	// GasPrice returns the expected price per gas of a transaction sent now,
+	// the base fee plus the priority fee on EIP-1559 chains.
+	GasPrice(ctx context.Context, chainId uint64) (*big.Int, error)
+	// Fees returns the maxFeePerGas and maxPriorityFeePerGas to send a
+	// transaction with, both equal the gas price when the oracle only
+	// knows a legacy price.
+	Fees(ctx context.Context, chainId uint64) (FeeEstimate, error)
+}
+
+// OracleFunc adapts a gas price function to an Oracle, its Fees are the
+// legacy split of the price.
+type OracleFunc func(ctx context.Context, chainId uint64) (*big.Int, error)
+
+func (f OracleFunc) GasPrice(ctx context.Context, chainId uint64) (*big.Int, error) {
+	return f(ctx, chainId)
+}
+
+func (f OracleFunc) Fees(ctx context.Context, chainId uint64) (FeeEstimate, error) {
+	price, err := f(ctx, chainId)
+	if err != nil {
+		return FeeEstimate{}, err
+	}
+	return legacyFees(price), nil
+}
+
+// NodeOracle returns eth_gasPrice of the node of the chain, rpcs maps a
+// chain id to its node.
+func NodeOracle(rpcs map[uint64]*w3.Client) Oracle {
+	return OracleFunc(func(ctx context.Context, chainId uint64) (*big.Int, error) {
+		client, err := chainClient(rpcs, chainId)
+		if err != nil {
+			return nil, err
+		}
+		return PriceEstimate(ctx, client)
+	})
+}
+
+type feeHistoryOracle struct {
+	rpcs       map[uint64]*w3.Client
+	percentile float64
+	blocks     uint64
+}
+
+// FeeHistoryOracle returns the projected base fee plus the priority fee paid
+// at percentile over the last blocks, see FeeHistoryEstimate. Its Fees are
+// the standard tier. Chains without EIP-1559 fall back to eth_gasPrice.
+func FeeHistoryOracle(rpcs map[uint64]*w3.Client, percentile float64, blocks uint64) Oracle {
+	return &feeHistoryOracle{rpcs: rpcs, percentile: percentile, blocks: blocks}
+}
+
+func (o *feeHistoryOracle) GasPrice(ctx context.Context, chainId uint64) (*big.Int, error) {
+	estimates, err := o.estimate(ctx, chainId)
+	if err != nil {
+		return nil, err
+	}
+
+	if estimates.Legacy {
+		return estimates.Standard.MaxFeePerGas, nil
+	}
+	return new(big.Int).Add(estimates.BaseFee, estimates.Standard.MaxPriorityFeePerGas), nil
+}
+
+func (o *feeHistoryOracle) Fees(ctx context.Context, chainId uint64) (FeeEstimate, error) {
+	estimates, err := o.estimate(ctx, chainId)
+	if err != nil {
+		return FeeEstimate{}, err
+	}
+	return estimates.Standard, nil
+}
+
+func (o *feeHistoryOracle) estimate(ctx context.Context, chainId uint64) (FeeEstimates, error) {
+	client, err := chainClient(o.rpcs, chainId)
+	if err != nil {
+		return FeeEstimates{}, err
+	}
+
+	return FeeHistoryEstimate(ctx, client,
+		WithFeeHistoryBlocks(o.blocks), WithFeePercentiles(o.percentile, o.percentile, o.percentile))
+}
+
+type fixedOracle struct {
+	prices   map[uint64]*big.Int
+	fallback Oracle
+}
+
+// FixedOracle returns the gas price set for the chain in prices, and asks
+// fallback for other chains. fallback may be nil, other chains then fail.
+func FixedOracle(prices map[uint64]*big.Int, fallback Oracle) Oracle {
+	return &fixedOracle{prices: prices, fallback: fallback}
+}
+
+func (o *fixedOracle) GasPrice(ctx context.Context, chainId uint64) (*big.Int, error) {
+	if price, ok := o.prices[chainId]; ok {
+		return new(big.Int).Set(price), nil
+	}
+
+	if o.fallback == nil {
+		return nil, fmt.Errorf("no gas price on chainId %d, err: %w", chainId, common.ErrUnsupportedChain)
+	}
+	return o.fallback.GasPrice(ctx, chainId)
+}
+
+func (o *fixedOracle) Fees(ctx context.Context, chainId uint64) (FeeEstimate, error) {
+	if price, ok := o.prices[chainId]; ok {
+		return legacyFees(price), nil
+	}
+
+	if o.fallback == nil {
+		return FeeEstimate{}, fmt.Errorf("no gas price on chainId %d, err: %w", chainId, common.ErrUnsupportedChain)
+	}
+	return o.fallback.Fees(ctx, chainId)
+}
+
+type medianOracle struct {
+	oracles []Oracle
+}
+
+// MedianOracle asks every oracle in parallel, e.g. a NodeOracle per RPC
+// provider, and returns the median price so a single node reporting an
+// outlier does not skew it. Fees takes the median of each fee on its own.
+// Failing oracles are ignored unless all fail.
+func MedianOracle(oracles ...Oracle) Oracle {
+	return &medianOracle{oracles: oracles}
+}
+
+func (o *medianOracle) GasPrice(ctx context.Context, chainId uint64) (*big.Int, error) {
+	prices, err := gather(ctx, o.oracles, func(ctx context.Context, oracle Oracle) (*big.Int, error) {
+		return oracle.GasPrice(ctx, chainId)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return median(prices), nil
+}
+
+func (o *medianOracle) Fees(ctx context.Context, chainId uint64) (FeeEstimate, error) {
+	fees, err := gather(ctx, o.oracles, func(ctx context.Context, oracle Oracle) (FeeEstimate, error) {
+		return oracle.Fees(ctx, chainId)
+	})
+	if err != nil {
+		return FeeEstimate{}, err
+	}
+
+	maxFees := make([]*big.Int, len(fees))
+	tips := make([]*big.Int, len(fees))
+	for i, fee := range fees {
+		maxFees[i], tips[i] = fee.MaxFeePerGas, fee.MaxPriorityFeePerGas
+	}
+	return boundTip(FeeEstimate{MaxFeePerGas: median(maxFees), MaxPriorityFeePerGas: median(tips)}), nil
+}
+
+// gather calls fetch on every oracle in parallel and returns the successful
+// results, an error only when every oracle failed.
+func gather[T any](ctx context.Context, oracles []Oracle, fetch func(context.Context, Oracle) (T, error)) ([]T, error) {
+	if len(oracles) == 0 {
+		return nil, fmt.Errorf("no gas oracle to take the median of")
+	}
+
+	var (
+		mu      sync.Mutex
+		wg      sync.WaitGroup
+		results []T
+		errs    []error
+	)
+	for _, oracle := range oracles {
+		wg.Add(1)
+		go func(oracle Oracle) {
+			defer wg.Done()
+			res, err := fetch(ctx, oracle)
+
+			mu.Lock()
+			defer mu.Unlock()
+			if err != nil {
+				errs = append(errs, err)
+				return
+			}
+			results = append(results, res)
+		}(oracle)
+	}
+	wg.Wait()
+
+	if len(results) == 0 {
+		return nil, fmt.Errorf("no gas oracle returned a price, err: %w", errors.Join(errs...))
+	}
+	return results, nil
+}
+
+func median(values []*big.Int) *big.Int {
+	sort.Slice(values, func(i, j int) bool { return values[i].Cmp(values[j]) < 0 })
+	mid := len(values) / 2
+	if len(values)%2 == 1 {
+		return values[mid]
+	}
+	m := new(big.Int).Add(values[mid-1], values[mid])
+	return m.Rsh(m, 1)
+}
+
+// Cap bounds the gas price of a chain, a nil Min or Max is not enforced.
+type Cap struct {
+	Min *big.Int
+	Max *big.Int
+}
+
+func (c Cap) clamp(price *big.Int) *big.Int {
+	switch {
+	case c.Min != nil && price.Cmp(c.Min) < 0:
+		return new(big.Int).Set(c.Min)
+	case c.Max != nil && price.Cmp(c.Max) > 0:
+		return new(big.Int).Set(c.Max)
+	}
+	return price
+}
+
+type cappedOracle struct {
+	oracle Oracle
+	caps   map[uint64]Cap
+}
+
+// CappedOracle clamps the price of oracle to the cap of the chain, chains
+// without a cap are returned as is. Fees clamps maxFeePerGas and keeps the
+// priority fee at or below it.
+func CappedOracle(oracle Oracle, caps map[uint64]Cap) Oracle {
+	return &cappedOracle{oracle: oracle, caps: caps}
+}
+
+func (o *cappedOracle) GasPrice(ctx context.Context, chainId uint64) (*big.Int, error) {
+	price, err := o.oracle.GasPrice(ctx, chainId)
+	if err != nil {
+		return nil, err
+	}
+
+	bounds, ok := o.caps[chainId]
+	if !ok {
+		return price, nil
+	}
+	return bounds.clamp(price), nil
+}
+
+func (o *cappedOracle) Fees(ctx context.Context, chainId uint64) (FeeEstimate, error) {
+	fees, err := o.oracle.Fees(ctx, chainId)
+	if err != nil {
+		return FeeEstimate{}, err
+	}
+
+	bounds, ok := o.caps[chainId]
+	if !ok {
+		return fees, nil
+	}
+	fees.MaxFeePerGas = bounds.clamp(fees.MaxFeePerGas)
+	return boundTip(fees), nil
+}
+
+func legacyFees(price *big.Int) FeeEstimate {
+	return FeeEstimate{MaxFeePerGas: new(big.Int).Set(price), MaxPriorityFeePerGas: new(big.Int).Set(price)}
+}
+
+// boundTip lowers the priority fee to maxFeePerGas, nodes reject a
+// transaction whose tip is above its max fee.
+func boundTip(fees FeeEstimate) FeeEstimate {
+	if fees.MaxPriorityFeePerGas.Cmp(fees.MaxFeePerGas) > 0 {
+		fees.MaxPriorityFeePerGas = new(big.Int).Set(fees.MaxFeePerGas)
+	}
+	return fees
+}
+
+func chainClient(rpcs map[uint64]*w3.Client, chainId uint64) (*w3.Client, error) {
+	client, ok := rpcs[chainId]
+	if !ok {
+		return nil, fmt.Errorf("no rpc to estimate the gas price on chainId %d, err: %w", chainId, common.ErrUnsupportedChain)
+	}
+	return client, nil
+}
@@ -0,0 +1,176 @@
+package gas
+
+import (
+	"context"
+	"errors"
+	"math/big"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/lmittmann/w3"
+	"github.com/onmetahq/go-evm/internal/http/common"
+)
+
+func fixed(price int64) Oracle {
+	return OracleFunc(func(ctx context.Context, chainId uint64) (*big.Int, error) {
+		return gwei(price), nil
+	})
+}
+
+var failing = OracleFunc(func(ctx context.Context, chainId uint64) (*big.Int, error) {
+	return nil, errors.New("node down")
+})
+
+func TestNodeOracle(t *testing.T) {
+	oracle := NodeOracle(map[uint64]*w3.Client{137: newNode(t, nil)})
+
+	price, err := oracle.GasPrice(context.Background(), 137)
+	if err != nil || price.Cmp(gwei(40)) != 0 {
+		t.Fatalf("unexpected node price: %v, err: %v", price, err)
+	}
+
+	_, err = oracle.GasPrice(context.Background(), 1)
+	if !errors.Is(err, common.ErrUnsupportedChain) {
+		t.Fatalf("expected unsupported chain, err: %v", err)
+	}
+}
+
+func TestFeeHistoryOracle(t *testing.T) {
+	client := newNode(t, &FeeHistory{
+		OldestBlock:   hexBig(big.NewInt(100)),
+		BaseFeePerGas: []*hexutil.Big{hexBig(gwei(30)), hexBig(gwei(32))},
+		GasUsedRatio:  []float64{0.7},
+		Reward:        rewards([3]int64{3, 3, 3}),
+	})
+
+	price, err := FeeHistoryOracle(map[uint64]*w3.Client{137: client}, 75, 10).GasPrice(context.Background(), 137)
+	if err != nil || price.Cmp(gwei(35)) != 0 {
+		t.Fatalf("expected base fee plus tip, price: %v, err: %v", price, err)
+	}
+}
+
+func TestFixedOracle(t *testing.T) {
+	oracle := FixedOracle(map[uint64]*big.Int{137: gwei(50)}, fixed(10))
+
+	for chainId, want := range map[uint64]*big.Int{137: gwei(50), 1: gwei(10)} {
+		price, err := oracle.GasPrice(context.Background(), chainId)
+		if err != nil || price.Cmp(want) != 0 {
+			t.Errorf("unexpected price on chainId %d: %v, err: %v", chainId, price, err)
+		}
+	}
+
+	// Callers bump the returned fees in place, the configured price must
+	// not change.
+	fees, err := oracle.Fees(context.Background(), 137)
+	if err != nil {
+		t.Fatalf("fees err: %v", err)
+	}
+	fees.MaxFeePerGas.Mul(fees.MaxFeePerGas, big.NewInt(2))
+	if price, _ := oracle.GasPrice(context.Background(), 137); price.Cmp(gwei(50)) != 0 {
+		t.Fatalf("expected the fixed price to be unchanged, price: %v", price)
+	}
+
+	_, err = FixedOracle(nil, nil).GasPrice(context.Background(), 1)
+	if !errors.Is(err, common.ErrUnsupportedChain) {
+		t.Fatalf("expected unsupported chain, err: %v", err)
+	}
+}
+
+func TestMedianOracle(t *testing.T) {
+	for _, tc := range []struct {
+		name    string
+		oracles []Oracle
+		want    *big.Int
+	}{
+		{name: "odd", oracles: []Oracle{fixed(30), fixed(500), fixed(20)}, want: gwei(30)},
+		{name: "even", oracles: []Oracle{fixed(30), fixed(40), fixed(20), fixed(500)}, want: gwei(35)},
+		{name: "failure ignored", oracles: []Oracle{fixed(30), failing, fixed(40)}, want: gwei(35)},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			price, err := MedianOracle(tc.oracles...).GasPrice(context.Background(), 1)
+			if err != nil || price.Cmp(tc.want) != 0 {
+				t.Fatalf("unexpected median: %v, err: %v", price, err)
+			}
+		})
+	}
+
+	if _, err := MedianOracle(failing, failing).GasPrice(context.Background(), 1); err == nil {
+		t.Fatalf("expected an error when every oracle fails")
+	}
+}
+
+func TestCappedOracle(t *testing.T) {
+	caps := map[uint64]Cap{
+		1:   {Min: gwei(5), Max: gwei(100)},
+		137: {Min: gwei(30)},
+	}
+
+	for _, tc := range []struct {
+		name    string
+		chainId uint64
+		price   int64
+		want    *big.Int
+	}{
+		{name: "below min", chainId: 1, price: 1, want: gwei(5)},
+		{name: "above max", chainId: 1, price: 300, want: gwei(100)},
+		{name: "within", chainId: 1, price: 50, want: gwei(50)},
+		{name: "no max", chainId: 137, price: 300, want: gwei(300)},
+		{name: "no cap", chainId: 10, price: 1, want: gwei(1)},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			price, err := CappedOracle(fixed(tc.price), caps).GasPrice(context.Background(), tc.chainId)
+			if err != nil || price.Cmp(tc.want) != 0 {
+				t.Fatalf("unexpected capped price: %v, err: %v", price, err)
+			}
+		})
+	}
+}
+
+// feeOracle reports EIP-1559 fees, its GasPrice is the max fee.
+type feeOracle FeeEstimate
+
+func fees(maxFee, tip int64) feeOracle {
+	return feeOracle{MaxFeePerGas: gwei(maxFee), MaxPriorityFeePerGas: gwei(tip)}
+}
+
+func (o feeOracle) GasPrice(ctx context.Context, chainId uint64) (*big.Int, error) {
+	return o.MaxFeePerGas, nil
+}
+
+func (o feeOracle) Fees(ctx context.Context, chainId uint64) (FeeEstimate, error) {
+	return FeeEstimate(o), nil
+}
+
+func TestOracleFees(t *testing.T) {
+	client := newNode(t, &FeeHistory{
+		OldestBlock:   hexBig(big.NewInt(100)),
+		BaseFeePerGas: []*hexutil.Big{hexBig(gwei(30)), hexBig(gwei(32))},
+		GasUsedRatio:  []float64{0.7},
+		Reward:        rewards([3]int64{3, 3, 3}),
+	})
+	caps := map[uint64]Cap{1: {Min: gwei(5), Max: gwei(50)}}
+
+	for _, tc := range []struct {
+		name    string
+		oracle  Oracle
+		chainId uint64
+		want    FeeEstimate
+	}{
+		// maxFee allows the projected 32 gwei base fee to double.
+		{name: "fee history", oracle: FeeHistoryOracle(map[uint64]*w3.Client{137: client}, 75, 10), chainId: 137, want: FeeEstimate(fees(67, 3))},
+		{name: "fixed", oracle: FixedOracle(map[uint64]*big.Int{1: gwei(50)}, nil), chainId: 1, want: FeeEstimate(fees(50, 50))},
+		{name: "fixed fallback", oracle: FixedOracle(nil, fees(60, 2)), chainId: 1, want: FeeEstimate(fees(60, 2))},
+		{name: "median", oracle: MedianOracle(fees(60, 2), fees(80, 3), fees(500, 400), failing), chainId: 1, want: FeeEstimate(fees(80, 3))},
+		{name: "median tip bound", oracle: MedianOracle(fees(10, 10), fees(20, 20), fees(30, 2), fees(40, 30)), chainId: 1, want: FeeEstimate(fees(25, 15))},
+		{name: "capped above max", oracle: CappedOracle(fees(80, 60), caps), chainId: 1, want: FeeEstimate(fees(50, 50))},
+		{name: "capped below min", oracle: CappedOracle(fees(1, 1), caps), chainId: 1, want: FeeEstimate(fees(5, 1))},
+		{name: "uncapped", oracle: CappedOracle(fees(80, 60), caps), chainId: 137, want: FeeEstimate(fees(80, 60))},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			got, err := tc.oracle.Fees(context.Background(), tc.chainId)
+			if err != nil || got.MaxFeePerGas.Cmp(tc.want.MaxFeePerGas) != 0 || got.MaxPriorityFeePerGas.Cmp(tc.want.MaxPriorityFeePerGas) != 0 {
+				t.Fatalf("unexpected fees: %+v, want: %+v, err: %v", got, tc.want, err)
+			}
+		})
+	}
+}
@@ -0,0 +1,43 @@
+package gas
+
+import (
+	"math/big"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/lmittmann/w3/w3types"
+)
+
+// BalanceOverride funds account with balance of native token, e.g. to
+// estimate a swap from a wallet that is not funded yet.
+func BalanceOverride(account string, balance *big.Int) w3types.State {
+	return w3types.State{
+		common.HexToAddress(account): {Balance: balance},
+	}
+}
+
+// ERC20Override sets the token balance of owner and its allowance to spender
+// to amount, e.g. to estimate a swap before the approval is mined.
+// balanceSlot and allowanceSlot are the storage slots of the token's
+// balances and allowances mappings, which depend on the token, e.g. 9 and 10
+// for the USDC implementation. Mappings are assumed laid out by Solidity,
+// Vyper tokens hash their keys in the opposite order.
+func ERC20Override(token, owner, spender string, amount *big.Int, balanceSlot, allowanceSlot uint64) w3types.State {
+	ownerAddr := common.HexToAddress(owner)
+	value := common.BigToHash(amount)
+
+	return w3types.State{
+		common.HexToAddress(token): {
+			Storage: w3types.Storage{
+				MappingSlot(ownerAddr, common.BigToHash(new(big.Int).SetUint64(balanceSlot))):                                              value,
+				MappingSlot(common.HexToAddress(spender), MappingSlot(ownerAddr, common.BigToHash(new(big.Int).SetUint64(allowanceSlot)))): value,
+			},
+		},
+	}
+}
+
+// MappingSlot returns the storage slot of key in the Solidity mapping stored
+// at slot.
+func MappingSlot(key common.Address, slot common.Hash) common.Hash {
+	return crypto.Keccak256Hash(common.LeftPadBytes(key.Bytes(), 32), slot.Bytes())
+}
@@ -35,27 +35,13 @@ func PriceEstimate(ctx context.Context, client *w3.Client) (*big.Int, error) {
 	return &gasPrice, nil
 }
 
+// EIP1559Estimate returns the projected base fee of the next block and the
+// standard priority fee, see FeeHistoryEstimate. On chains without EIP-1559
+// the base fee is zero and the priority fee is the gas price.
 func EIP1559Estimate(ctx context.Context, client *w3.Client) (*big.Int, *big.Int, error) {
-	var (
-		chainID     uint64
-		baseFee     big.Int
-		priorityFee big.Int
-		errs        w3.CallErrors
-	)
-
-	if err := client.CallCtx(ctx,
-		w3eth.ChainID().Returns(&chainID),
-		w3eth.GasPrice().Returns(&baseFee),
-		w3eth.GasTipCap().Returns(&priorityFee),
-	); errors.As(err, &errs) {
-		if errs[0] != nil {
-			return DEFAULT, DEFAULT, fmt.Errorf("failed to get chain ID: %s", err)
-		} else if errs[1] != nil {
-			return DEFAULT, DEFAULT, fmt.Errorf("failed to get gas price: %s", errs[1])
-		}
-	} else if err != nil {
-		return DEFAULT, DEFAULT, fmt.Errorf("failed RPC request: %s", err)
+	estimates, err := FeeHistoryEstimate(ctx, client)
+	if err != nil {
+		return DEFAULT, DEFAULT, err
 	}
-
-	return &baseFee, &priorityFee, nil
+	return estimates.BaseFee, estimates.Standard.MaxPriorityFeePerGas, nil
 }
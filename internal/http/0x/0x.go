@@ -2,10 +2,12 @@ package zerox
 
 import (
 	"context"
+	"encoding/json"
 	"fmt"
 	"math/big"
 	uri "net/url"
-	"os"
+	"strconv"
+	"strings"
 
 	"github.com/onmetahq/go-evm/internal/http/common"
 	metahttp "github.com/onmetahq/meta-http/pkg/meta_http"
@@ -14,25 +16,49 @@ import (
 type zeroX struct {
 	client      metahttp.Requests
 	chainUrlMap map[uint64]string
+	credentials common.CredentialProvider
 }
 
-func NewZeroX(client metahttp.Requests, chainUrlMap map[uint64]string) *zeroX {
-	return &zeroX{
+type Option func(*zeroX)
+
+// WithCredentials sets the API key source, the 0X_KEY environment variable
+// is read on every call by default.
+func WithCredentials(credentials common.CredentialProvider) Option {
+	return func(o *zeroX) {
+		o.credentials = credentials
+	}
+}
+
+func NewZeroX(client metahttp.Requests, chainUrlMap map[uint64]string, opts ...Option) *zeroX {
+	o := &zeroX{
 		client:      client,
 		chainUrlMap: chainUrlMap,
+		credentials: common.EnvCredentials("0X_KEY"),
+	}
+
+	for _, opt := range opts {
+		opt(o)
 	}
+	return o
 }
 
-func (o *zeroX) FetchSupportedTokens(ctx context.Context, chainId uint64) (any, error) {
-	return "", fmt.Errorf("operation not supported")
+var _ common.Aggregator = (*zeroX)(nil)
+
+func (o *zeroX) FetchSupportedTokens(ctx context.Context, chainId uint64) ([]common.Token, error) {
+	return []common.Token{}, fmt.Errorf("operation not supported")
 }
 
 func (o *zeroX) FetchExactInQuote(ctx context.Context, req common.QuoteReq) (common.QuoteRes, error) {
+	slippage, err := req.Slippage()
+	if err != nil {
+		return common.QuoteRes{}, err
+	}
+
 	v := uri.Values{}
 	v.Add("buyToken", req.Dst)
 	v.Add("sellToken", req.Src)
 	v.Add("sellAmount", req.Amount.String())
-	v.Add("slippagePercentage", "0.01")
+	v.Add("slippagePercentage", common.BpsFraction(slippage))
 	v.Add("skipValidation", "false")
 
 	if req.From != "" {
@@ -43,20 +69,29 @@ func (o *zeroX) FetchExactInQuote(ctx context.Context, req common.QuoteReq) (com
 		v.Add("skipValidation", "true")
 	}
 
+	if err := addV1Params(v, req); err != nil {
+		return common.QuoteRes{}, err
+	}
+
 	res, err := o.price(ctx, req.ChainId, v.Encode())
 	if err != nil {
 		return common.QuoteRes{}, err
 	}
 	res.OutAmount = res.BuyAmount
-	return parseZeroxResponse(req, res)
+	return parseZeroxResponse(req, res, slippage, false)
 }
 
 func (o *zeroX) FetchExactOutQuote(ctx context.Context, req common.QuoteReq) (common.QuoteRes, error) {
+	slippage, err := req.Slippage()
+	if err != nil {
+		return common.QuoteRes{}, err
+	}
+
 	v := uri.Values{}
 	v.Add("buyToken", req.Dst)
 	v.Add("sellToken", req.Src)
 	v.Add("buyAmount", req.Amount.String())
-	v.Add("slippagePercentage", "0.01")
+	v.Add("slippagePercentage", common.BpsFraction(slippage))
 	v.Add("skipValidation", "false")
 
 	if req.From != "" {
@@ -67,20 +102,29 @@ func (o *zeroX) FetchExactOutQuote(ctx context.Context, req common.QuoteReq) (co
 		v.Add("skipValidation", "true")
 	}
 
+	if err := addV1Params(v, req); err != nil {
+		return common.QuoteRes{}, err
+	}
+
 	res, err := o.price(ctx, req.ChainId, v.Encode())
 	if err != nil {
 		return common.QuoteRes{}, err
 	}
 	res.OutAmount = res.SellAmount
-	return parseZeroxResponse(req, res)
+	return parseZeroxResponse(req, res, slippage, true)
 }
 
-func (o *zeroX) FetchExactInSwapCallData(ctx context.Context, req common.QuoteReq) (ZeroXSwapResponse, error) {
+func (o *zeroX) FetchExactInSwapCallData(ctx context.Context, req common.QuoteReq) (common.SwapTx, error) {
+	slippage, err := req.Slippage()
+	if err != nil {
+		return common.SwapTx{}, err
+	}
+
 	v := uri.Values{}
 	v.Add("buyToken", req.Dst)
 	v.Add("sellToken", req.Src)
 	v.Add("sellAmount", req.Amount.String())
-	v.Add("slippagePercentage", "0.01")
+	v.Add("slippagePercentage", common.BpsFraction(slippage))
 
 	if req.From != "" {
 		v.Add("takerAddress", req.From)
@@ -92,15 +136,28 @@ func (o *zeroX) FetchExactInSwapCallData(ctx context.Context, req common.QuoteRe
 		v.Add("skipValidation", "false")
 	}
 
-	return o.quote(ctx, req.ChainId, v.Encode())
+	if err := addV1Params(v, req); err != nil {
+		return common.SwapTx{}, err
+	}
+
+	res, err := o.quote(ctx, req.ChainId, v.Encode())
+	if err != nil {
+		return common.SwapTx{}, err
+	}
+	return parseZeroxSwapResponse(req, res, slippage, false)
 }
 
-func (o *zeroX) FetchExactOutSwapCallData(ctx context.Context, req common.QuoteReq) (ZeroXSwapResponse, error) {
+func (o *zeroX) FetchExactOutSwapCallData(ctx context.Context, req common.QuoteReq) (common.SwapTx, error) {
+	slippage, err := req.Slippage()
+	if err != nil {
+		return common.SwapTx{}, err
+	}
+
 	v := uri.Values{}
 	v.Add("buyToken", req.Dst)
 	v.Add("sellToken", req.Src)
 	v.Add("buyAmount", req.Amount.String())
-	v.Add("slippagePercentage", "0.01")
+	v.Add("slippagePercentage", common.BpsFraction(slippage))
 
 	if req.From != "" {
 		v.Add("takerAddress", req.From)
@@ -112,24 +169,64 @@ func (o *zeroX) FetchExactOutSwapCallData(ctx context.Context, req common.QuoteR
 		v.Add("skipValidation", "false")
 	}
 
-	return o.quote(ctx, req.ChainId, v.Encode())
+	if err := addV1Params(v, req); err != nil {
+		return common.SwapTx{}, err
+	}
+
+	res, err := o.quote(ctx, req.ChainId, v.Encode())
+	if err != nil {
+		return common.SwapTx{}, err
+	}
+	return parseZeroxSwapResponse(req, res, slippage, true)
+}
+
+// addV1Params adds the optional parameters of req shared by /price and
+// /quote. v1 always pays the taker, a different receiver is rejected.
+func addV1Params(v uri.Values, req common.QuoteReq) error {
+	if req.Receiver != "" && !strings.EqualFold(req.Receiver, req.From) {
+		return fmt.Errorf("receiver is not supported by 0x v1, err: %w", common.ErrUnsupportedParameter)
+	}
+
+	if req.FeeBps > 0 {
+		if req.Referrer == "" {
+			return fmt.Errorf("0x fee requires a referrer to receive it")
+		}
+		v.Add("feeRecipient", req.Referrer)
+		v.Add("buyTokenPercentageFee", common.BpsFraction(req.FeeBps))
+	}
+
+	if req.GasPrice != nil {
+		v.Add("gasPrice", req.GasPrice.String())
+	}
+
+	if len(req.IncludedSources) > 0 {
+		v.Add("includedSources", strings.Join(req.IncludedSources, ","))
+	}
+
+	if len(req.ExcludedSources) > 0 {
+		v.Add("excludedSources", strings.Join(req.ExcludedSources, ","))
+	}
+	return nil
 }
 
 func (o *zeroX) quote(ctx context.Context, chainId uint64, queryParams string) (ZeroXSwapResponse, error) {
 	base, ok := o.chainUrlMap[chainId]
 	if !ok {
-		return ZeroXSwapResponse{}, fmt.Errorf("unsupported chainId %d", chainId)
+		return ZeroXSwapResponse{}, fmt.Errorf("unsupported chainId %d, err: %w", chainId, common.ErrUnsupportedChain)
 	}
 
 	url := fmt.Sprintf("%s/swap/v1/quote?%s", base, queryParams)
 	var res ZeroXSwapResponse
 
-	_, err := o.client.Get(ctx, url, map[string]string{
-		"0x-api-key": os.Getenv("0X_KEY"),
-	}, &res)
+	headers, err := o.headers(ctx)
+	if err != nil {
+		return ZeroXSwapResponse{}, err
+	}
+
+	_, err = o.client.Get(ctx, url, headers, &res)
 
 	if err != nil {
-		return ZeroXSwapResponse{}, fmt.Errorf("unable to fetch 0x quote, err: %v", err)
+		return ZeroXSwapResponse{}, fmt.Errorf("unable to fetch 0x quote, err: %w", parseError(err))
 	}
 
 	return res, nil
@@ -138,23 +235,74 @@ func (o *zeroX) quote(ctx context.Context, chainId uint64, queryParams string) (
 func (o *zeroX) price(ctx context.Context, chainId uint64, queryParams string) (ZeroXQuoteResponse, error) {
 	base, ok := o.chainUrlMap[chainId]
 	if !ok {
-		return ZeroXQuoteResponse{}, fmt.Errorf("unsupported chainId %d", chainId)
+		return ZeroXQuoteResponse{}, fmt.Errorf("unsupported chainId %d, err: %w", chainId, common.ErrUnsupportedChain)
 	}
 
 	url := fmt.Sprintf("%s/swap/v1/price?%s", base, queryParams)
 	var res ZeroXQuoteResponse
 
-	_, err := o.client.Get(ctx, url, map[string]string{
-		"0x-api-key": os.Getenv("0X_KEY"),
-	}, &res)
+	headers, err := o.headers(ctx)
+	if err != nil {
+		return ZeroXQuoteResponse{}, err
+	}
+
+	_, err = o.client.Get(ctx, url, headers, &res)
 
 	if err != nil {
-		return ZeroXQuoteResponse{}, fmt.Errorf("unable to fetch 0x quote, err: %v", err)
+		return ZeroXQuoteResponse{}, fmt.Errorf("unable to fetch 0x quote, err: %w", parseError(err))
 	}
 
 	return res, nil
 }
 
+func (o *zeroX) headers(ctx context.Context) (map[string]string, error) {
+	key, err := o.credentials.Credential(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("unable to load 0x credentials, err: %w", err)
+	}
+
+	return map[string]string{
+		"0x-api-key": key,
+	}, nil
+}
+
+type ZeroXErrorResponse struct {
+	Code             int    `json:"code"`
+	Reason           string `json:"reason"`
+	ValidationErrors []struct {
+		Field       string `json:"field"`
+		Code        int    `json:"code"`
+		Reason      string `json:"reason"`
+		Description string `json:"description"`
+	} `json:"validationErrors"`
+	Values struct {
+		Message string `json:"message"`
+	} `json:"values"`
+}
+
+// parseError classifies a failed 0x call from its error payload, e.g.
+// {"code":100,"reason":"Validation Failed","validationErrors":[{"field":"sellAmount","code":1004,"reason":"INSUFFICIENT_ASSET_LIQUIDITY"}]}.
+func parseError(err error) error {
+	status, body, ok := common.StatusAndBody(err)
+	if !ok {
+		return common.NewProviderError("0x", 0, "", "", err)
+	}
+
+	var payload ZeroXErrorResponse
+	if json.Unmarshal(body, &payload) != nil || payload.Reason == "" {
+		return common.NewProviderError("0x", status, "", string(body), err)
+	}
+
+	parts := []string{payload.Reason}
+	for _, v := range payload.ValidationErrors {
+		parts = append(parts, fmt.Sprintf("%s: %s", v.Field, strings.ReplaceAll(v.Reason, "_", " ")))
+	}
+	if payload.Values.Message != "" {
+		parts = append(parts, payload.Values.Message)
+	}
+	return common.NewProviderError("0x", status, strconv.Itoa(payload.Code), strings.Join(parts, ", "), err)
+}
+
 type ZeroXQuoteResponse struct {
 	AllowanceTarget      string `json:"allowanceTarget"`
 	BuyAmount            string `json:"buyAmount"`
@@ -246,7 +394,7 @@ type ZeroXSwapResponse struct {
 	Value string `json:"value"`
 }
 
-func parseZeroxResponse(req common.QuoteReq, quote ZeroXQuoteResponse) (common.QuoteRes, error) {
+func parseZeroxResponse(req common.QuoteReq, quote ZeroXQuoteResponse, slippage uint32, exactOut bool) (common.QuoteRes, error) {
 	outAmount, ok := new(big.Int).SetString(quote.OutAmount, 0)
 	if !ok {
 		return common.QuoteRes{}, fmt.Errorf("invalid out amount from 0x, amount: %v", quote.OutAmount)
@@ -262,13 +410,71 @@ func parseZeroxResponse(req common.QuoteReq, quote ZeroXQuoteResponse) (common.Q
 		return common.QuoteRes{}, fmt.Errorf("invalid out amount from 0x, amount: %v", quote.OutAmount)
 	}
 
+	// Exact out quotes carry the sell amount in ToAmount, the buy amount is
+	// the requested one.
+	minOut := req.Amount
+	if !exactOut {
+		minOut = common.MinReceived(outAmount, slippage)
+	}
+
 	return common.QuoteRes{
-		ChainId:    req.ChainId,
-		Src:        req.Src,
-		Dst:        req.Dst,
-		FromAmount: req.Amount,
-		ToAmount:   outAmount,
-		Gas:        gas,
-		GasPrice:   gasPrice,
+		ChainId:     req.ChainId,
+		Src:         req.Src,
+		Dst:         req.Dst,
+		FromAmount:  req.Amount,
+		ToAmount:    outAmount,
+		Gas:         gas,
+		GasPrice:    gasPrice,
+		MinToAmount: minOut,
+	}, nil
+}
+
+func parseZeroxSwapResponse(req common.QuoteReq, swap ZeroXSwapResponse, slippage uint32, exactOut bool) (common.SwapTx, error) {
+	sellAmount, ok := common.ParseBigInt(swap.SellAmount)
+	if !ok {
+		return common.SwapTx{}, fmt.Errorf("invalid sell amount from 0x, amount: %v", swap.SellAmount)
+	}
+
+	buyAmount, ok := common.ParseBigInt(swap.BuyAmount)
+	if !ok {
+		return common.SwapTx{}, fmt.Errorf("invalid buy amount from 0x, amount: %v", swap.BuyAmount)
+	}
+
+	value, ok := common.ParseBigInt(swap.Value)
+	if !ok {
+		return common.SwapTx{}, fmt.Errorf("invalid tx value from 0x, value: %v", swap.Value)
+	}
+
+	gas, ok := common.ParseBigInt(swap.Gas)
+	if !ok {
+		return common.SwapTx{}, fmt.Errorf("invalid gas from 0x, gas: %v", swap.Gas)
+	}
+
+	gasPrice, ok := common.ParseBigInt(swap.GasPrice)
+	if !ok {
+		return common.SwapTx{}, fmt.Errorf("invalid gas price from 0x, gasPrice: %v", swap.GasPrice)
+	}
+
+	// Exact out buys exactly buyAmount, exact in may receive up to the
+	// slippage less.
+	minOut := buyAmount
+	if !exactOut {
+		minOut = common.MinReceived(buyAmount, slippage)
+	}
+
+	return common.SwapTx{
+		ChainId:         req.ChainId,
+		Src:             req.Src,
+		Dst:             req.Dst,
+		FromAmount:      sellAmount,
+		ToAmount:        buyAmount,
+		MinToAmount:     minOut,
+		From:            req.From,
+		To:              swap.To,
+		Data:            swap.Data,
+		Value:           value,
+		Gas:             gas,
+		GasPrice:        gasPrice,
+		AllowanceTarget: swap.AllowanceTarget,
 	}, nil
 }
@@ -2,22 +2,44 @@ package zerox
 
 import (
 	"context"
-	"log/slog"
+	"errors"
 	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"path/filepath"
 	"testing"
-	"time"
 
 	"github.com/onmetahq/go-evm/internal/http/common"
+	"github.com/onmetahq/go-evm/internal/http/fake"
+	"github.com/onmetahq/go-evm/internal/http/recorder"
 	metahttp "github.com/onmetahq/meta-http/pkg/meta_http"
 )
 
 const TOKENA = "0xeeeeeeeeeeeeeeeeeeeeeeeeeeeeeeeeeeeeeeee"
 const TOKENB = "0x2791bca1f2de4661ed88a30c99a7a9449aa84174"
 
-func Test0XFetchExactInQuote(t *testing.T) {
-	c := metahttp.NewClient("", slog.Default(), 30*time.Second)
+// newRecordedClient replays testdata/<name>.json. The cassettes checked in
+// are all .synthetic, hand written after the documented 0x responses, so
+// these tests cover the parsing of those responses and not the live API.
+// Running with GO_EVM_RECORD=1 and 0X_KEY set overwrites a cassette with
+// live responses, drop its .synthetic suffix when committing it.
+func newRecordedClient(t *testing.T, name string) metahttp.Requests {
+	rec, err := recorder.New(filepath.Join("testdata", name+".json"), recorder.ModeFromEnv(), nil)
+	if err != nil {
+		t.Fatalf("cassette err: %v", err)
+	}
+	t.Cleanup(func() {
+		if err := rec.Save(); err != nil {
+			t.Errorf("save cassette err: %v", err)
+		}
+	})
 
-	oneClient := NewZeroX(c, map[uint64]string{
+	return common.NewClient("", rec.Client())
+}
+
+func Test0XFetchExactInQuote(t *testing.T) {
+	oneClient := NewZeroX(newRecordedClient(t, "price_exact_in.synthetic"), map[uint64]string{
 		137: "https://polygon.api.0x.org",
 	})
 
@@ -39,9 +61,7 @@ func Test0XFetchExactInQuote(t *testing.T) {
 }
 
 func Test0XFetchExactOutQuote(t *testing.T) {
-	c := metahttp.NewClient("", slog.Default(), 30*time.Second)
-
-	oneClient := NewZeroX(c, map[uint64]string{
+	oneClient := NewZeroX(newRecordedClient(t, "price_exact_out.synthetic"), map[uint64]string{
 		137: "https://polygon.api.0x.org",
 	})
 
@@ -68,9 +88,7 @@ func Test0XFetchExactOutQuote(t *testing.T) {
 }
 
 func Test0XFetchExactInSwapCallData(t *testing.T) {
-	c := metahttp.NewClient("", slog.Default(), 30*time.Second)
-
-	oneClient := NewZeroX(c, map[uint64]string{
+	oneClient := NewZeroX(newRecordedClient(t, "quote_exact_in.synthetic"), map[uint64]string{
 		137: "https://polygon.api.0x.org",
 	})
 
@@ -78,7 +96,7 @@ func Test0XFetchExactInSwapCallData(t *testing.T) {
 		Src:            TOKENB,
 		Dst:            TOKENA,
 		ChainId:        137,
-		Amount:         big.NewInt(1e18),
+		Amount:         big.NewInt(1000000),
 		SkipValidation: true,
 	}
 
@@ -91,3 +109,130 @@ func Test0XFetchExactInSwapCallData(t *testing.T) {
 		t.Fatalf("invalid call data, res: %v", res)
 	}
 }
+
+func Test0XErrorPaths(t *testing.T) {
+	server := fake.NewServer()
+	defer server.Close()
+
+	oneClient := NewZeroX(common.NewClient("", nil), map[uint64]string{
+		137: server.URL + fake.ZeroXPrefix,
+	})
+
+	req := common.QuoteReq{
+		Src:     TOKENB,
+		Dst:     TOKENA,
+		ChainId: 137,
+		Amount:  big.NewInt(1e18),
+	}
+
+	server.Fail("/swap/v1/quote", http.StatusBadRequest, `{"code":100,"reason":"Validation Failed","validationErrors":[{"field":"sellAmount","code":1004,"reason":"INSUFFICIENT_ASSET_LIQUIDITY"}]}`)
+	_, err := oneClient.FetchExactInSwapCallData(context.Background(), req)
+	if !errors.Is(err, common.ErrInsufficientLiquidity) {
+		t.Fatalf("expected insufficient liquidity, err: %v", err)
+	}
+
+	var providerErr *common.ProviderError
+	if !errors.As(err, &providerErr) || providerErr.Code != "100" || providerErr.StatusCode != http.StatusBadRequest {
+		t.Fatalf("invalid provider error, err: %+v", providerErr)
+	}
+
+	server.Fail("/swap/v1/price", http.StatusTooManyRequests, `{"reason":"Too Many Requests"}`)
+	if _, err := oneClient.FetchExactInQuote(context.Background(), req); !errors.Is(err, common.ErrRateLimited) || !common.IsRetryable(err) {
+		t.Fatalf("expected rate limited, err: %v", err)
+	}
+
+	req.ChainId = 1
+	if _, err := oneClient.FetchExactInQuote(context.Background(), req); !errors.Is(err, common.ErrUnsupportedChain) {
+		t.Fatalf("expected unsupported chain, err: %v", err)
+	}
+}
+
+func Test0XFetchExactOutSwapCallDataFake(t *testing.T) {
+	server := fake.NewServer()
+	defer server.Close()
+
+	oneClient := NewZeroX(common.NewClient("", nil), map[uint64]string{
+		137: server.URL + fake.ZeroXPrefix,
+	})
+
+	req := common.QuoteReq{
+		Src:         TOKENB,
+		Dst:         TOKENA,
+		ChainId:     137,
+		Amount:      big.NewInt(2e6),
+		SlippageBps: common.Bps(50),
+	}
+
+	res, err := oneClient.FetchExactOutSwapCallData(context.Background(), req)
+	if err != nil {
+		t.Fatalf("quote err: %v", err)
+	}
+
+	if res.FromAmount.Cmp(big.NewInt(1e6)) != 0 || res.MinToAmount.Cmp(req.Amount) != 0 {
+		t.Fatalf("invalid amounts, from: %s, min: %s", res.FromAmount, res.MinToAmount)
+	}
+
+	if res.AllowanceTarget != fake.ExchangeAddress || res.Data != fake.SwapCallData {
+		t.Fatalf("invalid tx, res: %+v", res)
+	}
+}
+
+// newCapturingServer serves the fake aggregator and records the query of
+// the last request.
+func newCapturingServer(t *testing.T) (*httptest.Server, *url.Values) {
+	upstream := fake.NewServer()
+	t.Cleanup(upstream.Close)
+
+	query := &url.Values{}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		*query = r.URL.Query()
+		upstream.Config.Handler.ServeHTTP(w, r)
+	}))
+	t.Cleanup(server.Close)
+	return server, query
+}
+
+func Test0XSwapParams(t *testing.T) {
+	server, query := newCapturingServer(t)
+
+	oneClient := NewZeroX(common.NewClient("", nil), map[uint64]string{137: server.URL + fake.ZeroXPrefix})
+	req := common.QuoteReq{
+		Src:             TOKENB,
+		Dst:             TOKENA,
+		ChainId:         137,
+		Amount:          big.NewInt(1e6),
+		From:            "0x15Ba05723b04785C3E21157171810892A4FB795c",
+		Referrer:        "0x000000000000000000000000000000000000dEaD",
+		FeeBps:          25,
+		GasPrice:        big.NewInt(30_000_000_000),
+		IncludedSources: []string{"Uniswap_V3", "QuickSwap"},
+		ExcludedSources: []string{"Curve"},
+	}
+
+	if _, err := oneClient.FetchExactInSwapCallData(context.Background(), req); err != nil {
+		t.Fatalf("swap err: %v", err)
+	}
+
+	want := map[string]string{
+		"feeRecipient":          req.Referrer,
+		"buyTokenPercentageFee": "0.0025",
+		"gasPrice":              "30000000000",
+		"includedSources":       "Uniswap_V3,QuickSwap",
+		"excludedSources":       "Curve",
+	}
+	for k, v := range want {
+		if query.Get(k) != v {
+			t.Errorf("invalid %s, want: %s, got: %s", k, v, query.Get(k))
+		}
+	}
+
+	req.Receiver = req.Referrer
+	if _, err := oneClient.FetchExactInQuote(context.Background(), req); !errors.Is(err, common.ErrUnsupportedParameter) {
+		t.Fatalf("expected receiver to be unsupported, err: %v", err)
+	}
+
+	req.Receiver, req.Referrer = "", ""
+	if _, err := oneClient.FetchExactOutSwapCallData(context.Background(), req); err == nil {
+		t.Fatalf("expected fee without referrer to fail")
+	}
+}
@@ -0,0 +1,349 @@
+package zerox
+
+import (
+	"context"
+	"fmt"
+	uri "net/url"
+	"strconv"
+	"strings"
+
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/signer/core/apitypes"
+	"github.com/onmetahq/go-evm/internal/http/common"
+)
+
+// eip712SignatureType is the 0x signature type of EIP-712 signatures.
+const eip712SignatureType = 2
+
+var _ common.OrderAggregator = (*zeroX)(nil)
+
+// FetchGaslessPrice returns the indicative gasless price of req, the gas is
+// paid by the 0x relayer and deducted from the buy amount.
+func (o *zeroX) FetchGaslessPrice(ctx context.Context, req common.QuoteReq) (ZeroXGaslessPriceResponse, error) {
+	var res ZeroXGaslessPriceResponse
+	if err := o.gaslessGet(ctx, "price", req, &res); err != nil {
+		return ZeroXGaslessPriceResponse{}, err
+	}
+
+	if !res.LiquidityAvailable {
+		return ZeroXGaslessPriceResponse{}, fmt.Errorf("unable to fetch 0x gasless price, err: %w", common.ErrInsufficientLiquidity)
+	}
+	return res, nil
+}
+
+// FetchGaslessQuote returns the firm gasless quote of req with the EIP-712
+// payloads the taker signs: the trade, and the approval when the sell token
+// supports gasless approvals and is not approved yet.
+func (o *zeroX) FetchGaslessQuote(ctx context.Context, req common.QuoteReq) (ZeroXGaslessQuoteResponse, error) {
+	if req.From == "" {
+		return ZeroXGaslessQuoteResponse{}, fmt.Errorf("0x gasless quote requires a taker")
+	}
+
+	var res ZeroXGaslessQuoteResponse
+	if err := o.gaslessGet(ctx, "quote", req, &res); err != nil {
+		return ZeroXGaslessQuoteResponse{}, err
+	}
+
+	if !res.LiquidityAvailable {
+		return ZeroXGaslessQuoteResponse{}, fmt.Errorf("unable to fetch 0x gasless quote, err: %w", common.ErrInsufficientLiquidity)
+	}
+	return res, nil
+}
+
+// SubmitGasless sends the signed quote to the relayer and returns the trade
+// hash to poll. approvalSignature is only used when the quote has an
+// approval payload.
+func (o *zeroX) SubmitGasless(ctx context.Context, chainId uint64, quote ZeroXGaslessQuoteResponse, approvalSignature, tradeSignature []byte) (string, error) {
+	base, ok := o.chainUrlMap[chainId]
+	if !ok {
+		return "", fmt.Errorf("unsupported chainId %d, err: %w", chainId, common.ErrUnsupportedChain)
+	}
+
+	trade, err := signedPayload(quote.Trade, tradeSignature)
+	if err != nil {
+		return "", err
+	}
+
+	body := ZeroXGaslessSubmission{
+		ChainId: chainId,
+		Trade:   trade,
+	}
+
+	if quote.Approval != nil {
+		approval, err := signedPayload(*quote.Approval, approvalSignature)
+		if err != nil {
+			return "", err
+		}
+		body.Approval = &approval
+	}
+
+	headers, err := o.headers(ctx)
+	if err != nil {
+		return "", err
+	}
+	headers["0x-version"] = "v2"
+
+	var res ZeroXGaslessSubmitResponse
+	_, err = o.client.Post(ctx, fmt.Sprintf("%s/gasless/submit", base), headers, body, &res)
+	if err != nil {
+		return "", fmt.Errorf("unable to submit 0x gasless trade, err: %w", parseError(err))
+	}
+	return res.TradeHash, nil
+}
+
+// FetchGaslessStatus returns the relayer status of the trade.
+func (o *zeroX) FetchGaslessStatus(ctx context.Context, chainId uint64, tradeHash string) (ZeroXGaslessStatusResponse, error) {
+	base, ok := o.chainUrlMap[chainId]
+	if !ok {
+		return ZeroXGaslessStatusResponse{}, fmt.Errorf("unsupported chainId %d, err: %w", chainId, common.ErrUnsupportedChain)
+	}
+
+	headers, err := o.headers(ctx)
+	if err != nil {
+		return ZeroXGaslessStatusResponse{}, err
+	}
+	headers["0x-version"] = "v2"
+
+	var res ZeroXGaslessStatusResponse
+	url := fmt.Sprintf("%s/gasless/status/%s?chainId=%d", base, tradeHash, chainId)
+	_, err = o.client.Get(ctx, url, headers, &res)
+	if err != nil {
+		return ZeroXGaslessStatusResponse{}, fmt.Errorf("unable to fetch 0x gasless status, err: %w", parseError(err))
+	}
+	return res, nil
+}
+
+// PlaceOrder quotes req through the gasless API, signs the approval and
+// trade payloads with signer and submits them to the relayer.
+func (o *zeroX) PlaceOrder(ctx context.Context, req common.QuoteReq, signer common.Signer) (common.Order, error) {
+	req.From = signer.Address()
+
+	quote, err := o.FetchGaslessQuote(ctx, req)
+	if err != nil {
+		return common.Order{}, err
+	}
+
+	var approvalSignature []byte
+	if quote.Approval != nil {
+		if approvalSignature, err = signer.SignTypedData(ctx, quote.Approval.EIP712); err != nil {
+			return common.Order{}, err
+		}
+	}
+
+	tradeSignature, err := signer.SignTypedData(ctx, quote.Trade.EIP712)
+	if err != nil {
+		return common.Order{}, err
+	}
+
+	tradeHash, err := o.SubmitGasless(ctx, req.ChainId, quote, approvalSignature, tradeSignature)
+	if err != nil {
+		return common.Order{}, err
+	}
+
+	sellAmount, ok := common.ParseBigInt(quote.SellAmount)
+	if !ok {
+		return common.Order{}, fmt.Errorf("invalid sell amount from 0x, amount: %v", quote.SellAmount)
+	}
+
+	minBuyAmount, ok := common.ParseBigInt(quote.MinBuyAmount)
+	if !ok {
+		return common.Order{}, fmt.Errorf("invalid min buy amount from 0x, amount: %v", quote.MinBuyAmount)
+	}
+
+	return common.Order{
+		ChainId:    req.ChainId,
+		Id:         tradeHash,
+		Owner:      req.From,
+		Src:        req.Src,
+		Dst:        req.Dst,
+		SellAmount: sellAmount,
+		BuyAmount:  minBuyAmount,
+		Status:     common.OrderPending,
+	}, nil
+}
+
+// FetchOrder returns the gasless trade id as an order. The status endpoint
+// only reports the state and transactions of the trade, so Owner, Src, Dst
+// and the amounts are left empty, the Order returned by PlaceOrder has them.
+func (o *zeroX) FetchOrder(ctx context.Context, chainId uint64, id string) (common.Order, error) {
+	res, err := o.FetchGaslessStatus(ctx, chainId, id)
+	if err != nil {
+		return common.Order{}, err
+	}
+
+	// submitted trades are broadcast but not mined, succeeded ones are mined
+	// and confirmed ones have enough confirmations. States added by 0x later
+	// are treated as pending rather than final.
+	status := common.OrderPending
+	switch res.Status {
+	case "succeeded", "confirmed":
+		status = common.OrderFilled
+	case "failed":
+		status = common.OrderFailed
+	}
+
+	txHash := ""
+	if len(res.Transactions) > 0 {
+		txHash = res.Transactions[len(res.Transactions)-1].Hash
+	}
+
+	return common.Order{
+		ChainId: chainId,
+		Id:      id,
+		Status:  status,
+		TxHash:  txHash,
+	}, nil
+}
+
+func (o *zeroX) gaslessGet(ctx context.Context, endpoint string, req common.QuoteReq, res any) error {
+	base, ok := o.chainUrlMap[req.ChainId]
+	if !ok {
+		return fmt.Errorf("unsupported chainId %d, err: %w", req.ChainId, common.ErrUnsupportedChain)
+	}
+
+	// The relayer pulls the sell token from the taker, ether cannot be.
+	if common.IsNativeToken(req.Src) {
+		return fmt.Errorf("native sell trades are not supported by 0x gasless, err: %w", common.ErrUnsupportedToken)
+	}
+
+	// The relayer settles the trade to the taker and prices the gas itself.
+	if req.Receiver != "" && !strings.EqualFold(req.Receiver, req.From) {
+		return fmt.Errorf("receiver is not supported by 0x gasless, err: %w", common.ErrUnsupportedParameter)
+	}
+
+	slippage, err := req.Slippage()
+	if err != nil {
+		return err
+	}
+
+	v := uri.Values{}
+	v.Add("chainId", strconv.FormatUint(req.ChainId, 10))
+	v.Add("buyToken", req.Dst)
+	v.Add("sellToken", req.Src)
+	v.Add("sellAmount", req.Amount.String())
+	v.Add("slippageBps", strconv.FormatUint(uint64(slippage), 10))
+
+	if req.From != "" {
+		v.Add("taker", req.From)
+	}
+
+	if err := addV2Params(v, req); err != nil {
+		return err
+	}
+
+	headers, err := o.headers(ctx)
+	if err != nil {
+		return err
+	}
+	headers["0x-version"] = "v2"
+
+	url := fmt.Sprintf("%s/gasless/%s?%s", base, endpoint, v.Encode())
+	if _, err = o.client.Get(ctx, url, headers, res); err != nil {
+		return fmt.Errorf("unable to fetch 0x gasless %s, err: %w", endpoint, parseError(err))
+	}
+	return nil
+}
+
+// signedPayload splits the 65 byte r || s || v signature into the fields
+// expected by the relayer.
+func signedPayload(payload ZeroXGaslessPayload, signature []byte) (ZeroXGaslessSignedPayload, error) {
+	if len(signature) != 65 {
+		return ZeroXGaslessSignedPayload{}, fmt.Errorf("invalid %s signature, length: %d", payload.Type, len(signature))
+	}
+
+	v := signature[64]
+	if v < 27 {
+		v += 27
+	}
+
+	return ZeroXGaslessSignedPayload{
+		Type:   payload.Type,
+		EIP712: payload.EIP712,
+		Signature: ZeroXGaslessSignature{
+			V:             v,
+			R:             hexutil.Encode(signature[:32]),
+			S:             hexutil.Encode(signature[32:64]),
+			SignatureType: eip712SignatureType,
+		},
+	}, nil
+}
+
+type ZeroXGaslessPriceResponse struct {
+	AllowanceTarget    string        `json:"allowanceTarget"`
+	BlockNumber        string        `json:"blockNumber"`
+	BuyAmount          string        `json:"buyAmount"`
+	BuyToken           string        `json:"buyToken"`
+	Fees               ZeroXV2Fees   `json:"fees"`
+	Issues             ZeroXV2Issues `json:"issues"`
+	LiquidityAvailable bool          `json:"liquidityAvailable"`
+	MinBuyAmount       string        `json:"minBuyAmount"`
+	Route              ZeroXV2Route  `json:"route"`
+	SellAmount         string        `json:"sellAmount"`
+	SellToken          string        `json:"sellToken"`
+	Target             string        `json:"target"`
+	Zid                string        `json:"zid"`
+}
+
+// ZeroXGaslessPayload is an EIP-712 payload to sign, Type is e.g. permit or
+// executeMetaTransaction::approve for approvals and settler_metatransaction
+// for trades.
+type ZeroXGaslessPayload struct {
+	Type   string             `json:"type"`
+	Hash   string             `json:"hash"`
+	EIP712 apitypes.TypedData `json:"eip712"`
+}
+
+type ZeroXGaslessQuoteResponse struct {
+	Approval           *ZeroXGaslessPayload `json:"approval"`
+	BlockNumber        string               `json:"blockNumber"`
+	BuyAmount          string               `json:"buyAmount"`
+	BuyToken           string               `json:"buyToken"`
+	Fees               ZeroXV2Fees          `json:"fees"`
+	Issues             ZeroXV2Issues        `json:"issues"`
+	LiquidityAvailable bool                 `json:"liquidityAvailable"`
+	MinBuyAmount       string               `json:"minBuyAmount"`
+	Route              ZeroXV2Route         `json:"route"`
+	SellAmount         string               `json:"sellAmount"`
+	SellToken          string               `json:"sellToken"`
+	Target             string               `json:"target"`
+	Trade              ZeroXGaslessPayload  `json:"trade"`
+	Zid                string               `json:"zid"`
+}
+
+type ZeroXGaslessSignature struct {
+	V             uint8  `json:"v"`
+	R             string `json:"r"`
+	S             string `json:"s"`
+	SignatureType int    `json:"signatureType"`
+}
+
+type ZeroXGaslessSignedPayload struct {
+	Type      string                `json:"type"`
+	EIP712    apitypes.TypedData    `json:"eip712"`
+	Signature ZeroXGaslessSignature `json:"signature"`
+}
+
+type ZeroXGaslessSubmission struct {
+	ChainId  uint64                     `json:"chainId"`
+	Approval *ZeroXGaslessSignedPayload `json:"approval,omitempty"`
+	Trade    ZeroXGaslessSignedPayload  `json:"trade"`
+}
+
+type ZeroXGaslessSubmitResponse struct {
+	TradeHash string `json:"tradeHash"`
+	Type      string `json:"type"`
+	Zid       string `json:"zid"`
+}
+
+type ZeroXGaslessStatusResponse struct {
+	Status       string `json:"status"`
+	Reason       string `json:"reason"`
+	Transactions []struct {
+		Hash      string `json:"hash"`
+		Timestamp int64  `json:"timestamp"`
+	} `json:"transactions"`
+	ApprovalTransactions []struct {
+		Hash      string `json:"hash"`
+		Timestamp int64  `json:"timestamp"`
+	} `json:"approvalTransactions"`
+}
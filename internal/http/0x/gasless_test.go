@@ -0,0 +1,172 @@
+package zerox
+
+import (
+	"context"
+	"errors"
+	"math/big"
+	"testing"
+	"time"
+
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/onmetahq/go-evm/internal/http/common"
+	"github.com/onmetahq/go-evm/internal/http/fake"
+)
+
+func TestGaslessPlaceOrder(t *testing.T) {
+	server := fake.NewServer()
+	defer server.Close()
+
+	key, err := crypto.GenerateKey()
+	if err != nil {
+		t.Fatalf("key err: %v", err)
+	}
+	signer := common.PrivateKeySigner(key)
+
+	client := NewZeroX(common.NewClient("", nil), map[uint64]string{137: server.URL + fake.ZeroXPrefix})
+	req := common.QuoteReq{
+		Src:         TOKENB,
+		Dst:         TOKENA,
+		ChainId:     137,
+		Amount:      big.NewInt(1e6),
+		SlippageBps: common.Bps(50),
+	}
+
+	price, err := client.FetchGaslessPrice(context.Background(), req)
+	if err != nil {
+		t.Fatalf("price err: %v", err)
+	}
+
+	// The relayer keeps 1% of the 2000000 quoted for gas.
+	if price.BuyAmount != "1980000" || price.Fees.GasFee == nil || price.Fees.GasFee.Amount != "20000" {
+		t.Fatalf("invalid gasless price, price: %+v", price)
+	}
+
+	order, err := client.PlaceOrder(context.Background(), req, signer)
+	if err != nil {
+		t.Fatalf("place order err: %v", err)
+	}
+
+	if order.BuyAmount.Cmp(big.NewInt(1_970_100)) != 0 || order.Status != common.OrderPending || order.Id == "" {
+		t.Fatalf("invalid order, order: %+v", order)
+	}
+
+	filled, err := common.WaitForOrder(context.Background(), client, 137, order.Id, time.Millisecond)
+	if err != nil {
+		t.Fatalf("wait err: %v", err)
+	}
+
+	if filled.Status != common.OrderFilled || filled.TxHash == "" {
+		t.Fatalf("expected a confirmed trade, order: %+v", filled)
+	}
+
+	if calls := server.Calls("/gasless/status/" + order.Id); calls != 2 {
+		t.Fatalf("expected a submitted then a confirmed poll, calls: %d", calls)
+	}
+}
+
+func TestGaslessErrorPaths(t *testing.T) {
+	server := fake.NewServer()
+	defer server.Close()
+
+	client := NewZeroX(common.NewClient("", nil), map[uint64]string{137: server.URL + fake.ZeroXPrefix})
+	req := common.QuoteReq{
+		Src:     TOKENB,
+		Dst:     TOKENA,
+		ChainId: 137,
+		Amount:  big.NewInt(1e6),
+		From:    "0x15Ba05723b04785C3E21157171810892A4FB795c",
+	}
+
+	if _, err := client.FetchGaslessPrice(context.Background(), common.QuoteReq{ChainId: 1, Src: TOKENB, Dst: TOKENA, Amount: big.NewInt(1)}); !errors.Is(err, common.ErrUnsupportedChain) {
+		t.Fatalf("expected ErrUnsupportedChain, err: %v", err)
+	}
+
+	native := req
+	native.Src, native.Dst = TOKENA, TOKENB
+	if _, err := client.FetchGaslessQuote(context.Background(), native); !errors.Is(err, common.ErrUnsupportedToken) {
+		t.Fatalf("expected ErrUnsupportedToken, err: %v", err)
+	}
+
+	quote, err := client.FetchGaslessQuote(context.Background(), req)
+	if err != nil {
+		t.Fatalf("quote err: %v", err)
+	}
+
+	if quote.Approval == nil || quote.Approval.Type != "permit" || quote.Trade.EIP712.PrimaryType != "SlippageAndActions" {
+		t.Fatalf("missing approval and trade payloads, quote: %+v", quote)
+	}
+
+	// Signed by another key than the taker.
+	key, _ := crypto.GenerateKey()
+	signer := common.PrivateKeySigner(key)
+	approval, _ := signer.SignTypedData(context.Background(), quote.Approval.EIP712)
+	trade, _ := signer.SignTypedData(context.Background(), quote.Trade.EIP712)
+	if _, err := client.SubmitGasless(context.Background(), 137, quote, approval, trade); err == nil {
+		t.Fatalf("expected the relayer to reject the trade")
+	}
+
+	if _, err := client.SubmitGasless(context.Background(), 137, quote, nil, trade); err == nil {
+		t.Fatalf("expected an error without the approval signature")
+	}
+}
+
+func TestGaslessFetchOrderStatus(t *testing.T) {
+	server := fake.NewServer()
+	defer server.Close()
+
+	client := NewZeroX(common.NewClient("", nil), map[uint64]string{137: server.URL + fake.ZeroXPrefix})
+	for body, want := range map[string]common.OrderStatus{
+		`{"status":"submitted"}`:     common.OrderPending,
+		`{"status":"confirmed"}`:     common.OrderFilled,
+		`{"status":"failed"}`:        common.OrderFailed,
+		`{"status":"rate_replaced"}`: common.OrderPending,
+	} {
+		server.Fail("/gasless/status/0x01", 200, body)
+		order, err := client.FetchOrder(context.Background(), 137, "0x01")
+		if err != nil || order.Status != want {
+			t.Fatalf("invalid status for %s, status: %s, err: %v", body, order.Status, err)
+		}
+	}
+}
+
+func TestGaslessSwapParams(t *testing.T) {
+	server, query := newCapturingServer(t)
+
+	client := NewZeroX(common.NewClient("", nil), map[uint64]string{137: server.URL + fake.ZeroXPrefix})
+	req := common.QuoteReq{
+		Src:             TOKENB,
+		Dst:             TOKENA,
+		ChainId:         137,
+		Amount:          big.NewInt(1e6),
+		From:            "0x15Ba05723b04785C3E21157171810892A4FB795c",
+		Referrer:        "0x000000000000000000000000000000000000dEaD",
+		FeeBps:          25,
+		ExcludedSources: []string{"Curve"},
+	}
+
+	if _, err := client.FetchGaslessQuote(context.Background(), req); err != nil {
+		t.Fatalf("quote err: %v", err)
+	}
+
+	want := map[string]string{
+		"swapFeeBps":       "25",
+		"swapFeeRecipient": req.Referrer,
+		"swapFeeToken":     req.Dst,
+		"excludedSources":  "Curve",
+	}
+	for k, v := range want {
+		if query.Get(k) != v {
+			t.Errorf("invalid %s, want: %s, got: %s", k, v, query.Get(k))
+		}
+	}
+
+	req.Receiver = req.Referrer
+	if _, err := client.FetchGaslessPrice(context.Background(), req); !errors.Is(err, common.ErrUnsupportedParameter) {
+		t.Fatalf("expected receiver to be unsupported, err: %v", err)
+	}
+
+	req.Receiver, req.IncludedSources = "", []string{"Uniswap_V3"}
+	if _, err := client.FetchGaslessPrice(context.Background(), req); !errors.Is(err, common.ErrUnsupportedParameter) {
+		t.Fatalf("expected included sources to be unsupported, err: %v", err)
+	}
+}
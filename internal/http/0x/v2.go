@@ -0,0 +1,379 @@
+package zerox
+
+import (
+	"context"
+	"encoding/hex"
+	"fmt"
+	"math/big"
+	uri "net/url"
+	"strconv"
+	"strings"
+
+	"github.com/ethereum/go-ethereum/signer/core/apitypes"
+	"github.com/onmetahq/go-evm/internal/http/common"
+	metahttp "github.com/onmetahq/meta-http/pkg/meta_http"
+)
+
+// V2Mode selects the 0x v2 settlement flow.
+type V2Mode string
+
+const (
+	// V2Permit2 sells through Permit2, the taker signs the returned EIP-712
+	// payload and appends the signature to the transaction data.
+	V2Permit2 V2Mode = "permit2"
+	// V2AllowanceHolder sells through the AllowanceHolder contract with a
+	// plain ERC-20 approval.
+	V2AllowanceHolder V2Mode = "allowance-holder"
+)
+
+const DefaultV2BaseUrl = "https://api.0x.org"
+
+type zeroXV2 struct {
+	*zeroX
+	baseUrl string
+	mode    V2Mode
+}
+
+// NewZeroXV2 returns a client for the 0x v2 swap API. Unlike v1 every chain
+// is served by baseUrl, DefaultV2BaseUrl when empty, with a chainId param.
+func NewZeroXV2(client metahttp.Requests, baseUrl string, mode V2Mode, opts ...Option) *zeroXV2 {
+	if baseUrl == "" {
+		baseUrl = DefaultV2BaseUrl
+	}
+
+	return &zeroXV2{
+		zeroX:   NewZeroX(client, nil, opts...),
+		baseUrl: strings.TrimSuffix(baseUrl, "/"),
+		mode:    mode,
+	}
+}
+
+var _ common.Aggregator = (*zeroXV2)(nil)
+
+func (o *zeroXV2) FetchExactInQuote(ctx context.Context, req common.QuoteReq) (common.QuoteRes, error) {
+	res, err := o.FetchPriceV2(ctx, req)
+	if err != nil {
+		return common.QuoteRes{}, err
+	}
+
+	outAmount, ok := common.ParseBigInt(res.BuyAmount)
+	if !ok {
+		return common.QuoteRes{}, fmt.Errorf("invalid out amount from 0x, amount: %v", res.BuyAmount)
+	}
+
+	minOut, ok := common.ParseBigInt(res.MinBuyAmount)
+	if !ok {
+		return common.QuoteRes{}, fmt.Errorf("invalid min out amount from 0x, amount: %v", res.MinBuyAmount)
+	}
+
+	gas, ok := common.ParseBigInt(res.Gas)
+	if !ok {
+		return common.QuoteRes{}, fmt.Errorf("invalid gas from 0x, gas: %v", res.Gas)
+	}
+
+	gasPrice, ok := common.ParseBigInt(res.GasPrice)
+	if !ok {
+		return common.QuoteRes{}, fmt.Errorf("invalid gas price from 0x, gasPrice: %v", res.GasPrice)
+	}
+
+	return common.QuoteRes{
+		ChainId:     req.ChainId,
+		Src:         req.Src,
+		Dst:         req.Dst,
+		FromAmount:  req.Amount,
+		ToAmount:    outAmount,
+		Gas:         gas,
+		GasPrice:    gasPrice,
+		MinToAmount: minOut,
+	}, nil
+}
+
+// FetchExactOutQuote is not supported, 0x v2 only sells exact amounts.
+func (o *zeroXV2) FetchExactOutQuote(ctx context.Context, req common.QuoteReq) (common.QuoteRes, error) {
+	return common.QuoteRes{}, fmt.Errorf("operation exact out is not supported by 0x v2, err: %w", common.ErrUnsupportedParameter)
+}
+
+// FetchExactInSwapCallData returns the transaction of an AllowanceHolder
+// swap. V2Permit2 transactions revert without the taker's Permit2
+// signature, in that mode sign the payload of FetchQuoteV2 and build the
+// transaction with V2SwapTx instead.
+func (o *zeroXV2) FetchExactInSwapCallData(ctx context.Context, req common.QuoteReq) (common.SwapTx, error) {
+	if o.mode == V2Permit2 {
+		return common.SwapTx{}, fmt.Errorf("0x permit2 swaps need the taker signature, use FetchQuoteV2 and V2SwapTx, err: %w", common.ErrUnsupportedParameter)
+	}
+
+	res, err := o.FetchQuoteV2(ctx, req)
+	if err != nil {
+		return common.SwapTx{}, err
+	}
+	return V2SwapTx(req, res, nil)
+}
+
+func (o *zeroXV2) FetchExactOutSwapCallData(ctx context.Context, req common.QuoteReq) (common.SwapTx, error) {
+	return common.SwapTx{}, fmt.Errorf("operation exact out is not supported by 0x v2, err: %w", common.ErrUnsupportedParameter)
+}
+
+// FetchPriceV2 returns the indicative v2 price including its issues block.
+func (o *zeroXV2) FetchPriceV2(ctx context.Context, req common.QuoteReq) (ZeroXV2PriceResponse, error) {
+	var res ZeroXV2PriceResponse
+	if err := o.get(ctx, "price", req, &res); err != nil {
+		return ZeroXV2PriceResponse{}, err
+	}
+
+	if !res.LiquidityAvailable {
+		return ZeroXV2PriceResponse{}, fmt.Errorf("unable to fetch 0x price, err: %w", common.ErrInsufficientLiquidity)
+	}
+	return res, nil
+}
+
+// FetchQuoteV2 returns the firm v2 quote, in V2Permit2 mode Permit2 holds
+// the EIP-712 payload the taker has to sign.
+func (o *zeroXV2) FetchQuoteV2(ctx context.Context, req common.QuoteReq) (ZeroXV2QuoteResponse, error) {
+	var res ZeroXV2QuoteResponse
+	if err := o.get(ctx, "quote", req, &res); err != nil {
+		return ZeroXV2QuoteResponse{}, err
+	}
+
+	if !res.LiquidityAvailable {
+		return ZeroXV2QuoteResponse{}, fmt.Errorf("unable to fetch 0x quote, err: %w", common.ErrInsufficientLiquidity)
+	}
+	return res, nil
+}
+
+func (o *zeroXV2) get(ctx context.Context, endpoint string, req common.QuoteReq, res any) error {
+	slippage, err := req.Slippage()
+	if err != nil {
+		return err
+	}
+
+	v := uri.Values{}
+	v.Add("chainId", strconv.FormatUint(req.ChainId, 10))
+	v.Add("buyToken", req.Dst)
+	v.Add("sellToken", req.Src)
+	v.Add("sellAmount", req.Amount.String())
+	v.Add("slippageBps", strconv.FormatUint(uint64(slippage), 10))
+
+	if req.From != "" {
+		v.Add("taker", req.From)
+	}
+
+	if req.Receiver != "" {
+		v.Add("recipient", req.Receiver)
+	}
+
+	if req.GasPrice != nil {
+		v.Add("gasPrice", req.GasPrice.String())
+	}
+
+	if err := addV2Params(v, req); err != nil {
+		return err
+	}
+
+	headers, err := o.headers(ctx)
+	if err != nil {
+		return err
+	}
+	headers["0x-version"] = "v2"
+
+	url := fmt.Sprintf("%s/swap/%s/%s?%s", o.baseUrl, o.mode, endpoint, v.Encode())
+	if _, err = o.client.Get(ctx, url, headers, res); err != nil {
+		return fmt.Errorf("unable to fetch 0x %s, err: %w", endpoint, parseError(err))
+	}
+	return nil
+}
+
+// addV2Params adds the fee and source parameters shared by the v2 swap and
+// gasless APIs. The fee is taken from the buy token, v2 can only exclude
+// sources.
+func addV2Params(v uri.Values, req common.QuoteReq) error {
+	if len(req.IncludedSources) > 0 {
+		return fmt.Errorf("included sources are not supported by 0x v2, err: %w", common.ErrUnsupportedParameter)
+	}
+
+	if req.FeeBps > 0 {
+		if req.Referrer == "" {
+			return fmt.Errorf("0x fee requires a referrer to receive it")
+		}
+		v.Add("swapFeeBps", strconv.FormatUint(uint64(req.FeeBps), 10))
+		v.Add("swapFeeRecipient", req.Referrer)
+		v.Add("swapFeeToken", req.Dst)
+	}
+
+	if len(req.ExcludedSources) > 0 {
+		v.Add("excludedSources", strings.Join(req.ExcludedSources, ","))
+	}
+	return nil
+}
+
+// AppendPermit2Signature appends the taker's Permit2 signature to the
+// transaction data of a V2Permit2 quote, prefixed by its length as a 32
+// byte big endian integer as required by the settler contract.
+func AppendPermit2Signature(data string, signature []byte) (string, error) {
+	if len(signature) == 0 {
+		return "", fmt.Errorf("empty permit2 signature")
+	}
+
+	raw, err := hex.DecodeString(strings.TrimPrefix(data, "0x"))
+	if err != nil {
+		return "", fmt.Errorf("invalid transaction data, err: %v", err)
+	}
+
+	size := make([]byte, 32)
+	new(big.Int).SetInt64(int64(len(signature))).FillBytes(size)
+
+	out := append(raw, size...)
+	out = append(out, signature...)
+	return "0x" + hex.EncodeToString(out), nil
+}
+
+type ZeroXV2Issues struct {
+	// Allowance is set when the taker has not approved Spender for enough
+	// of the sell token.
+	Allowance *struct {
+		Actual  string `json:"actual"`
+		Spender string `json:"spender"`
+	} `json:"allowance"`
+	// Balance is set when the taker holds less than the sell amount.
+	Balance *struct {
+		Token    string `json:"token"`
+		Actual   string `json:"actual"`
+		Expected string `json:"expected"`
+	} `json:"balance"`
+	SimulationIncomplete bool     `json:"simulationIncomplete"`
+	InvalidSourcesPassed []string `json:"invalidSourcesPassed"`
+}
+
+type ZeroXV2Fees struct {
+	IntegratorFee *ZeroXV2Fee `json:"integratorFee"`
+	ZeroExFee     *ZeroXV2Fee `json:"zeroExFee"`
+	GasFee        *ZeroXV2Fee `json:"gasFee"`
+}
+
+type ZeroXV2Fee struct {
+	Amount string `json:"amount"`
+	Token  string `json:"token"`
+	Type   string `json:"type"`
+}
+
+type ZeroXV2Route struct {
+	Fills []struct {
+		From          string `json:"from"`
+		To            string `json:"to"`
+		Source        string `json:"source"`
+		ProportionBps string `json:"proportionBps"`
+	} `json:"fills"`
+	Tokens []struct {
+		Address string `json:"address"`
+		Symbol  string `json:"symbol"`
+	} `json:"tokens"`
+}
+
+type ZeroXV2PriceResponse struct {
+	AllowanceTarget    string        `json:"allowanceTarget"`
+	BlockNumber        string        `json:"blockNumber"`
+	BuyAmount          string        `json:"buyAmount"`
+	BuyToken           string        `json:"buyToken"`
+	Fees               ZeroXV2Fees   `json:"fees"`
+	Gas                string        `json:"gas"`
+	GasPrice           string        `json:"gasPrice"`
+	Issues             ZeroXV2Issues `json:"issues"`
+	LiquidityAvailable bool          `json:"liquidityAvailable"`
+	MinBuyAmount       string        `json:"minBuyAmount"`
+	Route              ZeroXV2Route  `json:"route"`
+	SellAmount         string        `json:"sellAmount"`
+	SellToken          string        `json:"sellToken"`
+	TotalNetworkFee    string        `json:"totalNetworkFee"`
+	Zid                string        `json:"zid"`
+}
+
+type ZeroXV2Permit2 struct {
+	Type   string             `json:"type"`
+	Hash   string             `json:"hash"`
+	EIP712 apitypes.TypedData `json:"eip712"`
+}
+
+type ZeroXV2QuoteResponse struct {
+	AllowanceTarget    string          `json:"allowanceTarget"`
+	BlockNumber        string          `json:"blockNumber"`
+	BuyAmount          string          `json:"buyAmount"`
+	BuyToken           string          `json:"buyToken"`
+	Fees               ZeroXV2Fees     `json:"fees"`
+	Issues             ZeroXV2Issues   `json:"issues"`
+	LiquidityAvailable bool            `json:"liquidityAvailable"`
+	MinBuyAmount       string          `json:"minBuyAmount"`
+	Permit2            *ZeroXV2Permit2 `json:"permit2"`
+	Route              ZeroXV2Route    `json:"route"`
+	SellAmount         string          `json:"sellAmount"`
+	SellToken          string          `json:"sellToken"`
+	TotalNetworkFee    string          `json:"totalNetworkFee"`
+	Transaction        struct {
+		To       string `json:"to"`
+		Data     string `json:"data"`
+		Gas      string `json:"gas"`
+		GasPrice string `json:"gasPrice"`
+		Value    string `json:"value"`
+	} `json:"transaction"`
+	Zid string `json:"zid"`
+}
+
+// V2SwapTx returns the transaction of quote. Permit2 quotes need signature,
+// the taker's signature of quote.Permit2.EIP712, which is appended to the
+// transaction data, other quotes take none.
+func V2SwapTx(req common.QuoteReq, quote ZeroXV2QuoteResponse, signature []byte) (common.SwapTx, error) {
+	data := quote.Transaction.Data
+	switch {
+	case quote.Permit2 != nil:
+		var err error
+		if data, err = AppendPermit2Signature(data, signature); err != nil {
+			return common.SwapTx{}, err
+		}
+	case len(signature) > 0:
+		return common.SwapTx{}, fmt.Errorf("0x quote has no permit2 payload to sign")
+	}
+
+	sellAmount, ok := common.ParseBigInt(quote.SellAmount)
+	if !ok {
+		return common.SwapTx{}, fmt.Errorf("invalid sell amount from 0x, amount: %v", quote.SellAmount)
+	}
+
+	buyAmount, ok := common.ParseBigInt(quote.BuyAmount)
+	if !ok {
+		return common.SwapTx{}, fmt.Errorf("invalid buy amount from 0x, amount: %v", quote.BuyAmount)
+	}
+
+	minOut, ok := common.ParseBigInt(quote.MinBuyAmount)
+	if !ok {
+		return common.SwapTx{}, fmt.Errorf("invalid min buy amount from 0x, amount: %v", quote.MinBuyAmount)
+	}
+
+	value, ok := common.ParseBigInt(quote.Transaction.Value)
+	if !ok {
+		return common.SwapTx{}, fmt.Errorf("invalid tx value from 0x, value: %v", quote.Transaction.Value)
+	}
+
+	gas, ok := common.ParseBigInt(quote.Transaction.Gas)
+	if !ok {
+		return common.SwapTx{}, fmt.Errorf("invalid gas from 0x, gas: %v", quote.Transaction.Gas)
+	}
+
+	gasPrice, ok := common.ParseBigInt(quote.Transaction.GasPrice)
+	if !ok {
+		return common.SwapTx{}, fmt.Errorf("invalid gas price from 0x, gasPrice: %v", quote.Transaction.GasPrice)
+	}
+
+	return common.SwapTx{
+		ChainId:         req.ChainId,
+		Src:             req.Src,
+		Dst:             req.Dst,
+		FromAmount:      sellAmount,
+		ToAmount:        buyAmount,
+		MinToAmount:     minOut,
+		From:            req.From,
+		To:              quote.Transaction.To,
+		Data:            data,
+		Value:           value,
+		Gas:             gas,
+		GasPrice:        gasPrice,
+		AllowanceTarget: quote.AllowanceTarget,
+	}, nil
+}
@@ -0,0 +1,143 @@
+package zerox
+
+import (
+	"context"
+	"errors"
+	"math/big"
+	"strings"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/signer/core/apitypes"
+	"github.com/onmetahq/go-evm/internal/http/common"
+	"github.com/onmetahq/go-evm/internal/http/fake"
+)
+
+func TestV2Permit2Quote(t *testing.T) {
+	server := fake.NewServer()
+	defer server.Close()
+
+	client := NewZeroXV2(common.NewClient("", nil), server.URL+fake.ZeroXPrefix, V2Permit2)
+	req := common.QuoteReq{
+		Src:         TOKENB,
+		Dst:         TOKENA,
+		ChainId:     137,
+		Amount:      big.NewInt(1e6),
+		From:        "0x15Ba05723b04785C3E21157171810892A4FB795c",
+		SlippageBps: common.Bps(50),
+	}
+
+	quote, err := client.FetchQuoteV2(context.Background(), req)
+	if err != nil {
+		t.Fatalf("quote err: %v", err)
+	}
+
+	if quote.Permit2 == nil || quote.Permit2.EIP712.PrimaryType != "PermitTransferFrom" {
+		t.Fatalf("missing permit2 payload, quote: %+v", quote)
+	}
+
+	if _, _, err := apitypes.TypedDataAndHash(quote.Permit2.EIP712); err != nil {
+		t.Fatalf("permit2 payload cannot be hashed, err: %v", err)
+	}
+
+	if quote.Issues.Allowance == nil || quote.Issues.Allowance.Spender != fake.Permit2Address {
+		t.Fatalf("invalid allowance issue, issues: %+v", quote.Issues)
+	}
+
+	if _, err := client.FetchExactInSwapCallData(context.Background(), req); !errors.Is(err, common.ErrUnsupportedParameter) {
+		t.Fatalf("expected unsigned permit2 calldata to be unsupported, err: %v", err)
+	}
+
+	if _, err := V2SwapTx(req, quote, nil); err == nil {
+		t.Fatalf("expected an error without the permit2 signature")
+	}
+
+	sig := make([]byte, 65)
+	sig[64] = 0x1b
+	tx, err := V2SwapTx(req, quote, sig)
+	if err != nil {
+		t.Fatalf("swap tx err: %v", err)
+	}
+
+	if tx.MinToAmount.Cmp(big.NewInt(1_990_000)) != 0 || tx.To != fake.SettlerAddress {
+		t.Fatalf("invalid swap tx, tx: %+v", tx)
+	}
+
+	suffix := strings.Repeat("0", 62) + "41" + strings.Repeat("0", 128) + "1b"
+	if !strings.HasPrefix(tx.Data, quote.Transaction.Data) || !strings.HasSuffix(tx.Data, suffix) {
+		t.Fatalf("invalid signed data, data: %s", tx.Data)
+	}
+}
+
+func TestV2AllowanceHolderPrice(t *testing.T) {
+	server := fake.NewServer()
+	defer server.Close()
+
+	client := NewZeroXV2(common.NewClient("", nil), server.URL+fake.ZeroXPrefix, V2AllowanceHolder)
+	res, err := client.FetchExactInQuote(context.Background(), common.QuoteReq{
+		Src:     TOKENB,
+		Dst:     TOKENA,
+		ChainId: 137,
+		Amount:  big.NewInt(1e6),
+	})
+	if err != nil {
+		t.Fatalf("price err: %v", err)
+	}
+
+	if res.ToAmount.Cmp(big.NewInt(2e6)) != 0 || res.MinToAmount.Cmp(big.NewInt(1_980_000)) != 0 {
+		t.Fatalf("invalid price, res: %+v", res)
+	}
+
+	if server.Calls("/swap/allowance-holder/price") != 1 {
+		t.Fatalf("allowance holder endpoint not called")
+	}
+
+	if _, err := client.FetchExactOutQuote(context.Background(), common.QuoteReq{ChainId: 137}); !errors.Is(err, common.ErrUnsupportedParameter) {
+		t.Fatalf("expected exact out to be unsupported, err: %v", err)
+	}
+}
+
+func TestV2SwapParams(t *testing.T) {
+	server, query := newCapturingServer(t)
+
+	client := NewZeroXV2(common.NewClient("", nil), server.URL+fake.ZeroXPrefix, V2AllowanceHolder)
+	req := common.QuoteReq{
+		Src:             TOKENB,
+		Dst:             TOKENA,
+		ChainId:         137,
+		Amount:          big.NewInt(1e6),
+		From:            "0x15Ba05723b04785C3E21157171810892A4FB795c",
+		Receiver:        "0x000000000000000000000000000000000000bEEF",
+		Referrer:        "0x000000000000000000000000000000000000dEaD",
+		FeeBps:          25,
+		GasPrice:        big.NewInt(30_000_000_000),
+		ExcludedSources: []string{"Curve"},
+	}
+
+	if _, err := client.FetchExactInSwapCallData(context.Background(), req); err != nil {
+		t.Fatalf("swap err: %v", err)
+	}
+
+	want := map[string]string{
+		"recipient":        req.Receiver,
+		"swapFeeBps":       "25",
+		"swapFeeRecipient": req.Referrer,
+		"swapFeeToken":     req.Dst,
+		"gasPrice":         "30000000000",
+		"excludedSources":  "Curve",
+	}
+	for k, v := range want {
+		if query.Get(k) != v {
+			t.Errorf("invalid %s, want: %s, got: %s", k, v, query.Get(k))
+		}
+	}
+
+	req.IncludedSources = []string{"Uniswap_V3"}
+	if _, err := client.FetchExactInQuote(context.Background(), req); !errors.Is(err, common.ErrUnsupportedParameter) {
+		t.Fatalf("expected included sources to be unsupported, err: %v", err)
+	}
+
+	req.IncludedSources, req.Referrer = nil, ""
+	if _, err := client.FetchExactInQuote(context.Background(), req); err == nil {
+		t.Fatalf("expected fee without referrer to fail")
+	}
+}
@@ -2,73 +2,128 @@ package oneinch
 
 import (
 	"context"
+	"encoding/json"
 	"fmt"
 	"math/big"
 	uri "net/url"
-	"os"
-	"strconv"
+	"sync"
 
 	"github.com/onmetahq/go-evm/internal/http/common"
 	metahttp "github.com/onmetahq/meta-http/pkg/meta_http"
 )
 
 type oneInch struct {
-	client metahttp.Requests
+	client      metahttp.Requests
+	credentials common.CredentialProvider
+	exactOut    ExactOutConfig
+	fusion      metahttp.Requests
+	fusionPlus  metahttp.Requests
+	// verifyEscrows checks the escrows of a Fusion+ fill before its secret
+	// is shared, nil trusts the relayer.
+	verifyEscrows EscrowVerifier
+
+	mu sync.Mutex
+	// spenders caches the router address returned by FetchSpender per chain.
+	spenders map[uint64]string
 }
 
-func NewOneInch(client metahttp.Requests) *oneInch {
-	return &oneInch{
-		client: client,
+type Option func(*oneInch)
+
+// WithCredentials sets the API key source, the 1INCH_KEY environment
+// variable is read on every call by default.
+func WithCredentials(credentials common.CredentialProvider) Option {
+	return func(o *oneInch) {
+		o.credentials = credentials
+	}
+}
+
+func NewOneInch(client metahttp.Requests, opts ...Option) *oneInch {
+	o := &oneInch{
+		client:      client,
+		credentials: common.EnvCredentials("1INCH_KEY"),
+		exactOut:    DefaultExactOutConfig,
+		spenders:    map[uint64]string{},
+	}
+
+	for _, opt := range opts {
+		opt(o)
 	}
+	return o
 }
 
-func (o *oneInch) FetchSupportedTokens(ctx context.Context, chainId uint64) ([]OneInchToken, error) {
+var _ common.Aggregator = (*oneInch)(nil)
+
+func (o *oneInch) FetchSupportedTokens(ctx context.Context, chainId uint64) ([]common.Token, error) {
 	var res OneInchTokens
 	url := fmt.Sprintf("/%d/tokens", chainId)
-	_, err := o.client.Get(ctx, url, map[string]string{
-		"Authorization": fmt.Sprintf("Bearer %s", os.Getenv("1INCH_KEY")),
-	}, &res)
+	headers, err := o.headers(ctx)
+	if err != nil {
+		return []common.Token{}, err
+	}
+	_, err = o.client.Get(ctx, url, headers, &res)
 
 	if err != nil {
-		return []OneInchToken{}, fmt.Errorf("unable to fetch all tokens from 1inch, err: %v", err)
+		return []common.Token{}, fmt.Errorf("unable to fetch all tokens from 1inch, err: %w", parseError(err))
 	}
 
-	var out []OneInchToken
+	var out []common.Token
 	for _, v := range res.Tokens {
-		out = append(out, v)
+		out = append(out, common.Token{
+			ChainId:  chainId,
+			Address:  v.Address,
+			Symbol:   v.Symbol,
+			Name:     v.Name,
+			Decimals: v.Decimals,
+			LogoURI:  v.LogoURI,
+		})
 	}
 
 	return out, nil
 }
 
 func (o *oneInch) FetchExactInQuote(ctx context.Context, req common.QuoteReq) (common.QuoteRes, error) {
+	slippage, err := req.Slippage()
+	if err != nil {
+		return common.QuoteRes{}, err
+	}
+
 	v := uri.Values{}
 	v.Add("src", req.Src)
 	v.Add("dst", req.Dst)
 	v.Add("amount", req.Amount.String())
 	v.Add("includeTokensInfo", "true")
 	v.Add("includeGas", "true")
+	if err := addRouteParams(v, req); err != nil {
+		return common.QuoteRes{}, err
+	}
 	query := v.Encode()
 	url := fmt.Sprintf("/%d/quote?%s", req.ChainId, query)
 	var res OneInchQuoteResponse
-	_, err := o.client.Get(ctx, url, map[string]string{
-		"Authorization": fmt.Sprintf("Bearer %s", os.Getenv("1INCH_KEY")),
-	}, &res)
+	headers, err := o.headers(ctx)
+	if err != nil {
+		return common.QuoteRes{}, err
+	}
+	_, err = o.client.Get(ctx, url, headers, &res)
 	if err != nil {
-		return common.QuoteRes{}, fmt.Errorf("unable to fetch 1inch quote, err: %v", err)
+		return common.QuoteRes{}, fmt.Errorf("unable to fetch 1inch quote, err: %w", parseError(err))
 	}
 
-	return parse1inchResponse(req, res)
+	return parse1inchResponse(req, res, slippage)
 }
 
-func (o *oneInch) FetchExactInSwapCallData(ctx context.Context, req common.QuoteReq) (OneInchSwapResponse, error) {
+func (o *oneInch) FetchExactInSwapCallData(ctx context.Context, req common.QuoteReq) (common.SwapTx, error) {
+	slippage, err := req.Slippage()
+	if err != nil {
+		return common.SwapTx{}, err
+	}
+
 	v := uri.Values{}
 	v.Add("src", req.Src)
 	v.Add("dst", req.Dst)
 	v.Add("amount", req.Amount.String())
 	v.Add("from", req.From)
 	v.Add("origin", req.From)
-	v.Add("slippage", strconv.Itoa(int(req.SlippagePercentage)))
+	v.Add("slippage", common.BpsPercent(slippage))
 	v.Add("includeTokensInfo", "true")
 	v.Add("includeGas", "true")
 	v.Add("disableEstimate", "false")
@@ -80,25 +135,60 @@ func (o *oneInch) FetchExactInSwapCallData(ctx context.Context, req common.Quote
 	if len(req.Referrer) > 0 {
 		v.Add("referrer", req.Referrer)
 	}
+
+	if err := addRouteParams(v, req); err != nil {
+		return common.SwapTx{}, err
+	}
+
+	if err := addSwapParams(v, req); err != nil {
+		return common.SwapTx{}, err
+	}
 	query := v.Encode()
 	url := fmt.Sprintf("/%d/swap?%s", req.ChainId, query)
 	var res OneInchSwapResponse
-	_, err := o.client.Get(ctx, url, map[string]string{
-		"Authorization": fmt.Sprintf("Bearer %s", os.Getenv("1INCH_KEY")),
-	}, &res)
+	headers, err := o.headers(ctx)
+	if err != nil {
+		return common.SwapTx{}, err
+	}
+	_, err = o.client.Get(ctx, url, headers, &res)
+	if err != nil {
+		return common.SwapTx{}, fmt.Errorf("unable to fetch 1inch quote, err: %w", parseError(err))
+	}
+
+	return parse1inchSwapResponse(req, res, slippage)
+}
+
+func (o *oneInch) headers(ctx context.Context) (map[string]string, error) {
+	key, err := o.credentials.Credential(ctx)
 	if err != nil {
-		return OneInchSwapResponse{}, fmt.Errorf("unable to fetch 1inch quote, err: %v", err)
+		return nil, fmt.Errorf("unable to load 1inch credentials, err: %w", err)
 	}
 
-	return res, nil
+	return map[string]string{
+		"Authorization": fmt.Sprintf("Bearer %s", key),
+	}, nil
 }
 
-func (o *oneInch) FetchExactOutQuote(ctx context.Context, req common.QuoteReq) (common.QuoteRes, error) {
-	return common.QuoteRes{}, fmt.Errorf("operation exact out is not supported")
+type OneInchErrorResponse struct {
+	Error       string `json:"error"`
+	Description string `json:"description"`
+	StatusCode  int    `json:"statusCode"`
+	RequestId   string `json:"requestId"`
 }
 
-func (o *oneInch) FetchExactOutSwapCallData(ctx context.Context, req common.QuoteReq) (OneInchSwapResponse, error) {
-	return OneInchSwapResponse{}, fmt.Errorf("operation not supported")
+// parseError classifies a failed 1inch call from its error payload, e.g.
+// {"error":"Bad Request","description":"insufficient liquidity"}.
+func parseError(err error) error {
+	status, body, ok := common.StatusAndBody(err)
+	if !ok {
+		return common.NewProviderError("1inch", 0, "", "", err)
+	}
+
+	var payload OneInchErrorResponse
+	if json.Unmarshal(body, &payload) == nil && payload.Description != "" {
+		return common.NewProviderError("1inch", status, payload.Error, payload.Description, err)
+	}
+	return common.NewProviderError("1inch", status, "", string(body), err)
 }
 
 type OneInchToken struct {
@@ -115,17 +205,27 @@ type OneInchTokens struct {
 	Tokens map[string]OneInchToken `json:"tokens"`
 }
 
+// OneInchQuoteResponse accepts both v5.2 (fromToken, toAmount) and v6
+// (srcToken, dstAmount) field names.
 type OneInchQuoteResponse struct {
-	FromToken OneInchToken `json:"fromToken"`
-	Gas       int64        `json:"gas"`
-	ToAmount  string       `json:"toAmount"`
-	ToToken   OneInchToken `json:"toToken"`
+	FromToken OneInchToken              `json:"fromToken"`
+	SrcToken  OneInchToken              `json:"srcToken"`
+	Gas       int64                     `json:"gas"`
+	ToAmount  string                    `json:"toAmount"`
+	DstAmount string                    `json:"dstAmount"`
+	ToToken   OneInchToken              `json:"toToken"`
+	DstToken  OneInchToken              `json:"dstToken"`
+	Protocols [][][]OneInchProtocolPart `json:"protocols"`
 }
 
 type OneInchSwapResponse struct {
-	FromToken OneInchToken `json:"fromToken"`
-	ToAmount  string       `json:"toAmount"`
-	ToToken   OneInchToken `json:"toToken"`
+	FromToken OneInchToken              `json:"fromToken"`
+	SrcToken  OneInchToken              `json:"srcToken"`
+	ToAmount  string                    `json:"toAmount"`
+	DstAmount string                    `json:"dstAmount"`
+	ToToken   OneInchToken              `json:"toToken"`
+	DstToken  OneInchToken              `json:"dstToken"`
+	Protocols [][][]OneInchProtocolPart `json:"protocols"`
 	Tx        struct {
 		Data     string `json:"data"`
 		From     string `json:"from"`
@@ -136,18 +236,68 @@ type OneInchSwapResponse struct {
 	} `json:"tx"`
 }
 
-func parse1inchResponse(req common.QuoteReq, quote OneInchQuoteResponse) (common.QuoteRes, error) {
-	outAmount, ok := new(big.Int).SetString(quote.ToAmount, 0)
+func parse1inchResponse(req common.QuoteReq, quote OneInchQuoteResponse, slippage uint32) (common.QuoteRes, error) {
+	toAmount := quote.ToAmount
+	if quote.DstAmount != "" {
+		toAmount = quote.DstAmount
+	}
+
+	outAmount, ok := new(big.Int).SetString(toAmount, 0)
 	if !ok {
-		return common.QuoteRes{}, fmt.Errorf("invalid out amount from 0x, amount: %v", quote.ToAmount)
+		return common.QuoteRes{}, fmt.Errorf("invalid out amount from 1inch, amount: %v", toAmount)
 	}
 
 	return common.QuoteRes{
-		ChainId:    req.ChainId,
-		Src:        req.Src,
-		Dst:        req.Dst,
-		FromAmount: req.Amount,
-		ToAmount:   outAmount,
-		Gas:        big.NewInt(quote.Gas),
+		ChainId:     req.ChainId,
+		Src:         req.Src,
+		Dst:         req.Dst,
+		FromAmount:  req.Amount,
+		ToAmount:    outAmount,
+		Gas:         big.NewInt(quote.Gas),
+		MinToAmount: common.MinReceived(outAmount, slippage),
+		Routes:      parseProtocols(quote.Protocols),
+	}, nil
+}
+
+func parse1inchSwapResponse(req common.QuoteReq, swap OneInchSwapResponse, slippage uint32) (common.SwapTx, error) {
+	toAmount := swap.ToAmount
+	if swap.DstAmount != "" {
+		toAmount = swap.DstAmount
+	}
+
+	outAmount, ok := common.ParseBigInt(toAmount)
+	if !ok {
+		return common.SwapTx{}, fmt.Errorf("invalid out amount from 1inch, amount: %v", toAmount)
+	}
+
+	value, ok := common.ParseBigInt(swap.Tx.Value)
+	if !ok {
+		return common.SwapTx{}, fmt.Errorf("invalid tx value from 1inch, value: %v", swap.Tx.Value)
+	}
+
+	gasPrice, ok := common.ParseBigInt(swap.Tx.GasPrice)
+	if !ok {
+		return common.SwapTx{}, fmt.Errorf("invalid gas price from 1inch, gasPrice: %v", swap.Tx.GasPrice)
+	}
+
+	// 1inch does not return the minimum received amount, derive it from the
+	// requested slippage the same way the router enforces it.
+	minOut := common.MinReceived(outAmount, slippage)
+
+	return common.SwapTx{
+		ChainId:         req.ChainId,
+		Src:             req.Src,
+		Dst:             req.Dst,
+		FromAmount:      req.Amount,
+		ToAmount:        outAmount,
+		MinToAmount:     minOut,
+		From:            swap.Tx.From,
+		To:              swap.Tx.To,
+		Data:            swap.Tx.Data,
+		Value:           value,
+		Gas:             big.NewInt(int64(swap.Tx.Gas)),
+		GasPrice:        gasPrice,
+		AllowanceTarget: swap.Tx.To,
+		Routes:          parseProtocols(swap.Protocols),
 	}, nil
 }
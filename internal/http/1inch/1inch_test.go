@@ -2,22 +2,47 @@ package oneinch
 
 import (
 	"context"
+	"encoding/json"
+	"errors"
 	"log/slog"
 	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
 	"testing"
 	"time"
 
 	"github.com/onmetahq/go-evm/internal/http/common"
+	"github.com/onmetahq/go-evm/internal/http/fake"
+	"github.com/onmetahq/go-evm/internal/http/recorder"
 	metahttp "github.com/onmetahq/meta-http/pkg/meta_http"
 )
 
 const TOKENA = "0xeeeeeeeeeeeeeeeeeeeeeeeeeeeeeeeeeeeeeeee"
 const TOKENB = "0x2791bca1f2de4661ed88a30c99a7a9449aa84174"
 
-func TestFetchSupportedTokens(t *testing.T) {
-	c := metahttp.NewClient("https://api.1inch.dev/swap/v5.2", slog.Default(), 30*time.Second)
+// newRecordedClient replays testdata/<name>.json. The cassettes checked in
+// are all .synthetic, hand written after the documented 1inch responses, so
+// these tests cover the parsing of those responses and not the live API.
+// Running with GO_EVM_RECORD=1 and 1INCH_KEY set overwrites a cassette with
+// live responses, drop its .synthetic suffix when committing it.
+func newRecordedClient(t *testing.T, name string) metahttp.Requests {
+	rec, err := recorder.New(filepath.Join("testdata", name+".json"), recorder.ModeFromEnv(), nil)
+	if err != nil {
+		t.Fatalf("cassette err: %v", err)
+	}
+	t.Cleanup(func() {
+		if err := rec.Save(); err != nil {
+			t.Errorf("save cassette err: %v", err)
+		}
+	})
+
+	return common.NewClient("https://api.1inch.dev/swap/v5.2", rec.Client())
+}
 
-	oneClient := NewOneInch(c)
+func TestFetchSupportedTokens(t *testing.T) {
+	oneClient := NewOneInch(newRecordedClient(t, "tokens.synthetic"))
 	res, err := oneClient.FetchSupportedTokens(context.Background(), 137)
 	if err != nil {
 		t.Fatalf("tokens err: %v", err)
@@ -29,9 +54,7 @@ func TestFetchSupportedTokens(t *testing.T) {
 }
 
 func TestFetchExactInQuote(t *testing.T) {
-	c := metahttp.NewClient("https://api.1inch.dev/swap/v5.2", slog.Default(), 30*time.Second)
-
-	oneClient := NewOneInch(c)
+	oneClient := NewOneInch(newRecordedClient(t, "quote.synthetic"))
 	req := common.QuoteReq{
 		Src:     TOKENB,
 		Dst:     TOKENA,
@@ -46,21 +69,55 @@ func TestFetchExactInQuote(t *testing.T) {
 	if res.ToAmount.Cmp(big.NewInt(0)) < 1 {
 		t.Fatalf("invalid quote, quote: %s", res.ToAmount)
 	}
+
+	if len(res.Routes) != 1 || len(res.Routes[0].Hops) != 2 || len(res.Routes[0].Hops[1]) != 2 {
+		t.Fatalf("invalid routes, routes: %+v", res.Routes)
+	}
+
+	if part := res.Routes[0].Hops[1][0]; part.Protocol != "POLYGON_UNISWAP_V3" || part.Part != 60 || part.ToToken != TOKENA {
+		t.Fatalf("invalid route part, part: %+v", part)
+	}
 }
 
 func TestFetchExactInSwapCallData(t *testing.T) {
-	c := metahttp.NewClient("https://api.1inch.dev/swap/v5.2", slog.Default(), 30*time.Second)
+	oneClient := NewOneInch(newRecordedClient(t, "swap.synthetic"))
+	req := common.QuoteReq{
+		ChainId:        137,
+		Src:            TOKENB,
+		Dst:            TOKENA,
+		Amount:         big.NewInt(1000000),
+		From:           "0x15Ba05723b04785C3E21157171810892A4FB795c",
+		SlippageBps:    common.Bps(100),
+		Referrer:       "0x15Ba05723b04785C3E21157171810892A4FB795c",
+		SkipValidation: true,
+	}
 
-	oneClient := NewOneInch(c)
+	res, err := oneClient.FetchExactInSwapCallData(context.Background(), req)
+	if err != nil {
+		t.Fatalf("swap err: %v", err)
+	}
+
+	if res.Src != TOKENB {
+		t.Fatalf("invalid from token, token: %s", res.Src)
+	}
+
+	if len(res.Data) < 1 {
+		t.Fatalf("invalid txn data, tx: %s", res.Data)
+	}
+}
+
+func TestFetchExactInSwapCallDataFake(t *testing.T) {
+	server := fake.NewServer()
+	defer server.Close()
+
+	oneClient := NewOneInch(common.NewClient(server.URL+fake.OneInchPrefix, nil))
 	req := common.QuoteReq{
-		ChainId:            137,
-		Src:                TOKENB,
-		Dst:                TOKENA,
-		Amount:             big.NewInt(1000000),
-		From:               "0x15Ba05723b04785C3E21157171810892A4FB795c",
-		SlippagePercentage: 1,
-		Referrer:           "0x15Ba05723b04785C3E21157171810892A4FB795c",
-		SkipValidation:     true,
+		ChainId:     137,
+		Src:         TOKENB,
+		Dst:         TOKENA,
+		Amount:      big.NewInt(1000000),
+		From:        "0x15Ba05723b04785C3E21157171810892A4FB795c",
+		SlippageBps: common.Bps(50),
 	}
 
 	res, err := oneClient.FetchExactInSwapCallData(context.Background(), req)
@@ -68,11 +125,305 @@ func TestFetchExactInSwapCallData(t *testing.T) {
 		t.Fatalf("swap err: %v", err)
 	}
 
-	if res.FromToken.Address != TOKENB {
-		t.Fatalf("invalid from token, token: %s", res.FromToken.Address)
+	if res.ToAmount.Cmp(big.NewInt(2000000)) != 0 || res.MinToAmount.Cmp(big.NewInt(1990000)) != 0 {
+		t.Fatalf("invalid amounts, out: %s, min: %s", res.ToAmount, res.MinToAmount)
+	}
+
+	if res.To != fake.RouterAddress || res.AllowanceTarget != fake.RouterAddress || res.Data != fake.SwapCallData {
+		t.Fatalf("invalid tx, res: %+v", res)
+	}
+}
+
+func TestErrorPaths(t *testing.T) {
+	server := fake.NewServer()
+	defer server.Close()
+
+	oneClient := NewOneInch(common.NewClient(server.URL+fake.OneInchPrefix, nil))
+	req := common.QuoteReq{
+		ChainId: 137,
+		Src:     TOKENB,
+		Dst:     TOKENA,
+		Amount:  big.NewInt(1000000),
+	}
+
+	server.Fail("/quote", http.StatusBadRequest, `{"error":"Bad Request","description":"insufficient liquidity","statusCode":400}`)
+	_, err := oneClient.FetchExactInQuote(context.Background(), req)
+	if !errors.Is(err, common.ErrInsufficientLiquidity) || common.IsRetryable(err) {
+		t.Fatalf("expected insufficient liquidity, err: %v", err)
+	}
+
+	server.Fail("/swap", http.StatusTooManyRequests, `{"message":"Too Many Requests"}`)
+	_, err = oneClient.FetchExactInSwapCallData(context.Background(), req)
+	if !errors.Is(err, common.ErrRateLimited) || !common.IsRetryable(err) {
+		t.Fatalf("expected rate limited, err: %v", err)
+	}
+
+	server.Fail("/tokens", http.StatusUnauthorized, `{"statusCode":401,"message":"Unauthorized"}`)
+	_, err = oneClient.FetchSupportedTokens(context.Background(), 137)
+	if !errors.Is(err, common.ErrAuth) {
+		t.Fatalf("expected auth error, err: %v", err)
+	}
+}
+
+func TestSwapOptions(t *testing.T) {
+	var query map[string]string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		query = map[string]string{}
+		for k := range r.URL.Query() {
+			query[k] = r.URL.Query().Get(k)
+		}
+
+		swap := OneInchSwapResponse{DstAmount: "2000000"}
+		swap.Tx.To = fake.RouterAddress
+		swap.Tx.Data = fake.SwapCallData
+		swap.Tx.Value = "0"
+		_ = json.NewEncoder(w).Encode(swap)
+	}))
+	defer server.Close()
+
+	complexity, mainRouteParts := 2, 10
+	oneClient := NewOneInch(common.NewClient(server.URL, nil))
+	req := common.QuoteReq{
+		ChainId:         137,
+		Src:             TOKENB,
+		Dst:             TOKENA,
+		Amount:          big.NewInt(1000000),
+		From:            "0x15Ba05723b04785C3E21157171810892A4FB795c",
+		Receiver:        "0x000000000000000000000000000000000000dEaD",
+		Referrer:        "0x15Ba05723b04785C3E21157171810892A4FB795c",
+		FeeBps:          25,
+		GasPrice:        big.NewInt(30_000_000_000),
+		IncludedSources: []string{"POLYGON_UNISWAP_V3", "POLYGON_QUICKSWAP_V3"},
+		ExcludedSources: []string{"POLYGON_CURVE"},
+		Options: []common.ProviderOptions{OneInchOptions{
+			ComplexityLevel:  &complexity,
+			MainRouteParts:   &mainRouteParts,
+			AllowPartialFill: true,
+			Compatibility:    true,
+			Permit:           "0x1234",
+		}},
+	}
+
+	res, err := oneClient.FetchExactInSwapCallData(context.Background(), req)
+	if err != nil {
+		t.Fatalf("swap err: %v", err)
+	}
+
+	if res.ToAmount.Cmp(big.NewInt(2000000)) != 0 {
+		t.Fatalf("invalid v6 dstAmount, out: %s", res.ToAmount)
+	}
+
+	want := map[string]string{
+		"receiver":          req.Receiver,
+		"fee":               "0.25",
+		"gasPrice":          "30000000000",
+		"permit":            "0x1234",
+		"protocols":         "POLYGON_UNISWAP_V3,POLYGON_QUICKSWAP_V3",
+		"excludedProtocols": "POLYGON_CURVE",
+		"complexityLevel":   "2",
+		"mainRouteParts":    "10",
+		"allowPartialFill":  "true",
+		"compatibility":     "true",
+		"includeProtocols":  "true",
+	}
+	for k, v := range want {
+		if query[k] != v {
+			t.Errorf("invalid %s, want: %s, got: %s", k, v, query[k])
+		}
+	}
+
+	req.FeeBps = MaxFeeBps + 1
+	if _, err := oneClient.FetchExactInSwapCallData(context.Background(), req); err == nil {
+		t.Fatalf("expected fee above max to fail")
+	}
+
+	req.FeeBps, req.Referrer = 25, ""
+	if _, err := oneClient.FetchExactInSwapCallData(context.Background(), req); err == nil {
+		t.Fatalf("expected fee without referrer to fail")
+	}
+}
+
+// fakePriceServer quotes TOKENB to TOKENA at 2e12 wei per unit with a price
+// impact growing with the amount, and TOKENA to TOKENB at the spot price.
+func fakePriceServer(t *testing.T) *httptest.Server {
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		q := r.URL.Query()
+		amount, _ := new(big.Int).SetString(q.Get("amount"), 10)
+
+		out := new(big.Int)
+		if strings.EqualFold(q.Get("src"), TOKENB) {
+			out.Mul(amount, big.NewInt(2e12))
+			impact := new(big.Int).Mul(amount, amount)
+			out.Sub(out, impact.Mul(impact, big.NewInt(1e5)))
+		} else {
+			out.Div(amount, big.NewInt(2e12))
+		}
+
+		var res any
+		switch {
+		case strings.HasSuffix(r.URL.Path, "/quote"):
+			res = OneInchQuoteResponse{ToAmount: out.String(), Gas: 150000}
+		case strings.HasSuffix(r.URL.Path, "/swap"):
+			swap := OneInchSwapResponse{ToAmount: out.String()}
+			swap.Tx.To = "0x111111125421ca6dc452d289314280a0f8842a65"
+			swap.Tx.Data = "0x07ed2379"
+			swap.Tx.Value = "0"
+			swap.Tx.Gas = 150000
+			res = swap
+		default:
+			t.Errorf("unexpected path: %s", r.URL.Path)
+		}
+		_ = json.NewEncoder(w).Encode(res)
+	}))
+}
+
+func TestFetchExactOutQuote(t *testing.T) {
+	server := fakePriceServer(t)
+	defer server.Close()
+
+	oneClient := NewOneInch(metahttp.NewClient(server.URL, slog.Default(), 30*time.Second))
+	want := big.NewInt(1e18)
+	req := common.QuoteReq{
+		Src:     TOKENB,
+		Dst:     TOKENA,
+		ChainId: 137,
+		Amount:  want,
+	}
+	res, err := oneClient.FetchExactOutQuote(context.Background(), req)
+	if err != nil {
+		t.Fatalf("quote err: %v", err)
+	}
+
+	// The minimum output after the default slippage covers the amount.
+	above := new(big.Int).Sub(res.MinToAmount, want)
+	if above.Sign() < 0 || above.Cmp(new(big.Int).Div(want, big.NewInt(1000))) > 0 {
+		t.Fatalf("min out outside tolerance, min out: %s", res.MinToAmount)
+	}
+
+	if res.Surplus.Cmp(above) < 0 {
+		t.Fatalf("invalid surplus, surplus: %s", res.Surplus)
+	}
+
+	if res.ToAmount.Cmp(big.NewInt(500000)) <= 0 {
+		t.Fatalf("input does not cover price impact, input: %s", res.ToAmount)
+	}
+}
+
+func TestFetchExactOutSwapCallData(t *testing.T) {
+	server := fakePriceServer(t)
+	defer server.Close()
+
+	oneClient := NewOneInch(
+		metahttp.NewClient(server.URL, slog.Default(), 30*time.Second),
+		WithExactOutConfig(ExactOutConfig{MaxIterations: 1, ToleranceBps: 1}),
+	)
+	req := common.QuoteReq{
+		Src:     TOKENB,
+		Dst:     TOKENA,
+		ChainId: 137,
+		Amount:  big.NewInt(1e18),
+		From:    "0x15Ba05723b04785C3E21157171810892A4FB795c",
+	}
+
+	// A single iteration from the spot price cannot cover the price impact.
+	if _, err := oneClient.FetchExactOutSwapCallData(context.Background(), req); err == nil {
+		t.Fatalf("expected error when search is exhausted")
+	}
+
+	oneClient = NewOneInch(metahttp.NewClient(server.URL, slog.Default(), 30*time.Second))
+	res, err := oneClient.FetchExactOutSwapCallData(context.Background(), req)
+	if err != nil {
+		t.Fatalf("swap err: %v", err)
+	}
+
+	if res.MinToAmount.Cmp(req.Amount) < 0 || res.Surplus.Sign() < 0 {
+		t.Fatalf("invalid exact out swap, out: %s, surplus: %s", res.ToAmount, res.Surplus)
+	}
+
+	if len(res.Data) < 1 {
+		t.Fatalf("invalid txn data, tx: %s", res.Data)
+	}
+}
+
+func TestFetchExactOutSwapBelowAmount(t *testing.T) {
+	// The swap returns half the quoted output, as if the price moved.
+	swaps := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		amount, _ := new(big.Int).SetString(r.URL.Query().Get("amount"), 10)
+		out := new(big.Int).Mul(amount, big.NewInt(2))
+
+		var res any = OneInchQuoteResponse{ToAmount: out.String(), Gas: 150000}
+		if strings.HasSuffix(r.URL.Path, "/swap") {
+			swaps++
+			swap := OneInchSwapResponse{ToAmount: amount.String()}
+			swap.Tx.Value = "0"
+			res = swap
+		}
+		_ = json.NewEncoder(w).Encode(res)
+	}))
+	defer server.Close()
+
+	oneClient := NewOneInch(metahttp.NewClient(server.URL, slog.Default(), 30*time.Second))
+	_, err := oneClient.FetchExactOutSwapCallData(context.Background(), common.QuoteReq{
+		Src:     TOKENB,
+		Dst:     TOKENA,
+		ChainId: 137,
+		Amount:  big.NewInt(1e18),
+		From:    "0x15Ba05723b04785C3E21157171810892A4FB795c",
+	})
+	if err == nil || swaps != exactOutSwapAttempts {
+		t.Fatalf("expected an error after %d swaps, swaps: %d, err: %v", exactOutSwapAttempts, swaps, err)
+	}
+}
+
+func TestNextExactOutGuess(t *testing.T) {
+	target := big.NewInt(10)
+	huge := &exactOutCandidate{in: big.NewInt(1), quote: common.QuoteRes{ToAmount: big.NewInt(1e18)}}
+	if guess := nextExactOutGuess(huge, nil, huge, target, 0); guess.Cmp(big.NewInt(1)) != 0 {
+		t.Fatalf("expected the guess clamped to 1, guess: %s", guess)
+	}
+
+	lo := &exactOutCandidate{in: big.NewInt(100), quote: common.QuoteRes{ToAmount: big.NewInt(9)}}
+	last := &exactOutCandidate{in: big.NewInt(100), quote: common.QuoteRes{ToAmount: big.NewInt(1e6)}}
+	if guess := nextExactOutGuess(last, lo, nil, target, 0); guess.Cmp(big.NewInt(101)) != 0 {
+		t.Fatalf("expected the guess above lo, guess: %s", guess)
+	}
+}
+
+func TestApprove(t *testing.T) {
+	server := fake.NewServer()
+	defer server.Close()
+
+	oneClient := NewOneInch(common.NewClient(server.URL+fake.OneInchPrefix, nil))
+	spender, err := oneClient.FetchSpender(context.Background(), 137)
+	if err != nil || spender != fake.RouterAddress {
+		t.Fatalf("invalid spender, spender: %s, err: %v", spender, err)
+	}
+
+	allowance, err := oneClient.FetchAllowance(context.Background(), 137, TOKENB, "0x15Ba05723b04785C3E21157171810892A4FB795c")
+	if err != nil || allowance.Sign() != 0 {
+		t.Fatalf("invalid allowance, allowance: %s, err: %v", allowance, err)
+	}
+
+	tx, err := oneClient.FetchApproveTransaction(context.Background(), 137, TOKENB, big.NewInt(1000000))
+	if err != nil {
+		t.Fatalf("approve err: %v", err)
+	}
+
+	if tx.To != TOKENB || tx.Spender != fake.RouterAddress || tx.Amount.Cmp(big.NewInt(1000000)) != 0 || !strings.HasSuffix(tx.Data, "f4240") {
+		t.Fatalf("invalid approve tx, tx: %+v", tx)
+	}
+
+	tx, err = oneClient.FetchApproveTransaction(context.Background(), 137, TOKENB, nil)
+	if err != nil {
+		t.Fatalf("approve err: %v", err)
+	}
+
+	if tx.Amount.Cmp(common.MaxUint256()) != 0 || !strings.HasSuffix(tx.Data, strings.Repeat("f", 64)) {
+		t.Fatalf("invalid unlimited approve tx, tx: %+v", tx)
 	}
 
-	if len(res.Tx.Data) < 1 {
-		t.Fatalf("invalid txn data, tx: %s", res.Tx.Data)
+	if calls := server.Calls("/approve/spender"); calls != 1 {
+		t.Fatalf("expected the spender to be cached, calls: %d", calls)
 	}
 }
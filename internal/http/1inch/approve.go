@@ -0,0 +1,131 @@
+package oneinch
+
+import (
+	"context"
+	"fmt"
+	"math/big"
+	uri "net/url"
+
+	"github.com/onmetahq/go-evm/internal/http/common"
+)
+
+type OneInchSpenderResponse struct {
+	Address string `json:"address"`
+}
+
+type OneInchAllowanceResponse struct {
+	Allowance string `json:"allowance"`
+}
+
+type OneInchApproveTxResponse struct {
+	Data     string `json:"data"`
+	GasPrice string `json:"gasPrice"`
+	To       string `json:"to"`
+	Value    string `json:"value"`
+}
+
+// FetchSpender returns the 1inch router that must be approved to spend the
+// source token of a swap, it is fetched once per chain.
+func (o *oneInch) FetchSpender(ctx context.Context, chainId uint64) (string, error) {
+	o.mu.Lock()
+	spender, ok := o.spenders[chainId]
+	o.mu.Unlock()
+	if ok {
+		return spender, nil
+	}
+
+	var res OneInchSpenderResponse
+	url := fmt.Sprintf("/%d/approve/spender", chainId)
+	headers, err := o.headers(ctx)
+	if err != nil {
+		return "", err
+	}
+
+	_, err = o.client.Get(ctx, url, headers, &res)
+	if err != nil {
+		return "", fmt.Errorf("unable to fetch 1inch spender, err: %w", parseError(err))
+	}
+
+	o.mu.Lock()
+	o.spenders[chainId] = res.Address
+	o.mu.Unlock()
+	return res.Address, nil
+}
+
+// FetchAllowance returns the allowance of wallet to the 1inch router as
+// seen by the 1inch API.
+func (o *oneInch) FetchAllowance(ctx context.Context, chainId uint64, token, wallet string) (*big.Int, error) {
+	v := uri.Values{}
+	v.Add("tokenAddress", token)
+	v.Add("walletAddress", wallet)
+
+	var res OneInchAllowanceResponse
+	url := fmt.Sprintf("/%d/approve/allowance?%s", chainId, v.Encode())
+	headers, err := o.headers(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	_, err = o.client.Get(ctx, url, headers, &res)
+	if err != nil {
+		return nil, fmt.Errorf("unable to fetch 1inch allowance, err: %w", parseError(err))
+	}
+
+	allowance, ok := common.ParseBigInt(res.Allowance)
+	if !ok {
+		return nil, fmt.Errorf("invalid allowance from 1inch, allowance: %v", res.Allowance)
+	}
+	return allowance, nil
+}
+
+// FetchApproveTransaction returns the transaction approving the 1inch router
+// to spend amount of token, an unlimited approval when amount is nil.
+func (o *oneInch) FetchApproveTransaction(ctx context.Context, chainId uint64, token string, amount *big.Int) (common.ApproveTx, error) {
+	v := uri.Values{}
+	v.Add("tokenAddress", token)
+	if amount != nil {
+		v.Add("amount", amount.String())
+	}
+
+	var res OneInchApproveTxResponse
+	url := fmt.Sprintf("/%d/approve/transaction?%s", chainId, v.Encode())
+	headers, err := o.headers(ctx)
+	if err != nil {
+		return common.ApproveTx{}, err
+	}
+
+	_, err = o.client.Get(ctx, url, headers, &res)
+	if err != nil {
+		return common.ApproveTx{}, fmt.Errorf("unable to fetch 1inch approve transaction, err: %w", parseError(err))
+	}
+
+	value, ok := common.ParseBigInt(res.Value)
+	if !ok {
+		return common.ApproveTx{}, fmt.Errorf("invalid value from 1inch, value: %v", res.Value)
+	}
+
+	gasPrice, ok := common.ParseBigInt(res.GasPrice)
+	if !ok {
+		return common.ApproveTx{}, fmt.Errorf("invalid gas price from 1inch, gasPrice: %v", res.GasPrice)
+	}
+
+	spender, err := o.FetchSpender(ctx, chainId)
+	if err != nil {
+		return common.ApproveTx{}, err
+	}
+
+	if amount == nil {
+		amount = common.MaxUint256()
+	}
+
+	return common.ApproveTx{
+		ChainId:  chainId,
+		Token:    token,
+		Spender:  spender,
+		Amount:   amount,
+		To:       res.To,
+		Data:     res.Data,
+		Value:    value,
+		GasPrice: gasPrice,
+	}, nil
+}
@@ -0,0 +1,199 @@
+package oneinch
+
+import (
+	"context"
+	"fmt"
+	"math/big"
+
+	"github.com/onmetahq/go-evm/internal/http/common"
+)
+
+// ExactOutConfig bounds the input amount search used to emulate exact out
+// swaps on top of the exact in /quote endpoint.
+type ExactOutConfig struct {
+	// MaxIterations is the maximum number of /quote calls spent searching
+	// for the input, excluding the initial reverse quote.
+	MaxIterations int
+	// ToleranceBps is the accepted minimum output above the requested
+	// amount, the search stops once it lands within it.
+	ToleranceBps int64
+	// OvershootBps is added to every extrapolated input so the next quote
+	// lands above the requested amount rather than below it.
+	OvershootBps int64
+}
+
+var DefaultExactOutConfig = ExactOutConfig{
+	MaxIterations: 6,
+	ToleranceBps:  10,
+	OvershootBps:  5,
+}
+
+// WithExactOutConfig overrides DefaultExactOutConfig.
+func WithExactOutConfig(config ExactOutConfig) Option {
+	return func(o *oneInch) {
+		o.exactOut = config
+	}
+}
+
+// exactOutSwapAttempts is the number of searches FetchExactOutSwapCallData
+// runs when the swap returned for the input found guarantees less than the
+// requested amount, e.g. because the price moved between the calls.
+const exactOutSwapAttempts = 2
+
+type exactOutCandidate struct {
+	in    *big.Int
+	quote common.QuoteRes
+}
+
+// minOut is the output guaranteed by the candidate quote after slippage.
+func (c *exactOutCandidate) minOut() *big.Int {
+	if c.quote.MinToAmount != nil {
+		return c.quote.MinToAmount
+	}
+	return c.quote.ToAmount
+}
+
+func (o *oneInch) FetchExactOutQuote(ctx context.Context, req common.QuoteReq) (common.QuoteRes, error) {
+	found, err := o.searchExactOutInput(ctx, req)
+	if err != nil {
+		return common.QuoteRes{}, err
+	}
+
+	// Exact out quotes report the requested output as FromAmount and the
+	// required input as ToAmount, the same as 0x. The input is searched so
+	// that MinToAmount is never below the requested output.
+	return common.QuoteRes{
+		ChainId:     req.ChainId,
+		Src:         req.Src,
+		Dst:         req.Dst,
+		FromAmount:  req.Amount,
+		ToAmount:    found.in,
+		Gas:         found.quote.Gas,
+		GasPrice:    found.quote.GasPrice,
+		MinToAmount: found.quote.MinToAmount,
+		Surplus:     new(big.Int).Sub(found.quote.ToAmount, req.Amount),
+	}, nil
+}
+
+// FetchExactOutSwapCallData searches the input of req.Amount and fetches
+// the exact in swap for it. The search is run again when the swap
+// guarantees less than req.Amount and fails after exactOutSwapAttempts.
+func (o *oneInch) FetchExactOutSwapCallData(ctx context.Context, req common.QuoteReq) (common.SwapTx, error) {
+	var tx common.SwapTx
+	for i := 0; i < exactOutSwapAttempts; i++ {
+		found, err := o.searchExactOutInput(ctx, req)
+		if err != nil {
+			return common.SwapTx{}, err
+		}
+
+		swapReq := req
+		swapReq.Amount = found.in
+		tx, err = o.FetchExactInSwapCallData(ctx, swapReq)
+		if err != nil {
+			return common.SwapTx{}, err
+		}
+
+		if tx.MinToAmount.Cmp(req.Amount) >= 0 {
+			tx.Surplus = new(big.Int).Sub(tx.ToAmount, req.Amount)
+			return tx, nil
+		}
+	}
+	return common.SwapTx{}, fmt.Errorf("1inch swap guarantees %s, below the exact out amount %s after %d attempts", tx.MinToAmount, req.Amount, exactOutSwapAttempts)
+}
+
+// searchExactOutInput looks for the smallest input whose quoted output
+// after slippage is at least req.Amount. It starts from a reverse quote,
+// extrapolates linearly from the last quote and falls back to bisection
+// once the input is bracketed.
+func (o *oneInch) searchExactOutInput(ctx context.Context, req common.QuoteReq) (exactOutCandidate, error) {
+	target := req.Amount
+	if target == nil || target.Sign() <= 0 {
+		return exactOutCandidate{}, fmt.Errorf("invalid exact out amount, amount: %v", target)
+	}
+
+	reverse, err := o.FetchExactInQuote(ctx, common.QuoteReq{
+		ChainId: req.ChainId,
+		Src:     req.Dst,
+		Dst:     req.Src,
+		Amount:  target,
+	})
+	if err != nil {
+		return exactOutCandidate{}, fmt.Errorf("unable to estimate 1inch exact out input, err: %w", err)
+	}
+
+	cfg := o.exactOut
+	upper := addBps(target, cfg.ToleranceBps)
+	guess := addBps(reverse.ToAmount, cfg.OvershootBps)
+	if guess.Sign() <= 0 {
+		guess = big.NewInt(1)
+	}
+
+	var lo, hi *exactOutCandidate
+	for i := 0; i < cfg.MaxIterations && guess != nil; i++ {
+		quoteReq := req
+		quoteReq.Amount = guess
+		quote, err := o.FetchExactInQuote(ctx, quoteReq)
+		if err != nil {
+			return exactOutCandidate{}, fmt.Errorf("unable to search 1inch exact out input, err: %w", err)
+		}
+
+		c := &exactOutCandidate{in: guess, quote: quote}
+		if c.minOut().Cmp(target) >= 0 {
+			if hi == nil || c.in.Cmp(hi.in) < 0 {
+				hi = c
+			}
+			if c.minOut().Cmp(upper) <= 0 {
+				break
+			}
+		} else if lo == nil || c.in.Cmp(lo.in) > 0 {
+			lo = c
+		}
+
+		guess = nextExactOutGuess(c, lo, hi, target, cfg.OvershootBps)
+	}
+
+	if hi == nil {
+		return exactOutCandidate{}, fmt.Errorf("unable to find 1inch input for exact out amount %s within %d iterations", target, cfg.MaxIterations)
+	}
+	return *hi, nil
+}
+
+// nextExactOutGuess returns the next input to quote or nil when the
+// bracket cannot be narrowed any further. The guess is at least 1 and
+// above the largest input known to fall short.
+func nextExactOutGuess(last, lo, hi *exactOutCandidate, target *big.Int, overshootBps int64) *big.Int {
+	var guess *big.Int
+	if out := last.minOut(); out.Sign() > 0 {
+		guess = new(big.Int).Mul(last.in, addBps(target, overshootBps))
+		guess.Div(guess, out)
+	} else {
+		guess = new(big.Int).Mul(last.in, big.NewInt(2))
+	}
+
+	if guess.Sign() <= 0 {
+		guess = big.NewInt(1)
+	}
+	if lo != nil && guess.Cmp(lo.in) <= 0 {
+		guess = new(big.Int).Add(lo.in, big.NewInt(1))
+	}
+
+	if lo == nil || hi == nil {
+		return guess
+	}
+
+	gap := new(big.Int).Sub(hi.in, lo.in)
+	if gap.Cmp(big.NewInt(1)) <= 0 {
+		return nil
+	}
+
+	if guess.Cmp(lo.in) <= 0 || guess.Cmp(hi.in) >= 0 {
+		guess = gap.Div(gap, big.NewInt(2))
+		guess.Add(guess, lo.in)
+	}
+	return guess
+}
+
+func addBps(amount *big.Int, bps int64) *big.Int {
+	out := new(big.Int).Mul(amount, big.NewInt(10_000+bps))
+	return out.Div(out, big.NewInt(10_000))
+}
@@ -0,0 +1,549 @@
+package oneinch
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/binary"
+	"fmt"
+	"math/big"
+	uri "net/url"
+	"time"
+
+	ethcommon "github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/common/math"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/signer/core/apitypes"
+	"github.com/onmetahq/go-evm/internal/http/common"
+	metahttp "github.com/onmetahq/meta-http/pkg/meta_http"
+)
+
+// LimitOrderProtocolAddress is the 1inch aggregation router v6, the
+// verifying contract of limit orders on every chain.
+const LimitOrderProtocolAddress = "0x111111125421cA6dc452d289314280a0f8842A65"
+
+const (
+	FusionPresetFast   = "fast"
+	FusionPresetMedium = "medium"
+	FusionPresetSlow   = "slow"
+)
+
+// fusionExpirationDelay keeps the order valid for a few blocks after the
+// auction ends so the last resolver can still fill it.
+const fusionExpirationDelay = 12 * time.Second
+
+// makerTraits flags of the limit order protocol v4.
+const (
+	noPartialFillsFlag     = 255
+	allowMultipleFillsFlag = 254
+	postInteractionFlag    = 251
+	hasExtensionFlag       = 249
+	unwrapWethFlag         = 247
+)
+
+// WithFusionClient enables gasless Fusion orders, client must be configured
+// with the 1inch Fusion API base url, e.g. https://api.1inch.dev/fusion.
+func WithFusionClient(client metahttp.Requests) Option {
+	return func(o *oneInch) {
+		o.fusion = client
+	}
+}
+
+var _ common.OrderAggregator = (*oneInch)(nil)
+
+// PlaceOrder quotes req through Fusion, signs the limit order with signer
+// and submits it to the relayer. Resolvers pay the gas, the order is filled
+// by a Dutch auction so req.SlippageBps is not used. Cross chain orders are
+// placed with PlaceFusionPlusOrder.
+func (o *oneInch) PlaceOrder(ctx context.Context, req common.QuoteReq, signer common.Signer) (common.Order, error) {
+	req.From = signer.Address()
+
+	quote, err := o.FetchFusionQuote(ctx, req)
+	if err != nil {
+		return common.Order{}, err
+	}
+
+	opts, err := common.OptionsOf[OneInchOptions](req)
+	if err != nil {
+		return common.Order{}, err
+	}
+
+	order, err := o.BuildFusionOrder(req, quote, opts.FusionPreset)
+	if err != nil {
+		return common.Order{}, err
+	}
+
+	typedData := FusionTypedData(req.ChainId, order.Order)
+	signature, err := signer.SignTypedData(ctx, typedData)
+	if err != nil {
+		return common.Order{}, err
+	}
+
+	orderHash, err := o.SubmitFusionOrder(ctx, req.ChainId, order, signature)
+	if err != nil {
+		return common.Order{}, err
+	}
+
+	sellAmount, _ := common.ParseBigInt(order.Order.MakingAmount)
+	buyAmount, _ := common.ParseBigInt(order.Order.TakingAmount)
+	return common.Order{
+		ChainId:    req.ChainId,
+		Id:         orderHash,
+		Owner:      req.From,
+		Src:        req.Src,
+		Dst:        req.Dst,
+		SellAmount: sellAmount,
+		BuyAmount:  buyAmount,
+		ValidTo:    order.ValidTo,
+		Status:     common.OrderPending,
+	}, nil
+}
+
+// FetchFusionQuote returns the Fusion quote of req with its auction presets.
+func (o *oneInch) FetchFusionQuote(ctx context.Context, req common.QuoteReq) (OneInchFusionQuote, error) {
+	if o.fusion == nil {
+		return OneInchFusionQuote{}, fmt.Errorf("1inch fusion is not configured, use WithFusionClient")
+	}
+
+	// Fusion orders move ERC-20s only, the maker cannot sign away ether.
+	if common.IsNativeToken(req.Src) {
+		return OneInchFusionQuote{}, fmt.Errorf("native sell orders are not supported by 1inch fusion, err: %w", common.ErrUnsupportedToken)
+	}
+
+	if req.FeeBps > 0 {
+		return OneInchFusionQuote{}, fmt.Errorf("integrator fee is not supported by 1inch fusion, err: %w", common.ErrUnsupportedParameter)
+	}
+
+	v := uri.Values{}
+	v.Add("fromTokenAddress", req.Src)
+	v.Add("toTokenAddress", req.Dst)
+	v.Add("amount", req.Amount.String())
+	v.Add("walletAddress", req.From)
+	v.Add("enableEstimate", "true")
+	url := fmt.Sprintf("/quoter/v2.0/%d/quote/receive?%s", req.ChainId, v.Encode())
+
+	headers, err := o.headers(ctx)
+	if err != nil {
+		return OneInchFusionQuote{}, err
+	}
+
+	var res OneInchFusionQuote
+	_, err = o.fusion.Get(ctx, url, headers, &res)
+	if err != nil {
+		return OneInchFusionQuote{}, fmt.Errorf("unable to fetch 1inch fusion quote, err: %w", parseError(err))
+	}
+	return res, nil
+}
+
+// BuildFusionOrder builds the limit order auctioned with preset, the
+// recommended preset of quote when empty. The auction starts at the
+// preset start amount and never goes below its end amount.
+func (o *oneInch) BuildFusionOrder(req common.QuoteReq, quote OneInchFusionQuote, preset string) (OneInchFusionOrder, error) {
+	if preset == "" {
+		preset = quote.RecommendedPreset
+	}
+
+	p, ok := quote.Presets[preset]
+	if !ok || p == nil {
+		return OneInchFusionOrder{}, fmt.Errorf("invalid 1inch fusion preset, preset: %s", preset)
+	}
+
+	takingAmount, ok := common.ParseBigInt(p.AuctionEndAmount)
+	if !ok {
+		return OneInchFusionOrder{}, fmt.Errorf("invalid auction end amount from 1inch, amount: %v", p.AuctionEndAmount)
+	}
+
+	gasPriceEstimate, ok := common.ParseBigInt(p.GasCost.GasPriceEstimate)
+	if !ok {
+		return OneInchFusionOrder{}, fmt.Errorf("invalid gas price estimate from 1inch, gasPrice: %v", p.GasCost.GasPriceEstimate)
+	}
+
+	takerAsset := req.Dst
+	unwrap := common.IsNativeToken(req.Dst)
+	if unwrap {
+		if takerAsset, ok = common.WrappedNativeTokens[req.ChainId]; !ok {
+			return OneInchFusionOrder{}, fmt.Errorf("no wrapped native token on chainId %d, err: %w", req.ChainId, common.ErrUnsupportedChain)
+		}
+	}
+
+	receiver := req.Receiver
+	if receiver == "" {
+		receiver = ethcommon.Address{}.Hex()
+	}
+
+	startTime := uint64(time.Now().Unix()) + p.StartAuctionIn
+	validTo := time.Unix(int64(startTime+p.AuctionDuration), 0).Add(fusionExpirationDelay)
+	settlement := ethcommon.HexToAddress(quote.SettlementAddress)
+
+	auction := encodeAuctionDetails(*p, gasPriceEstimate.Uint64(), startTime)
+	extension := encodeExtension(
+		append(settlement.Bytes(), auction...),
+		append(settlement.Bytes(), auction...),
+		append(settlement.Bytes(), encodeSettlementData(quote.Whitelist, startTime)...),
+	)
+
+	salt, err := fusionSalt(extension)
+	if err != nil {
+		return OneInchFusionOrder{}, err
+	}
+
+	traits, err := fusionMakerTraits(settlement, validTo, p.AllowPartialFills, p.AllowMultipleFills, unwrap)
+	if err != nil {
+		return OneInchFusionOrder{}, err
+	}
+
+	return OneInchFusionOrder{
+		Order: OneInchLimitOrder{
+			Salt:         salt.String(),
+			Maker:        req.From,
+			Receiver:     receiver,
+			MakerAsset:   req.Src,
+			TakerAsset:   takerAsset,
+			MakingAmount: req.Amount.String(),
+			TakingAmount: takingAmount.String(),
+			MakerTraits:  traits.String(),
+		},
+		Extension: hexutil.Encode(extension),
+		QuoteId:   quote.QuoteId,
+		ValidTo:   validTo,
+	}, nil
+}
+
+// FusionTypedData returns the EIP-712 payload of order to sign, its hash is
+// the order hash tracked by the relayer.
+func FusionTypedData(chainId uint64, order OneInchLimitOrder) apitypes.TypedData {
+	return apitypes.TypedData{
+		Types: apitypes.Types{
+			"EIP712Domain": {
+				{Name: "name", Type: "string"},
+				{Name: "version", Type: "string"},
+				{Name: "chainId", Type: "uint256"},
+				{Name: "verifyingContract", Type: "address"},
+			},
+			"Order": {
+				{Name: "salt", Type: "uint256"},
+				{Name: "maker", Type: "address"},
+				{Name: "receiver", Type: "address"},
+				{Name: "makerAsset", Type: "address"},
+				{Name: "takerAsset", Type: "address"},
+				{Name: "makingAmount", Type: "uint256"},
+				{Name: "takingAmount", Type: "uint256"},
+				{Name: "makerTraits", Type: "uint256"},
+			},
+		},
+		PrimaryType: "Order",
+		Domain: apitypes.TypedDataDomain{
+			Name:              "1inch Aggregation Router",
+			Version:           "6",
+			ChainId:           math.NewHexOrDecimal256(int64(chainId)),
+			VerifyingContract: LimitOrderProtocolAddress,
+		},
+		Message: apitypes.TypedDataMessage{
+			"salt":         order.Salt,
+			"maker":        order.Maker,
+			"receiver":     order.Receiver,
+			"makerAsset":   order.MakerAsset,
+			"takerAsset":   order.TakerAsset,
+			"makingAmount": order.MakingAmount,
+			"takingAmount": order.TakingAmount,
+			"makerTraits":  order.MakerTraits,
+		},
+	}
+}
+
+// SubmitFusionOrder sends the signed order to the relayer and returns its
+// order hash.
+func (o *oneInch) SubmitFusionOrder(ctx context.Context, chainId uint64, order OneInchFusionOrder, signature []byte) (string, error) {
+	if o.fusion == nil {
+		return "", fmt.Errorf("1inch fusion is not configured, use WithFusionClient")
+	}
+
+	hash, _, err := apitypes.TypedDataAndHash(FusionTypedData(chainId, order.Order))
+	if err != nil {
+		return "", fmt.Errorf("unable to hash 1inch fusion order, err: %w", err)
+	}
+
+	headers, err := o.headers(ctx)
+	if err != nil {
+		return "", err
+	}
+
+	body := OneInchFusionSubmission{
+		Order:     order.Order,
+		Signature: hexutil.Encode(signature),
+		Extension: order.Extension,
+		QuoteId:   order.QuoteId,
+	}
+
+	// The relayer answers 201 with an empty body.
+	url := fmt.Sprintf("/relayer/v2.0/%d/order/submit", chainId)
+	_, err = o.fusion.Post(ctx, url, headers, body, nil)
+	if err != nil {
+		return "", fmt.Errorf("unable to submit 1inch fusion order, err: %w", parseError(err))
+	}
+	return hexutil.Encode(hash), nil
+}
+
+func (o *oneInch) FetchOrder(ctx context.Context, chainId uint64, id string) (common.Order, error) {
+	if o.fusion == nil {
+		return common.Order{}, fmt.Errorf("1inch fusion is not configured, use WithFusionClient")
+	}
+
+	headers, err := o.headers(ctx)
+	if err != nil {
+		return common.Order{}, err
+	}
+
+	var res OneInchFusionOrderStatus
+	url := fmt.Sprintf("/orders/v2.0/%d/order/status/%s", chainId, id)
+	_, err = o.fusion.Get(ctx, url, headers, &res)
+	if err != nil {
+		return common.Order{}, fmt.Errorf("unable to fetch 1inch fusion order, err: %w", parseError(err))
+	}
+	return parseFusionOrder(chainId, id, res)
+}
+
+type OneInchFusionQuote struct {
+	QuoteId           string                          `json:"quoteId"`
+	FromTokenAmount   string                          `json:"fromTokenAmount"`
+	ToTokenAmount     string                          `json:"toTokenAmount"`
+	Presets           map[string]*OneInchFusionPreset `json:"presets"`
+	RecommendedPreset string                          `json:"recommended_preset"`
+	SettlementAddress string                          `json:"settlementAddress"`
+	Whitelist         []string                        `json:"whitelist"`
+}
+
+// OneInchFusionPreset is a Dutch auction configuration, the rate decreases
+// from AuctionStartAmount to AuctionEndAmount over AuctionDuration seconds
+// following Points.
+type OneInchFusionPreset struct {
+	AuctionDuration    uint64               `json:"auctionDuration"`
+	StartAuctionIn     uint64               `json:"startAuctionIn"`
+	InitialRateBump    uint64               `json:"initialRateBump"`
+	AuctionStartAmount string               `json:"auctionStartAmount"`
+	AuctionEndAmount   string               `json:"auctionEndAmount"`
+	Points             []OneInchFusionPoint `json:"points"`
+	AllowPartialFills  bool                 `json:"allowPartialFills"`
+	AllowMultipleFills bool                 `json:"allowMultipleFills"`
+	GasCost            struct {
+		GasBumpEstimate  uint64 `json:"gasBumpEstimate"`
+		GasPriceEstimate string `json:"gasPriceEstimate"`
+	} `json:"gasCost"`
+}
+
+type OneInchFusionPoint struct {
+	Delay       uint64 `json:"delay"`
+	Coefficient uint64 `json:"coefficient"`
+}
+
+// OneInchLimitOrder is a limit order protocol v4 order.
+type OneInchLimitOrder struct {
+	Salt         string `json:"salt"`
+	Maker        string `json:"maker"`
+	Receiver     string `json:"receiver"`
+	MakerAsset   string `json:"makerAsset"`
+	TakerAsset   string `json:"takerAsset"`
+	MakingAmount string `json:"makingAmount"`
+	TakingAmount string `json:"takingAmount"`
+	MakerTraits  string `json:"makerTraits"`
+}
+
+// OneInchFusionOrder is a limit order with the Fusion extension carrying its
+// auction, the extension hash is committed to in the salt.
+type OneInchFusionOrder struct {
+	Order     OneInchLimitOrder
+	Extension string
+	QuoteId   string
+	ValidTo   time.Time
+}
+
+type OneInchFusionSubmission struct {
+	Order     OneInchLimitOrder `json:"order"`
+	Signature string            `json:"signature"`
+	Extension string            `json:"extension"`
+	QuoteId   string            `json:"quoteId"`
+}
+
+type OneInchFusionFill struct {
+	TxHash                   string `json:"txHash"`
+	FilledMakerAmount        string `json:"filledMakerAmount"`
+	FilledAuctionTakerAmount string `json:"filledAuctionTakerAmount"`
+}
+
+type OneInchFusionOrderStatus struct {
+	OrderHash string              `json:"orderHash"`
+	Status    string              `json:"status"`
+	Order     OneInchLimitOrder   `json:"order"`
+	Fills     []OneInchFusionFill `json:"fills"`
+}
+
+func parseFusionOrder(chainId uint64, id string, res OneInchFusionOrderStatus) (common.Order, error) {
+	sellAmount, ok := common.ParseBigInt(res.Order.MakingAmount)
+	if !ok {
+		return common.Order{}, fmt.Errorf("invalid making amount from 1inch, amount: %v", res.Order.MakingAmount)
+	}
+
+	buyAmount, ok := common.ParseBigInt(res.Order.TakingAmount)
+	if !ok {
+		return common.Order{}, fmt.Errorf("invalid taking amount from 1inch, amount: %v", res.Order.TakingAmount)
+	}
+
+	traits, ok := common.ParseBigInt(res.Order.MakerTraits)
+	if !ok {
+		return common.Order{}, fmt.Errorf("invalid maker traits from 1inch, traits: %v", res.Order.MakerTraits)
+	}
+	expiration := new(big.Int).Rsh(traits, 80)
+	expiration.And(expiration, big.NewInt(1<<40-1))
+
+	executedSell, executedBuy := new(big.Int), new(big.Int)
+	txHash := ""
+	for _, fill := range res.Fills {
+		maker, ok := common.ParseBigInt(fill.FilledMakerAmount)
+		if !ok {
+			return common.Order{}, fmt.Errorf("invalid filled amount from 1inch, amount: %v", fill.FilledMakerAmount)
+		}
+		taker, ok := common.ParseBigInt(fill.FilledAuctionTakerAmount)
+		if !ok {
+			return common.Order{}, fmt.Errorf("invalid filled amount from 1inch, amount: %v", fill.FilledAuctionTakerAmount)
+		}
+		executedSell.Add(executedSell, maker)
+		executedBuy.Add(executedBuy, taker)
+		txHash = fill.TxHash
+	}
+
+	// The relayer drops orders it can no longer fill, e.g. false-predicate,
+	// not-enough-balance-or-allowance, wrong-permit or invalid-signature.
+	var status common.OrderStatus
+	switch res.Status {
+	case "pending":
+		status = common.OrderPending
+		if executedSell.Sign() > 0 {
+			status = common.OrderPartiallyFilled
+		}
+	case "partially-filled":
+		status = common.OrderPartiallyFilled
+	case "filled":
+		status = common.OrderFilled
+	case "expired":
+		status = common.OrderExpired
+	case "cancelled":
+		status = common.OrderCancelled
+	default:
+		status = common.OrderFailed
+	}
+
+	return common.Order{
+		ChainId:            chainId,
+		Id:                 id,
+		Owner:              res.Order.Maker,
+		Src:                res.Order.MakerAsset,
+		Dst:                res.Order.TakerAsset,
+		SellAmount:         sellAmount,
+		BuyAmount:          buyAmount,
+		ExecutedSellAmount: executedSell,
+		ExecutedBuyAmount:  executedBuy,
+		ValidTo:            time.Unix(expiration.Int64(), 0),
+		Status:             status,
+		TxHash:             txHash,
+	}, nil
+}
+
+// encodeAuctionDetails packs the auction as read by the settlement
+// extension: gasBumpEstimate uint24, gasPriceEstimate uint32, startTime
+// uint32, duration uint24, initialRateBump uint24, then a (coefficient
+// uint24, delay uint16) pair per point.
+func encodeAuctionDetails(preset OneInchFusionPreset, gasPriceEstimate, startTime uint64) []byte {
+	var out []byte
+	out = appendUint(out, preset.GasCost.GasBumpEstimate, 3)
+	out = appendUint(out, gasPriceEstimate, 4)
+	out = appendUint(out, startTime, 4)
+	out = appendUint(out, preset.AuctionDuration, 3)
+	out = appendUint(out, preset.InitialRateBump, 3)
+	for _, point := range preset.Points {
+		out = appendUint(out, point.Coefficient, 3)
+		out = appendUint(out, point.Delay, 2)
+	}
+	return out
+}
+
+// encodeSettlementData packs the post interaction data: resolvingStartTime
+// uint32, a (last 10 address bytes, delay uint16) pair per whitelisted
+// resolver, then a flags byte holding the whitelist size in its upper 5 bits.
+func encodeSettlementData(whitelist []string, startTime uint64) []byte {
+	out := appendUint(nil, startTime, 4)
+	for _, resolver := range whitelist {
+		out = append(out, ethcommon.HexToAddress(resolver).Bytes()[10:]...)
+		out = appendUint(out, 0, 2)
+	}
+	return append(out, byte(len(whitelist)<<3))
+}
+
+// encodeExtension packs the limit order extension: a 32 byte word holding
+// the cumulative end offset of each field in 4 byte slots, lowest first,
+// followed by the fields. Only the amount getters and the post interaction
+// are used by Fusion.
+func encodeExtension(makingAmountData, takingAmountData, postInteraction []byte) []byte {
+	// makerAssetSuffix, takerAssetSuffix, makingAmountData, takingAmountData,
+	// predicate, makerPermit, preInteraction, postInteraction.
+	fields := [][]byte{nil, nil, makingAmountData, takingAmountData, nil, nil, nil, postInteraction}
+
+	offsets := make([]byte, 32)
+	var data []byte
+	for i, field := range fields {
+		data = append(data, field...)
+		binary.BigEndian.PutUint32(offsets[28-4*i:32-4*i], uint32(len(data)))
+	}
+	return append(offsets, data...)
+}
+
+// fusionSalt returns a random salt committing to extension in its lower
+// 160 bits, as the limit order protocol checks for orders with extensions.
+func fusionSalt(extension []byte) (*big.Int, error) {
+	salt, err := randomBits(96)
+	if err != nil {
+		return nil, err
+	}
+	return salt.Lsh(salt, 160).Or(salt, new(big.Int).SetBytes(crypto.Keccak256(extension)[12:])), nil
+}
+
+// fusionMakerTraits returns the traits of an order settled by the post
+// interaction of settlement, only settlement may fill it. A random nonce is
+// set unless the order can be filled several times.
+func fusionMakerTraits(settlement ethcommon.Address, validTo time.Time, partialFills, multipleFills, unwrap bool) (*big.Int, error) {
+	nonce, err := randomBits(40)
+	if err != nil {
+		return nil, err
+	}
+
+	traits := new(big.Int).SetBytes(settlement.Bytes()[10:])
+	traits.Or(traits, new(big.Int).Lsh(big.NewInt(validTo.Unix()), 80))
+	if !partialFills || !multipleFills {
+		traits.Or(traits, new(big.Int).Lsh(nonce, 120))
+	}
+	if !partialFills {
+		traits.SetBit(traits, noPartialFillsFlag, 1)
+	}
+	if multipleFills {
+		traits.SetBit(traits, allowMultipleFillsFlag, 1)
+	}
+	if unwrap {
+		traits.SetBit(traits, unwrapWethFlag, 1)
+	}
+	traits.SetBit(traits, postInteractionFlag, 1)
+	traits.SetBit(traits, hasExtensionFlag, 1)
+	return traits, nil
+}
+
+func appendUint(out []byte, v uint64, size int) []byte {
+	var buf [8]byte
+	binary.BigEndian.PutUint64(buf[:], v)
+	return append(out, buf[8-size:]...)
+}
+
+func randomBits(bits int) (*big.Int, error) {
+	n, err := rand.Int(rand.Reader, new(big.Int).Lsh(big.NewInt(1), uint(bits)))
+	if err != nil {
+		return nil, fmt.Errorf("unable to generate random bits, err: %w", err)
+	}
+	return n, nil
+}
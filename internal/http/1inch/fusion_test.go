@@ -0,0 +1,201 @@
+package oneinch
+
+import (
+	"context"
+	"errors"
+	"math/big"
+	"testing"
+	"time"
+
+	ethcommon "github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/signer/core/apitypes"
+	"github.com/onmetahq/go-evm/internal/http/common"
+	"github.com/onmetahq/go-evm/internal/http/fake"
+)
+
+func TestPlaceOrder(t *testing.T) {
+	server := fake.NewServer()
+	defer server.Close()
+
+	key, err := crypto.GenerateKey()
+	if err != nil {
+		t.Fatalf("key err: %v", err)
+	}
+	signer := common.PrivateKeySigner(key)
+
+	oneClient := NewOneInch(common.NewClient(server.URL+fake.OneInchPrefix, nil), WithFusionClient(common.NewClient(server.URL+fake.FusionPrefix, nil)))
+	req := common.QuoteReq{
+		ChainId: 137,
+		Src:     TOKENB,
+		Dst:     TOKENA,
+		Amount:  big.NewInt(1000000),
+	}
+
+	order, err := oneClient.PlaceOrder(context.Background(), req, signer)
+	if err != nil {
+		t.Fatalf("place order err: %v", err)
+	}
+
+	// The recommended medium preset ends 0.3% below the 2000000 quote.
+	if order.SellAmount.Cmp(big.NewInt(1000000)) != 0 || order.BuyAmount.Cmp(big.NewInt(1994000)) != 0 {
+		t.Fatalf("invalid amounts, sell: %s, buy: %s", order.SellAmount, order.BuyAmount)
+	}
+
+	if order.Owner != signer.Address() || order.Status != common.OrderPending || !order.ValidTo.After(time.Now()) {
+		t.Fatalf("invalid order, order: %+v", order)
+	}
+
+	pending, err := oneClient.FetchOrder(context.Background(), 137, order.Id)
+	if err != nil || pending.Status != common.OrderPending {
+		t.Fatalf("expected a pending order, order: %+v, err: %v", pending, err)
+	}
+
+	partial, err := oneClient.FetchOrder(context.Background(), 137, order.Id)
+	if err != nil || partial.Status != common.OrderPartiallyFilled || partial.ExecutedSellAmount.Cmp(big.NewInt(500000)) != 0 {
+		t.Fatalf("expected a partially filled order, order: %+v, err: %v", partial, err)
+	}
+
+	filled, err := common.WaitForOrder(context.Background(), oneClient, 137, order.Id, time.Millisecond)
+	if err != nil {
+		t.Fatalf("wait err: %v", err)
+	}
+
+	if filled.Status != common.OrderFilled || filled.ExecutedBuyAmount.Cmp(order.BuyAmount) != 0 || filled.TxHash == "" {
+		t.Fatalf("expected a filled order, order: %+v", filled)
+	}
+
+	if filled.ValidTo.Unix() != order.ValidTo.Unix() {
+		t.Fatalf("expected the expiration from the maker traits, validTo: %v, want: %v", filled.ValidTo, order.ValidTo)
+	}
+}
+
+func TestBuildFusionOrder(t *testing.T) {
+	server := fake.NewServer()
+	defer server.Close()
+
+	oneClient := NewOneInch(common.NewClient(server.URL+fake.OneInchPrefix, nil), WithFusionClient(common.NewClient(server.URL+fake.FusionPrefix, nil)))
+	req := common.QuoteReq{
+		ChainId: 137,
+		Src:     TOKENB,
+		Dst:     common.NativeToken,
+		Amount:  big.NewInt(1000000),
+		From:    "0x15Ba05723b04785C3E21157171810892A4FB795c",
+	}
+
+	quote, err := oneClient.FetchFusionQuote(context.Background(), req)
+	if err != nil {
+		t.Fatalf("quote err: %v", err)
+	}
+
+	order, err := oneClient.BuildFusionOrder(req, quote, FusionPresetFast)
+	if err != nil {
+		t.Fatalf("build err: %v", err)
+	}
+
+	if order.Order.TakerAsset != common.WrappedNativeTokens[137] || order.Order.TakingAmount != "1990000" {
+		t.Fatalf("expected wmatic at the fast preset, order: %+v", order.Order)
+	}
+
+	traits, _ := new(big.Int).SetString(order.Order.MakerTraits, 10)
+	for _, flag := range []int{allowMultipleFillsFlag, postInteractionFlag, hasExtensionFlag, unwrapWethFlag} {
+		if traits.Bit(flag) != 1 {
+			t.Fatalf("expected maker traits flag %d, traits: %x", flag, traits)
+		}
+	}
+	if traits.Bit(noPartialFillsFlag) != 0 {
+		t.Fatalf("expected partial fills, traits: %x", traits)
+	}
+
+	if _, err := oneClient.BuildFusionOrder(req, quote, "custom"); err == nil {
+		t.Fatalf("expected an error for the empty custom preset")
+	}
+}
+
+func TestFusionErrorPaths(t *testing.T) {
+	server := fake.NewServer()
+	defer server.Close()
+
+	req := common.QuoteReq{
+		ChainId: 137,
+		Src:     TOKENA,
+		Dst:     TOKENB,
+		Amount:  big.NewInt(1000000),
+		From:    "0x15Ba05723b04785C3E21157171810892A4FB795c",
+	}
+
+	if _, err := NewOneInch(common.NewClient(server.URL+fake.OneInchPrefix, nil)).FetchFusionQuote(context.Background(), req); err == nil {
+		t.Fatalf("expected an error without a fusion client")
+	}
+
+	oneClient := NewOneInch(common.NewClient(server.URL+fake.OneInchPrefix, nil), WithFusionClient(common.NewClient(server.URL+fake.FusionPrefix, nil)))
+	if _, err := oneClient.FetchFusionQuote(context.Background(), req); !errors.Is(err, common.ErrUnsupportedToken) {
+		t.Fatalf("expected ErrUnsupportedToken for a native sell, err: %v", err)
+	}
+
+	req.Src, req.Dst = TOKENB, TOKENA
+	req.FeeBps = 10
+	if _, err := oneClient.FetchFusionQuote(context.Background(), req); !errors.Is(err, common.ErrUnsupportedParameter) {
+		t.Fatalf("expected ErrUnsupportedParameter for an integrator fee, err: %v", err)
+	}
+
+	req.FeeBps = 0
+	quote, err := oneClient.FetchFusionQuote(context.Background(), req)
+	if err != nil {
+		t.Fatalf("quote err: %v", err)
+	}
+	order, err := oneClient.BuildFusionOrder(req, quote, "")
+	if err != nil {
+		t.Fatalf("build err: %v", err)
+	}
+
+	if _, err := oneClient.SubmitFusionOrder(context.Background(), 137, order, make([]byte, 65)); err == nil {
+		t.Fatalf("expected the relayer to reject an invalid signature")
+	}
+}
+
+// TestFusionTypedDataKnownAnswer checks the order digest against an
+// encoding written from the Order struct and domain of the 1inch limit
+// order protocol v4, without going through apitypes.
+func TestFusionTypedDataKnownAnswer(t *testing.T) {
+	salt, _ := new(big.Int).SetString("102412815605188492651817525322578916420282362917187830307431355596813185396053", 10)
+	traits, _ := new(big.Int).SetString("62419173104490761595518734106557662061518414611782227068396304425790442831872", 10)
+	order := OneInchLimitOrder{
+		Salt:         salt.String(),
+		Maker:        "0x15Ba05723b04785C3E21157171810892A4FB795c",
+		Receiver:     "0x0000000000000000000000000000000000000000",
+		MakerAsset:   "0xa0b86991c6218b36c1d19d4a2e9eb0ce3606eb48",
+		TakerAsset:   "0xc02aaa39b223fe8d0a0e5c4f27ead9083c756cc2",
+		MakingAmount: "1000000000",
+		TakingAmount: "280000000000000000",
+		MakerTraits:  traits.String(),
+	}
+
+	word := func(v *big.Int) []byte { return ethcommon.LeftPadBytes(v.Bytes(), 32) }
+	address := func(a string) []byte { return ethcommon.LeftPadBytes(ethcommon.HexToAddress(a).Bytes(), 32) }
+
+	domainSeparator := crypto.Keccak256(
+		crypto.Keccak256([]byte("EIP712Domain(string name,string version,uint256 chainId,address verifyingContract)")),
+		crypto.Keccak256([]byte("1inch Aggregation Router")),
+		crypto.Keccak256([]byte("6")),
+		word(big.NewInt(1)),
+		address(LimitOrderProtocolAddress),
+	)
+	structHash := crypto.Keccak256(
+		crypto.Keccak256([]byte("Order(uint256 salt,address maker,address receiver,address makerAsset,address takerAsset,uint256 makingAmount,uint256 takingAmount,uint256 makerTraits)")),
+		word(salt),
+		address(order.Maker),
+		address(order.Receiver),
+		address(order.MakerAsset),
+		address(order.TakerAsset),
+		word(big.NewInt(1_000_000_000)),
+		word(big.NewInt(280_000_000_000_000_000)),
+		word(traits),
+	)
+	want := crypto.Keccak256Hash([]byte{0x19, 0x01}, domainSeparator, structHash)
+
+	digest, _, err := apitypes.TypedDataAndHash(FusionTypedData(1, order))
+	if err != nil || ethcommon.BytesToHash(digest) != want {
+		t.Fatalf("invalid order digest, digest: %x, want: %s, err: %v", digest, want, err)
+	}
+}
@@ -0,0 +1,577 @@
+package oneinch
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"fmt"
+	"math/big"
+	uri "net/url"
+	"sort"
+	"time"
+
+	ethcommon "github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/signer/core/apitypes"
+	"github.com/onmetahq/go-evm/internal/http/common"
+	metahttp "github.com/onmetahq/meta-http/pkg/meta_http"
+)
+
+// TrueERC20Address is the taker asset of Fusion+ orders on the source
+// chain, the destination token is only committed to in the extension and
+// delivered through the destination escrow.
+const TrueERC20Address = "0xda0000d4000015a526378bb6fafc650cea5966f8"
+
+// FusionPlusBridge names the transfers of Fusion+ orders in
+// common.CrossChainStatus.
+const FusionPlusBridge = "fusion+"
+
+// WithFusionPlusClient enables cross chain Fusion+ orders, client must be
+// configured with the 1inch Fusion+ API base url, e.g.
+// https://api.1inch.dev/fusion-plus.
+func WithFusionPlusClient(client metahttp.Requests) Option {
+	return func(o *oneInch) {
+		o.fusionPlus = client
+	}
+}
+
+// EscrowVerifier checks on chain that the escrows of fill are deployed on
+// both chains and lock the amounts and hashlock of order, it is called
+// before the secret of fill is shared.
+type EscrowVerifier func(ctx context.Context, order OneInchFusionPlusOrder, fill OneInchFusionPlusReadyFill) error
+
+// WithEscrowVerifier makes WaitForFusionPlusOrder verify the escrows of
+// every fill with verify before sharing its secret, the relayer's list of
+// ready fills is trusted as is otherwise.
+func WithEscrowVerifier(verify EscrowVerifier) Option {
+	return func(o *oneInch) {
+		o.verifyEscrows = verify
+	}
+}
+
+// PlaceFusionPlusOrder quotes req through Fusion+, signs the order on the
+// source chain with signer and submits it with the hashes of its secrets.
+// Resolvers lock the funds in escrows on both chains, the returned order
+// holds the secrets unlocking them and must be passed to
+// WaitForFusionPlusOrder, which shares each secret once the relayer
+// reports its escrows as deployed. req.SlippageBps is not used, the order
+// is filled by a Dutch auction.
+func (o *oneInch) PlaceFusionPlusOrder(ctx context.Context, req common.CrossChainQuoteReq, signer common.Signer) (OneInchFusionPlusOrder, error) {
+	req.From = signer.Address()
+
+	quote, err := o.FetchFusionPlusQuote(ctx, req)
+	if err != nil {
+		return OneInchFusionPlusOrder{}, err
+	}
+
+	order, err := o.BuildFusionPlusOrder(req, quote, "")
+	if err != nil {
+		return OneInchFusionPlusOrder{}, err
+	}
+
+	signature, err := signer.SignTypedData(ctx, FusionTypedData(order.SrcChainId, order.Order))
+	if err != nil {
+		return OneInchFusionPlusOrder{}, err
+	}
+
+	order.OrderHash, err = o.SubmitFusionPlusOrder(ctx, order, signature)
+	if err != nil {
+		return OneInchFusionPlusOrder{}, err
+	}
+	return order, nil
+}
+
+// FetchFusionPlusQuote returns the Fusion+ quote of req with its auction
+// presets, escrow factories, safety deposits and time locks.
+func (o *oneInch) FetchFusionPlusQuote(ctx context.Context, req common.CrossChainQuoteReq) (OneInchFusionPlusQuote, error) {
+	if o.fusionPlus == nil {
+		return OneInchFusionPlusQuote{}, fmt.Errorf("1inch fusion+ is not configured, use WithFusionPlusClient")
+	}
+
+	if req.SrcChainId == req.DstChainId {
+		return OneInchFusionPlusQuote{}, fmt.Errorf("1inch fusion+ orders are cross chain, use PlaceOrder on chainId %d, err: %w", req.SrcChainId, common.ErrUnsupportedParameter)
+	}
+
+	// As with Fusion, the maker cannot sign away ether.
+	if common.IsNativeToken(req.Src) {
+		return OneInchFusionPlusQuote{}, fmt.Errorf("native sell orders are not supported by 1inch fusion+, err: %w", common.ErrUnsupportedToken)
+	}
+
+	if len(req.AllowedBridges) > 0 || len(req.DeniedBridges) > 0 {
+		return OneInchFusionPlusQuote{}, fmt.Errorf("bridge selection is not supported by 1inch fusion+, err: %w", common.ErrUnsupportedParameter)
+	}
+
+	v := uri.Values{}
+	v.Add("srcChain", fmt.Sprint(req.SrcChainId))
+	v.Add("dstChain", fmt.Sprint(req.DstChainId))
+	v.Add("srcTokenAddress", req.Src)
+	v.Add("dstTokenAddress", req.Dst)
+	v.Add("amount", req.Amount.String())
+	v.Add("walletAddress", req.From)
+	v.Add("enableEstimate", "true")
+	url := fmt.Sprintf("/quoter/v1.0/quote/receive?%s", v.Encode())
+
+	headers, err := o.headers(ctx)
+	if err != nil {
+		return OneInchFusionPlusQuote{}, err
+	}
+
+	var res OneInchFusionPlusQuote
+	_, err = o.fusionPlus.Get(ctx, url, headers, &res)
+	if err != nil {
+		return OneInchFusionPlusQuote{}, fmt.Errorf("unable to fetch 1inch fusion+ quote, err: %w", parseError(err))
+	}
+	return res, nil
+}
+
+// BuildFusionPlusOrder builds the source chain order auctioned with preset,
+// the recommended preset of quote when empty, and generates the secrets of
+// its hashlock. Orders with a single secret are filled at once, with n
+// secrets they can be filled in n-1 parts.
+func (o *oneInch) BuildFusionPlusOrder(req common.CrossChainQuoteReq, quote OneInchFusionPlusQuote, preset string) (OneInchFusionPlusOrder, error) {
+	if preset == "" {
+		preset = quote.RecommendedPreset
+	}
+
+	p, ok := quote.Presets[preset]
+	if !ok || p == nil {
+		return OneInchFusionPlusOrder{}, fmt.Errorf("invalid 1inch fusion+ preset, preset: %s", preset)
+	}
+
+	if p.SecretsCount < 1 {
+		return OneInchFusionPlusOrder{}, fmt.Errorf("invalid secrets count from 1inch, count: %d", p.SecretsCount)
+	}
+
+	takingAmount, ok := common.ParseBigInt(p.AuctionEndAmount)
+	if !ok {
+		return OneInchFusionPlusOrder{}, fmt.Errorf("invalid auction end amount from 1inch, amount: %v", p.AuctionEndAmount)
+	}
+
+	gasPriceEstimate, ok := common.ParseBigInt(p.GasCost.GasPriceEstimate)
+	if !ok {
+		return OneInchFusionPlusOrder{}, fmt.Errorf("invalid gas price estimate from 1inch, gasPrice: %v", p.GasCost.GasPriceEstimate)
+	}
+
+	srcDeposit, ok := common.ParseBigInt(quote.SrcSafetyDeposit)
+	if !ok {
+		return OneInchFusionPlusOrder{}, fmt.Errorf("invalid source safety deposit from 1inch, deposit: %v", quote.SrcSafetyDeposit)
+	}
+
+	dstDeposit, ok := common.ParseBigInt(quote.DstSafetyDeposit)
+	if !ok {
+		return OneInchFusionPlusOrder{}, fmt.Errorf("invalid destination safety deposit from 1inch, deposit: %v", quote.DstSafetyDeposit)
+	}
+
+	secrets := make([]string, p.SecretsCount)
+	secretHashes := make([]string, p.SecretsCount)
+	for i := range secrets {
+		secret := make([]byte, 32)
+		if _, err := rand.Read(secret); err != nil {
+			return OneInchFusionPlusOrder{}, fmt.Errorf("unable to generate fusion+ secret, err: %w", err)
+		}
+		secrets[i] = hexutil.Encode(secret)
+		secretHashes[i] = crypto.Keccak256Hash(secret).Hex()
+	}
+	hashlock := fusionPlusHashlock(secretHashes)
+
+	receiver := req.Recipient
+	if receiver == "" {
+		receiver = ethcommon.Address{}.Hex()
+	}
+
+	startTime := uint64(time.Now().Unix()) + p.StartAuctionIn
+	validTo := time.Unix(int64(startTime+p.AuctionDuration), 0).Add(fusionExpirationDelay)
+	factory := ethcommon.HexToAddress(quote.SrcEscrowFactory)
+
+	auction := encodeAuctionDetails(p.OneInchFusionPreset, gasPriceEstimate.Uint64(), startTime)
+	postInteraction := append(factory.Bytes(), encodeSettlementData(quote.Whitelist, startTime)...)
+	postInteraction = append(postInteraction, encodeEscrowData(hashlock, req.DstChainId, req.Dst, srcDeposit, dstDeposit, quote.TimeLocks)...)
+	extension := encodeExtension(
+		append(factory.Bytes(), auction...),
+		append(factory.Bytes(), auction...),
+		postInteraction,
+	)
+
+	salt, err := fusionSalt(extension)
+	if err != nil {
+		return OneInchFusionPlusOrder{}, err
+	}
+
+	// A single secret is revealed once, so the order cannot be split.
+	multipleFills := p.SecretsCount > 1
+	traits, err := fusionMakerTraits(factory, validTo, multipleFills, multipleFills, false)
+	if err != nil {
+		return OneInchFusionPlusOrder{}, err
+	}
+
+	return OneInchFusionPlusOrder{
+		SrcChainId: req.SrcChainId,
+		DstChainId: req.DstChainId,
+		Order: OneInchLimitOrder{
+			Salt:         salt.String(),
+			Maker:        req.From,
+			Receiver:     receiver,
+			MakerAsset:   req.Src,
+			TakerAsset:   TrueERC20Address,
+			MakingAmount: req.Amount.String(),
+			TakingAmount: takingAmount.String(),
+			MakerTraits:  traits.String(),
+		},
+		Extension:    hexutil.Encode(extension),
+		QuoteId:      quote.QuoteId,
+		Dst:          req.Dst,
+		Secrets:      secrets,
+		SecretHashes: secretHashes,
+		Hashlock:     hexutil.Encode(hashlock),
+		ValidTo:      validTo,
+	}, nil
+}
+
+// SubmitFusionPlusOrder sends the signed order and the hashes of its
+// secrets to the relayer and returns its order hash.
+func (o *oneInch) SubmitFusionPlusOrder(ctx context.Context, order OneInchFusionPlusOrder, signature []byte) (string, error) {
+	if o.fusionPlus == nil {
+		return "", fmt.Errorf("1inch fusion+ is not configured, use WithFusionPlusClient")
+	}
+
+	hash, _, err := apitypes.TypedDataAndHash(FusionTypedData(order.SrcChainId, order.Order))
+	if err != nil {
+		return "", fmt.Errorf("unable to hash 1inch fusion+ order, err: %w", err)
+	}
+
+	headers, err := o.headers(ctx)
+	if err != nil {
+		return "", err
+	}
+
+	body := OneInchFusionPlusSubmission{
+		Order:      order.Order,
+		SrcChainId: order.SrcChainId,
+		Signature:  hexutil.Encode(signature),
+		Extension:  order.Extension,
+		QuoteId:    order.QuoteId,
+	}
+	// The hashes of a single secret order are known from its hashlock.
+	if len(order.SecretHashes) > 1 {
+		body.SecretHashes = order.SecretHashes
+	}
+
+	_, err = o.fusionPlus.Post(ctx, "/relayer/v1.0/submit", headers, body, nil)
+	if err != nil {
+		return "", fmt.Errorf("unable to submit 1inch fusion+ order, err: %w", parseError(err))
+	}
+	return hexutil.Encode(hash), nil
+}
+
+// FetchReadyFusionPlusFills returns the fills whose escrows are deployed
+// on both chains and waiting for their secret.
+func (o *oneInch) FetchReadyFusionPlusFills(ctx context.Context, orderHash string) ([]OneInchFusionPlusReadyFill, error) {
+	if o.fusionPlus == nil {
+		return nil, fmt.Errorf("1inch fusion+ is not configured, use WithFusionPlusClient")
+	}
+
+	headers, err := o.headers(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	var res struct {
+		Fills []OneInchFusionPlusReadyFill `json:"fills"`
+	}
+	url := fmt.Sprintf("/orders/v1.0/order/ready-to-accept-secret-fills/%s", orderHash)
+	_, err = o.fusionPlus.Get(ctx, url, headers, &res)
+	if err != nil {
+		return nil, fmt.Errorf("unable to fetch 1inch fusion+ ready fills, err: %w", parseError(err))
+	}
+	return res.Fills, nil
+}
+
+// SubmitFusionPlusSecret shares secret with the resolvers through the
+// relayer. It must only be called for a fill returned by
+// FetchReadyFusionPlusFills, the relayer does not check that its escrows
+// are deployed.
+func (o *oneInch) SubmitFusionPlusSecret(ctx context.Context, orderHash, secret string) error {
+	if o.fusionPlus == nil {
+		return fmt.Errorf("1inch fusion+ is not configured, use WithFusionPlusClient")
+	}
+
+	headers, err := o.headers(ctx)
+	if err != nil {
+		return err
+	}
+
+	body := map[string]string{"orderHash": orderHash, "secret": secret}
+	_, err = o.fusionPlus.Post(ctx, "/relayer/v1.0/submit/secret", headers, body, nil)
+	if err != nil {
+		return fmt.Errorf("unable to submit 1inch fusion+ secret, err: %w", parseError(err))
+	}
+	return nil
+}
+
+// FetchFusionPlusOrder returns the transfer status of the order, SrcTxHash
+// is the source escrow deployment and DstTxHash the withdrawal to the
+// receiver on the destination chain.
+func (o *oneInch) FetchFusionPlusOrder(ctx context.Context, orderHash string) (common.CrossChainStatus, error) {
+	if o.fusionPlus == nil {
+		return common.CrossChainStatus{}, fmt.Errorf("1inch fusion+ is not configured, use WithFusionPlusClient")
+	}
+
+	headers, err := o.headers(ctx)
+	if err != nil {
+		return common.CrossChainStatus{}, err
+	}
+
+	var res OneInchFusionPlusOrderStatus
+	url := fmt.Sprintf("/orders/v1.0/order/status/%s", orderHash)
+	_, err = o.fusionPlus.Get(ctx, url, headers, &res)
+	if err != nil {
+		return common.CrossChainStatus{}, fmt.Errorf("unable to fetch 1inch fusion+ order, err: %w", parseError(err))
+	}
+	return parseFusionPlusOrder(res)
+}
+
+// WaitForFusionPlusOrder polls order every interval, sharing the secret of
+// each fill the relayer reports as ready, until its status is final or ctx
+// is done, in which case the last fetched status is returned with the
+// context error. The escrows of each fill are checked with the
+// EscrowVerifier set by WithEscrowVerifier first, without one the
+// relayer's ready list is trusted as is. A failed check stops the wait
+// without sharing the secret.
+func (o *oneInch) WaitForFusionPlusOrder(ctx context.Context, order OneInchFusionPlusOrder, interval time.Duration) (common.CrossChainStatus, error) {
+	shared := map[int]bool{}
+	fetch := func(ctx context.Context) (common.CrossChainStatus, error) {
+		fills, err := o.FetchReadyFusionPlusFills(ctx, order.OrderHash)
+		if err != nil {
+			return common.CrossChainStatus{}, err
+		}
+
+		for _, fill := range fills {
+			if shared[fill.Idx] {
+				continue
+			}
+			if fill.Idx < 0 || fill.Idx >= len(order.Secrets) {
+				return common.CrossChainStatus{}, fmt.Errorf("invalid secret index from 1inch, idx: %d, secrets: %d", fill.Idx, len(order.Secrets))
+			}
+			if o.verifyEscrows != nil {
+				if err := o.verifyEscrows(ctx, order, fill); err != nil {
+					return common.CrossChainStatus{}, fmt.Errorf("unable to verify 1inch fusion+ escrows of fill %d, err: %w", fill.Idx, err)
+				}
+			}
+			if err := o.SubmitFusionPlusSecret(ctx, order.OrderHash, order.Secrets[fill.Idx]); err != nil {
+				return common.CrossChainStatus{}, err
+			}
+			shared[fill.Idx] = true
+		}
+		return o.FetchFusionPlusOrder(ctx, order.OrderHash)
+	}
+	final := func(s common.CrossChainStatus) bool { return s.Status.Final() }
+	pending := func(s common.CrossChainStatus, err error) error {
+		return fmt.Errorf("fusion+ order %s not final, status: %s, err: %w", order.OrderHash, s.Status, err)
+	}
+	return common.Poll(ctx, interval, fetch, final, pending)
+}
+
+type OneInchFusionPlusQuote struct {
+	QuoteId           string                              `json:"quoteId"`
+	SrcTokenAmount    string                              `json:"srcTokenAmount"`
+	DstTokenAmount    string                              `json:"dstTokenAmount"`
+	Presets           map[string]*OneInchFusionPlusPreset `json:"presets"`
+	RecommendedPreset string                              `json:"recommendedPreset"`
+	SrcEscrowFactory  string                              `json:"srcEscrowFactory"`
+	DstEscrowFactory  string                              `json:"dstEscrowFactory"`
+	// SrcSafetyDeposit and DstSafetyDeposit are paid in native tokens by
+	// the resolver deploying each escrow.
+	SrcSafetyDeposit string           `json:"srcSafetyDeposit"`
+	DstSafetyDeposit string           `json:"dstSafetyDeposit"`
+	TimeLocks        OneInchTimeLocks `json:"timeLocks"`
+	Whitelist        []string         `json:"whitelist"`
+}
+
+// OneInchFusionPlusPreset is a Fusion auction preset, the order hashlock
+// commits to SecretsCount secrets.
+type OneInchFusionPlusPreset struct {
+	OneInchFusionPreset
+	SecretsCount int `json:"secretsCount"`
+}
+
+// OneInchTimeLocks are the escrow stages in seconds after deployment: the
+// receiver may withdraw with the secret after the withdrawal delay, anyone
+// after the public withdrawal delay, and the funds return to the maker
+// after the cancellation delays.
+type OneInchTimeLocks struct {
+	SrcWithdrawal         uint32 `json:"srcWithdrawal"`
+	SrcPublicWithdrawal   uint32 `json:"srcPublicWithdrawal"`
+	SrcCancellation       uint32 `json:"srcCancellation"`
+	SrcPublicCancellation uint32 `json:"srcPublicCancellation"`
+	DstWithdrawal         uint32 `json:"dstWithdrawal"`
+	DstPublicWithdrawal   uint32 `json:"dstPublicWithdrawal"`
+	DstCancellation       uint32 `json:"dstCancellation"`
+}
+
+// OneInchFusionPlusOrder is a limit order on SrcChainId whose escrow
+// extension commits to Dst on DstChainId and to the hashlock of Secrets.
+// The secrets must stay private until WaitForFusionPlusOrder shares them,
+// anyone knowing one before the escrows are deployed can take the funds.
+type OneInchFusionPlusOrder struct {
+	SrcChainId   uint64
+	DstChainId   uint64
+	Order        OneInchLimitOrder
+	Extension    string
+	QuoteId      string
+	Dst          string
+	Secrets      []string
+	SecretHashes []string
+	Hashlock     string
+	ValidTo      time.Time
+	// OrderHash is set once the order is submitted.
+	OrderHash string
+}
+
+type OneInchFusionPlusSubmission struct {
+	Order        OneInchLimitOrder `json:"order"`
+	SrcChainId   uint64            `json:"srcChainId"`
+	Signature    string            `json:"signature"`
+	Extension    string            `json:"extension"`
+	QuoteId      string            `json:"quoteId"`
+	SecretHashes []string          `json:"secretHashes,omitempty"`
+}
+
+// OneInchFusionPlusReadyFill is a fill whose secret Idx can be shared.
+type OneInchFusionPlusReadyFill struct {
+	Idx                   int    `json:"idx"`
+	SrcEscrowDeployTxHash string `json:"srcEscrowDeployTxHash"`
+	DstEscrowDeployTxHash string `json:"dstEscrowDeployTxHash"`
+}
+
+type OneInchEscrowEvent struct {
+	TransactionHash string `json:"transactionHash"`
+	// Side is src or dst, Action is src_escrow_created, dst_escrow_created,
+	// withdrawn, funds_rescued or escrow_cancelled.
+	Side           string `json:"side"`
+	Action         string `json:"action"`
+	BlockTimestamp int64  `json:"blockTimestamp"`
+}
+
+type OneInchFusionPlusFill struct {
+	Status                   string               `json:"status"`
+	TxHash                   string               `json:"txHash"`
+	FilledMakerAmount        string               `json:"filledMakerAmount"`
+	FilledAuctionTakerAmount string               `json:"filledAuctionTakerAmount"`
+	EscrowEvents             []OneInchEscrowEvent `json:"escrowEvents"`
+}
+
+type OneInchFusionPlusOrderStatus struct {
+	OrderHash  string                  `json:"orderHash"`
+	Status     string                  `json:"status"`
+	SrcChainId uint64                  `json:"srcChainId"`
+	DstChainId uint64                  `json:"dstChainId"`
+	Order      OneInchLimitOrder       `json:"order"`
+	TakerAsset string                  `json:"takerAsset"`
+	Fills      []OneInchFusionPlusFill `json:"fills"`
+}
+
+func parseFusionPlusOrder(res OneInchFusionPlusOrderStatus) (common.CrossChainStatus, error) {
+	received := new(big.Int)
+	srcTxHash, dstTxHash := "", ""
+	for _, fill := range res.Fills {
+		taker, ok := common.ParseBigInt(fill.FilledAuctionTakerAmount)
+		if !ok {
+			return common.CrossChainStatus{}, fmt.Errorf("invalid filled amount from 1inch, amount: %v", fill.FilledAuctionTakerAmount)
+		}
+		received.Add(received, taker)
+
+		for _, event := range fill.EscrowEvents {
+			switch {
+			case event.Side == "src" && event.Action == "src_escrow_created":
+				srcTxHash = event.TransactionHash
+			case event.Side == "dst" && event.Action == "withdrawn":
+				dstTxHash = event.TransactionHash
+			}
+		}
+	}
+
+	// Refunding orders had their escrows cancelled, expired and cancelled
+	// orders never locked any funds.
+	var status common.TransferStatus
+	switch res.Status {
+	case "pending", "refunding":
+		status = common.TransferPending
+	case "executed":
+		status = common.TransferDone
+	case "refunded":
+		status = common.TransferRefunded
+	default:
+		status = common.TransferFailed
+	}
+
+	out := common.CrossChainStatus{
+		Status:        status,
+		SubStatus:     res.Status,
+		Bridge:        FusionPlusBridge,
+		SrcTxHash:     srcTxHash,
+		DstTxHash:     dstTxHash,
+		ReceivedToken: res.TakerAsset,
+	}
+	if status == common.TransferDone {
+		out.ReceivedAmount = received
+	}
+	return out, nil
+}
+
+// fusionPlusHashlock returns the secret hash of a single secret order. With
+// several secrets it returns the root of the Merkle tree of their
+// keccak256(uint64 idx, bytes32 secretHash) leaves, built as the
+// OpenZeppelin SimpleMerkleTree, with the secrets count minus one in its
+// upper 16 bits.
+func fusionPlusHashlock(secretHashes []string) []byte {
+	if len(secretHashes) == 1 {
+		return ethcommon.HexToHash(secretHashes[0]).Bytes()
+	}
+
+	leaves := make([][]byte, len(secretHashes))
+	for i, secretHash := range secretHashes {
+		leaves[i] = crypto.Keccak256(appendUint(nil, uint64(i), 8), ethcommon.HexToHash(secretHash).Bytes())
+	}
+	sort.Slice(leaves, func(i, j int) bool { return bytes.Compare(leaves[i], leaves[j]) < 0 })
+
+	tree := make([][]byte, 2*len(leaves)-1)
+	for i, leaf := range leaves {
+		tree[len(tree)-1-i] = leaf
+	}
+	for i := len(tree) - 1 - len(leaves); i >= 0; i-- {
+		left, right := tree[2*i+1], tree[2*i+2]
+		if bytes.Compare(left, right) > 0 {
+			left, right = right, left
+		}
+		tree[i] = crypto.Keccak256(left, right)
+	}
+
+	root := new(big.Int).SetBytes(tree[0])
+	root.And(root, new(big.Int).Sub(new(big.Int).Lsh(big.NewInt(1), 240), big.NewInt(1)))
+	root.Or(root, new(big.Int).Lsh(big.NewInt(int64(len(secretHashes)-1)), 240))
+	return ethcommon.LeftPadBytes(root.Bytes(), 32)
+}
+
+// encodeEscrowData packs the escrow parameters appended to the post
+// interaction: hashlock bytes32, dstChainId uint256, dstToken address,
+// the safety deposits as src << 128 | dst, then the time locks as 32 bit
+// values, srcWithdrawal lowest, the deployment time left for the factory.
+func encodeEscrowData(hashlock []byte, dstChainId uint64, dstToken string, srcDeposit, dstDeposit *big.Int, locks OneInchTimeLocks) []byte {
+	deposits := new(big.Int).Lsh(srcDeposit, 128)
+	deposits.Or(deposits, dstDeposit)
+
+	timeLocks := new(big.Int)
+	for i, stage := range []uint32{
+		locks.SrcWithdrawal, locks.SrcPublicWithdrawal, locks.SrcCancellation, locks.SrcPublicCancellation,
+		locks.DstWithdrawal, locks.DstPublicWithdrawal, locks.DstCancellation,
+	} {
+		timeLocks.Or(timeLocks, new(big.Int).Lsh(big.NewInt(int64(stage)), uint(32*i)))
+	}
+
+	out := ethcommon.LeftPadBytes(hashlock, 32)
+	out = append(out, ethcommon.LeftPadBytes(new(big.Int).SetUint64(dstChainId).Bytes(), 32)...)
+	out = append(out, ethcommon.LeftPadBytes(ethcommon.HexToAddress(dstToken).Bytes(), 32)...)
+	out = append(out, ethcommon.LeftPadBytes(deposits.Bytes(), 32)...)
+	return append(out, ethcommon.LeftPadBytes(timeLocks.Bytes(), 32)...)
+}
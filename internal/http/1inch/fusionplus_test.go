@@ -0,0 +1,287 @@
+package oneinch
+
+import (
+	"context"
+	"encoding/binary"
+	"errors"
+	"math/big"
+	"strings"
+	"testing"
+	"time"
+
+	ethcommon "github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/onmetahq/go-evm/internal/http/common"
+	"github.com/onmetahq/go-evm/internal/http/fake"
+)
+
+const mainnetUSDC = "0xA0b86991c6218b36c1d19D4a2e9Eb0cE3606eB48"
+
+func newFusionPlusClient(server *fake.Server) *oneInch {
+	return NewOneInch(common.NewClient(server.URL+fake.OneInchPrefix, nil), WithFusionPlusClient(common.NewClient(server.URL+fake.FusionPlusPrefix, nil)))
+}
+
+func TestPlaceFusionPlusOrder(t *testing.T) {
+	server := fake.NewServer()
+	defer server.Close()
+
+	key, err := crypto.GenerateKey()
+	if err != nil {
+		t.Fatalf("key err: %v", err)
+	}
+	signer := common.PrivateKeySigner(key)
+
+	oneClient := newFusionPlusClient(server)
+	req := common.CrossChainQuoteReq{
+		SrcChainId: 137,
+		DstChainId: 1,
+		Src:        TOKENB,
+		Dst:        mainnetUSDC,
+		Amount:     big.NewInt(1000000),
+	}
+
+	order, err := oneClient.PlaceFusionPlusOrder(context.Background(), req, signer)
+	if err != nil {
+		t.Fatalf("place order err: %v", err)
+	}
+
+	// The recommended medium preset ends 0.3% below the 2000000 quote and
+	// is filled at once with a single secret.
+	if order.Order.TakingAmount != "1994000" || order.Order.TakerAsset != TrueERC20Address || order.OrderHash == "" {
+		t.Fatalf("invalid order, order: %+v", order.Order)
+	}
+
+	if len(order.Secrets) != 1 || crypto.Keccak256Hash(hexutil.MustDecode(order.Secrets[0])).Hex() != order.Hashlock {
+		t.Fatalf("expected the hashlock of a single secret, order: %+v", order)
+	}
+
+	traits, _ := new(big.Int).SetString(order.Order.MakerTraits, 10)
+	if traits.Bit(noPartialFillsFlag) != 1 || traits.Bit(allowMultipleFillsFlag) != 0 {
+		t.Fatalf("expected a single fill order, traits: %x", traits)
+	}
+
+	status, err := oneClient.FetchFusionPlusOrder(context.Background(), order.OrderHash)
+	if err != nil || status.Status != common.TransferPending {
+		t.Fatalf("expected a pending order, status: %+v, err: %v", status, err)
+	}
+
+	done, err := oneClient.WaitForFusionPlusOrder(context.Background(), order, time.Millisecond)
+	if err != nil {
+		t.Fatalf("wait err: %v", err)
+	}
+
+	if done.Status != common.TransferDone || done.SubStatus != "executed" || done.Bridge != FusionPlusBridge {
+		t.Fatalf("expected an executed order, status: %+v", done)
+	}
+
+	if done.ReceivedAmount.Cmp(big.NewInt(1994000)) != 0 || !strings.EqualFold(done.ReceivedToken, mainnetUSDC) || done.SrcTxHash == "" || done.DstTxHash == "" {
+		t.Fatalf("invalid transfer, status: %+v", done)
+	}
+
+	if server.Calls("/submit/secret") != 1 {
+		t.Fatalf("expected the secret to be shared once, calls: %d", server.Calls("/submit/secret"))
+	}
+}
+
+func TestFusionPlusMultipleFills(t *testing.T) {
+	server := fake.NewServer()
+	defer server.Close()
+
+	key, err := crypto.GenerateKey()
+	if err != nil {
+		t.Fatalf("key err: %v", err)
+	}
+	signer := common.PrivateKeySigner(key)
+
+	oneClient := newFusionPlusClient(server)
+	req := common.CrossChainQuoteReq{
+		SrcChainId: 137,
+		DstChainId: 1,
+		Src:        TOKENB,
+		Dst:        common.NativeToken,
+		Amount:     big.NewInt(1000000),
+		From:       signer.Address(),
+	}
+
+	quote, err := oneClient.FetchFusionPlusQuote(context.Background(), req)
+	if err != nil {
+		t.Fatalf("quote err: %v", err)
+	}
+
+	order, err := oneClient.BuildFusionPlusOrder(req, quote, FusionPresetSlow)
+	if err != nil {
+		t.Fatalf("build err: %v", err)
+	}
+
+	if len(order.Secrets) != 3 || len(order.SecretHashes) != 3 {
+		t.Fatalf("expected three secrets, order: %+v", order)
+	}
+
+	// The escrow data closes the extension: hashlock, dstChainId, dstToken,
+	// safety deposits and time locks.
+	extension := hexutil.MustDecode(order.Extension)
+	escrow := extension[len(extension)-160:]
+	if hexutil.Encode(escrow[:32]) != order.Hashlock || binary.BigEndian.Uint16(escrow[:2]) != 2 {
+		t.Fatalf("expected the merkle hashlock with the secrets count, hashlock: %x", escrow[:32])
+	}
+	if new(big.Int).SetBytes(escrow[32:64]).Uint64() != 1 || ethcommon.BytesToAddress(escrow[64:96]) != ethcommon.HexToAddress(common.NativeToken) {
+		t.Fatalf("invalid destination in the escrow data, escrow: %x", escrow)
+	}
+	deposits := new(big.Int).SetBytes(escrow[96:128])
+	if deposits.Cmp(new(big.Int).Or(new(big.Int).Lsh(big.NewInt(1e15), 128), big.NewInt(2e15))) != 0 {
+		t.Fatalf("invalid safety deposits, deposits: %x", deposits)
+	}
+	locks := new(big.Int).SetBytes(escrow[128:160])
+	if new(big.Int).And(locks, big.NewInt(1<<32-1)).Uint64() != 12 || new(big.Int).Rsh(locks, 192).Uint64() != 600 {
+		t.Fatalf("invalid time locks, locks: %x", locks)
+	}
+
+	traits, _ := new(big.Int).SetString(order.Order.MakerTraits, 10)
+	if traits.Bit(noPartialFillsFlag) != 0 || traits.Bit(allowMultipleFillsFlag) != 1 {
+		t.Fatalf("expected a multiple fills order, traits: %x", traits)
+	}
+
+	signature, err := signer.SignTypedData(context.Background(), FusionTypedData(order.SrcChainId, order.Order))
+	if err != nil {
+		t.Fatalf("sign err: %v", err)
+	}
+
+	order.OrderHash, err = oneClient.SubmitFusionPlusOrder(context.Background(), order, signature)
+	if err != nil {
+		t.Fatalf("submit err: %v", err)
+	}
+
+	done, err := oneClient.WaitForFusionPlusOrder(context.Background(), order, time.Millisecond)
+	if err != nil {
+		t.Fatalf("wait err: %v", err)
+	}
+
+	taking, _ := new(big.Int).SetString(order.Order.TakingAmount, 10)
+	if done.Status != common.TransferDone || done.ReceivedAmount.Cmp(taking) != 0 {
+		t.Fatalf("expected both parts to be filled, status: %+v", done)
+	}
+
+	// The first part reveals secret 0, the second fills the rest with the
+	// last secret.
+	if server.Calls("/submit/secret") != 2 {
+		t.Fatalf("expected a secret per fill, calls: %d", server.Calls("/submit/secret"))
+	}
+}
+
+func TestFusionPlusErrorPaths(t *testing.T) {
+	server := fake.NewServer()
+	defer server.Close()
+
+	req := common.CrossChainQuoteReq{
+		SrcChainId: 137,
+		DstChainId: 1,
+		Src:        TOKENB,
+		Dst:        mainnetUSDC,
+		Amount:     big.NewInt(1000000),
+		From:       "0x15Ba05723b04785C3E21157171810892A4FB795c",
+	}
+
+	if _, err := NewOneInch(common.NewClient(server.URL+fake.OneInchPrefix, nil)).FetchFusionPlusQuote(context.Background(), req); err == nil {
+		t.Fatalf("expected an error without a fusion+ client")
+	}
+
+	oneClient := newFusionPlusClient(server)
+
+	same := req
+	same.DstChainId = 137
+	if _, err := oneClient.FetchFusionPlusQuote(context.Background(), same); !errors.Is(err, common.ErrUnsupportedParameter) {
+		t.Fatalf("expected ErrUnsupportedParameter for a single chain order, err: %v", err)
+	}
+
+	native := req
+	native.Src = TOKENA
+	if _, err := oneClient.FetchFusionPlusQuote(context.Background(), native); !errors.Is(err, common.ErrUnsupportedToken) {
+		t.Fatalf("expected ErrUnsupportedToken for a native sell, err: %v", err)
+	}
+
+	bridges := req
+	bridges.AllowedBridges = []string{"stargate"}
+	if _, err := oneClient.FetchFusionPlusQuote(context.Background(), bridges); !errors.Is(err, common.ErrUnsupportedParameter) {
+		t.Fatalf("expected ErrUnsupportedParameter for a bridge filter, err: %v", err)
+	}
+
+	quote, err := oneClient.FetchFusionPlusQuote(context.Background(), req)
+	if err != nil {
+		t.Fatalf("quote err: %v", err)
+	}
+
+	if _, err := oneClient.BuildFusionPlusOrder(req, quote, "custom"); err == nil {
+		t.Fatalf("expected an error for the empty custom preset")
+	}
+
+	order, err := oneClient.BuildFusionPlusOrder(req, quote, "")
+	if err != nil {
+		t.Fatalf("build err: %v", err)
+	}
+
+	if _, err := oneClient.SubmitFusionPlusOrder(context.Background(), order, make([]byte, 65)); err == nil {
+		t.Fatalf("expected the relayer to reject an invalid signature")
+	}
+}
+
+func TestFusionPlusEscrowVerifier(t *testing.T) {
+	server := fake.NewServer()
+	defer server.Close()
+
+	key, err := crypto.GenerateKey()
+	if err != nil {
+		t.Fatalf("key err: %v", err)
+	}
+	signer := common.PrivateKeySigner(key)
+
+	req := common.CrossChainQuoteReq{
+		SrcChainId: 137,
+		DstChainId: 1,
+		Src:        TOKENB,
+		Dst:        mainnetUSDC,
+		Amount:     big.NewInt(1000000),
+	}
+
+	errEscrow := errors.New("escrow not deployed")
+	rejecting := NewOneInch(common.NewClient(server.URL+fake.OneInchPrefix, nil),
+		WithFusionPlusClient(common.NewClient(server.URL+fake.FusionPlusPrefix, nil)),
+		WithEscrowVerifier(func(ctx context.Context, order OneInchFusionPlusOrder, fill OneInchFusionPlusReadyFill) error {
+			return errEscrow
+		}),
+	)
+
+	order, err := rejecting.PlaceFusionPlusOrder(context.Background(), req, signer)
+	if err != nil {
+		t.Fatalf("place order err: %v", err)
+	}
+
+	if _, err := rejecting.WaitForFusionPlusOrder(context.Background(), order, time.Millisecond); !errors.Is(err, errEscrow) {
+		t.Fatalf("expected the verifier error, err: %v", err)
+	}
+
+	if server.Calls("/submit/secret") != 0 {
+		t.Fatalf("expected no secret to be shared, calls: %d", server.Calls("/submit/secret"))
+	}
+
+	var verified []OneInchFusionPlusReadyFill
+	accepting := NewOneInch(common.NewClient(server.URL+fake.OneInchPrefix, nil),
+		WithFusionPlusClient(common.NewClient(server.URL+fake.FusionPlusPrefix, nil)),
+		WithEscrowVerifier(func(ctx context.Context, o OneInchFusionPlusOrder, fill OneInchFusionPlusReadyFill) error {
+			if o.OrderHash != order.OrderHash {
+				t.Errorf("unexpected order, hash: %s", o.OrderHash)
+			}
+			verified = append(verified, fill)
+			return nil
+		}),
+	)
+
+	done, err := accepting.WaitForFusionPlusOrder(context.Background(), order, time.Millisecond)
+	if err != nil || done.Status != common.TransferDone {
+		t.Fatalf("expected an executed order, status: %+v, err: %v", done, err)
+	}
+
+	if len(verified) != 1 || verified[0].SrcEscrowDeployTxHash == "" || verified[0].DstEscrowDeployTxHash == "" {
+		t.Fatalf("expected the ready fill to be verified, fills: %+v", verified)
+	}
+}
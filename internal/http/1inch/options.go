@@ -0,0 +1,130 @@
+package oneinch
+
+import (
+	"fmt"
+	uri "net/url"
+	"strconv"
+	"strings"
+
+	"github.com/onmetahq/go-evm/internal/http/common"
+)
+
+// MaxFeeBps is the highest integrator fee accepted by 1inch, 3%.
+const MaxFeeBps uint32 = 300
+
+// OneInchOptions are the QuoteReq.Options only understood by 1inch.
+type OneInchOptions struct {
+	ComplexityLevel  *int
+	MainRouteParts   *int
+	Parts            *int
+	AllowPartialFill bool
+	// Compatibility returns calldata compatible with contract wallets.
+	Compatibility bool
+	// Permit is an EIP-2612 permit for Src signed by From, encoded as 1inch
+	// expects it.
+	Permit string
+	// FusionPreset selects the auction preset of gasless Fusion orders,
+	// e.g. fast or slow, the recommended one when empty.
+	FusionPreset string
+}
+
+func (OneInchOptions) Provider() string {
+	return "1inch"
+}
+
+// addRouteParams adds the parameters shared by /quote and /swap.
+func addRouteParams(v uri.Values, req common.QuoteReq) error {
+	v.Add("includeProtocols", "true")
+
+	if req.FeeBps > 0 {
+		if req.FeeBps > MaxFeeBps {
+			return fmt.Errorf("invalid 1inch fee, bps: %d, max: %d", req.FeeBps, MaxFeeBps)
+		}
+		v.Add("fee", common.BpsPercent(req.FeeBps))
+	}
+
+	if req.GasPrice != nil {
+		v.Add("gasPrice", req.GasPrice.String())
+	}
+
+	if len(req.IncludedSources) > 0 {
+		v.Add("protocols", strings.Join(req.IncludedSources, ","))
+	}
+
+	if len(req.ExcludedSources) > 0 {
+		v.Add("excludedProtocols", strings.Join(req.ExcludedSources, ","))
+	}
+
+	opts, err := common.OptionsOf[OneInchOptions](req)
+	if err != nil {
+		return err
+	}
+
+	if opts.ComplexityLevel != nil {
+		v.Add("complexityLevel", strconv.Itoa(*opts.ComplexityLevel))
+	}
+	if opts.MainRouteParts != nil {
+		v.Add("mainRouteParts", strconv.Itoa(*opts.MainRouteParts))
+	}
+	if opts.Parts != nil {
+		v.Add("parts", strconv.Itoa(*opts.Parts))
+	}
+	return nil
+}
+
+// addSwapParams adds the parameters only understood by /swap.
+func addSwapParams(v uri.Values, req common.QuoteReq) error {
+	if req.FeeBps > 0 && req.Referrer == "" {
+		return fmt.Errorf("1inch fee requires a referrer to receive it")
+	}
+
+	if req.Receiver != "" {
+		v.Add("receiver", req.Receiver)
+	}
+
+	opts, err := common.OptionsOf[OneInchOptions](req)
+	if err != nil {
+		return err
+	}
+
+	if opts.Permit != "" {
+		v.Add("permit", opts.Permit)
+	}
+	if opts.AllowPartialFill {
+		v.Add("allowPartialFill", "true")
+	}
+	if opts.Compatibility {
+		v.Add("compatibility", "true")
+	}
+	return nil
+}
+
+type OneInchProtocolPart struct {
+	Name             string  `json:"name"`
+	Part             float64 `json:"part"`
+	FromTokenAddress string  `json:"fromTokenAddress"`
+	ToTokenAddress   string  `json:"toTokenAddress"`
+}
+
+// parseProtocols decodes the 1inch protocols field, a list of routes made
+// of hops made of protocol parts.
+func parseProtocols(protocols [][][]OneInchProtocolPart) []common.Route {
+	routes := make([]common.Route, 0, len(protocols))
+	for _, route := range protocols {
+		hops := make([][]common.RoutePart, 0, len(route))
+		for _, hop := range route {
+			parts := make([]common.RoutePart, 0, len(hop))
+			for _, p := range hop {
+				parts = append(parts, common.RoutePart{
+					Protocol:  p.Name,
+					Part:      p.Part,
+					FromToken: p.FromTokenAddress,
+					ToToken:   p.ToTokenAddress,
+				})
+			}
+			hops = append(hops, parts)
+		}
+		routes = append(routes, common.Route{Hops: hops})
+	}
+	return routes
+}
@@ -0,0 +1,19 @@
+package common
+
+import "context"
+
+// Aggregator is the method set shared by every DEX aggregator provider.
+type Aggregator interface {
+	FetchSupportedTokens(ctx context.Context, chainId uint64) ([]Token, error)
+	FetchExactInQuote(ctx context.Context, req QuoteReq) (QuoteRes, error)
+	FetchExactOutQuote(ctx context.Context, req QuoteReq) (QuoteRes, error)
+	FetchExactInSwapCallData(ctx context.Context, req QuoteReq) (SwapTx, error)
+	FetchExactOutSwapCallData(ctx context.Context, req QuoteReq) (SwapTx, error)
+}
+
+// MultiAggregator is implemented by providers able to swap several inputs
+// into several outputs in a single transaction.
+type MultiAggregator interface {
+	FetchMultiQuote(ctx context.Context, req MultiQuoteReq) (MultiQuoteRes, error)
+	FetchMultiSwapCallData(ctx context.Context, req MultiQuoteReq) (MultiSwapTx, error)
+}
@@ -0,0 +1,157 @@
+package common
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	metahttp "github.com/onmetahq/meta-http/pkg/meta_http"
+	"github.com/onmetahq/meta-http/pkg/models"
+	"github.com/onmetahq/meta-http/pkg/utils"
+)
+
+// HTTPError is returned by clients created with NewClient for non 2xx
+// responses, it keeps the raw body so providers can parse their own error
+// payloads.
+type HTTPError struct {
+	StatusCode int
+	Status     string
+	Body       []byte
+}
+
+func (e *HTTPError) Error() string {
+	return fmt.Sprintf("StatusCode: %d, Body: %s", e.StatusCode, string(e.Body))
+}
+
+type client struct {
+	baseURL        string
+	httpClient     *http.Client
+	defaultHeaders map[string]string
+}
+
+// NewClient returns a metahttp.Requests backed by httpClient which, unlike
+// metahttp.NewClient, keeps the response body of failed calls and accepts
+// any transport. A client with a 30 second timeout is used when httpClient
+// is nil.
+func NewClient(baseUrl string, httpClient *http.Client) metahttp.Requests {
+	if httpClient == nil {
+		httpClient = &http.Client{Timeout: 30 * time.Second}
+	}
+
+	return &client{
+		baseURL:    baseUrl,
+		httpClient: httpClient,
+	}
+}
+
+func (c *client) SetDefaultHeaders(headers map[string]string) {
+	c.defaultHeaders = headers
+}
+
+func (c *client) GetConfig() metahttp.RequestOptions {
+	return metahttp.RequestOptions{
+		URL:     c.baseURL,
+		Timeout: c.httpClient.Timeout,
+	}
+}
+
+func (c *client) Get(ctx context.Context, path string, headers map[string]string, v interface{}) (*models.ResponseData, error) {
+	return c.do(ctx, http.MethodGet, path, headers, nil, v)
+}
+
+func (c *client) Post(ctx context.Context, path string, headers map[string]string, v interface{}, res interface{}) (*models.ResponseData, error) {
+	return c.do(ctx, http.MethodPost, path, headers, v, res)
+}
+
+func (c *client) Put(ctx context.Context, path string, headers map[string]string, v interface{}, res interface{}) (*models.ResponseData, error) {
+	return c.do(ctx, http.MethodPut, path, headers, v, res)
+}
+
+func (c *client) do(ctx context.Context, method, path string, headers map[string]string, body interface{}, res interface{}) (*models.ResponseData, error) {
+	ul := joinURL(c.baseURL, path)
+	u, err := url.ParseRequestURI(ul)
+	if err != nil || u.Host == "" || u.Scheme == "" {
+		return nil, fmt.Errorf("%w url: %s, err: %v", models.ErrBadURL, ul, err)
+	}
+
+	var reqBody io.Reader
+	if body != nil {
+		b, err := json.Marshal(body)
+		if err != nil {
+			return nil, err
+		}
+		reqBody = bytes.NewReader(b)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, method, ul, reqBody)
+	if err != nil {
+		return nil, err
+	}
+
+	for k, v := range utils.FetchHeadersFromContext(ctx) {
+		req.Header.Set(k, v)
+	}
+
+	req.Header.Set("Content-Type", "application/json; charset=utf-8")
+	req.Header.Set("Accept", "application/json; charset=utf-8")
+
+	for k, v := range c.defaultHeaders {
+		req.Header.Set(k, v)
+	}
+
+	for k, v := range headers {
+		req.Header.Set(k, v)
+	}
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	out := &models.ResponseData{
+		Status:     resp.Status,
+		StatusCode: resp.StatusCode,
+		Header:     resp.Header,
+	}
+
+	data, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return out, err
+	}
+
+	if resp.StatusCode < http.StatusOK || resp.StatusCode >= http.StatusBadRequest {
+		return nil, &HTTPError{
+			StatusCode: resp.StatusCode,
+			Status:     resp.Status,
+			Body:       data,
+		}
+	}
+
+	if res == nil || len(data) == 0 {
+		return out, nil
+	}
+
+	if err := json.Unmarshal(data, res); err != nil {
+		return out, fmt.Errorf("unable to decode response, err: %w", err)
+	}
+	return out, nil
+}
+
+func joinURL(base, path string) string {
+	switch {
+	case base == "":
+		return path
+	case path == "":
+		return strings.TrimSuffix(base, "/")
+	case strings.HasPrefix(path, "/"):
+		return strings.TrimSuffix(base, "/") + path
+	}
+	return strings.TrimSuffix(base, "/") + "/" + path
+}
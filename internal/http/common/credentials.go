@@ -0,0 +1,173 @@
+package common
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"strings"
+	"sync"
+	"time"
+)
+
+// CredentialProvider supplies the API key used for a single provider call.
+type CredentialProvider interface {
+	Credential(ctx context.Context) (string, error)
+}
+
+type staticCredentials string
+
+// StaticCredentials always returns key.
+func StaticCredentials(key string) CredentialProvider {
+	return staticCredentials(key)
+}
+
+func (s staticCredentials) Credential(ctx context.Context) (string, error) {
+	return string(s), nil
+}
+
+type envCredentials string
+
+// EnvCredentials reads the key from the environment variable name on every
+// call, this is the default of every provider.
+func EnvCredentials(name string) CredentialProvider {
+	return envCredentials(name)
+}
+
+func (e envCredentials) Credential(ctx context.Context) (string, error) {
+	return os.Getenv(string(e)), nil
+}
+
+type fileCredentials struct {
+	path     string
+	interval time.Duration
+
+	mu      sync.Mutex
+	key     string
+	modTime time.Time
+	checked time.Time
+}
+
+// FileCredentials reads the key from path, e.g. a mounted secret, and
+// reloads it when the file changes. The file is checked at most once per
+// interval.
+func FileCredentials(path string, interval time.Duration) CredentialProvider {
+	return &fileCredentials{
+		path:     path,
+		interval: interval,
+	}
+}
+
+func (f *fileCredentials) Credential(ctx context.Context) (string, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	now := time.Now()
+	if !f.checked.IsZero() && now.Sub(f.checked) < f.interval {
+		return f.key, nil
+	}
+
+	info, err := os.Stat(f.path)
+	if err != nil {
+		return "", fmt.Errorf("unable to stat credentials file, err: %w", err)
+	}
+	f.checked = now
+
+	if !f.modTime.IsZero() && info.ModTime().Equal(f.modTime) {
+		return f.key, nil
+	}
+
+	data, err := os.ReadFile(f.path)
+	if err != nil {
+		return "", fmt.Errorf("unable to read credentials file, err: %w", err)
+	}
+
+	f.key = strings.TrimSpace(string(data))
+	f.modTime = info.ModTime()
+	return f.key, nil
+}
+
+// KeyBudget is a key of a rotating pool allowed Limit calls every Per.
+type KeyBudget struct {
+	Key   string
+	Limit int
+	Per   time.Duration
+}
+
+type pooledKey struct {
+	KeyBudget
+	used        int
+	windowStart time.Time
+}
+
+type rotatingCredentials struct {
+	mu   sync.Mutex
+	keys []*pooledKey
+	next int
+	now  func() time.Time
+}
+
+// RotatingCredentials spreads calls over keys round robin, skipping keys
+// whose budget is spent for the current window. When every key is spent it
+// waits for the first window to reset or for ctx to be done.
+func RotatingCredentials(keys []KeyBudget) CredentialProvider {
+	r := &rotatingCredentials{
+		now: time.Now,
+	}
+	for _, k := range keys {
+		r.keys = append(r.keys, &pooledKey{KeyBudget: k})
+	}
+	return r
+}
+
+func (r *rotatingCredentials) Credential(ctx context.Context) (string, error) {
+	if len(r.keys) == 0 {
+		return "", fmt.Errorf("empty credentials pool, err: %w", ErrAuth)
+	}
+
+	for {
+		key, wait := r.take()
+		if wait == 0 {
+			return key, nil
+		}
+
+		timer := time.NewTimer(wait)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return "", fmt.Errorf("credentials pool exhausted, err: %w", ErrRateLimited)
+		case <-timer.C:
+		}
+	}
+}
+
+// take returns the next key with budget left or how long to wait for one.
+func (r *rotatingCredentials) take() (string, time.Duration) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	now := r.now()
+	var wait time.Duration
+	for i := range r.keys {
+		k := r.keys[(r.next+i)%len(r.keys)]
+		if k.Limit <= 0 {
+			r.next = (r.next + i + 1) % len(r.keys)
+			return k.Key, 0
+		}
+
+		if now.Sub(k.windowStart) >= k.Per {
+			k.windowStart = now
+			k.used = 0
+		}
+
+		if k.used < k.Limit {
+			k.used++
+			r.next = (r.next + i + 1) % len(r.keys)
+			return k.Key, 0
+		}
+
+		if reset := k.Per - now.Sub(k.windowStart); wait == 0 || reset < wait {
+			wait = reset
+		}
+	}
+	return "", wait
+}
@@ -0,0 +1,62 @@
+package common
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestFileCredentialsReload(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "key")
+	if err := os.WriteFile(path, []byte("first\n"), 0o600); err != nil {
+		t.Fatalf("write err: %v", err)
+	}
+
+	creds := FileCredentials(path, 0)
+	key, err := creds.Credential(context.Background())
+	if err != nil || key != "first" {
+		t.Fatalf("invalid key, key: %s, err: %v", key, err)
+	}
+
+	if err := os.WriteFile(path, []byte("second"), 0o600); err != nil {
+		t.Fatalf("write err: %v", err)
+	}
+	later := time.Now().Add(time.Second)
+	if err := os.Chtimes(path, later, later); err != nil {
+		t.Fatalf("chtimes err: %v", err)
+	}
+
+	key, err = creds.Credential(context.Background())
+	if err != nil || key != "second" {
+		t.Fatalf("key not reloaded, key: %s, err: %v", key, err)
+	}
+}
+
+func TestRotatingCredentialsBudget(t *testing.T) {
+	creds := RotatingCredentials([]KeyBudget{
+		{Key: "a", Limit: 1, Per: time.Hour},
+		{Key: "b", Limit: 2, Per: time.Hour},
+	})
+
+	var got []string
+	for i := 0; i < 3; i++ {
+		key, err := creds.Credential(context.Background())
+		if err != nil {
+			t.Fatalf("credential err: %v", err)
+		}
+		got = append(got, key)
+	}
+
+	if got[0] != "a" || got[1] != "b" || got[2] != "b" {
+		t.Fatalf("invalid rotation, keys: %v", got)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	if _, err := creds.Credential(ctx); !errors.Is(err, ErrRateLimited) {
+		t.Fatalf("expected rate limited once budget is spent, err: %v", err)
+	}
+}
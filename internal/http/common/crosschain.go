@@ -0,0 +1,136 @@
+package common
+
+import (
+	"context"
+	"fmt"
+	"math/big"
+	"time"
+)
+
+// CrossChainQuoteReq swaps Src on SrcChainId into Dst on DstChainId, the
+// bridge and any swap on either side are picked by the provider.
+type CrossChainQuoteReq struct {
+	SrcChainId uint64
+	DstChainId uint64
+	Src        string
+	Dst        string
+	Amount     *big.Int
+	From       string
+	// Recipient receives Dst on DstChainId, From when empty.
+	Recipient string
+	// SlippageBps is the accepted slippage in basis points over the whole
+	// route, DefaultSlippageBps is used when nil.
+	SlippageBps *uint32
+	// AllowedBridges and DeniedBridges restrict the bridges by provider key,
+	// e.g. stargate or across.
+	AllowedBridges []string
+	DeniedBridges  []string
+}
+
+// Slippage returns the slippage over the whole route in basis points,
+// DefaultSlippageBps when SlippageBps is nil.
+func (r CrossChainQuoteReq) Slippage() (uint32, error) {
+	return slippage(r.SlippageBps)
+}
+
+// CrossChainStep is a single swap or bridge transfer of a route.
+type CrossChainStep struct {
+	// Type is swap for a swap on a single chain and cross for a bridge
+	// transfer.
+	Type       string
+	Tool       string
+	SrcChainId uint64
+	DstChainId uint64
+	Src        string
+	Dst        string
+	FromAmount *big.Int
+	ToAmount   *big.Int
+}
+
+// CrossChainFee is a fee charged along the route, Included fees are already
+// deducted from the output, the others are paid on top, e.g. in the
+// transaction value.
+type CrossChainFee struct {
+	Name     string
+	ChainId  uint64
+	Token    string
+	Amount   *big.Int
+	Included bool
+}
+
+type CrossChainQuoteRes struct {
+	Id          string
+	SrcChainId  uint64
+	DstChainId  uint64
+	Src         string
+	Dst         string
+	FromAmount  *big.Int
+	ToAmount    *big.Int
+	MinToAmount *big.Int
+	// Bridge is the bridge used by the route, e.g. stargate.
+	Bridge   string
+	Steps    []CrossChainStep
+	Duration time.Duration
+	Fees     []CrossChainFee
+	// Tx is the transaction to send on SrcChainId, its AllowanceTarget must
+	// be approved for Src first.
+	Tx SwapTx
+}
+
+// TransferStatus is the state of a bridge transfer.
+type TransferStatus string
+
+const (
+	TransferPending TransferStatus = "pending"
+	TransferDone    TransferStatus = "done"
+	// TransferRefunded transfers failed on the destination chain and were
+	// refunded on the source chain.
+	TransferRefunded TransferStatus = "refunded"
+	TransferFailed   TransferStatus = "failed"
+)
+
+// Final reports whether the transfer can no longer change state.
+func (s TransferStatus) Final() bool {
+	return s == TransferDone || s == TransferRefunded || s == TransferFailed
+}
+
+type CrossChainStatusReq struct {
+	SrcChainId uint64
+	DstChainId uint64
+	// TxHash is the hash of the source chain transaction.
+	TxHash string
+	// Bridge speeds up the lookup, it is optional.
+	Bridge string
+}
+
+type CrossChainStatus struct {
+	Status TransferStatus
+	// SubStatus is the provider specific detail, e.g. PARTIAL when another
+	// token than Dst was received.
+	SubStatus      string
+	Bridge         string
+	SrcTxHash      string
+	DstTxHash      string
+	ReceivedToken  string
+	ReceivedAmount *big.Int
+}
+
+// CrossChainAggregator is implemented by bridge aggregators.
+type CrossChainAggregator interface {
+	FetchCrossChainQuote(ctx context.Context, req CrossChainQuoteReq) (CrossChainQuoteRes, error)
+	FetchTransferStatus(ctx context.Context, req CrossChainStatusReq) (CrossChainStatus, error)
+}
+
+// WaitForTransfer polls the transfer every interval until its status is
+// final or ctx is done, in which case the last fetched status is returned
+// with the context error.
+func WaitForTransfer(ctx context.Context, aggregator CrossChainAggregator, req CrossChainStatusReq, interval time.Duration) (CrossChainStatus, error) {
+	fetch := func(ctx context.Context) (CrossChainStatus, error) {
+		return aggregator.FetchTransferStatus(ctx, req)
+	}
+	final := func(s CrossChainStatus) bool { return s.Status.Final() }
+	pending := func(s CrossChainStatus, err error) error {
+		return fmt.Errorf("transfer %s not final, status: %s, err: %w", req.TxHash, s.Status, err)
+	}
+	return Poll(ctx, interval, fetch, final, pending)
+}
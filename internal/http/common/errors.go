@@ -0,0 +1,140 @@
+package common
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"strings"
+
+	"github.com/onmetahq/meta-http/pkg/models"
+)
+
+var (
+	ErrInsufficientLiquidity = errors.New("insufficient liquidity")
+	ErrRateLimited           = errors.New("rate limited")
+	ErrUnsupportedToken      = errors.New("unsupported token")
+	ErrUnsupportedChain      = errors.New("unsupported chain")
+	ErrInsufficientAllowance = errors.New("insufficient allowance")
+	ErrAuth                  = errors.New("authentication failed")
+	ErrUnsupportedParameter  = errors.New("unsupported parameter")
+)
+
+// ProviderError is returned by the providers for every failed API call. It
+// matches its Kind, one of the sentinel errors above when classified, and
+// the underlying transport error with errors.Is and errors.As.
+type ProviderError struct {
+	Provider   string
+	StatusCode int
+	// Code and Message are taken from the provider's error payload.
+	Code    string
+	Message string
+	Kind    error
+	Err     error
+}
+
+func (e *ProviderError) Error() string {
+	msg := e.Message
+	if msg == "" && e.Err != nil {
+		msg = e.Err.Error()
+	}
+
+	if e.StatusCode != 0 {
+		return fmt.Sprintf("%s: status %d: %s", e.Provider, e.StatusCode, msg)
+	}
+	return fmt.Sprintf("%s: %s", e.Provider, msg)
+}
+
+func (e *ProviderError) Unwrap() []error {
+	var errs []error
+	if e.Kind != nil {
+		errs = append(errs, e.Kind)
+	}
+	if e.Err != nil {
+		errs = append(errs, e.Err)
+	}
+	return errs
+}
+
+// NewProviderError classifies a failed call from its status code and the
+// code and message of the provider's error payload.
+func NewProviderError(provider string, statusCode int, code, message string, err error) *ProviderError {
+	return &ProviderError{
+		Provider:   provider,
+		StatusCode: statusCode,
+		Code:       code,
+		Message:    message,
+		Kind:       classify(statusCode, code+" "+message),
+		Err:        err,
+	}
+}
+
+// StatusAndBody extracts the status code and response body from errors
+// returned by a metahttp.Requests, the body is only available for clients
+// created with NewClient.
+func StatusAndBody(err error) (int, []byte, bool) {
+	var httpErr *HTTPError
+	if errors.As(err, &httpErr) {
+		return httpErr.StatusCode, httpErr.Body, true
+	}
+
+	var metaErr *models.HttpClientErrorResponse
+	if errors.As(err, &metaErr) {
+		return metaErr.StatusCode, []byte(metaErr.Err.Message), true
+	}
+	return 0, nil, false
+}
+
+func classify(statusCode int, payload string) error {
+	switch statusCode {
+	case http.StatusUnauthorized, http.StatusForbidden:
+		return ErrAuth
+	case http.StatusTooManyRequests:
+		return ErrRateLimited
+	}
+
+	msg := strings.ReplaceAll(strings.ToLower(payload), "_", " ")
+	switch {
+	case strings.Contains(msg, "rate limit"), strings.Contains(msg, "too many requests"):
+		return ErrRateLimited
+	case strings.Contains(msg, "liquidity"), strings.Contains(msg, "no route"), strings.Contains(msg, "route not found"),
+		strings.Contains(msg, "no viable path"), strings.Contains(msg, "no available quotes"):
+		return ErrInsufficientLiquidity
+	case strings.Contains(msg, "allowance"):
+		return ErrInsufficientAllowance
+	case strings.Contains(msg, "chain") && (strings.Contains(msg, "unsupported") || strings.Contains(msg, "not supported")):
+		return ErrUnsupportedChain
+	case strings.Contains(msg, "token") && (strings.Contains(msg, "unsupported") || strings.Contains(msg, "not supported") ||
+		strings.Contains(msg, "not found") || strings.Contains(msg, "not valid") || strings.Contains(msg, "invalid")):
+		return ErrUnsupportedToken
+	case strings.Contains(msg, "api key"), strings.Contains(msg, "unauthorized"):
+		return ErrAuth
+	}
+	return nil
+}
+
+// IsRetryable reports whether the same request may succeed when retried,
+// e.g. on rate limits, timeouts, network and 5xx errors. Errors caused by
+// the request itself, such as an unsupported token, are not retryable.
+func IsRetryable(err error) bool {
+	if err == nil {
+		return false
+	}
+
+	switch {
+	case errors.Is(err, ErrRateLimited), errors.Is(err, context.DeadlineExceeded):
+		return true
+	case errors.Is(err, ErrAuth), errors.Is(err, ErrUnsupportedChain), errors.Is(err, ErrUnsupportedToken),
+		errors.Is(err, ErrInsufficientAllowance), errors.Is(err, ErrInsufficientLiquidity), errors.Is(err, context.Canceled):
+		return false
+	}
+
+	var providerErr *ProviderError
+	if errors.As(err, &providerErr) && providerErr.StatusCode != 0 {
+		return providerErr.StatusCode >= http.StatusInternalServerError || providerErr.StatusCode == http.StatusRequestTimeout
+	}
+
+	var netErr net.Error
+	return errors.As(err, &netErr)
+}
@@ -0,0 +1,60 @@
+package common
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestClientKeepsErrorBody(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusBadRequest)
+		_, _ = w.Write([]byte(`{"error":"Bad Request","description":"insufficient liquidity"}`))
+	}))
+	defer server.Close()
+
+	var res map[string]any
+	_, err := NewClient(server.URL, nil).Get(context.Background(), "/137/quote", nil, &res)
+
+	status, body, ok := StatusAndBody(err)
+	if !ok || status != http.StatusBadRequest {
+		t.Fatalf("invalid status, status: %d, err: %v", status, err)
+	}
+
+	if string(body) != `{"error":"Bad Request","description":"insufficient liquidity"}` {
+		t.Fatalf("invalid body, body: %s", body)
+	}
+}
+
+func TestProviderErrorClassification(t *testing.T) {
+	tests := []struct {
+		status    int
+		message   string
+		kind      error
+		retryable bool
+	}{
+		{http.StatusBadRequest, "insufficient liquidity", ErrInsufficientLiquidity, false},
+		{http.StatusBadRequest, "sellAmount: INSUFFICIENT_ASSET_LIQUIDITY", ErrInsufficientLiquidity, false},
+		{http.StatusBadRequest, "No viable path found", ErrInsufficientLiquidity, false},
+		{http.StatusNotFound, "No available quotes for the requested transfer", ErrInsufficientLiquidity, false},
+		{http.StatusTooManyRequests, "", ErrRateLimited, true},
+		{http.StatusUnauthorized, "", ErrAuth, false},
+		{http.StatusBadRequest, "buyToken: TOKEN_NOT_SUPPORTED", ErrUnsupportedToken, false},
+		{http.StatusBadRequest, "Not enough allowance. Amount: 1. Allowance: 0", ErrInsufficientAllowance, false},
+		{http.StatusBadGateway, "upstream error", nil, true},
+	}
+
+	for _, tt := range tests {
+		err := fmt.Errorf("unable to fetch quote, err: %w", NewProviderError("test", tt.status, "", tt.message, nil))
+		if tt.kind != nil && !errors.Is(err, tt.kind) {
+			t.Fatalf("invalid kind, message: %s, err: %v", tt.message, err)
+		}
+
+		if IsRetryable(err) != tt.retryable {
+			t.Fatalf("invalid retryable, message: %s, want: %v", tt.message, tt.retryable)
+		}
+	}
+}
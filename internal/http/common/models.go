@@ -1,16 +1,75 @@
 package common
 
-import "math/big"
+import (
+	"math/big"
+	"strings"
+)
+
+// NativeToken is the placeholder address aggregators use for the chain's
+// native token.
+const NativeToken = "0xeeeeeeeeeeeeeeeeeeeeeeeeeeeeeeeeeeeeeeee"
+
+func IsNativeToken(token string) bool {
+	return strings.EqualFold(token, NativeToken)
+}
+
+// WrappedNativeTokens maps chain ids to the ERC-20 wrapper of the native
+// token, e.g. WETH or WMATIC, for protocols that only settle ERC-20s.
+var WrappedNativeTokens = map[uint64]string{
+	1:     "0xC02aaA39b223FE8D0A0e5C4F27eAD9083C756Cc2",
+	10:    "0x4200000000000000000000000000000000000006",
+	56:    "0xbb4CdB9CBd36B01bD1cBaEBF2De08d9173bc095c",
+	100:   "0xe91D153E0b41518A2Ce8Dd3D7944Fa863463a97d",
+	137:   "0x0d500B1d8E8eF31E21C99d1Db9A6444d3ADf1270",
+	8453:  "0x4200000000000000000000000000000000000006",
+	42161: "0x82aF49447D8a07e3bd95BD0d56f35241523fBab1",
+	43114: "0xB31f66AA3C1e785363F0875A1B74E27b85FD66c7",
+}
 
 type QuoteReq struct {
-	ChainId            uint64
-	Src                string
-	Dst                string
-	Amount             *big.Int
-	From               string
-	SlippagePercentage uint8
-	SkipValidation     bool
-	Referrer           string
+	ChainId uint64
+	Src     string
+	Dst     string
+	Amount  *big.Int
+	From    string
+	// SrcDecimals and DstDecimals are only needed by providers that take
+	// token decimals, they are looked up from the provider's token list when
+	// zero.
+	SrcDecimals int
+	DstDecimals int
+	// SlippageBps is the accepted slippage in basis points, Bps(50) is 0.5%.
+	// DefaultSlippageBps is used when nil, Bps(0) allows no slippage.
+	SlippageBps    *uint32
+	SkipValidation bool
+	Referrer       string
+	// Receiver gets the output when it differs from From.
+	Receiver string
+	// FeeBps is the integrator fee taken from the output and paid to
+	// Referrer.
+	FeeBps uint32
+	// GasPrice overrides the gas price the provider would use.
+	GasPrice *big.Int
+	// IncludedSources and ExcludedSources filter the liquidity sources,
+	// using the provider's own source names.
+	IncludedSources []string
+	ExcludedSources []string
+	// Options holds options only understood by a single provider, e.g.
+	// oneinch.OneInchOptions, providers ignore the options of the others.
+	Options []ProviderOptions
+}
+
+// RoutePart is the share of a hop swapped through a single protocol.
+type RoutePart struct {
+	Protocol  string
+	Part      float64
+	FromToken string
+	ToToken   string
+}
+
+// Route is one path of a split swap, every hop lists the protocols sharing
+// it.
+type Route struct {
+	Hops [][]RoutePart
 }
 
 type QuoteRes struct {
@@ -21,4 +80,69 @@ type QuoteRes struct {
 	ToAmount   *big.Int
 	Gas        *big.Int `json:"gas"`
 	GasPrice   *big.Int `json:"gasPrice"`
+	// MinToAmount is the minimum amount of Dst received after slippage.
+	MinToAmount *big.Int `json:"minToAmount"`
+	Routes      []Route  `json:"routes,omitempty"`
+	// Surplus is the output received above the requested amount by providers
+	// that emulate exact out, nil otherwise.
+	Surplus *big.Int `json:"surplus,omitempty"`
+}
+
+type Token struct {
+	ChainId  uint64
+	Address  string
+	Symbol   string
+	Name     string
+	Decimals int
+	LogoURI  string
+}
+
+// SwapTx is the provider independent transaction returned by the swap
+// calldata methods, ready to be signed and sent by From.
+type SwapTx struct {
+	ChainId         uint64
+	Src             string
+	Dst             string
+	FromAmount      *big.Int
+	ToAmount        *big.Int
+	MinToAmount     *big.Int
+	From            string
+	To              string
+	Data            string
+	Value           *big.Int
+	Gas             *big.Int
+	GasPrice        *big.Int
+	AllowanceTarget string
+	Routes          []Route
+	// Surplus is the output received above the requested amount by providers
+	// that emulate exact out, nil otherwise.
+	Surplus *big.Int
+}
+
+// ApproveTx is an ERC-20 approve transaction letting Spender move Amount of
+// Token, To is the token contract.
+type ApproveTx struct {
+	ChainId  uint64
+	Token    string
+	Spender  string
+	Amount   *big.Int
+	To       string
+	Data     string
+	Value    *big.Int
+	GasPrice *big.Int
+}
+
+// MaxUint256 returns 2^256 - 1, the amount of an unlimited approval.
+func MaxUint256() *big.Int {
+	max := new(big.Int).Lsh(big.NewInt(1), 256)
+	return max.Sub(max, big.NewInt(1))
+}
+
+// ParseBigInt parses a decimal or 0x prefixed integer returned by an
+// aggregator, empty values are treated as zero.
+func ParseBigInt(value string) (*big.Int, bool) {
+	if value == "" {
+		return big.NewInt(0), true
+	}
+	return new(big.Int).SetString(value, 0)
 }
@@ -0,0 +1,109 @@
+package common
+
+import (
+	"fmt"
+	"math"
+	"math/big"
+	"strings"
+)
+
+// TokenAmount is a raw amount of Token.
+type TokenAmount struct {
+	Token  string
+	Amount *big.Int
+}
+
+// TokenProportion is the share of the total output received in Token,
+// the proportions of a request sum to 1.
+type TokenProportion struct {
+	Token      string
+	Proportion float64
+}
+
+// MultiQuoteReq swaps several input tokens into one or more output tokens in
+// a single transaction, e.g. to sweep dust balances into USDC.
+type MultiQuoteReq struct {
+	ChainId uint64
+	Inputs  []TokenAmount
+	Outputs []TokenProportion
+	From    string
+	// Receiver gets the outputs when it differs from From.
+	Receiver string
+	// SlippageBps is the accepted slippage in basis points, Bps(50) is 0.5%.
+	// DefaultSlippageBps is used when nil, Bps(0) allows no slippage.
+	SlippageBps     *uint32
+	SkipValidation  bool
+	GasPrice        *big.Int
+	IncludedSources []string
+	ExcludedSources []string
+}
+
+// Validate checks that the request has inputs with positive amounts, no
+// token on both sides and output proportions summing to 1.
+func (r MultiQuoteReq) Validate() error {
+	if len(r.Inputs) == 0 || len(r.Outputs) == 0 {
+		return fmt.Errorf("multi quote needs at least one input and one output")
+	}
+
+	inputs := map[string]bool{}
+	for _, in := range r.Inputs {
+		if in.Amount == nil || in.Amount.Sign() <= 0 {
+			return fmt.Errorf("invalid amount of input %s: %v", in.Token, in.Amount)
+		}
+
+		token := strings.ToLower(in.Token)
+		if inputs[token] {
+			return fmt.Errorf("duplicate input %s", in.Token)
+		}
+		inputs[token] = true
+	}
+
+	total := 0.0
+	outputs := map[string]bool{}
+	for _, out := range r.Outputs {
+		token := strings.ToLower(out.Token)
+		if inputs[token] {
+			return fmt.Errorf("token %s is both an input and an output", out.Token)
+		}
+		if outputs[token] {
+			return fmt.Errorf("duplicate output %s", out.Token)
+		}
+		if out.Proportion <= 0 {
+			return fmt.Errorf("invalid proportion of output %s: %v", out.Token, out.Proportion)
+		}
+		outputs[token] = true
+		total += out.Proportion
+	}
+
+	if math.Abs(total-1) > 1e-9 {
+		return fmt.Errorf("output proportions sum to %v, expected 1", total)
+	}
+	return nil
+}
+
+type MultiQuoteRes struct {
+	ChainId uint64
+	Inputs  []TokenAmount
+	Outputs []TokenAmount
+	// MinOutputs are the Outputs reduced by the slippage.
+	MinOutputs []TokenAmount
+	Gas        *big.Int
+	GasPrice   *big.Int
+	// PriceImpact is the price impact in percent as reported by the provider.
+	PriceImpact float64
+}
+
+type MultiSwapTx struct {
+	ChainId    uint64
+	Inputs     []TokenAmount
+	Outputs    []TokenAmount
+	MinOutputs []TokenAmount
+	From       string
+	To         string
+	Data       string
+	Value      *big.Int
+	Gas        *big.Int
+	GasPrice   *big.Int
+	// AllowanceTarget must be approved for every non native input.
+	AllowanceTarget string
+}
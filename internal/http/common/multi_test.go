@@ -0,0 +1,33 @@
+package common
+
+import (
+	"math/big"
+	"testing"
+)
+
+func TestMultiQuoteReqValidate(t *testing.T) {
+	usdc := "0x2791bca1f2de4661ed88a30c99a7a9449aa84174"
+	weth := "0x7ceb23fd6bc0add59e62ac25578270cff1b9f619"
+	dai := "0x8f3cf7ad23cd3cadbd9735aff958023239c6a063"
+
+	valid := MultiQuoteReq{
+		Inputs:  []TokenAmount{{Token: weth, Amount: big.NewInt(1)}, {Token: dai, Amount: big.NewInt(2)}},
+		Outputs: []TokenProportion{{Token: usdc, Proportion: 0.7}, {Token: NativeToken, Proportion: 0.3}},
+	}
+	if err := valid.Validate(); err != nil {
+		t.Fatalf("expected valid request, err: %v", err)
+	}
+
+	tests := map[string]MultiQuoteReq{
+		"no inputs":      {Outputs: valid.Outputs},
+		"zero amount":    {Inputs: []TokenAmount{{Token: weth, Amount: big.NewInt(0)}}, Outputs: valid.Outputs},
+		"duplicate":      {Inputs: []TokenAmount{valid.Inputs[0], valid.Inputs[0]}, Outputs: valid.Outputs},
+		"input output":   {Inputs: valid.Inputs, Outputs: []TokenProportion{{Token: dai, Proportion: 1}}},
+		"proportion sum": {Inputs: valid.Inputs, Outputs: []TokenProportion{{Token: usdc, Proportion: 0.5}}},
+	}
+	for name, req := range tests {
+		if err := req.Validate(); err == nil {
+			t.Fatalf("%s: expected invalid request", name)
+		}
+	}
+}
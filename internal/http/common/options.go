@@ -0,0 +1,44 @@
+package common
+
+import (
+	"fmt"
+	"reflect"
+)
+
+// ProviderOptions are request options only understood by one provider. Each
+// provider defines its own options type in its package, callers add it to
+// QuoteReq.Options by value and the provider reads it with OptionsOf.
+type ProviderOptions interface {
+	// Provider names the provider reading the options, e.g. "1inch".
+	Provider() string
+}
+
+// OptionsOf returns the first options of type T set on req, or the zero T
+// when there are none. Options are matched by their Provider, pointers and
+// options of another type naming the same provider as T are rejected
+// instead of being ignored.
+func OptionsOf[T ProviderOptions](req QuoteReq) (T, error) {
+	var zero T
+	provider := zero.Provider()
+
+	for _, opts := range req.Options {
+		if opts == nil {
+			continue
+		}
+
+		if reflect.TypeOf(opts).Kind() == reflect.Pointer {
+			return zero, fmt.Errorf("invalid options type %T, options are passed by value, err: %w", opts, ErrUnsupportedParameter)
+		}
+
+		if opts.Provider() != provider {
+			continue
+		}
+
+		o, ok := opts.(T)
+		if !ok {
+			return zero, fmt.Errorf("invalid %s options type %T, expected %v, err: %w", provider, opts, reflect.TypeOf(zero), ErrUnsupportedParameter)
+		}
+		return o, nil
+	}
+	return zero, nil
+}
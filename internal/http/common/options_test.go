@@ -0,0 +1,44 @@
+package common
+
+import (
+	"errors"
+	"testing"
+)
+
+type testOptions struct{ level int }
+
+func (testOptions) Provider() string { return "test" }
+
+type otherOptions struct{}
+
+func (otherOptions) Provider() string { return "other" }
+
+type renamedOptions struct{}
+
+func (renamedOptions) Provider() string { return "test" }
+
+func TestOptionsOf(t *testing.T) {
+	req := QuoteReq{Options: []ProviderOptions{otherOptions{}, testOptions{level: 2}, testOptions{level: 3}}}
+
+	opts, err := OptionsOf[testOptions](req)
+	if err != nil || opts.level != 2 {
+		t.Fatalf("expected the first test options, opts: %+v, err: %v", opts, err)
+	}
+
+	opts, err = OptionsOf[testOptions](QuoteReq{Options: []ProviderOptions{otherOptions{}}})
+	if err != nil || opts.level != 0 {
+		t.Fatalf("expected no test options, opts: %+v, err: %v", opts, err)
+	}
+
+	invalid := map[string]ProviderOptions{
+		"pointer":       &testOptions{level: 2},
+		"other pointer": &otherOptions{},
+		"same provider": renamedOptions{},
+		"nil pointer":   (*testOptions)(nil),
+	}
+	for name, o := range invalid {
+		if _, err := OptionsOf[testOptions](QuoteReq{Options: []ProviderOptions{o}}); !errors.Is(err, ErrUnsupportedParameter) {
+			t.Errorf("%s: expected unsupported parameter, err: %v", name, err)
+		}
+	}
+}
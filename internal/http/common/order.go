@@ -0,0 +1,75 @@
+package common
+
+import (
+	"context"
+	"fmt"
+	"math/big"
+	"time"
+)
+
+// OrderStatus is the state of an order submitted to an orderbook or relayer.
+type OrderStatus string
+
+const (
+	OrderPending         OrderStatus = "pending"
+	OrderPartiallyFilled OrderStatus = "partially_filled"
+	OrderFilled          OrderStatus = "filled"
+	OrderExpired         OrderStatus = "expired"
+	OrderCancelled       OrderStatus = "cancelled"
+	// OrderFailed is an order dropped because it can no longer be filled,
+	// e.g. its signature, balance or allowance is invalid.
+	OrderFailed OrderStatus = "failed"
+)
+
+// Final reports whether the order can no longer change state. Partially
+// filled orders may still be filled further.
+func (s OrderStatus) Final() bool {
+	return s == OrderFilled || s == OrderExpired || s == OrderCancelled || s == OrderFailed
+}
+
+// Order is a signed swap settled off-chain by a solver or resolver, the
+// owner pays no gas for the swap itself.
+type Order struct {
+	ChainId uint64
+	Id      string
+	Owner   string
+	Src     string
+	Dst     string
+	// SellAmount and BuyAmount are the limits signed by the owner, the
+	// order never sells more or buys less.
+	SellAmount *big.Int
+	BuyAmount  *big.Int
+	// ExecutedSellAmount and ExecutedBuyAmount are the amounts filled so
+	// far, nil until the order is fetched.
+	ExecutedSellAmount *big.Int
+	ExecutedBuyAmount  *big.Int
+	ValidTo            time.Time
+	Status             OrderStatus
+	// TxHash is the settlement transaction once known.
+	TxHash string
+}
+
+// OrderAggregator is implemented by intent based providers: instead of
+// returning calldata they quote, sign and submit an order, then report its
+// status until a solver fills it or it expires.
+type OrderAggregator interface {
+	PlaceOrder(ctx context.Context, req QuoteReq, signer Signer) (Order, error)
+	// FetchOrder always sets the id, chain and status of the order. Providers
+	// whose status endpoint does not report the other fields, such as 0x
+	// gasless, leave them empty.
+	FetchOrder(ctx context.Context, chainId uint64, id string) (Order, error)
+}
+
+// WaitForOrder polls the order every interval until its status is final or
+// ctx is done, in which case the last fetched order is returned with the
+// context error.
+func WaitForOrder(ctx context.Context, aggregator OrderAggregator, chainId uint64, id string, interval time.Duration) (Order, error) {
+	fetch := func(ctx context.Context) (Order, error) {
+		return aggregator.FetchOrder(ctx, chainId, id)
+	}
+	final := func(o Order) bool { return o.Status.Final() }
+	pending := func(o Order, err error) error {
+		return fmt.Errorf("order %s not final, status: %s, err: %w", id, o.Status, err)
+	}
+	return Poll(ctx, interval, fetch, final, pending)
+}
@@ -0,0 +1,46 @@
+package common
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+type pendingOrders struct {
+	calls int
+	err   error
+}
+
+func (p *pendingOrders) PlaceOrder(ctx context.Context, req QuoteReq, signer Signer) (Order, error) {
+	return Order{}, nil
+}
+
+func (p *pendingOrders) FetchOrder(ctx context.Context, chainId uint64, id string) (Order, error) {
+	p.calls++
+	if p.err != nil {
+		return Order{}, p.err
+	}
+	return Order{Id: id, Status: OrderPending}, nil
+}
+
+func TestWaitForOrder(t *testing.T) {
+	t.Run("deadline", func(t *testing.T) {
+		ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+		defer cancel()
+
+		orders := &pendingOrders{}
+		order, err := WaitForOrder(ctx, orders, 1, "0x01", time.Millisecond)
+		if !errors.Is(err, context.DeadlineExceeded) || order.Status != OrderPending || orders.calls < 2 {
+			t.Fatalf("expected the last pending order, order: %+v, calls: %d, err: %v", order, orders.calls, err)
+		}
+	})
+
+	t.Run("not retryable", func(t *testing.T) {
+		orders := &pendingOrders{err: NewProviderError("cow", 404, "", "order not found", nil)}
+		_, err := WaitForOrder(context.Background(), orders, 1, "0x01", time.Millisecond)
+		if err == nil || orders.calls != 1 {
+			t.Fatalf("expected a single call, calls: %d, err: %v", orders.calls, err)
+		}
+	})
+}
@@ -0,0 +1,35 @@
+package common
+
+import (
+	"context"
+	"time"
+)
+
+// Poll calls fetch every interval until final reports true for its result,
+// fetch fails with an error that is not retryable or ctx is done. The last
+// successfully fetched value is returned with the error, when ctx is done
+// the error is built by pending from that value and ctx.Err().
+func Poll[T any](ctx context.Context, interval time.Duration, fetch func(context.Context) (T, error), final func(T) bool, pending func(T, error) error) (T, error) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	var last T
+	for {
+		v, err := fetch(ctx)
+		switch {
+		case err == nil:
+			last = v
+			if final(v) {
+				return v, nil
+			}
+		case !IsRetryable(err):
+			return last, err
+		}
+
+		select {
+		case <-ctx.Done():
+			return last, pending(last, ctx.Err())
+		case <-ticker.C:
+		}
+	}
+}
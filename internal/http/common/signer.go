@@ -0,0 +1,46 @@
+package common
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"fmt"
+
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/signer/core/apitypes"
+)
+
+// Signer signs the EIP-712 payloads of order based providers, e.g. with a
+// local key, a KMS or a wallet.
+type Signer interface {
+	Address() string
+	// SignTypedData returns the 65 byte r || s || v signature of the
+	// EIP-712 hash of data, v being 27 or 28.
+	SignTypedData(ctx context.Context, data apitypes.TypedData) ([]byte, error)
+}
+
+type privateKeySigner struct {
+	key *ecdsa.PrivateKey
+}
+
+// PrivateKeySigner signs with key in process.
+func PrivateKeySigner(key *ecdsa.PrivateKey) Signer {
+	return privateKeySigner{key: key}
+}
+
+func (s privateKeySigner) Address() string {
+	return crypto.PubkeyToAddress(s.key.PublicKey).Hex()
+}
+
+func (s privateKeySigner) SignTypedData(ctx context.Context, data apitypes.TypedData) ([]byte, error) {
+	hash, _, err := apitypes.TypedDataAndHash(data)
+	if err != nil {
+		return nil, fmt.Errorf("unable to hash typed data, err: %w", err)
+	}
+
+	sig, err := crypto.Sign(hash, s.key)
+	if err != nil {
+		return nil, fmt.Errorf("unable to sign typed data, err: %w", err)
+	}
+	sig[64] += 27
+	return sig, nil
+}
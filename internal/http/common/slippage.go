@@ -0,0 +1,72 @@
+package common
+
+import (
+	"fmt"
+	"math/big"
+	"strconv"
+)
+
+const (
+	// DefaultSlippageBps is used when no slippage is set, for every
+	// provider. It is the 1% 0x always used, 1inch used to send 0% when
+	// SlippagePercentage was unset.
+	DefaultSlippageBps uint32 = 100
+	// MaxSlippageBps is the highest slippage accepted by every provider,
+	// 1inch rejects anything above 50%.
+	MaxSlippageBps uint32 = 5000
+)
+
+// Bps returns a pointer to bps, for the SlippageBps request fields, e.g.
+// Bps(0) for a swap that must not slip.
+func Bps(bps uint32) *uint32 {
+	return &bps
+}
+
+// Slippage returns the slippage of the swap in basis points,
+// DefaultSlippageBps when SlippageBps is nil.
+func (r QuoteReq) Slippage() (uint32, error) {
+	return slippage(r.SlippageBps)
+}
+
+// Slippage returns the slippage shared by every input and output in basis
+// points, DefaultSlippageBps when SlippageBps is nil.
+func (r MultiQuoteReq) Slippage() (uint32, error) {
+	return slippage(r.SlippageBps)
+}
+
+// slippage checks bps against the 0 to MaxSlippageBps bounds, 0 is a valid
+// slippage and only a nil bps falls back to the default.
+func slippage(bps *uint32) (uint32, error) {
+	if bps == nil {
+		return DefaultSlippageBps, nil
+	}
+
+	if *bps > MaxSlippageBps {
+		return 0, fmt.Errorf("invalid slippage, bps: %d, max: %d", *bps, MaxSlippageBps)
+	}
+	return *bps, nil
+}
+
+// BpsFraction formats bps as a fraction, e.g. 50 as "0.005".
+func BpsFraction(bps uint32) string {
+	return strconv.FormatFloat(float64(bps)/10_000, 'f', -1, 64)
+}
+
+// BpsPercent formats bps as a decimal percent, e.g. 25 as "0.25".
+func BpsPercent(bps uint32) string {
+	return strconv.FormatFloat(float64(bps)/100, 'f', -1, 64)
+}
+
+// MinReceived returns amount reduced by bps, rounded down.
+func MinReceived(amount *big.Int, bps uint32) *big.Int {
+	out := new(big.Int).Mul(amount, big.NewInt(int64(10_000-bps)))
+	return out.Div(out, big.NewInt(10_000))
+}
+
+// MaxSent returns amount increased by bps, rounded up, the most an exact
+// out swap may pull.
+func MaxSent(amount *big.Int, bps uint32) *big.Int {
+	out := new(big.Int).Mul(amount, big.NewInt(int64(10_000+bps)))
+	out.Add(out, big.NewInt(9_999))
+	return out.Div(out, big.NewInt(10_000))
+}
@@ -0,0 +1,43 @@
+package common
+
+import (
+	"math/big"
+	"testing"
+)
+
+func TestSlippage(t *testing.T) {
+	bps, err := QuoteReq{}.Slippage()
+	if err != nil || bps != DefaultSlippageBps {
+		t.Fatalf("invalid default slippage, bps: %d, err: %v", bps, err)
+	}
+
+	bps, err = QuoteReq{SlippageBps: Bps(0)}.Slippage()
+	if err != nil || bps != 0 {
+		t.Fatalf("expected zero slippage to be kept, bps: %d, err: %v", bps, err)
+	}
+
+	if _, err := (QuoteReq{SlippageBps: Bps(MaxSlippageBps + 1)}).Slippage(); err == nil {
+		t.Fatalf("expected error above max slippage")
+	}
+
+	bps, err = CrossChainQuoteReq{SlippageBps: Bps(MaxSlippageBps)}.Slippage()
+	if err != nil || bps != MaxSlippageBps {
+		t.Fatalf("expected the max slippage to be accepted, bps: %d, err: %v", bps, err)
+	}
+
+	if got := BpsFraction(50); got != "0.005" {
+		t.Fatalf("invalid 0x slippage, got: %s", got)
+	}
+
+	if got := BpsPercent(50); got != "0.5" {
+		t.Fatalf("invalid 1inch slippage, got: %s", got)
+	}
+
+	if got := MinReceived(big.NewInt(1_000_000), 50); got.Cmp(big.NewInt(995_000)) != 0 {
+		t.Fatalf("invalid min received, got: %s", got)
+	}
+
+	if got := MaxSent(big.NewInt(1_000_001), 33); got.Cmp(big.NewInt(1_003_302)) != 0 {
+		t.Fatalf("invalid max sent, got: %s", got)
+	}
+}
@@ -0,0 +1,59 @@
+package common
+
+import (
+	"fmt"
+	"math/big"
+	"strings"
+)
+
+// FormatUnits formats a raw amount as a decimal string of whole tokens,
+// e.g. 1500000 with 6 decimals as "1.5".
+func FormatUnits(amount *big.Int, decimals int) string {
+	if decimals <= 0 {
+		return amount.String()
+	}
+
+	digits := new(big.Int).Abs(amount).String()
+	if len(digits) <= decimals {
+		digits = strings.Repeat("0", decimals-len(digits)+1) + digits
+	}
+
+	whole, frac := digits[:len(digits)-decimals], strings.TrimRight(digits[len(digits)-decimals:], "0")
+	out := whole
+	if frac != "" {
+		out += "." + frac
+	}
+	if amount.Sign() < 0 {
+		out = "-" + out
+	}
+	return out
+}
+
+// ParseUnits parses a decimal string of whole tokens into a raw amount,
+// e.g. "1.5" with 6 decimals as 1500000. Digits beyond decimals are
+// rejected rather than rounded.
+func ParseUnits(value string, decimals int) (*big.Int, error) {
+	whole, frac, hasFrac := strings.Cut(strings.TrimSpace(value), ".")
+	if !isDigits(whole) || hasFrac && !isDigits(frac) {
+		return nil, fmt.Errorf("invalid amount %s", value)
+	}
+
+	if len(frac) > decimals {
+		return nil, fmt.Errorf("invalid amount %s, more than %d decimals", value, decimals)
+	}
+
+	out, _ := new(big.Int).SetString(whole+frac+strings.Repeat("0", decimals-len(frac)), 10)
+	return out, nil
+}
+
+func isDigits(s string) bool {
+	if s == "" {
+		return false
+	}
+	for _, c := range s {
+		if c < '0' || c > '9' {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,38 @@
+package common
+
+import (
+	"math/big"
+	"testing"
+)
+
+func TestUnits(t *testing.T) {
+	tests := []struct {
+		raw      string
+		decimals int
+		value    string
+	}{
+		{"1500000", 6, "1.5"},
+		{"1", 18, "0.000000000000000001"},
+		{"30000000000", 9, "30"},
+		{"0", 6, "0"},
+		{"42", 0, "42"},
+	}
+
+	for _, tt := range tests {
+		raw, _ := new(big.Int).SetString(tt.raw, 10)
+		if got := FormatUnits(raw, tt.decimals); got != tt.value {
+			t.Fatalf("invalid format, raw: %s, want: %s, got: %s", tt.raw, tt.value, got)
+		}
+
+		parsed, err := ParseUnits(tt.value, tt.decimals)
+		if err != nil || parsed.Cmp(raw) != 0 {
+			t.Fatalf("invalid parse, value: %s, want: %s, got: %v, err: %v", tt.value, tt.raw, parsed, err)
+		}
+	}
+
+	for _, value := range []string{"1.0000001", "abc", "1.-5", ""} {
+		if _, err := ParseUnits(value, 6); err == nil {
+			t.Fatalf("expected %q to be invalid", value)
+		}
+	}
+}
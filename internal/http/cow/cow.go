@@ -0,0 +1,521 @@
+package cow
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"math/big"
+	"strconv"
+	"time"
+
+	ethcommon "github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/common/math"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/signer/core/apitypes"
+	"github.com/onmetahq/go-evm/internal/http/common"
+	metahttp "github.com/onmetahq/meta-http/pkg/meta_http"
+)
+
+// SettlementAddress is the GPv2Settlement contract, the verifying contract
+// of every order on every chain.
+const SettlementAddress = "0x9008D19f58AAbD9eD0D60971565AA8510560ab41"
+
+// VaultRelayerAddress is the spender that must be approved for the sell
+// token.
+const VaultRelayerAddress = "0xC92E8bdf79f0507f65a392b0ab4667716BFE0110"
+
+// DefaultAppData is the app data document sent when none is configured.
+const DefaultAppData = "{}"
+
+// appDataVersion is the app data schema version of the referrer and
+// partnerFee metadata, set when the configured document has none.
+const appDataVersion = "1.1.0"
+
+// DefaultChainUrls maps chain ids to the orderbook API base url of each
+// network.
+var DefaultChainUrls = map[uint64]string{
+	1:        "https://api.cow.fi/mainnet",
+	100:      "https://api.cow.fi/xdai",
+	8453:     "https://api.cow.fi/base",
+	42161:    "https://api.cow.fi/arbitrum_one",
+	11155111: "https://api.cow.fi/sepolia",
+}
+
+type cow struct {
+	client      metahttp.Requests
+	chainUrlMap map[uint64]string
+	appData     string
+}
+
+type Option func(*cow)
+
+// WithAppData sets the app data JSON document attached to every order,
+// DefaultAppData by default.
+func WithAppData(appData string) Option {
+	return func(o *cow) {
+		o.appData = appData
+	}
+}
+
+// NewCow returns a CoW Protocol provider, chainUrlMap maps a chain id to its
+// orderbook API base url, DefaultChainUrls when nil.
+func NewCow(client metahttp.Requests, chainUrlMap map[uint64]string, opts ...Option) *cow {
+	if chainUrlMap == nil {
+		chainUrlMap = DefaultChainUrls
+	}
+
+	o := &cow{
+		client:      client,
+		chainUrlMap: chainUrlMap,
+		appData:     DefaultAppData,
+	}
+
+	for _, opt := range opts {
+		opt(o)
+	}
+	return o
+}
+
+var _ common.OrderAggregator = (*cow)(nil)
+
+// PlaceOrder quotes req as a sell order, signs it with signer and submits
+// it to the orderbook.
+func (o *cow) PlaceOrder(ctx context.Context, req common.QuoteReq, signer common.Signer) (common.Order, error) {
+	return o.placeOrder(ctx, req, KindSell, signer)
+}
+
+// PlaceExactOutOrder quotes req as a buy order of exactly req.Amount of
+// req.Dst, signs it with signer and submits it to the orderbook.
+func (o *cow) PlaceExactOutOrder(ctx context.Context, req common.QuoteReq, signer common.Signer) (common.Order, error) {
+	return o.placeOrder(ctx, req, KindBuy, signer)
+}
+
+func (o *cow) placeOrder(ctx context.Context, req common.QuoteReq, kind OrderKind, signer common.Signer) (common.Order, error) {
+	req.From = signer.Address()
+
+	quote, err := o.FetchQuote(ctx, req, kind)
+	if err != nil {
+		return common.Order{}, err
+	}
+
+	order, err := o.BuildOrder(req, quote)
+	if err != nil {
+		return common.Order{}, err
+	}
+
+	signature, err := signer.SignTypedData(ctx, o.TypedData(req.ChainId, order))
+	if err != nil {
+		return common.Order{}, err
+	}
+
+	uid, err := o.SubmitOrder(ctx, req, order, quote, signature)
+	if err != nil {
+		return common.Order{}, err
+	}
+
+	sellAmount, _ := common.ParseBigInt(order.SellAmount)
+	buyAmount, _ := common.ParseBigInt(order.BuyAmount)
+	return common.Order{
+		ChainId:    req.ChainId,
+		Id:         uid,
+		Owner:      req.From,
+		Src:        req.Src,
+		Dst:        req.Dst,
+		SellAmount: sellAmount,
+		BuyAmount:  buyAmount,
+		ValidTo:    time.Unix(int64(order.ValidTo), 0),
+		Status:     common.OrderPending,
+	}, nil
+}
+
+// FetchQuote returns the orderbook quote for req, kind selects whether
+// req.Amount is sold or bought.
+func (o *cow) FetchQuote(ctx context.Context, req common.QuoteReq, kind OrderKind) (CowQuoteResponse, error) {
+	base, ok := o.chainUrlMap[req.ChainId]
+	if !ok {
+		return CowQuoteResponse{}, fmt.Errorf("unsupported chainId %d, err: %w", req.ChainId, common.ErrUnsupportedChain)
+	}
+
+	// Selling the native token needs an on-chain eth-flow order.
+	if common.IsNativeToken(req.Src) {
+		return CowQuoteResponse{}, fmt.Errorf("native sell orders are not supported by cow, err: %w", common.ErrUnsupportedToken)
+	}
+
+	appData, err := o.appDataOf(req)
+	if err != nil {
+		return CowQuoteResponse{}, err
+	}
+
+	body := CowQuoteReq{
+		SellToken:        req.Src,
+		BuyToken:         req.Dst,
+		Receiver:         req.Receiver,
+		AppData:          appData,
+		AppDataHash:      appDataHash(appData),
+		SellTokenBalance: "erc20",
+		BuyTokenBalance:  "erc20",
+		From:             req.From,
+		PriceQuality:     "optimal",
+		SigningScheme:    "eip712",
+		Kind:             kind,
+	}
+
+	if kind == KindSell {
+		body.SellAmountBefore = req.Amount.String()
+	} else {
+		body.BuyAmountAfterFee = req.Amount.String()
+	}
+
+	var res CowQuoteResponse
+	_, err = o.client.Post(ctx, fmt.Sprintf("%s/api/v1/quote", base), map[string]string{}, body, &res)
+	if err != nil {
+		return CowQuoteResponse{}, fmt.Errorf("unable to fetch cow quote, err: %w", parseError(err))
+	}
+	return res, nil
+}
+
+// BuildOrder turns quote into the order to sign. The fee is folded into
+// the sell amount, the partner fee and slippage of req are applied to the
+// amount that is not fixed by the order kind.
+func (o *cow) BuildOrder(req common.QuoteReq, quote CowQuoteResponse) (CowOrder, error) {
+	slippage, err := req.Slippage()
+	if err != nil {
+		return CowOrder{}, err
+	}
+
+	appData, err := o.appDataOf(req)
+	if err != nil {
+		return CowOrder{}, err
+	}
+
+	sellAmount, ok := common.ParseBigInt(quote.Quote.SellAmount)
+	if !ok {
+		return CowOrder{}, fmt.Errorf("invalid sell amount from cow, amount: %v", quote.Quote.SellAmount)
+	}
+
+	buyAmount, ok := common.ParseBigInt(quote.Quote.BuyAmount)
+	if !ok {
+		return CowOrder{}, fmt.Errorf("invalid buy amount from cow, amount: %v", quote.Quote.BuyAmount)
+	}
+
+	fee, ok := common.ParseBigInt(quote.Quote.FeeAmount)
+	if !ok {
+		return CowOrder{}, fmt.Errorf("invalid fee amount from cow, amount: %v", quote.Quote.FeeAmount)
+	}
+
+	// The quote does not include the partner fee, it is taken from the buy
+	// token of sell orders and the sell token of buy orders.
+	sellAmount = new(big.Int).Add(sellAmount, fee)
+	if quote.Quote.Kind == KindSell {
+		buyAmount = common.MinReceived(common.MinReceived(buyAmount, req.FeeBps), slippage)
+	} else {
+		sellAmount = addBps(addBps(sellAmount, req.FeeBps), slippage)
+	}
+
+	receiver := quote.Quote.Receiver
+	if receiver == "" {
+		receiver = req.Receiver
+	}
+
+	return CowOrder{
+		SellToken:         quote.Quote.SellToken,
+		BuyToken:          quote.Quote.BuyToken,
+		Receiver:          receiver,
+		SellAmount:        sellAmount.String(),
+		BuyAmount:         buyAmount.String(),
+		ValidTo:           quote.Quote.ValidTo,
+		AppData:           appDataHash(appData),
+		FeeAmount:         "0",
+		Kind:              quote.Quote.Kind,
+		PartiallyFillable: false,
+		SellTokenBalance:  "erc20",
+		BuyTokenBalance:   "erc20",
+	}, nil
+}
+
+// TypedData returns the EIP-712 payload of order to sign.
+func (o *cow) TypedData(chainId uint64, order CowOrder) apitypes.TypedData {
+	receiver := order.Receiver
+	if receiver == "" {
+		receiver = ethcommon.Address{}.Hex()
+	}
+
+	return apitypes.TypedData{
+		Types: apitypes.Types{
+			"EIP712Domain": {
+				{Name: "name", Type: "string"},
+				{Name: "version", Type: "string"},
+				{Name: "chainId", Type: "uint256"},
+				{Name: "verifyingContract", Type: "address"},
+			},
+			"Order": {
+				{Name: "sellToken", Type: "address"},
+				{Name: "buyToken", Type: "address"},
+				{Name: "receiver", Type: "address"},
+				{Name: "sellAmount", Type: "uint256"},
+				{Name: "buyAmount", Type: "uint256"},
+				{Name: "validTo", Type: "uint32"},
+				{Name: "appData", Type: "bytes32"},
+				{Name: "feeAmount", Type: "uint256"},
+				{Name: "kind", Type: "string"},
+				{Name: "partiallyFillable", Type: "bool"},
+				{Name: "sellTokenBalance", Type: "string"},
+				{Name: "buyTokenBalance", Type: "string"},
+			},
+		},
+		PrimaryType: "Order",
+		Domain: apitypes.TypedDataDomain{
+			Name:              "Gnosis Protocol",
+			Version:           "v2",
+			ChainId:           math.NewHexOrDecimal256(int64(chainId)),
+			VerifyingContract: SettlementAddress,
+		},
+		Message: apitypes.TypedDataMessage{
+			"sellToken":         order.SellToken,
+			"buyToken":          order.BuyToken,
+			"receiver":          receiver,
+			"sellAmount":        order.SellAmount,
+			"buyAmount":         order.BuyAmount,
+			"validTo":           strconv.FormatUint(uint64(order.ValidTo), 10),
+			"appData":           order.AppData,
+			"feeAmount":         order.FeeAmount,
+			"kind":              string(order.Kind),
+			"partiallyFillable": order.PartiallyFillable,
+			"sellTokenBalance":  order.SellTokenBalance,
+			"buyTokenBalance":   order.BuyTokenBalance,
+		},
+	}
+}
+
+// SubmitOrder sends the order built from req and signed to the orderbook
+// and returns its uid.
+func (o *cow) SubmitOrder(ctx context.Context, req common.QuoteReq, order CowOrder, quote CowQuoteResponse, signature []byte) (string, error) {
+	base, ok := o.chainUrlMap[req.ChainId]
+	if !ok {
+		return "", fmt.Errorf("unsupported chainId %d, err: %w", req.ChainId, common.ErrUnsupportedChain)
+	}
+
+	appData, err := o.appDataOf(req)
+	if err != nil {
+		return "", err
+	}
+
+	body := CowOrderCreation{
+		CowOrder:      order,
+		AppData:       appData,
+		AppDataHash:   order.AppData,
+		SigningScheme: "eip712",
+		Signature:     hexutil.Encode(signature),
+		From:          quote.From,
+		QuoteId:       quote.Id,
+	}
+
+	var uid string
+	_, err = o.client.Post(ctx, fmt.Sprintf("%s/api/v1/orders", base), map[string]string{}, body, &uid)
+	if err != nil {
+		return "", fmt.Errorf("unable to submit cow order, err: %w", parseError(err))
+	}
+	return uid, nil
+}
+
+func (o *cow) FetchOrder(ctx context.Context, chainId uint64, id string) (common.Order, error) {
+	base, ok := o.chainUrlMap[chainId]
+	if !ok {
+		return common.Order{}, fmt.Errorf("unsupported chainId %d, err: %w", chainId, common.ErrUnsupportedChain)
+	}
+
+	var res CowOrderResponse
+	_, err := o.client.Get(ctx, fmt.Sprintf("%s/api/v1/orders/%s", base, id), map[string]string{}, &res)
+	if err != nil {
+		return common.Order{}, fmt.Errorf("unable to fetch cow order, err: %w", parseError(err))
+	}
+	return parseCowOrder(chainId, res)
+}
+
+// OrderKind is whether the sell or the buy amount of an order is fixed.
+type OrderKind string
+
+const (
+	KindSell OrderKind = "sell"
+	KindBuy  OrderKind = "buy"
+)
+
+type CowErrorResponse struct {
+	ErrorType   string `json:"errorType"`
+	Description string `json:"description"`
+}
+
+// parseError classifies a failed orderbook call from its error payload, e.g.
+// {"errorType":"NoLiquidity","description":"no route found"}.
+func parseError(err error) error {
+	status, body, ok := common.StatusAndBody(err)
+	if !ok {
+		return common.NewProviderError("cow", 0, "", "", err)
+	}
+
+	var payload CowErrorResponse
+	if json.Unmarshal(body, &payload) != nil || payload.Description == "" {
+		return common.NewProviderError("cow", status, "", string(body), err)
+	}
+
+	return common.NewProviderError("cow", status, payload.ErrorType, payload.Description, err)
+}
+
+type CowQuoteReq struct {
+	SellToken         string    `json:"sellToken"`
+	BuyToken          string    `json:"buyToken"`
+	Receiver          string    `json:"receiver,omitempty"`
+	AppData           string    `json:"appData"`
+	AppDataHash       string    `json:"appDataHash"`
+	SellTokenBalance  string    `json:"sellTokenBalance"`
+	BuyTokenBalance   string    `json:"buyTokenBalance"`
+	From              string    `json:"from"`
+	PriceQuality      string    `json:"priceQuality"`
+	SigningScheme     string    `json:"signingScheme"`
+	Kind              OrderKind `json:"kind"`
+	SellAmountBefore  string    `json:"sellAmountBeforeFee,omitempty"`
+	BuyAmountAfterFee string    `json:"buyAmountAfterFee,omitempty"`
+}
+
+// CowOrder is the GPv2 order signed by the owner, AppData is the hash of the
+// app data document.
+type CowOrder struct {
+	SellToken         string    `json:"sellToken"`
+	BuyToken          string    `json:"buyToken"`
+	Receiver          string    `json:"receiver,omitempty"`
+	SellAmount        string    `json:"sellAmount"`
+	BuyAmount         string    `json:"buyAmount"`
+	ValidTo           uint32    `json:"validTo"`
+	AppData           string    `json:"appData"`
+	FeeAmount         string    `json:"feeAmount"`
+	Kind              OrderKind `json:"kind"`
+	PartiallyFillable bool      `json:"partiallyFillable"`
+	SellTokenBalance  string    `json:"sellTokenBalance"`
+	BuyTokenBalance   string    `json:"buyTokenBalance"`
+}
+
+type CowQuoteResponse struct {
+	Quote      CowOrder `json:"quote"`
+	From       string   `json:"from"`
+	Expiration string   `json:"expiration"`
+	Id         int64    `json:"id"`
+	Verified   bool     `json:"verified"`
+}
+
+// CowOrderCreation is the body of POST /orders, AppData is the full app data
+// document and AppDataHash its hash.
+type CowOrderCreation struct {
+	CowOrder
+	AppData       string `json:"appData"`
+	AppDataHash   string `json:"appDataHash"`
+	SigningScheme string `json:"signingScheme"`
+	Signature     string `json:"signature"`
+	From          string `json:"from"`
+	QuoteId       int64  `json:"quoteId"`
+}
+
+type CowOrderResponse struct {
+	CowOrder
+	Uid                string `json:"uid"`
+	Owner              string `json:"owner"`
+	Status             string `json:"status"`
+	ExecutedSellAmount string `json:"executedSellAmount"`
+	ExecutedBuyAmount  string `json:"executedBuyAmount"`
+	TxHash             string `json:"txHash"`
+}
+
+// appDataOf returns the app data document of the orders of req, the
+// configured one with the referrer and partner fee of req added to its
+// metadata. Solvers pick the liquidity sources, source filters are
+// rejected.
+func (o *cow) appDataOf(req common.QuoteReq) (string, error) {
+	if len(req.IncludedSources) > 0 || len(req.ExcludedSources) > 0 {
+		return "", fmt.Errorf("source filters are not supported by cow, err: %w", common.ErrUnsupportedParameter)
+	}
+
+	if req.FeeBps > 0 && req.Referrer == "" {
+		return "", fmt.Errorf("cow fee requires a referrer to receive it")
+	}
+
+	if req.Referrer == "" {
+		return o.appData, nil
+	}
+
+	var doc map[string]any
+	if err := json.Unmarshal([]byte(o.appData), &doc); err != nil {
+		return "", fmt.Errorf("invalid cow app data, err: %v", err)
+	}
+
+	metadata, _ := doc["metadata"].(map[string]any)
+	if metadata == nil {
+		metadata = map[string]any{}
+	}
+
+	metadata["referrer"] = map[string]any{"address": req.Referrer}
+	if req.FeeBps > 0 {
+		metadata["partnerFee"] = map[string]any{"bps": req.FeeBps, "recipient": req.Referrer}
+	}
+
+	doc["metadata"] = metadata
+	if _, ok := doc["version"]; !ok {
+		doc["version"] = appDataVersion
+	}
+
+	out, err := json.Marshal(doc)
+	if err != nil {
+		return "", fmt.Errorf("invalid cow app data, err: %v", err)
+	}
+	return string(out), nil
+}
+
+func appDataHash(appData string) string {
+	return crypto.Keccak256Hash([]byte(appData)).Hex()
+}
+
+func addBps(amount *big.Int, bps uint32) *big.Int {
+	out := new(big.Int).Mul(amount, big.NewInt(int64(10_000+bps)))
+	return out.Div(out, big.NewInt(10_000))
+}
+
+func parseCowOrder(chainId uint64, res CowOrderResponse) (common.Order, error) {
+	var amounts [4]*big.Int
+	for i, v := range []string{res.SellAmount, res.BuyAmount, res.ExecutedSellAmount, res.ExecutedBuyAmount} {
+		amount, ok := common.ParseBigInt(v)
+		if !ok {
+			return common.Order{}, fmt.Errorf("invalid amount from cow, amount: %v", v)
+		}
+		amounts[i] = amount
+	}
+
+	// presignaturePending orders wait for an on-chain signature, they are
+	// pending like open ones.
+	status := common.OrderPending
+	switch res.Status {
+	case "fulfilled":
+		status = common.OrderFilled
+	case "cancelled":
+		status = common.OrderCancelled
+	case "expired":
+		status = common.OrderExpired
+	case "open":
+		if amounts[2].Sign() > 0 {
+			status = common.OrderPartiallyFilled
+		}
+	}
+
+	return common.Order{
+		ChainId:            chainId,
+		Id:                 res.Uid,
+		Owner:              res.Owner,
+		Src:                res.SellToken,
+		Dst:                res.BuyToken,
+		SellAmount:         amounts[0],
+		BuyAmount:          amounts[1],
+		ExecutedSellAmount: amounts[2],
+		ExecutedBuyAmount:  amounts[3],
+		ValidTo:            time.Unix(int64(res.ValidTo), 0),
+		Status:             status,
+		TxHash:             res.TxHash,
+	}, nil
+}
@@ -0,0 +1,262 @@
+package cow
+
+import (
+	"context"
+	"errors"
+	"math/big"
+	"net/http"
+	"testing"
+	"time"
+
+	ethcommon "github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/signer/core/apitypes"
+	"github.com/onmetahq/go-evm/internal/http/common"
+	"github.com/onmetahq/go-evm/internal/http/fake"
+)
+
+const TOKENA = "0xc02aaa39b223fe8d0a0e5c4f27ead9083c756cc2"
+const TOKENB = "0xa0b86991c6218b36c1d19d4a2e9eb0ce3606eb48"
+
+func newSigner(t *testing.T) common.Signer {
+	key, err := crypto.GenerateKey()
+	if err != nil {
+		t.Fatalf("key err: %v", err)
+	}
+	return common.PrivateKeySigner(key)
+}
+
+func TestPlaceOrder(t *testing.T) {
+	server := fake.NewServer()
+	defer server.Close()
+
+	cowClient := NewCow(common.NewClient("", nil), map[uint64]string{1: server.URL + fake.CowPrefix + "/mainnet"})
+	signer := newSigner(t)
+	req := common.QuoteReq{
+		ChainId:     1,
+		Src:         TOKENB,
+		Dst:         TOKENA,
+		Amount:      big.NewInt(1000000),
+		SlippageBps: common.Bps(50),
+	}
+
+	order, err := cowClient.PlaceOrder(context.Background(), req, signer)
+	if err != nil {
+		t.Fatalf("place order err: %v", err)
+	}
+
+	// 1% fee is folded back into the sell amount, slippage applies to the
+	// buy amount of 990000 * 2.
+	if order.SellAmount.Cmp(big.NewInt(1000000)) != 0 || order.BuyAmount.Cmp(big.NewInt(1970100)) != 0 {
+		t.Fatalf("invalid amounts, sell: %s, buy: %s", order.SellAmount, order.BuyAmount)
+	}
+
+	if order.Owner != signer.Address() || order.Status != common.OrderPending || len(order.Id) != 2+56*2 {
+		t.Fatalf("invalid order, order: %+v", order)
+	}
+
+	filled, err := common.WaitForOrder(context.Background(), cowClient, 1, order.Id, time.Millisecond)
+	if err != nil {
+		t.Fatalf("wait err: %v", err)
+	}
+
+	if filled.Status != common.OrderFilled || filled.ExecutedBuyAmount.Cmp(order.BuyAmount) != 0 || filled.TxHash == "" {
+		t.Fatalf("expected a filled order, order: %+v", filled)
+	}
+
+	if calls := server.Calls("/mainnet/api/v1/orders/" + order.Id); calls != 2 {
+		t.Fatalf("expected an open then a fulfilled poll, calls: %d", calls)
+	}
+}
+
+func TestPlaceExactOutOrder(t *testing.T) {
+	server := fake.NewServer()
+	defer server.Close()
+
+	cowClient := NewCow(common.NewClient("", nil), map[uint64]string{1: server.URL + fake.CowPrefix + "/mainnet"}, WithAppData(`{"appCode":"go-evm"}`))
+	req := common.QuoteReq{
+		ChainId:     1,
+		Src:         TOKENB,
+		Dst:         TOKENA,
+		Amount:      big.NewInt(2000000),
+		SlippageBps: common.Bps(100),
+	}
+
+	order, err := cowClient.PlaceExactOutOrder(context.Background(), req, newSigner(t))
+	if err != nil {
+		t.Fatalf("place order err: %v", err)
+	}
+
+	// 1000000 sold plus a 10000 fee, raised by 1% slippage.
+	if order.BuyAmount.Cmp(big.NewInt(2000000)) != 0 || order.SellAmount.Cmp(big.NewInt(1020100)) != 0 {
+		t.Fatalf("invalid amounts, sell: %s, buy: %s", order.SellAmount, order.BuyAmount)
+	}
+}
+
+func TestErrorPaths(t *testing.T) {
+	server := fake.NewServer()
+	defer server.Close()
+
+	cowClient := NewCow(common.NewClient("", nil), map[uint64]string{1: server.URL + fake.CowPrefix + "/mainnet"})
+	req := common.QuoteReq{
+		ChainId: 1,
+		Src:     TOKENB,
+		Dst:     TOKENA,
+		Amount:  big.NewInt(1000000),
+	}
+
+	t.Run("unsupported chain", func(t *testing.T) {
+		_, err := cowClient.PlaceOrder(context.Background(), common.QuoteReq{ChainId: 56, Src: TOKENB, Dst: TOKENA, Amount: big.NewInt(1)}, newSigner(t))
+		if !errors.Is(err, common.ErrUnsupportedChain) {
+			t.Fatalf("expected ErrUnsupportedChain, err: %v", err)
+		}
+	})
+
+	t.Run("native sell", func(t *testing.T) {
+		native := req
+		native.Src = "0xeeeeeeeeeeeeeeeeeeeeeeeeeeeeeeeeeeeeeeee"
+		_, err := cowClient.PlaceOrder(context.Background(), native, newSigner(t))
+		if !errors.Is(err, common.ErrUnsupportedToken) {
+			t.Fatalf("expected ErrUnsupportedToken, err: %v", err)
+		}
+	})
+
+	t.Run("invalid signature", func(t *testing.T) {
+		quote, err := cowClient.FetchQuote(context.Background(), req, KindSell)
+		if err != nil {
+			t.Fatalf("quote err: %v", err)
+		}
+		order, err := cowClient.BuildOrder(req, quote)
+		if err != nil {
+			t.Fatalf("build err: %v", err)
+		}
+
+		_, err = cowClient.SubmitOrder(context.Background(), req, order, quote, make([]byte, 65))
+		var providerErr *common.ProviderError
+		if !errors.As(err, &providerErr) || providerErr.StatusCode != http.StatusBadRequest {
+			t.Fatalf("expected a rejected order, err: %v", err)
+		}
+	})
+
+	t.Run("no liquidity", func(t *testing.T) {
+		server.Fail("/api/v1/quote", http.StatusNotFound, `{"errorType":"NoLiquidity","description":"no route found"}`)
+		_, err := cowClient.PlaceOrder(context.Background(), req, newSigner(t))
+		if !errors.Is(err, common.ErrInsufficientLiquidity) || common.IsRetryable(err) {
+			t.Fatalf("expected ErrInsufficientLiquidity, err: %v", err)
+		}
+	})
+}
+
+// TestTypedDataKnownAnswer checks the order type hash and the mainnet
+// domain separator against the values published by GPv2Settlement, and the
+// order digest against an encoding written from GPv2Order.sol.
+func TestTypedDataKnownAnswer(t *testing.T) {
+	order := CowOrder{
+		SellToken:        TOKENB,
+		BuyToken:         TOKENA,
+		Receiver:         "0x15Ba05723b04785C3E21157171810892A4FB795c",
+		SellAmount:       "1000000000",
+		BuyAmount:        "280000000000000000",
+		ValidTo:          1718110000,
+		AppData:          "0xb48d38f93eaa084033fc5970bf96e559c33c4cdc07d889ab00b4d63f9590739d",
+		FeeAmount:        "0",
+		Kind:             KindSell,
+		SellTokenBalance: "erc20",
+		BuyTokenBalance:  "erc20",
+	}
+	typedData := NewCow(nil, nil).TypedData(1, order)
+
+	typeHash := ethcommon.HexToHash("0xd5a25ba2e97094ad7d83dc28a6572da797d6b3e7fc6663bd93efb789fc17e489")
+	if got := ethcommon.BytesToHash(typedData.TypeHash("Order")); got != typeHash {
+		t.Fatalf("invalid order type hash, hash: %s", got)
+	}
+
+	domainSeparator := ethcommon.HexToHash("0xc078f884a2676e1345748b1feace7b0abee5d00ecadb6e574dcdd109a63e8943")
+	got, err := typedData.HashStruct("EIP712Domain", typedData.Domain.Map())
+	if err != nil || ethcommon.BytesToHash(got) != domainSeparator {
+		t.Fatalf("invalid mainnet domain separator, separator: %x, err: %v", got, err)
+	}
+
+	word := func(v *big.Int) []byte { return ethcommon.LeftPadBytes(v.Bytes(), 32) }
+	address := func(a string) []byte { return ethcommon.LeftPadBytes(ethcommon.HexToAddress(a).Bytes(), 32) }
+	structHash := crypto.Keccak256(
+		typeHash.Bytes(),
+		address(order.SellToken),
+		address(order.BuyToken),
+		address(order.Receiver),
+		word(big.NewInt(1_000_000_000)),
+		word(big.NewInt(280_000_000_000_000_000)),
+		word(big.NewInt(1718110000)),
+		ethcommon.HexToHash(order.AppData).Bytes(),
+		word(big.NewInt(0)),
+		crypto.Keccak256([]byte("sell")),
+		word(big.NewInt(0)),
+		crypto.Keccak256([]byte("erc20")),
+		crypto.Keccak256([]byte("erc20")),
+	)
+	want := crypto.Keccak256Hash([]byte{0x19, 0x01}, domainSeparator.Bytes(), structHash)
+
+	digest, _, err := apitypes.TypedDataAndHash(typedData)
+	if err != nil || ethcommon.BytesToHash(digest) != want {
+		t.Fatalf("invalid order digest, digest: %x, want: %s, err: %v", digest, want, err)
+	}
+}
+
+func TestPartnerFee(t *testing.T) {
+	server := fake.NewServer()
+	defer server.Close()
+
+	cowClient := NewCow(common.NewClient("", nil), map[uint64]string{1: server.URL + fake.CowPrefix + "/mainnet"}, WithAppData(`{"appCode":"go-evm"}`))
+	req := common.QuoteReq{
+		ChainId:     1,
+		Src:         TOKENB,
+		Dst:         TOKENA,
+		Amount:      big.NewInt(1000000),
+		SlippageBps: common.Bps(50),
+		Referrer:    "0x000000000000000000000000000000000000dEaD",
+		FeeBps:      25,
+	}
+
+	appData, err := cowClient.appDataOf(req)
+	if err != nil {
+		t.Fatalf("app data err: %v", err)
+	}
+
+	want := `{"appCode":"go-evm","metadata":{"partnerFee":{"bps":25,"recipient":"0x000000000000000000000000000000000000dEaD"},"referrer":{"address":"0x000000000000000000000000000000000000dEaD"}},"version":"1.1.0"}`
+	if appData != want {
+		t.Fatalf("invalid app data, want: %s, got: %s", want, appData)
+	}
+
+	order, err := cowClient.PlaceOrder(context.Background(), req, newSigner(t))
+	if err != nil {
+		t.Fatalf("place order err: %v", err)
+	}
+
+	// 1980000 less the 0.25% partner fee, then the 0.5% slippage.
+	if order.BuyAmount.Cmp(big.NewInt(1965174)) != 0 {
+		t.Fatalf("invalid buy amount, buy: %s", order.BuyAmount)
+	}
+
+	quote, err := cowClient.FetchQuote(context.Background(), req, KindSell)
+	if err != nil {
+		t.Fatalf("quote err: %v", err)
+	}
+	built, err := cowClient.BuildOrder(req, quote)
+	if err != nil {
+		t.Fatalf("build err: %v", err)
+	}
+	if built.AppData != appDataHash(want) {
+		t.Fatalf("invalid app data hash, hash: %s", built.AppData)
+	}
+
+	sources := req
+	sources.ExcludedSources = []string{"UniswapV3"}
+	if _, err := cowClient.PlaceOrder(context.Background(), sources, newSigner(t)); !errors.Is(err, common.ErrUnsupportedParameter) {
+		t.Fatalf("expected source filters to be unsupported, err: %v", err)
+	}
+
+	req.Referrer = ""
+	if _, err := cowClient.PlaceOrder(context.Background(), req, newSigner(t)); err == nil {
+		t.Fatalf("expected fee without referrer to fail")
+	}
+}
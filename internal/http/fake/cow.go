@@ -0,0 +1,253 @@
+package fake
+
+import (
+	"encoding/json"
+	"fmt"
+	"math/big"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	ethcommon "github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/common/math"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/signer/core/apitypes"
+)
+
+// CowSettlementAddress is the GPv2Settlement contract verifying orders.
+const CowSettlementAddress = "0x9008D19f58AAbD9eD0D60971565AA8510560ab41"
+
+// cowOrder is a submitted order, polls counts the status requests so the
+// order is open on the first one and fulfilled afterwards.
+type cowOrder struct {
+	body  cowOrderBody
+	owner string
+	polls int
+}
+
+type cowOrderBody struct {
+	SellToken         string `json:"sellToken"`
+	BuyToken          string `json:"buyToken"`
+	Receiver          string `json:"receiver"`
+	SellAmount        string `json:"sellAmount"`
+	BuyAmount         string `json:"buyAmount"`
+	ValidTo           uint32 `json:"validTo"`
+	AppData           string `json:"appData"`
+	AppDataHash       string `json:"appDataHash"`
+	FeeAmount         string `json:"feeAmount"`
+	Kind              string `json:"kind"`
+	PartiallyFillable bool   `json:"partiallyFillable"`
+	SellTokenBalance  string `json:"sellTokenBalance"`
+	BuyTokenBalance   string `json:"buyTokenBalance"`
+	SigningScheme     string `json:"signingScheme"`
+	Signature         string `json:"signature"`
+	From              string `json:"from"`
+}
+
+// cowMux serves the orderbook API of every network in cowChainIds.
+func (s *Server) cowMux() *http.ServeMux {
+	mux := newMux()
+	mux.HandleFunc("POST /{network}/api/v1/quote", serve(func(r *http.Request) (any, error) {
+		return s.cowQuote(r)
+	}))
+	mux.HandleFunc("POST /{network}/api/v1/orders", serve(func(r *http.Request) (any, error) {
+		chainId, ok := cowChainIds[r.PathValue("network")]
+		if !ok {
+			return nil, fmt.Errorf("unknown network %s", r.PathValue("network"))
+		}
+		return s.cowCreateOrder(r, chainId)
+	}))
+	mux.HandleFunc("GET /{network}/api/v1/orders/{uid}", serve(func(r *http.Request) (any, error) {
+		return s.cowFetchOrder(r.PathValue("uid"))
+	}))
+	return mux
+}
+
+// cowQuote quotes at the server rate and charges 1% of the sell amount as
+// the network fee.
+func (s *Server) cowQuote(r *http.Request) (map[string]any, error) {
+	var body struct {
+		SellToken           string `json:"sellToken"`
+		BuyToken            string `json:"buyToken"`
+		Receiver            string `json:"receiver"`
+		AppDataHash         string `json:"appDataHash"`
+		From                string `json:"from"`
+		Kind                string `json:"kind"`
+		SellAmountBeforeFee string `json:"sellAmountBeforeFee"`
+		BuyAmountAfterFee   string `json:"buyAmountAfterFee"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+		return nil, fmt.Errorf("invalid body: %v", err)
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var sell, buy, fee *big.Int
+	switch body.Kind {
+	case "sell":
+		amount, ok := new(big.Int).SetString(body.SellAmountBeforeFee, 10)
+		if !ok {
+			return nil, fmt.Errorf("invalid sellAmountBeforeFee %s", body.SellAmountBeforeFee)
+		}
+		fee = new(big.Int).Div(amount, big.NewInt(100))
+		sell = new(big.Int).Sub(amount, fee)
+		buy = s.convert(sell, false)
+	case "buy":
+		amount, ok := new(big.Int).SetString(body.BuyAmountAfterFee, 10)
+		if !ok {
+			return nil, fmt.Errorf("invalid buyAmountAfterFee %s", body.BuyAmountAfterFee)
+		}
+		buy = amount
+		sell = s.convert(amount, true)
+		fee = new(big.Int).Div(sell, big.NewInt(100))
+	default:
+		return nil, fmt.Errorf("invalid kind %s", body.Kind)
+	}
+
+	return map[string]any{
+		"quote": map[string]any{
+			"sellToken":         body.SellToken,
+			"buyToken":          body.BuyToken,
+			"receiver":          body.Receiver,
+			"sellAmount":        sell.String(),
+			"buyAmount":         buy.String(),
+			"validTo":           time.Now().Add(30 * time.Minute).Unix(),
+			"appData":           body.AppDataHash,
+			"feeAmount":         fee.String(),
+			"kind":              body.Kind,
+			"partiallyFillable": false,
+			"sellTokenBalance":  "erc20",
+			"buyTokenBalance":   "erc20",
+			"signingScheme":     "eip712",
+		},
+		"from":       body.From,
+		"expiration": time.Now().Add(time.Minute).UTC().Format(time.RFC3339),
+		"id":         len(s.cowOrders) + 1,
+		"verified":   true,
+	}, nil
+}
+
+// cowCreateOrder recovers the owner from the EIP-712 signature, rejects
+// orders not signed by from and returns the order uid.
+func (s *Server) cowCreateOrder(r *http.Request, chainId uint64) (string, error) {
+	var body cowOrderBody
+	if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+		return "", fmt.Errorf("invalid body: %v", err)
+	}
+
+	hash, owner, err := recoverTypedData(cowTypedData(chainId, body), body.Signature)
+	if err != nil {
+		return "", err
+	}
+
+	if !strings.EqualFold(owner.Hex(), body.From) {
+		return "", fmt.Errorf("signature does not match from, owner: %s", owner.Hex())
+	}
+
+	// The uid is the order digest, the owner and validTo.
+	uid := make([]byte, 0, 56)
+	uid = append(uid, hash...)
+	uid = append(uid, owner.Bytes()...)
+	uid = append(uid, byte(body.ValidTo>>24), byte(body.ValidTo>>16), byte(body.ValidTo>>8), byte(body.ValidTo))
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.cowOrders[hexutil.Encode(uid)] = &cowOrder{body: body, owner: owner.Hex()}
+	return hexutil.Encode(uid), nil
+}
+
+func (s *Server) cowFetchOrder(uid string) (map[string]any, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	order, ok := s.cowOrders[uid]
+	if !ok {
+		return nil, fmt.Errorf("order %s not found", uid)
+	}
+	order.polls++
+
+	status, executedSell, executedBuy := "open", "0", "0"
+	txHash := ""
+	if order.polls > 1 {
+		status, executedSell, executedBuy = "fulfilled", order.body.SellAmount, order.body.BuyAmount
+		txHash = crypto.Keccak256Hash([]byte(uid)).Hex()
+	}
+
+	return map[string]any{
+		"uid":                uid,
+		"owner":              order.owner,
+		"sellToken":          order.body.SellToken,
+		"buyToken":           order.body.BuyToken,
+		"receiver":           order.body.Receiver,
+		"sellAmount":         order.body.SellAmount,
+		"buyAmount":          order.body.BuyAmount,
+		"validTo":            order.body.ValidTo,
+		"appData":            order.body.AppDataHash,
+		"feeAmount":          order.body.FeeAmount,
+		"kind":               order.body.Kind,
+		"partiallyFillable":  order.body.PartiallyFillable,
+		"status":             status,
+		"executedSellAmount": executedSell,
+		"executedBuyAmount":  executedBuy,
+		"txHash":             txHash,
+	}, nil
+}
+
+func cowTypedData(chainId uint64, body cowOrderBody) apitypes.TypedData {
+	receiver := body.Receiver
+	if receiver == "" {
+		receiver = ethcommon.Address{}.Hex()
+	}
+
+	return apitypes.TypedData{
+		Types: apitypes.Types{
+			"EIP712Domain": {
+				{Name: "name", Type: "string"},
+				{Name: "version", Type: "string"},
+				{Name: "chainId", Type: "uint256"},
+				{Name: "verifyingContract", Type: "address"},
+			},
+			"Order": {
+				{Name: "sellToken", Type: "address"},
+				{Name: "buyToken", Type: "address"},
+				{Name: "receiver", Type: "address"},
+				{Name: "sellAmount", Type: "uint256"},
+				{Name: "buyAmount", Type: "uint256"},
+				{Name: "validTo", Type: "uint32"},
+				{Name: "appData", Type: "bytes32"},
+				{Name: "feeAmount", Type: "uint256"},
+				{Name: "kind", Type: "string"},
+				{Name: "partiallyFillable", Type: "bool"},
+				{Name: "sellTokenBalance", Type: "string"},
+				{Name: "buyTokenBalance", Type: "string"},
+			},
+		},
+		PrimaryType: "Order",
+		Domain: apitypes.TypedDataDomain{
+			Name:              "Gnosis Protocol",
+			Version:           "v2",
+			ChainId:           math.NewHexOrDecimal256(int64(chainId)),
+			VerifyingContract: CowSettlementAddress,
+		},
+		Message: apitypes.TypedDataMessage{
+			"sellToken":         body.SellToken,
+			"buyToken":          body.BuyToken,
+			"receiver":          receiver,
+			"sellAmount":        body.SellAmount,
+			"buyAmount":         body.BuyAmount,
+			"validTo":           strconv.FormatUint(uint64(body.ValidTo), 10),
+			"appData":           body.AppDataHash,
+			"feeAmount":         body.FeeAmount,
+			"kind":              body.Kind,
+			"partiallyFillable": body.PartiallyFillable,
+			"sellTokenBalance":  body.SellTokenBalance,
+			"buyTokenBalance":   body.BuyTokenBalance,
+		},
+	}
+}
+
+// cowChainIds maps the network segment of an orderbook path to its chain id.
+var cowChainIds = map[string]uint64{"mainnet": 1, "xdai": 100, "arbitrum_one": 42161, "base": 8453, "sepolia": 11155111}
@@ -0,0 +1,250 @@
+// Package fake is an in-process aggregator server implementing the quote,
+// swap and token endpoints of the 0x v1, v2 and gasless, 1inch, ParaSwap,
+// KyberSwap, Odos, OpenOcean v3 and LI.FI APIs, the 1inch Fusion and Fusion+
+// relayers and the CoW Protocol orderbook, with injectable failures for
+// error path tests, and a JSON-RPC node answering registered methods.
+//
+// Each provider is served under its own path prefix, e.g. the 1inch quote is
+// at URL + "/1inch/137/quote", so providers sharing an endpoint name such as
+// /quote cannot collide.
+package fake
+
+import (
+	"encoding/json"
+	"fmt"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+)
+
+const (
+	RouterAddress   = "0x1111111254eeb25477b68fb85ed929f73a960582"
+	ExchangeAddress = "0xdef1c0ded9bec7f1a1670819833240f027b25eff"
+	Permit2Address  = "0x000000000022d473030f116ddee9f6b43ac78ba3"
+	SettlerAddress  = "0x7f6cee965959295cc64d0e6c00d99d6532d8e86b"
+	SwapCallData    = "0x12aa3caf0000000000000000000000000000000000000000000000000000000000000000"
+)
+
+// Path prefixes of the providers, a client's base URL is the server URL
+// followed by the prefix of its provider.
+const (
+	ZeroXPrefix      = "/0x"
+	OneInchPrefix    = "/1inch"
+	FusionPrefix     = "/fusion"
+	FusionPlusPrefix = "/fusion-plus"
+	CowPrefix        = "/cow"
+	OpenOceanPrefix  = "/openocean"
+	OdosPrefix       = "/odos"
+	KyberSwapPrefix  = "/kyberswap"
+	ParaSwapPrefix   = "/paraswap"
+	LiFiPrefix       = "/lifi"
+)
+
+type Token struct {
+	Address  string `json:"address"`
+	Symbol   string `json:"symbol"`
+	Name     string `json:"name"`
+	Decimals int    `json:"decimals"`
+	LogoURI  string `json:"logoURI"`
+}
+
+type failure struct {
+	suffix string
+	status int
+	body   string
+}
+
+// Server quotes every pair at Rate destination units per source unit.
+type Server struct {
+	*httptest.Server
+
+	mu        sync.Mutex
+	rate      *big.Rat
+	gas       int64
+	gasPrice  *big.Int
+	tokens    []Token
+	failures  []failure
+	calls     map[string]int
+	odosPaths map[string]odosPath
+	cowOrders map[string]*cowOrder
+
+	fusionOrders     map[string]*fusionOrder
+	fusionPlusOrders map[string]*fusionPlusOrder
+	gaslessTrades    map[string]*gaslessTrade
+	lifiPolls        map[string]int
+}
+
+func NewServer() *Server {
+	s := &Server{
+		rate:     big.NewRat(2, 1),
+		gas:      150_000,
+		gasPrice: big.NewInt(30_000_000_000),
+		tokens: []Token{
+			{Address: "0xeeeeeeeeeeeeeeeeeeeeeeeeeeeeeeeeeeeeeeee", Symbol: "MATIC", Name: "Matic", Decimals: 18},
+			{Address: "0x2791bca1f2de4661ed88a30c99a7a9449aa84174", Symbol: "USDC", Name: "USD Coin (PoS)", Decimals: 6},
+		},
+		calls:     map[string]int{},
+		odosPaths: map[string]odosPath{},
+		cowOrders: map[string]*cowOrder{},
+
+		fusionOrders:     map[string]*fusionOrder{},
+		fusionPlusOrders: map[string]*fusionPlusOrder{},
+		gaslessTrades:    map[string]*gaslessTrade{},
+		lifiPolls:        map[string]int{},
+	}
+
+	mux := newMux()
+	for prefix, provider := range map[string]http.Handler{
+		ZeroXPrefix:      s.zeroXMux(),
+		OneInchPrefix:    s.oneInchMux(),
+		FusionPrefix:     s.fusionMux(),
+		FusionPlusPrefix: s.fusionPlusMux(),
+		CowPrefix:        s.cowMux(),
+		OpenOceanPrefix:  s.openOceanMux(),
+		OdosPrefix:       s.odosMux(),
+		KyberSwapPrefix:  s.kyberSwapMux(),
+		ParaSwapPrefix:   s.paraSwapMux(),
+		LiFiPrefix:       s.lifiMux(),
+	} {
+		mux.Handle(prefix+"/", http.StripPrefix(prefix, provider))
+	}
+	s.Server = httptest.NewServer(s.intercept(mux))
+	return s
+}
+
+// SetRate sets the destination units received per source unit.
+func (s *Server) SetRate(rate *big.Rat) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.rate = rate
+}
+
+// Fail answers every request whose path ends with suffix, e.g.
+// "/swap/v1/quote" or "/swap", with status and body. When several suffixes
+// match a path the longest one answers, failing the same suffix again
+// replaces its answer.
+func (s *Server) Fail(suffix string, status int, body string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	f := failure{suffix: suffix, status: status, body: body}
+	for i := range s.failures {
+		if s.failures[i].suffix == suffix {
+			s.failures[i] = f
+			return
+		}
+	}
+	s.failures = append(s.failures, f)
+}
+
+// Clear removes the failure registered for suffix.
+func (s *Server) Clear(suffix string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for i := range s.failures {
+		if s.failures[i].suffix == suffix {
+			s.failures = append(s.failures[:i], s.failures[i+1:]...)
+			return
+		}
+	}
+}
+
+// Reset removes every failure and zeroes the call counts.
+func (s *Server) Reset() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.failures = nil
+	s.calls = map[string]int{}
+}
+
+// failure returns the failure with the longest suffix matching path.
+func (s *Server) failure(path string) (failure, bool) {
+	var match failure
+	found := false
+	for _, f := range s.failures {
+		if strings.HasSuffix(path, f.suffix) && (!found || len(f.suffix) > len(match.suffix)) {
+			match, found = f, true
+		}
+	}
+	return match, found
+}
+
+// Calls returns the number of requests whose path ends with suffix.
+func (s *Server) Calls(suffix string) int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	total := 0
+	for path, n := range s.calls {
+		if strings.HasSuffix(path, suffix) {
+			total += n
+		}
+	}
+	return total
+}
+
+// intercept counts every request and answers it with the registered failure
+// matching its path before it reaches a provider.
+func (s *Server) intercept(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		s.mu.Lock()
+		s.calls[r.URL.Path]++
+		f, failed := s.failure(r.URL.Path)
+		s.mu.Unlock()
+		if failed {
+			w.WriteHeader(f.status)
+			_, _ = w.Write([]byte(f.body))
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// newMux returns a mux answering unknown paths with a JSON 404.
+func newMux() *http.ServeMux {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/", func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNotFound)
+		_, _ = fmt.Fprintf(w, `{"error":"Not Found","description":"unknown path %s","statusCode":404}`, r.URL.Path)
+	})
+	return mux
+}
+
+// serve encodes the result of handle as the JSON response, errors are
+// answered with a 400.
+func serve(handle func(r *http.Request) (any, error)) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		res, err := handle(r)
+		if err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			_, _ = fmt.Fprintf(w, `{"error":"Bad Request","description":%q,"statusCode":400}`, err.Error())
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		_ = json.NewEncoder(w).Encode(res)
+	}
+}
+
+func (s *Server) token(address string) Token {
+	for _, t := range s.tokens {
+		if strings.EqualFold(t.Address, address) {
+			return t
+		}
+	}
+	return Token{Address: address}
+}
+
+// convert applies the rate to amount, or its inverse when reverse is set.
+func (s *Server) convert(amount *big.Int, reverse bool) *big.Int {
+	num, den := s.rate.Num(), s.rate.Denom()
+	if reverse {
+		num, den = den, num
+	}
+	out := new(big.Int).Mul(amount, num)
+	return out.Div(out, den)
+}
@@ -0,0 +1,91 @@
+package fake
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"testing"
+)
+
+func TestFailLongestSuffix(t *testing.T) {
+	server := NewServer()
+	defer server.Close()
+
+	status := func(path string) int {
+		res, err := http.Get(server.URL + path)
+		if err != nil {
+			t.Fatalf("get %s err: %v", path, err)
+		}
+		defer res.Body.Close()
+		_, _ = io.Copy(io.Discard, res.Body)
+		return res.StatusCode
+	}
+
+	server.Fail("/quote", http.StatusBadRequest, `{}`)
+	server.Fail("/swap/v1/quote", http.StatusTooManyRequests, `{}`)
+	server.Fail("/quote", http.StatusInternalServerError, `{}`)
+
+	for i := 0; i < 10; i++ {
+		if got := status(ZeroXPrefix + "/swap/v1/quote"); got != http.StatusTooManyRequests {
+			t.Fatalf("expected the longest suffix to answer, status: %d", got)
+		}
+	}
+
+	if got := status(LiFiPrefix + "/v1/quote"); got != http.StatusInternalServerError {
+		t.Fatalf("expected the replaced failure, status: %d", got)
+	}
+
+	server.Clear("/swap/v1/quote")
+	if got := status(ZeroXPrefix + "/swap/v1/quote"); got != http.StatusInternalServerError {
+		t.Fatalf("expected the shorter suffix after clear, status: %d", got)
+	}
+
+	server.Reset()
+	if got := status(LiFiPrefix + "/v1/quote"); got == http.StatusInternalServerError || server.Calls("/quote") != 1 {
+		t.Fatalf("expected no failures after reset, status: %d, calls: %d", got, server.Calls("/quote"))
+	}
+}
+
+func TestProviderPrefixes(t *testing.T) {
+	server := NewServer()
+	defer server.Close()
+
+	get := func(path string) map[string]any {
+		res, err := http.Get(server.URL + path)
+		if err != nil {
+			t.Fatalf("get %s err: %v", path, err)
+		}
+		defer res.Body.Close()
+		if res.StatusCode != http.StatusOK {
+			t.Fatalf("get %s status: %d", path, res.StatusCode)
+		}
+		var body map[string]any
+		if err := json.NewDecoder(res.Body).Decode(&body); err != nil {
+			t.Fatalf("decode %s err: %v", path, err)
+		}
+		return body
+	}
+
+	oneInch := get(OneInchPrefix + "/137/quote?src=0xa&dst=0xb&amount=100")
+	if oneInch["toAmount"] != "200" {
+		t.Errorf("expected the 1inch quote, body: %v", oneInch)
+	}
+
+	lifi := get(LiFiPrefix + "/v1/quote?fromChain=137&toChain=1&fromToken=0xa&toToken=0xb&fromAmount=10000&fromAddress=0xc&slippage=0.01")
+	if _, ok := lifi["estimate"]; !ok {
+		t.Errorf("expected the LI.FI quote, body: %v", lifi)
+	}
+
+	res, err := http.Get(server.URL + "/137/quote?src=0xa&dst=0xb&amount=100")
+	if err != nil {
+		t.Fatalf("get err: %v", err)
+	}
+	res.Body.Close()
+	if res.StatusCode != http.StatusNotFound {
+		t.Errorf("expected unprefixed paths to be unknown, status: %d", res.StatusCode)
+	}
+
+	if server.Calls("/quote") != 3 {
+		t.Errorf("expected every request to be counted, calls: %d", server.Calls("/quote"))
+	}
+}
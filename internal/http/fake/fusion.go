@@ -0,0 +1,233 @@
+package fake
+
+import (
+	"encoding/json"
+	"fmt"
+	"math/big"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/common/math"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/signer/core/apitypes"
+)
+
+const (
+	// FusionSettlementAddress is the Fusion settlement extension.
+	FusionSettlementAddress = "0x8273f37417da37c4a6c3995e82cf442f87a25d9c"
+	// LimitOrderAddress is the 1inch router v6 verifying limit orders.
+	LimitOrderAddress = "0x111111125421cA6dc452d289314280a0f8842A65"
+	// FusionResolver is the only whitelisted resolver.
+	FusionResolver = "0xf63392356a985ead50b767a3e97a253ff870e91a"
+)
+
+// fusionOrder is a submitted order, polls counts the status requests so the
+// order is pending, then half filled, then filled.
+type fusionOrder struct {
+	order fusionLimitOrder
+	polls int
+}
+
+type fusionLimitOrder struct {
+	Salt         string `json:"salt"`
+	Maker        string `json:"maker"`
+	Receiver     string `json:"receiver"`
+	MakerAsset   string `json:"makerAsset"`
+	TakerAsset   string `json:"takerAsset"`
+	MakingAmount string `json:"makingAmount"`
+	TakingAmount string `json:"takingAmount"`
+	MakerTraits  string `json:"makerTraits"`
+}
+
+// fusionMux serves the Fusion quoter, relayer and orders APIs.
+func (s *Server) fusionMux() *http.ServeMux {
+	mux := newMux()
+	mux.HandleFunc("GET /quoter/v2.0/{chainId}/quote/receive", serve(func(r *http.Request) (any, error) {
+		return s.fusionQuote(r.URL.Query())
+	}))
+	mux.HandleFunc("POST /relayer/v2.0/{chainId}/order/submit", serve(func(r *http.Request) (any, error) {
+		chainId, err := strconv.ParseUint(r.PathValue("chainId"), 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("invalid chain id %s", r.PathValue("chainId"))
+		}
+		return s.fusionSubmit(r, chainId)
+	}))
+	mux.HandleFunc("GET /orders/v2.0/{chainId}/order/status/{orderHash}", serve(func(r *http.Request) (any, error) {
+		return s.fusionStatus(r.PathValue("orderHash"))
+	}))
+	return mux
+}
+
+// fusionQuote quotes at the server rate, the fast, medium and slow presets
+// end their auction 0.5%, 0.3% and 0.1% below the quote.
+func (s *Server) fusionQuote(q map[string][]string) (map[string]any, error) {
+	get := func(key string) string {
+		if v := q[key]; len(v) > 0 {
+			return v[0]
+		}
+		return ""
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	amount, ok := new(big.Int).SetString(get("amount"), 10)
+	if !ok {
+		return nil, fmt.Errorf("invalid amount %s", get("amount"))
+	}
+	out := s.convert(amount, false)
+
+	presets := map[string]any{}
+	for name, bps := range map[string]int64{"fast": 50, "medium": 30, "slow": 10} {
+		end := new(big.Int).Mul(out, big.NewInt(10_000-bps))
+		end.Div(end, big.NewInt(10_000))
+		presets[name] = map[string]any{
+			"auctionDuration":    180 * (60 - bps) / 10,
+			"startAuctionIn":     24,
+			"initialRateBump":    bps * 1_000,
+			"auctionStartAmount": out.String(),
+			"startAmount":        amount.String(),
+			"auctionEndAmount":   end.String(),
+			"points":             []map[string]any{{"delay": 60, "coefficient": bps * 500}},
+			"allowPartialFills":  true,
+			"allowMultipleFills": true,
+			"gasCost":            map[string]any{"gasBumpEstimate": 1_000, "gasPriceEstimate": "1250"},
+		}
+	}
+	presets["custom"] = nil
+
+	return map[string]any{
+		"quoteId":            fmt.Sprintf("%08x-0000-4000-8000-000000000000", len(s.fusionOrders)+1),
+		"fromTokenAmount":    amount.String(),
+		"toTokenAmount":      out.String(),
+		"presets":            presets,
+		"recommended_preset": "medium",
+		"settlementAddress":  FusionSettlementAddress,
+		"whitelist":          []string{FusionResolver},
+	}, nil
+}
+
+// fusionSubmit checks the signature and that the salt commits to the
+// extension, like the relayer does.
+func (s *Server) fusionSubmit(r *http.Request, chainId uint64) (any, error) {
+	var body struct {
+		Order     fusionLimitOrder `json:"order"`
+		Signature string           `json:"signature"`
+		Extension string           `json:"extension"`
+		QuoteId   string           `json:"quoteId"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+		return nil, fmt.Errorf("invalid body: %v", err)
+	}
+
+	hash, maker, err := recoverTypedData(fusionTypedData(chainId, body.Order), body.Signature)
+	if err != nil {
+		return nil, err
+	}
+
+	if !strings.EqualFold(maker.Hex(), body.Order.Maker) {
+		return nil, fmt.Errorf("invalid-signature, signer: %s", maker.Hex())
+	}
+
+	extension, err := hexutil.Decode(body.Extension)
+	if err != nil {
+		return nil, fmt.Errorf("invalid extension %s", body.Extension)
+	}
+	salt, ok := new(big.Int).SetString(body.Order.Salt, 10)
+	if !ok {
+		return nil, fmt.Errorf("invalid salt %s", body.Order.Salt)
+	}
+	mask := new(big.Int).Sub(new(big.Int).Lsh(big.NewInt(1), 160), big.NewInt(1))
+	if new(big.Int).And(salt, mask).Cmp(new(big.Int).SetBytes(crypto.Keccak256(extension)[12:])) != 0 {
+		return nil, fmt.Errorf("salt does not match the extension hash")
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.fusionOrders[hexutil.Encode(hash)] = &fusionOrder{order: body.Order}
+	return nil, nil
+}
+
+func (s *Server) fusionStatus(orderHash string) (map[string]any, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	order, ok := s.fusionOrders[orderHash]
+	if !ok {
+		return nil, fmt.Errorf("order %s not found", orderHash)
+	}
+	order.polls++
+
+	making, _ := new(big.Int).SetString(order.order.MakingAmount, 10)
+	taking, _ := new(big.Int).SetString(order.order.TakingAmount, 10)
+	fills := []map[string]any{}
+	status := "pending"
+	if order.polls > 1 {
+		fills = append(fills, map[string]any{
+			"txHash":                   crypto.Keccak256Hash([]byte(orderHash + "1")).Hex(),
+			"filledMakerAmount":        new(big.Int).Div(making, big.NewInt(2)).String(),
+			"filledAuctionTakerAmount": new(big.Int).Div(taking, big.NewInt(2)).String(),
+		})
+	}
+	if order.polls > 2 {
+		status = "filled"
+		fills = append(fills, map[string]any{
+			"txHash":                   crypto.Keccak256Hash([]byte(orderHash + "2")).Hex(),
+			"filledMakerAmount":        new(big.Int).Sub(making, new(big.Int).Div(making, big.NewInt(2))).String(),
+			"filledAuctionTakerAmount": new(big.Int).Sub(taking, new(big.Int).Div(taking, big.NewInt(2))).String(),
+		})
+	}
+
+	return map[string]any{
+		"orderHash":        orderHash,
+		"status":           status,
+		"order":            order.order,
+		"fills":            fills,
+		"createdAt":        time.Now().UnixMilli(),
+		"auctionStartDate": time.Now().UnixMilli(),
+		"auctionDuration":  180,
+	}, nil
+}
+
+func fusionTypedData(chainId uint64, order fusionLimitOrder) apitypes.TypedData {
+	return apitypes.TypedData{
+		Types: apitypes.Types{
+			"EIP712Domain": {
+				{Name: "name", Type: "string"},
+				{Name: "version", Type: "string"},
+				{Name: "chainId", Type: "uint256"},
+				{Name: "verifyingContract", Type: "address"},
+			},
+			"Order": {
+				{Name: "salt", Type: "uint256"},
+				{Name: "maker", Type: "address"},
+				{Name: "receiver", Type: "address"},
+				{Name: "makerAsset", Type: "address"},
+				{Name: "takerAsset", Type: "address"},
+				{Name: "makingAmount", Type: "uint256"},
+				{Name: "takingAmount", Type: "uint256"},
+				{Name: "makerTraits", Type: "uint256"},
+			},
+		},
+		PrimaryType: "Order",
+		Domain: apitypes.TypedDataDomain{
+			Name:              "1inch Aggregation Router",
+			Version:           "6",
+			ChainId:           math.NewHexOrDecimal256(int64(chainId)),
+			VerifyingContract: LimitOrderAddress,
+		},
+		Message: apitypes.TypedDataMessage{
+			"salt":         order.Salt,
+			"maker":        order.Maker,
+			"receiver":     order.Receiver,
+			"makerAsset":   order.MakerAsset,
+			"takerAsset":   order.TakerAsset,
+			"makingAmount": order.MakingAmount,
+			"takingAmount": order.TakingAmount,
+			"makerTraits":  order.MakerTraits,
+		},
+	}
+}
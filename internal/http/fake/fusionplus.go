@@ -0,0 +1,364 @@
+package fake
+
+import (
+	"bytes"
+	"encoding/binary"
+	"encoding/json"
+	"fmt"
+	"math/big"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	ethcommon "github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/crypto"
+)
+
+const (
+	// EscrowFactoryAddress deploys the Fusion+ escrows on every chain.
+	EscrowFactoryAddress = "0xa7bcb4eac8964306f9e3764f67db6a7af6ddf99a"
+	// TrueERC20Address is the taker asset of every Fusion+ order.
+	TrueERC20Address = "0xda0000d4000015a526378bb6fafc650cea5966f8"
+)
+
+// fusionPlusOrder is a submitted cross chain order. fills holds the secret
+// index revealed by each fill, shared the number of secrets received so far
+// and polls the ready fills requests since the last one, the escrows of a
+// fill are deployed on the second request.
+type fusionPlusOrder struct {
+	order        fusionLimitOrder
+	srcChainId   uint64
+	dstChainId   uint64
+	dstToken     string
+	secretHashes []ethcommon.Hash
+	fills        []int
+	shared       int
+	polls        int
+}
+
+// fusionPlusMux serves the Fusion+ quoter, relayer and orders APIs.
+func (s *Server) fusionPlusMux() *http.ServeMux {
+	mux := newMux()
+	mux.HandleFunc("GET /quoter/v1.0/quote/receive", serve(func(r *http.Request) (any, error) {
+		return s.fusionPlusQuote(r.URL.Query())
+	}))
+	mux.HandleFunc("POST /relayer/v1.0/submit", serve(func(r *http.Request) (any, error) {
+		return s.fusionPlusSubmit(r)
+	}))
+	mux.HandleFunc("POST /relayer/v1.0/submit/secret", serve(func(r *http.Request) (any, error) {
+		return s.fusionPlusSecret(r)
+	}))
+	mux.HandleFunc("GET /orders/v1.0/order/ready-to-accept-secret-fills/{orderHash}", serve(func(r *http.Request) (any, error) {
+		return s.fusionPlusReadyFills(r.PathValue("orderHash"))
+	}))
+	mux.HandleFunc("GET /orders/v1.0/order/status/{orderHash}", serve(func(r *http.Request) (any, error) {
+		return s.fusionPlusStatus(r.PathValue("orderHash"))
+	}))
+	return mux
+}
+
+// fusionPlusQuote quotes at the server rate like fusionQuote, the fast and
+// medium presets fill the order at once with a single secret, the slow one
+// in two parts with three secrets.
+func (s *Server) fusionPlusQuote(q map[string][]string) (map[string]any, error) {
+	get := func(key string) string {
+		if v := q[key]; len(v) > 0 {
+			return v[0]
+		}
+		return ""
+	}
+
+	if get("srcChain") == get("dstChain") {
+		return nil, fmt.Errorf("srcChain and dstChain must differ")
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	amount, ok := new(big.Int).SetString(get("amount"), 10)
+	if !ok {
+		return nil, fmt.Errorf("invalid amount %s", get("amount"))
+	}
+	out := s.convert(amount, false)
+
+	presets := map[string]any{}
+	for name, bps := range map[string]int64{"fast": 50, "medium": 30, "slow": 10} {
+		end := new(big.Int).Mul(out, big.NewInt(10_000-bps))
+		end.Div(end, big.NewInt(10_000))
+		secrets := 1
+		if name == "slow" {
+			secrets = 3
+		}
+		presets[name] = map[string]any{
+			"auctionDuration":    180 * (60 - bps) / 10,
+			"startAuctionIn":     24,
+			"initialRateBump":    bps * 1_000,
+			"auctionStartAmount": out.String(),
+			"startAmount":        amount.String(),
+			"auctionEndAmount":   end.String(),
+			"points":             []map[string]any{{"delay": 60, "coefficient": bps * 500}},
+			"allowPartialFills":  secrets > 1,
+			"allowMultipleFills": secrets > 1,
+			"gasCost":            map[string]any{"gasBumpEstimate": 1_000, "gasPriceEstimate": "1250"},
+			"secretsCount":       secrets,
+		}
+	}
+	presets["custom"] = nil
+
+	return map[string]any{
+		"quoteId":           fmt.Sprintf("%08x-0000-4000-8000-000000000001", len(s.fusionPlusOrders)+1),
+		"srcTokenAmount":    amount.String(),
+		"dstTokenAmount":    out.String(),
+		"presets":           presets,
+		"recommendedPreset": "medium",
+		"srcEscrowFactory":  EscrowFactoryAddress,
+		"dstEscrowFactory":  EscrowFactoryAddress,
+		"srcSafetyDeposit":  "1000000000000000",
+		"dstSafetyDeposit":  "2000000000000000",
+		"timeLocks": map[string]any{
+			"srcWithdrawal":         12,
+			"srcPublicWithdrawal":   600,
+			"srcCancellation":       900,
+			"srcPublicCancellation": 1200,
+			"dstWithdrawal":         12,
+			"dstPublicWithdrawal":   300,
+			"dstCancellation":       600,
+		},
+		"whitelist": []string{FusionResolver},
+	}, nil
+}
+
+// fusionPlusSubmit checks the signature on the source chain, that the salt
+// commits to the extension and that the hashlock of the escrow data
+// matches the secret hashes, like the relayer does.
+func (s *Server) fusionPlusSubmit(r *http.Request) (any, error) {
+	var body struct {
+		Order        fusionLimitOrder `json:"order"`
+		SrcChainId   uint64           `json:"srcChainId"`
+		Signature    string           `json:"signature"`
+		Extension    string           `json:"extension"`
+		QuoteId      string           `json:"quoteId"`
+		SecretHashes []string         `json:"secretHashes"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+		return nil, fmt.Errorf("invalid body: %v", err)
+	}
+
+	hash, maker, err := recoverTypedData(fusionTypedData(body.SrcChainId, body.Order), body.Signature)
+	if err != nil {
+		return nil, err
+	}
+
+	if !strings.EqualFold(maker.Hex(), body.Order.Maker) {
+		return nil, fmt.Errorf("invalid-signature, signer: %s", maker.Hex())
+	}
+
+	if !strings.EqualFold(body.Order.TakerAsset, TrueERC20Address) {
+		return nil, fmt.Errorf("invalid taker asset %s", body.Order.TakerAsset)
+	}
+
+	extension, err := hexutil.Decode(body.Extension)
+	if err != nil || len(extension) < 32 {
+		return nil, fmt.Errorf("invalid extension %s", body.Extension)
+	}
+	salt, ok := new(big.Int).SetString(body.Order.Salt, 10)
+	if !ok {
+		return nil, fmt.Errorf("invalid salt %s", body.Order.Salt)
+	}
+	mask := new(big.Int).Sub(new(big.Int).Lsh(big.NewInt(1), 160), big.NewInt(1))
+	if new(big.Int).And(salt, mask).Cmp(new(big.Int).SetBytes(crypto.Keccak256(extension)[12:])) != 0 {
+		return nil, fmt.Errorf("salt does not match the extension hash")
+	}
+
+	// The escrow data closes the post interaction, the last extension
+	// field: hashlock, dstChainId, dstToken, deposits and time locks.
+	start := binary.BigEndian.Uint32(extension[4:8])
+	end := binary.BigEndian.Uint32(extension[0:4])
+	postInteraction := extension[32:]
+	if end > uint32(len(postInteraction)) || end < start+160 {
+		return nil, fmt.Errorf("invalid post interaction")
+	}
+	escrow := postInteraction[end-160 : end]
+	hashlock := ethcommon.BytesToHash(escrow[:32])
+	dstChainId := new(big.Int).SetBytes(escrow[32:64]).Uint64()
+	dstToken := ethcommon.BytesToAddress(escrow[64:96]).Hex()
+
+	secretHashes := []ethcommon.Hash{hashlock}
+	fills := []int{0}
+	if len(body.SecretHashes) > 0 {
+		secretHashes = nil
+		for _, h := range body.SecretHashes {
+			secretHashes = append(secretHashes, ethcommon.HexToHash(h))
+		}
+		if len(secretHashes) < 2 || merkleHashlock(secretHashes) != hashlock {
+			return nil, fmt.Errorf("hashlock does not match the secret hashes")
+		}
+		fills = []int{0, len(secretHashes) - 1}
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.fusionPlusOrders[hexutil.Encode(hash)] = &fusionPlusOrder{
+		order:        body.Order,
+		srcChainId:   body.SrcChainId,
+		dstChainId:   dstChainId,
+		dstToken:     dstToken,
+		secretHashes: secretHashes,
+		fills:        fills,
+	}
+	return nil, nil
+}
+
+func (s *Server) fusionPlusReadyFills(orderHash string) (map[string]any, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	order, ok := s.fusionPlusOrders[orderHash]
+	if !ok {
+		return nil, fmt.Errorf("order %s not found", orderHash)
+	}
+
+	fills := []map[string]any{}
+	if order.shared < len(order.fills) {
+		order.polls++
+		if order.polls > 1 {
+			fills = append(fills, map[string]any{
+				"idx":                   order.fills[order.shared],
+				"srcEscrowDeployTxHash": fusionPlusTxHash(orderHash, order.shared, "src_escrow_created"),
+				"dstEscrowDeployTxHash": fusionPlusTxHash(orderHash, order.shared, "dst_escrow_created"),
+			})
+		}
+	}
+	return map[string]any{"fills": fills}, nil
+}
+
+// fusionPlusSecret accepts the secret of the next ready fill.
+func (s *Server) fusionPlusSecret(r *http.Request) (any, error) {
+	var body struct {
+		OrderHash string `json:"orderHash"`
+		Secret    string `json:"secret"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+		return nil, fmt.Errorf("invalid body: %v", err)
+	}
+
+	secret, err := hexutil.Decode(body.Secret)
+	if err != nil {
+		return nil, fmt.Errorf("invalid secret %s", body.Secret)
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	order, ok := s.fusionPlusOrders[body.OrderHash]
+	if !ok {
+		return nil, fmt.Errorf("order %s not found", body.OrderHash)
+	}
+
+	if order.shared >= len(order.fills) || order.polls < 2 {
+		return nil, fmt.Errorf("no fill is waiting for a secret")
+	}
+
+	if crypto.Keccak256Hash(secret) != order.secretHashes[order.fills[order.shared]] {
+		return nil, fmt.Errorf("invalid secret for fill %d", order.fills[order.shared])
+	}
+	order.shared++
+	order.polls = 0
+	return nil, nil
+}
+
+// fusionPlusStatus reports a fill per shared secret, a two part order is
+// filled by halves, and the order executed once every secret is shared.
+func (s *Server) fusionPlusStatus(orderHash string) (map[string]any, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	order, ok := s.fusionPlusOrders[orderHash]
+	if !ok {
+		return nil, fmt.Errorf("order %s not found", orderHash)
+	}
+
+	making, _ := new(big.Int).SetString(order.order.MakingAmount, 10)
+	taking, _ := new(big.Int).SetString(order.order.TakingAmount, 10)
+	parts := int64(len(order.fills))
+
+	fills := []map[string]any{}
+	for i := 0; i < order.shared; i++ {
+		maker := new(big.Int).Div(making, big.NewInt(parts))
+		taker := new(big.Int).Div(taking, big.NewInt(parts))
+		if i == len(order.fills)-1 {
+			maker.Sub(making, new(big.Int).Mul(maker, big.NewInt(parts-1)))
+			taker.Sub(taking, new(big.Int).Mul(taker, big.NewInt(parts-1)))
+		}
+
+		events := []map[string]any{}
+		for _, e := range []struct{ side, action string }{
+			{"src", "src_escrow_created"}, {"dst", "dst_escrow_created"}, {"dst", "withdrawn"}, {"src", "withdrawn"},
+		} {
+			events = append(events, map[string]any{
+				"transactionHash": fusionPlusTxHash(orderHash, i, e.side+e.action),
+				"side":            e.side,
+				"action":          e.action,
+				"blockTimestamp":  time.Now().UnixMilli(),
+			})
+		}
+
+		fills = append(fills, map[string]any{
+			"status":                   "executed",
+			"txHash":                   fusionPlusTxHash(orderHash, i, "fill"),
+			"filledMakerAmount":        maker.String(),
+			"filledAuctionTakerAmount": taker.String(),
+			"escrowEvents":             events,
+		})
+	}
+
+	status := "pending"
+	if order.shared == len(order.fills) {
+		status = "executed"
+	}
+
+	return map[string]any{
+		"orderHash":  orderHash,
+		"status":     status,
+		"srcChainId": order.srcChainId,
+		"dstChainId": order.dstChainId,
+		"order":      order.order,
+		"takerAsset": order.dstToken,
+		"fills":      fills,
+		"createdAt":  time.Now().UnixMilli(),
+	}, nil
+}
+
+func fusionPlusTxHash(orderHash string, fill int, event string) string {
+	return crypto.Keccak256Hash([]byte(orderHash + strconv.Itoa(fill) + event)).Hex()
+}
+
+// merkleHashlock rebuilds the hashlock of a multiple fills order, the
+// OpenZeppelin SimpleMerkleTree root of the keccak256(uint64 idx, bytes32
+// secretHash) leaves with the secrets count minus one in its upper 16 bits.
+func merkleHashlock(secretHashes []ethcommon.Hash) ethcommon.Hash {
+	leaves := make([][]byte, len(secretHashes))
+	for i, h := range secretHashes {
+		idx := make([]byte, 8)
+		binary.BigEndian.PutUint64(idx, uint64(i))
+		leaves[i] = crypto.Keccak256(idx, h.Bytes())
+	}
+	sort.Slice(leaves, func(i, j int) bool { return bytes.Compare(leaves[i], leaves[j]) < 0 })
+
+	tree := make([][]byte, 2*len(leaves)-1)
+	for i, leaf := range leaves {
+		tree[len(tree)-1-i] = leaf
+	}
+	for i := len(tree) - 1 - len(leaves); i >= 0; i-- {
+		pair := [][]byte{tree[2*i+1], tree[2*i+2]}
+		sort.Slice(pair, func(a, b int) bool { return bytes.Compare(pair[a], pair[b]) < 0 })
+		tree[i] = crypto.Keccak256(pair[0], pair[1])
+	}
+
+	root := tree[0]
+	binary.BigEndian.PutUint16(root[:2], uint16(len(secretHashes)-1))
+	return ethcommon.BytesToHash(root)
+}
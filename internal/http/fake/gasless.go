@@ -0,0 +1,195 @@
+package fake
+
+import (
+	"encoding/json"
+	"fmt"
+	"math/big"
+	"net/http"
+	"strings"
+
+	ethcommon "github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/signer/core/apitypes"
+)
+
+// gaslessTrade is a submitted trade, polls counts the status requests so the
+// trade is submitted on the first one and confirmed afterwards.
+type gaslessTrade struct {
+	polls int
+}
+
+type gaslessSigned struct {
+	Type      string             `json:"type"`
+	EIP712    apitypes.TypedData `json:"eip712"`
+	Signature struct {
+		V             uint8  `json:"v"`
+		R             string `json:"r"`
+		S             string `json:"s"`
+		SignatureType int    `json:"signatureType"`
+	} `json:"signature"`
+}
+
+// zeroXGasless answers /gasless/price and /gasless/quote, the quote asks
+// for a permit approval and a meta-transaction trade signature. 1% of the
+// buy amount pays the relayer gas.
+func (s *Server) zeroXGasless(path string, q map[string][]string) (map[string]any, error) {
+	get := func(key string) string {
+		if v := q[key]; len(v) > 0 {
+			return v[0]
+		}
+		return ""
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	sell, ok := new(big.Int).SetString(get("sellAmount"), 10)
+	if !ok {
+		return nil, fmt.Errorf("invalid sellAmount %s", get("sellAmount"))
+	}
+	bps, ok := new(big.Int).SetString(get("slippageBps"), 10)
+	if !ok {
+		bps = big.NewInt(100)
+	}
+
+	quoted := s.convert(sell, false)
+	gasFee := new(big.Int).Div(quoted, big.NewInt(100))
+	buy := new(big.Int).Sub(quoted, gasFee)
+	minBuy := new(big.Int).Mul(buy, new(big.Int).Sub(big.NewInt(10_000), bps))
+	minBuy.Div(minBuy, big.NewInt(10_000))
+
+	res := map[string]any{
+		"allowanceTarget":    Permit2Address,
+		"blockNumber":        "58000000",
+		"buyAmount":          buy.String(),
+		"buyToken":           get("buyToken"),
+		"fees":               map[string]any{"integratorFee": nil, "zeroExFee": nil, "gasFee": map[string]any{"amount": gasFee.String(), "token": get("buyToken"), "type": "gas"}},
+		"issues":             map[string]any{"allowance": map[string]any{"actual": "0", "spender": Permit2Address}, "balance": nil, "simulationIncomplete": false, "invalidSourcesPassed": []string{}},
+		"liquidityAvailable": true,
+		"minBuyAmount":       minBuy.String(),
+		"route":              map[string]any{"fills": []map[string]any{{"from": get("sellToken"), "to": get("buyToken"), "source": "Uniswap_V3", "proportionBps": "10000"}}, "tokens": []map[string]any{}},
+		"sellAmount":         sell.String(),
+		"sellToken":          get("sellToken"),
+		"target":             SettlerAddress,
+		"zid":                "0x5f3ab2d1c4e8f6a7b9c0d1e3",
+	}
+
+	if !strings.HasSuffix(path, "/quote") {
+		return res, nil
+	}
+
+	chainId, _ := new(big.Int).SetString(get("chainId"), 10)
+	res["approval"] = map[string]any{
+		"type": "permit",
+		"hash": "0x",
+		"eip712": map[string]any{
+			"types": map[string]any{
+				"EIP712Domain": []map[string]string{
+					{"name": "name", "type": "string"},
+					{"name": "version", "type": "string"},
+					{"name": "chainId", "type": "uint256"},
+					{"name": "verifyingContract", "type": "address"},
+				},
+				"Permit": []map[string]string{
+					{"name": "owner", "type": "address"},
+					{"name": "spender", "type": "address"},
+					{"name": "value", "type": "uint256"},
+					{"name": "nonce", "type": "uint256"},
+					{"name": "deadline", "type": "uint256"},
+				},
+			},
+			"domain":      map[string]any{"name": "USD Coin", "version": "2", "chainId": chainId, "verifyingContract": get("sellToken")},
+			"message":     map[string]any{"owner": get("taker"), "spender": Permit2Address, "value": sell.String(), "nonce": "0", "deadline": "1718110000"},
+			"primaryType": "Permit",
+		},
+	}
+	res["trade"] = map[string]any{
+		"type": "settler_metatransaction",
+		"hash": "0x",
+		"eip712": map[string]any{
+			"types": map[string]any{
+				"EIP712Domain": []map[string]string{
+					{"name": "name", "type": "string"},
+					{"name": "chainId", "type": "uint256"},
+					{"name": "verifyingContract", "type": "address"},
+				},
+				"SlippageAndActions": []map[string]string{
+					{"name": "recipient", "type": "address"},
+					{"name": "buyToken", "type": "address"},
+					{"name": "minAmountOut", "type": "uint256"},
+					{"name": "actions", "type": "bytes"},
+				},
+			},
+			"domain":      map[string]any{"name": "Settler", "chainId": chainId, "verifyingContract": SettlerAddress},
+			"message":     map[string]any{"recipient": get("taker"), "buyToken": get("buyToken"), "minAmountOut": minBuy.String(), "actions": SwapCallData},
+			"primaryType": "SlippageAndActions",
+		},
+	}
+	return res, nil
+}
+
+// zeroXGaslessSubmit checks that the trade and the approval are signed by
+// the taker and returns the trade hash.
+func (s *Server) zeroXGaslessSubmit(r *http.Request) (map[string]any, error) {
+	var body struct {
+		ChainId  uint64         `json:"chainId"`
+		Approval *gaslessSigned `json:"approval"`
+		Trade    gaslessSigned  `json:"trade"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+		return nil, fmt.Errorf("invalid body: %v", err)
+	}
+
+	hash, taker, err := recoverGasless(body.Trade)
+	if err != nil {
+		return nil, err
+	}
+
+	if recipient, _ := body.Trade.EIP712.Message["recipient"].(string); !strings.EqualFold(recipient, taker.Hex()) {
+		return nil, fmt.Errorf("trade signature does not match the taker, signer: %s", taker.Hex())
+	}
+
+	if body.Approval != nil {
+		_, owner, err := recoverGasless(*body.Approval)
+		if err != nil {
+			return nil, err
+		}
+		if owner != taker {
+			return nil, fmt.Errorf("approval signature does not match the taker, signer: %s", owner.Hex())
+		}
+	}
+
+	tradeHash := crypto.Keccak256Hash(hash).Hex()
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.gaslessTrades[tradeHash] = &gaslessTrade{}
+	return map[string]any{"tradeHash": tradeHash, "type": body.Trade.Type, "zid": "0x5f3ab2d1c4e8f6a7b9c0d1e3"}, nil
+}
+
+func (s *Server) zeroXGaslessStatus(tradeHash string) (map[string]any, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	trade, ok := s.gaslessTrades[tradeHash]
+	if !ok {
+		return nil, fmt.Errorf("trade %s not found", tradeHash)
+	}
+	trade.polls++
+
+	res := map[string]any{
+		"status":       "submitted",
+		"transactions": []map[string]any{{"hash": crypto.Keccak256Hash([]byte(tradeHash)).Hex(), "timestamp": 1718110000}},
+	}
+	if trade.polls > 1 {
+		res["status"] = "confirmed"
+	}
+	return res, nil
+}
+
+func recoverGasless(payload gaslessSigned) ([]byte, ethcommon.Address, error) {
+	sig := payload.Signature
+	signature := hexutil.Encode(append(append(ethcommon.FromHex(sig.R), ethcommon.FromHex(sig.S)...), sig.V))
+	return recoverTypedData(payload.EIP712, signature)
+}
@@ -0,0 +1,103 @@
+package fake
+
+import (
+	"encoding/json"
+	"fmt"
+	"math/big"
+	"net/http"
+)
+
+// kyberSwapMux serves the KyberSwap aggregator API of every chain.
+func (s *Server) kyberSwapMux() *http.ServeMux {
+	mux := newMux()
+	mux.HandleFunc("GET /{chain}/api/v1/routes", serve(func(r *http.Request) (any, error) {
+		q := r.URL.Query()
+		return s.kyberSwapRoutes(q.Get("tokenIn"), q.Get("tokenOut"), q.Get("amountIn"))
+	}))
+	mux.HandleFunc("POST /{chain}/api/v1/route/build", serve(func(r *http.Request) (any, error) {
+		return s.kyberSwapBuild(r)
+	}))
+	return mux
+}
+
+// KyberRouterAddress is the KyberSwap meta aggregation router.
+const KyberRouterAddress = "0x6131b5fae19ea4f9d964eac0408e4408b66337b5"
+
+func (s *Server) kyberSwapRoutes(tokenIn, tokenOut, amountIn string) (map[string]any, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	in, ok := new(big.Int).SetString(amountIn, 10)
+	if !ok {
+		return nil, fmt.Errorf("invalid amountIn %s", amountIn)
+	}
+	out := s.convert(in, false)
+
+	// The input is split 60/40 over two single pool paths.
+	first := new(big.Int).Div(new(big.Int).Mul(in, big.NewInt(60)), big.NewInt(100))
+	second := new(big.Int).Sub(in, first)
+
+	return map[string]any{
+		"code":    0,
+		"message": "successfully",
+		"data": map[string]any{
+			"routeSummary": map[string]any{
+				"tokenIn":      tokenIn,
+				"amountIn":     in.String(),
+				"amountInUsd":  "1",
+				"tokenOut":     tokenOut,
+				"amountOut":    out.String(),
+				"amountOutUsd": "1",
+				"gas":          fmt.Sprint(s.gas),
+				"gasPrice":     s.gasPrice.String(),
+				"gasUsd":       "0.01",
+				"extraFee":     map[string]any{"feeAmount": "", "chargeFeeBy": "", "isInBps": false, "feeReceiver": ""},
+				"route": [][]map[string]any{
+					{{"pool": "0x45dda9cb7c25131df268515131f647d726f50608", "tokenIn": tokenIn, "tokenOut": tokenOut, "swapAmount": first.String(), "exchange": "uniswapv3", "poolType": "uniswapv3"}},
+					{{"pool": "0x6e7a5fafcec6bb1e78bae2a1f0b612012bf14827", "tokenIn": tokenIn, "tokenOut": tokenOut, "swapAmount": second.String(), "exchange": "quickswap", "poolType": "uniswap-v2"}},
+				},
+				"checksum":  "6214365133498370424",
+				"timestamp": 1718110000,
+			},
+			"routerAddress": KyberRouterAddress,
+		},
+		"requestId": "5b5ef6a4-4a0d-4c2b-9c3a-1f1c2e3d4b5a",
+	}, nil
+}
+
+func (s *Server) kyberSwapBuild(r *http.Request) (map[string]any, error) {
+	var body struct {
+		RouteSummary struct {
+			AmountIn  string `json:"amountIn"`
+			AmountOut string `json:"amountOut"`
+			Checksum  string `json:"checksum"`
+		} `json:"routeSummary"`
+		Sender            string `json:"sender"`
+		Recipient         string `json:"recipient"`
+		SlippageTolerance int    `json:"slippageTolerance"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+		return nil, fmt.Errorf("invalid body: %v", err)
+	}
+
+	if body.RouteSummary.Checksum == "" || body.Sender == "" || body.Recipient == "" {
+		return nil, fmt.Errorf("invalid route summary")
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return map[string]any{
+		"code":    0,
+		"message": "successfully",
+		"data": map[string]any{
+			"amountIn":         body.RouteSummary.AmountIn,
+			"amountOut":        body.RouteSummary.AmountOut,
+			"gas":              fmt.Sprint(s.gas),
+			"data":             SwapCallData,
+			"routerAddress":    KyberRouterAddress,
+			"transactionValue": "0",
+		},
+		"requestId": "5b5ef6a4-4a0d-4c2b-9c3a-1f1c2e3d4b5b",
+	}, nil
+}
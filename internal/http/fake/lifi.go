@@ -0,0 +1,182 @@
+package fake
+
+import (
+	"fmt"
+	"math/big"
+	"net/http"
+	"strconv"
+
+	"github.com/ethereum/go-ethereum/crypto"
+)
+
+// LiFiDiamondAddress is the LI.FI diamond executing every route.
+const LiFiDiamondAddress = "0x1231deb6f5749ef6ce6943a275a1d3e7486f4eae"
+
+// lifiMux serves the LI.FI v1 quote and status APIs.
+func (s *Server) lifiMux() *http.ServeMux {
+	mux := newMux()
+	mux.HandleFunc("GET /v1/quote", serve(func(r *http.Request) (any, error) {
+		return s.lifiQuote(r.URL.Query())
+	}))
+	mux.HandleFunc("GET /v1/status", serve(func(r *http.Request) (any, error) {
+		return s.lifiStatus(r.URL.Query().Get("txHash")), nil
+	}))
+	return mux
+}
+
+// lifiQuote collects the 0.25% integrator fee from the input in a protocol
+// step, then bridges the rest with stargate at the server rate. The bridge
+// relayer fee is paid in the transaction value and, as in the live API, the
+// top level estimate repeats the fees of the included steps.
+func (s *Server) lifiQuote(q map[string][]string) (map[string]any, error) {
+	get := func(key string) string {
+		if v := q[key]; len(v) > 0 {
+			return v[0]
+		}
+		return ""
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	amount, ok := new(big.Int).SetString(get("fromAmount"), 10)
+	if !ok {
+		return nil, fmt.Errorf("invalid fromAmount %s", get("fromAmount"))
+	}
+	slippage, err := strconv.ParseFloat(get("slippage"), 64)
+	if err != nil {
+		return nil, fmt.Errorf("invalid slippage %s", get("slippage"))
+	}
+	fromChain, _ := strconv.ParseUint(get("fromChain"), 10, 64)
+	toChain, _ := strconv.ParseUint(get("toChain"), 10, 64)
+
+	fee := new(big.Int).Div(new(big.Int).Mul(amount, big.NewInt(25)), big.NewInt(10_000))
+	bridged := new(big.Int).Sub(amount, fee)
+	out := s.convert(bridged, false)
+	minOut := new(big.Int).Mul(out, big.NewInt(int64(10_000-slippage*10_000)))
+	minOut.Div(minOut, big.NewInt(10_000))
+	relayerFee := big.NewInt(400_000_000_000_000)
+
+	fromToken := map[string]any{"address": get("fromToken"), "chainId": fromChain, "symbol": s.token(get("fromToken")).Symbol, "decimals": s.token(get("fromToken")).Decimals}
+	toToken := map[string]any{"address": get("toToken"), "chainId": toChain, "symbol": "ETH", "decimals": 18}
+	action := map[string]any{
+		"fromChainId": fromChain,
+		"toChainId":   toChain,
+		"fromToken":   fromToken,
+		"toToken":     toToken,
+		"fromAmount":  amount.String(),
+		"fromAddress": get("fromAddress"),
+		"toAddress":   get("toAddress"),
+	}
+
+	feeCost := map[string]any{
+		"name":     "LIFI Fixed Fee",
+		"token":    fromToken,
+		"amount":   fee.String(),
+		"included": true,
+	}
+	relayerCost := map[string]any{
+		"name":     "Relayer fee",
+		"token":    map[string]any{"address": "0xeeeeeeeeeeeeeeeeeeeeeeeeeeeeeeeeeeeeeeee", "chainId": fromChain, "symbol": "MATIC", "decimals": 18},
+		"amount":   relayerFee.String(),
+		"included": false,
+	}
+
+	collect := map[string]any{
+		"id":   "b7e2d5c8-fee",
+		"type": "protocol",
+		"tool": "feeCollection",
+		"action": map[string]any{
+			"fromChainId": fromChain,
+			"toChainId":   fromChain,
+			"fromToken":   fromToken,
+			"toToken":     fromToken,
+			"fromAmount":  amount.String(),
+		},
+		"estimate": map[string]any{
+			"tool":       "feeCollection",
+			"fromAmount": amount.String(),
+			"toAmount":   bridged.String(),
+			"feeCosts":   []map[string]any{feeCost},
+		},
+	}
+
+	crossAction := map[string]any{}
+	for k, v := range action {
+		crossAction[k] = v
+	}
+	crossAction["fromAmount"] = bridged.String()
+
+	cross := map[string]any{
+		"id":     "b7e2d5c8-cross",
+		"type":   "cross",
+		"tool":   "stargate",
+		"action": crossAction,
+		"estimate": map[string]any{
+			"tool":              "stargate",
+			"fromAmount":        bridged.String(),
+			"toAmount":          out.String(),
+			"toAmountMin":       minOut.String(),
+			"approvalAddress":   LiFiDiamondAddress,
+			"executionDuration": 90,
+			"feeCosts":          []map[string]any{relayerCost},
+		},
+	}
+
+	return map[string]any{
+		"id":     "b7e2d5c8-0f4a-4c1e-9a3b-5d6e7f8a9b0c",
+		"type":   "lifi",
+		"tool":   "stargate",
+		"action": action,
+		"estimate": map[string]any{
+			"tool":              "stargate",
+			"fromAmount":        amount.String(),
+			"toAmount":          out.String(),
+			"toAmountMin":       minOut.String(),
+			"approvalAddress":   LiFiDiamondAddress,
+			"executionDuration": 90.5,
+			"feeCosts":          []map[string]any{feeCost, relayerCost},
+			"gasCosts":          []map[string]any{{"type": "SEND", "price": s.gasPrice.String(), "estimate": fmt.Sprint(s.gas), "limit": fmt.Sprint(s.gas), "amount": new(big.Int).Mul(s.gasPrice, big.NewInt(s.gas)).String()}},
+		},
+		"includedSteps": []map[string]any{collect, cross},
+		"transactionRequest": map[string]any{
+			"from":     get("fromAddress"),
+			"to":       LiFiDiamondAddress,
+			"chainId":  fromChain,
+			"data":     SwapCallData,
+			"value":    fmt.Sprintf("0x%x", relayerFee),
+			"gasPrice": fmt.Sprintf("0x%x", s.gasPrice),
+			"gasLimit": fmt.Sprintf("0x%x", s.gas),
+		},
+	}, nil
+}
+
+// lifiStatus reports NOT_FOUND until the transfer is indexed, then PENDING,
+// then DONE.
+func (s *Server) lifiStatus(txHash string) map[string]any {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.lifiPolls[txHash]++
+	sending := map[string]any{"txHash": txHash, "chainId": 137, "amount": "1000000"}
+	switch s.lifiPolls[txHash] {
+	case 1:
+		return map[string]any{"status": "NOT_FOUND"}
+	case 2:
+		return map[string]any{"status": "PENDING", "substatus": "WAIT_DESTINATION_TRANSACTION", "tool": "stargate", "sending": sending}
+	}
+
+	return map[string]any{
+		"transactionId": "0x" + fmt.Sprintf("%064x", s.lifiPolls[txHash]),
+		"status":        "DONE",
+		"substatus":     "COMPLETED",
+		"tool":          "stargate",
+		"sending":       sending,
+		"receiving": map[string]any{
+			"txHash":  crypto.Keccak256Hash([]byte(txHash)).Hex(),
+			"chainId": 42161,
+			"amount":  "1995000",
+			"token":   map[string]any{"address": "0xeeeeeeeeeeeeeeeeeeeeeeeeeeeeeeeeeeeeeeee", "chainId": 42161, "symbol": "ETH", "decimals": 18},
+		},
+	}
+}
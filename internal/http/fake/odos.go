@@ -0,0 +1,162 @@
+package fake
+
+import (
+	"encoding/json"
+	"fmt"
+	"math/big"
+	"net/http"
+)
+
+// OdosRouterAddress is the Odos router v2.
+const OdosRouterAddress = "0x4e3288c9ca110bcc82bf38f09a7b425c095d92bf"
+
+// odosPath is a quote returned by /sor/quote/v2, assembled by path id.
+type odosPath struct {
+	inputs  []odosToken
+	outputs []odosToken
+}
+
+type odosToken struct {
+	TokenAddress string  `json:"tokenAddress"`
+	Amount       string  `json:"amount,omitempty"`
+	Proportion   float64 `json:"proportion,omitempty"`
+}
+
+// odosMux serves the Odos smart order routing and token APIs.
+func (s *Server) odosMux() *http.ServeMux {
+	mux := newMux()
+	mux.HandleFunc("POST /sor/quote/v2", serve(func(r *http.Request) (any, error) {
+		return s.odosQuote(r)
+	}))
+	mux.HandleFunc("POST /sor/assemble", serve(func(r *http.Request) (any, error) {
+		return s.odosAssemble(r)
+	}))
+	mux.HandleFunc("GET /info/tokens/{chainId}", serve(func(r *http.Request) (any, error) {
+		return s.odosTokens(), nil
+	}))
+	return mux
+}
+
+// odosQuote converts every input at the server rate and splits the total
+// over the outputs by proportion.
+func (s *Server) odosQuote(r *http.Request) (map[string]any, error) {
+	var body struct {
+		ChainId      uint64      `json:"chainId"`
+		InputTokens  []odosToken `json:"inputTokens"`
+		OutputTokens []odosToken `json:"outputTokens"`
+		UserAddr     string      `json:"userAddr"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+		return nil, fmt.Errorf("invalid body: %v", err)
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	total := new(big.Int)
+	var path odosPath
+	var inTokens, inAmounts []string
+	for _, in := range body.InputTokens {
+		amount, ok := new(big.Int).SetString(in.Amount, 10)
+		if !ok {
+			return nil, fmt.Errorf("invalid amount %s", in.Amount)
+		}
+		total.Add(total, s.convert(amount, false))
+		inTokens, inAmounts = append(inTokens, in.TokenAddress), append(inAmounts, in.Amount)
+		path.inputs = append(path.inputs, odosToken{TokenAddress: in.TokenAddress, Amount: in.Amount})
+	}
+
+	var outTokens, outAmounts []string
+	for _, out := range body.OutputTokens {
+		share, _ := new(big.Float).Mul(new(big.Float).SetInt(total), big.NewFloat(out.Proportion)).Int(nil)
+		outTokens, outAmounts = append(outTokens, out.TokenAddress), append(outAmounts, share.String())
+		path.outputs = append(path.outputs, odosToken{TokenAddress: out.TokenAddress, Amount: share.String()})
+	}
+
+	pathId := fmt.Sprintf("%032x", len(s.odosPaths)+1)
+	s.odosPaths[pathId] = path
+
+	gwei, _ := new(big.Float).Quo(new(big.Float).SetInt(s.gasPrice), big.NewFloat(1e9)).Float64()
+	return map[string]any{
+		"inTokens":          inTokens,
+		"outTokens":         outTokens,
+		"inAmounts":         inAmounts,
+		"outAmounts":        outAmounts,
+		"gasEstimate":       s.gas,
+		"dataGasEstimate":   0,
+		"gweiPerGas":        gwei,
+		"gasEstimateValue":  0.01,
+		"inValues":          []float64{},
+		"outValues":         []float64{},
+		"netOutValue":       1,
+		"priceImpact":       -0.05,
+		"percentDiff":       0,
+		"partnerFeePercent": 0,
+		"pathId":            pathId,
+		"blockNumber":       58000000,
+	}, nil
+}
+
+func (s *Server) odosAssemble(r *http.Request) (map[string]any, error) {
+	var body struct {
+		UserAddr string `json:"userAddr"`
+		PathId   string `json:"pathId"`
+		Simulate bool   `json:"simulate"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+		return nil, fmt.Errorf("invalid body: %v", err)
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	path, ok := s.odosPaths[body.PathId]
+	if !ok {
+		return nil, fmt.Errorf("path %s not found", body.PathId)
+	}
+
+	res := map[string]any{
+		"deprecated":       nil,
+		"blockNumber":      58000000,
+		"gasEstimate":      s.gas,
+		"gasEstimateValue": 0.01,
+		"inputTokens":      path.inputs,
+		"outputTokens":     path.outputs,
+		"netOutValue":      1,
+		"outValues":        []string{},
+		"transaction": map[string]any{
+			"gas":      s.gas,
+			"gasPrice": s.gasPrice.Int64(),
+			"value":    "0",
+			"to":       OdosRouterAddress,
+			"from":     body.UserAddr,
+			"data":     SwapCallData,
+			"nonce":    0,
+			"chainId":  137,
+		},
+		"simulation": nil,
+	}
+	if body.Simulate {
+		res["simulation"] = map[string]any{
+			"isSuccess":       true,
+			"gasEstimate":     s.gas,
+			"simulationError": nil,
+		}
+	}
+	return res, nil
+}
+
+func (s *Server) odosTokens() map[string]any {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	tokens := map[string]any{}
+	for _, t := range s.tokens {
+		address := t.Address
+		if address == "0xeeeeeeeeeeeeeeeeeeeeeeeeeeeeeeeeeeeeeeee" {
+			address = "0x0000000000000000000000000000000000000000"
+		}
+		tokens[address] = map[string]any{"name": t.Name, "symbol": t.Symbol, "decimals": t.Decimals}
+	}
+	return map[string]any{"tokenMap": tokens}
+}
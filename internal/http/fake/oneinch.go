@@ -0,0 +1,106 @@
+package fake
+
+import (
+	"fmt"
+	"math/big"
+	"net/http"
+	"strings"
+)
+
+// oneInchMux serves the 1inch swap, token and approve APIs.
+func (s *Server) oneInchMux() *http.ServeMux {
+	mux := newMux()
+	mux.HandleFunc("GET /{chainId}/quote", serve(func(r *http.Request) (any, error) {
+		q := r.URL.Query()
+		return s.oneInchQuote(q.Get("src"), q.Get("dst"), q.Get("amount"))
+	}))
+	mux.HandleFunc("GET /{chainId}/swap", serve(func(r *http.Request) (any, error) {
+		q := r.URL.Query()
+		return s.oneInchSwap(q.Get("src"), q.Get("dst"), q.Get("amount"), q.Get("from"))
+	}))
+	mux.HandleFunc("GET /{chainId}/tokens", serve(func(r *http.Request) (any, error) {
+		return s.oneInchTokens(), nil
+	}))
+	mux.HandleFunc("GET /{chainId}/approve/spender", serve(func(r *http.Request) (any, error) {
+		return map[string]any{"address": RouterAddress}, nil
+	}))
+	mux.HandleFunc("GET /{chainId}/approve/allowance", serve(func(r *http.Request) (any, error) {
+		return map[string]any{"allowance": "0"}, nil
+	}))
+	mux.HandleFunc("GET /{chainId}/approve/transaction", serve(func(r *http.Request) (any, error) {
+		q := r.URL.Query()
+		return s.oneInchApprove(q.Get("tokenAddress"), q.Get("amount"))
+	}))
+	return mux
+}
+
+func (s *Server) oneInchQuote(src, dst, amount string) (map[string]any, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	in, ok := new(big.Int).SetString(amount, 10)
+	if !ok {
+		return nil, fmt.Errorf("invalid amount %s", amount)
+	}
+
+	return map[string]any{
+		"fromToken": s.token(src),
+		"toToken":   s.token(dst),
+		"toAmount":  s.convert(in, false).String(),
+		"gas":       s.gas,
+	}, nil
+}
+
+func (s *Server) oneInchSwap(src, dst, amount, from string) (map[string]any, error) {
+	res, err := s.oneInchQuote(src, dst, amount)
+	if err != nil {
+		return nil, err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	delete(res, "gas")
+	res["tx"] = map[string]any{
+		"from":     from,
+		"to":       RouterAddress,
+		"data":     SwapCallData,
+		"value":    "0",
+		"gas":      s.gas,
+		"gasPrice": s.gasPrice.String(),
+	}
+	return res, nil
+}
+
+// oneInchApprove encodes approve(RouterAddress, amount), an unlimited
+// approval when amount is empty.
+func (s *Server) oneInchApprove(token, amount string) (map[string]any, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	value := new(big.Int).Sub(new(big.Int).Lsh(big.NewInt(1), 256), big.NewInt(1))
+	if amount != "" {
+		var ok bool
+		if value, ok = new(big.Int).SetString(amount, 10); !ok {
+			return nil, fmt.Errorf("invalid amount %s", amount)
+		}
+	}
+
+	return map[string]any{
+		"data":     fmt.Sprintf("0x095ea7b3%s%s%064x", strings.Repeat("0", 24), strings.TrimPrefix(RouterAddress, "0x"), value),
+		"gasPrice": s.gasPrice.String(),
+		"to":       token,
+		"value":    "0",
+	}, nil
+}
+
+func (s *Server) oneInchTokens() map[string]any {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	tokens := map[string]Token{}
+	for _, t := range s.tokens {
+		tokens[t.Address] = t
+	}
+	return map[string]any{"tokens": tokens}
+}
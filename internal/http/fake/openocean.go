@@ -0,0 +1,95 @@
+package fake
+
+import (
+	"fmt"
+	"math/big"
+	"net/http"
+	"strings"
+)
+
+// OpenOceanExchangeAddress is the OpenOcean exchange v2 router.
+const OpenOceanExchangeAddress = "0x6352a56caadc4f1e25cd6c75970fa768a3304e64"
+
+// openOceanMux serves the OpenOcean v3 API.
+func (s *Server) openOceanMux() *http.ServeMux {
+	mux := newMux()
+	for _, endpoint := range []string{"quote", "swap_quote"} {
+		mux.HandleFunc("GET /v3/{chainId}/"+endpoint, serve(func(r *http.Request) (any, error) {
+			return s.openOcean(r.URL.Path, r.URL.Query()), nil
+		}))
+	}
+	mux.HandleFunc("GET /v3/{chainId}/tokenList", serve(func(r *http.Request) (any, error) {
+		return s.openOceanTokens(), nil
+	}))
+	return mux
+}
+
+// openOcean answers quote and swap_quote, amount is in whole tokens and
+// gasPrice in gwei. Like the real API, invalid params are reported with a
+// 200 status and an error code in the body.
+func (s *Server) openOcean(path string, q map[string][]string) map[string]any {
+	get := func(key string) string {
+		if v := q[key]; len(v) > 0 {
+			return v[0]
+		}
+		return ""
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if get("gasPrice") == "" {
+		return map[string]any{"code": 400, "error": "Invalid params", "message": "gasPrice is required"}
+	}
+
+	in, out := s.token(get("inTokenAddress")), s.token(get("outTokenAddress"))
+	if in.Decimals == 0 {
+		in.Decimals = 18
+	}
+	if out.Decimals == 0 {
+		out.Decimals = 18
+	}
+
+	amount, ok := new(big.Float).SetString(get("amount"))
+	if !ok {
+		return map[string]any{"code": 400, "error": "Invalid params", "message": "invalid amount " + get("amount")}
+	}
+	raw, _ := amount.Mul(amount, new(big.Float).SetInt(new(big.Int).Exp(big.NewInt(10), big.NewInt(int64(in.Decimals)), nil))).Int(nil)
+	outAmount := s.convert(raw, false)
+
+	gwei, _ := new(big.Float).SetString(get("gasPrice"))
+	gasPrice, _ := gwei.Mul(gwei, big.NewFloat(1e9)).Int(nil)
+
+	data := map[string]any{
+		"inToken":      in,
+		"outToken":     out,
+		"inAmount":     raw.String(),
+		"outAmount":    outAmount.String(),
+		"estimatedGas": s.gas,
+		"dexes":        []map[string]any{{"dexIndex": 5, "dexCode": "QuickSwap", "swapAmount": raw.String()}},
+		"price_impact": "-0.01%",
+	}
+
+	if strings.HasSuffix(path, "/swap_quote") {
+		delete(data, "dexes")
+		data["from"] = get("account")
+		data["to"] = OpenOceanExchangeAddress
+		data["value"] = "0"
+		data["gasPrice"] = gasPrice.String()
+		data["data"] = SwapCallData
+		data["chainId"] = 137
+		data["minOutAmount"] = fmt.Sprint(new(big.Int).Div(new(big.Int).Mul(outAmount, big.NewInt(99)), big.NewInt(100)))
+	}
+	return map[string]any{"code": 200, "data": data}
+}
+
+func (s *Server) openOceanTokens() map[string]any {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	tokens := []map[string]any{}
+	for _, t := range s.tokens {
+		tokens = append(tokens, map[string]any{"address": t.Address, "decimals": t.Decimals, "symbol": t.Symbol, "name": t.Name})
+	}
+	return map[string]any{"code": 200, "data": tokens}
+}
@@ -0,0 +1,148 @@
+package fake
+
+import (
+	"encoding/json"
+	"fmt"
+	"math/big"
+	"net/http"
+	"strings"
+)
+
+// paraSwapMux serves the ParaSwap prices, transactions and tokens APIs.
+func (s *Server) paraSwapMux() *http.ServeMux {
+	mux := newMux()
+	mux.HandleFunc("GET /prices", serve(func(r *http.Request) (any, error) {
+		return s.paraSwapPrices(r.URL.Query())
+	}))
+	mux.HandleFunc("POST /transactions/{chainId}", serve(func(r *http.Request) (any, error) {
+		return s.paraSwapTransaction(r)
+	}))
+	mux.HandleFunc("GET /tokens/{chainId}", serve(func(r *http.Request) (any, error) {
+		return s.paraSwapTokens(), nil
+	}))
+	return mux
+}
+
+// AugustusAddress is the ParaSwap router returned as contract and token
+// transfer proxy.
+const AugustusAddress = "0x6a000f20005980200259b80c5102003040001068"
+
+func (s *Server) paraSwapPrices(q map[string][]string) (map[string]any, error) {
+	get := func(key string) string {
+		if v := q[key]; len(v) > 0 {
+			return v[0]
+		}
+		return ""
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	amount, ok := new(big.Int).SetString(get("amount"), 10)
+	if !ok {
+		return nil, fmt.Errorf("invalid amount %s", get("amount"))
+	}
+
+	if get("srcDecimals") == "" || get("destDecimals") == "" {
+		return nil, fmt.Errorf("missing token decimals")
+	}
+
+	partnerFee := 0.0
+	if bps, ok := new(big.Int).SetString(get("partnerFeeBps"), 10); ok {
+		partnerFee = float64(bps.Int64()) / 100
+	}
+
+	side := get("side")
+	src, dst := amount, s.convert(amount, false)
+	if side == "BUY" {
+		src, dst = s.convert(amount, true), amount
+	}
+
+	return map[string]any{
+		"priceRoute": map[string]any{
+			"blockNumber":  58000000,
+			"network":      137,
+			"srcToken":     get("srcToken"),
+			"srcDecimals":  json.Number(get("srcDecimals")),
+			"srcAmount":    src.String(),
+			"destToken":    get("destToken"),
+			"destDecimals": json.Number(get("destDecimals")),
+			"destAmount":   dst.String(),
+			"bestRoute": []map[string]any{{
+				"percent": 100,
+				"swaps": []map[string]any{{
+					"srcToken":     get("srcToken"),
+					"srcDecimals":  json.Number(get("srcDecimals")),
+					"destToken":    get("destToken"),
+					"destDecimals": json.Number(get("destDecimals")),
+					"swapExchanges": []map[string]any{
+						{"exchange": "UniswapV3", "srcAmount": src.String(), "destAmount": dst.String(), "percent": 100},
+					},
+				}},
+			}},
+			"gasCost":            fmt.Sprint(s.gas),
+			"gasCostUSD":         "0.01",
+			"side":               side,
+			"version":            get("version"),
+			"contractAddress":    AugustusAddress,
+			"tokenTransferProxy": AugustusAddress,
+			"contractMethod":     "swapExactAmountIn",
+			"partner":            get("partner"),
+			"partnerFee":         partnerFee,
+			"maxImpactReached":   false,
+			"hmac":               "8b9d0f6a0c0e4f5b",
+		},
+	}, nil
+}
+
+func (s *Server) paraSwapTransaction(r *http.Request) (map[string]any, error) {
+	var body struct {
+		SrcAmount   string          `json:"srcAmount"`
+		DestAmount  string          `json:"destAmount"`
+		UserAddress string          `json:"userAddress"`
+		PriceRoute  json.RawMessage `json:"priceRoute"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+		return nil, fmt.Errorf("invalid body: %v", err)
+	}
+
+	var route struct {
+		SrcAmount  string `json:"srcAmount"`
+		DestAmount string `json:"destAmount"`
+		Hmac       string `json:"hmac"`
+	}
+	if err := json.Unmarshal(body.PriceRoute, &route); err != nil || route.Hmac == "" {
+		return nil, fmt.Errorf("invalid priceRoute")
+	}
+
+	if body.SrcAmount != "" && body.SrcAmount != route.SrcAmount || body.DestAmount != "" && body.DestAmount != route.DestAmount {
+		return nil, fmt.Errorf("amounts do not match priceRoute")
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	res := map[string]any{
+		"from":     body.UserAddress,
+		"to":       AugustusAddress,
+		"value":    "0",
+		"data":     SwapCallData,
+		"gasPrice": s.gasPrice.String(),
+		"chainId":  137,
+	}
+	if !strings.Contains(r.URL.RawQuery, "ignoreChecks=true") {
+		res["gas"] = fmt.Sprint(s.gas)
+	}
+	return res, nil
+}
+
+func (s *Server) paraSwapTokens() map[string]any {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	tokens := []map[string]any{}
+	for _, t := range s.tokens {
+		tokens = append(tokens, map[string]any{"symbol": t.Symbol, "address": t.Address, "decimals": t.Decimals, "network": 137})
+	}
+	return map[string]any{"tokens": tokens}
+}
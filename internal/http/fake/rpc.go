@@ -0,0 +1,131 @@
+package fake
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+)
+
+// RPCHandler answers a JSON-RPC method, a returned error is sent as a
+// JSON-RPC error with code -32000.
+type RPCHandler func(params []json.RawMessage) (any, error)
+
+type rpcRequest struct {
+	ID     json.RawMessage   `json:"id"`
+	Method string            `json:"method"`
+	Params []json.RawMessage `json:"params"`
+}
+
+type rpcError struct {
+	Code    int    `json:"code"`
+	Message string `json:"message"`
+	Data    string `json:"data,omitempty"`
+}
+
+type rpcResponse struct {
+	JSONRPC string          `json:"jsonrpc"`
+	ID      json.RawMessage `json:"id"`
+	Result  any             `json:"result,omitempty"`
+	Error   *rpcError       `json:"error,omitempty"`
+}
+
+// RevertError is returned by an RPCHandler to answer with an execution
+// reverted error carrying Data.
+type RevertError struct {
+	Message string
+	Data    string
+}
+
+func (e *RevertError) Error() string {
+	return e.Message
+}
+
+// RPC is an in-process JSON-RPC node answering the methods registered with
+// Handle, batches included.
+type RPC struct {
+	*httptest.Server
+
+	mu       sync.Mutex
+	handlers map[string]RPCHandler
+	calls    map[string]int
+}
+
+func NewRPC() *RPC {
+	r := &RPC{
+		handlers: map[string]RPCHandler{},
+		calls:    map[string]int{},
+	}
+	r.Server = httptest.NewServer(http.HandlerFunc(r.handle))
+	return r
+}
+
+// Handle registers handler for method, replacing any previous one.
+func (r *RPC) Handle(method string, handler RPCHandler) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.handlers[method] = handler
+}
+
+// Calls returns the number of requests received for method.
+func (r *RPC) Calls(method string) int {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.calls[method]
+}
+
+func (r *RPC) handle(w http.ResponseWriter, req *http.Request) {
+	var raw json.RawMessage
+	if err := json.NewDecoder(req.Body).Decode(&raw); err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	if len(raw) > 0 && raw[0] == '[' {
+		var batch []rpcRequest
+		if err := json.Unmarshal(raw, &batch); err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+
+		res := make([]rpcResponse, 0, len(batch))
+		for _, call := range batch {
+			res = append(res, r.call(call))
+		}
+		_ = json.NewEncoder(w).Encode(res)
+		return
+	}
+
+	var call rpcRequest
+	if err := json.Unmarshal(raw, &call); err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+	_ = json.NewEncoder(w).Encode(r.call(call))
+}
+
+func (r *RPC) call(call rpcRequest) rpcResponse {
+	r.mu.Lock()
+	r.calls[call.Method]++
+	handler, ok := r.handlers[call.Method]
+	r.mu.Unlock()
+
+	res := rpcResponse{JSONRPC: "2.0", ID: call.ID}
+	if !ok {
+		res.Error = &rpcError{Code: -32601, Message: "the method " + call.Method + " does not exist/is not available"}
+		return res
+	}
+
+	result, err := handler(call.Params)
+	if revert, ok := err.(*RevertError); ok {
+		res.Error = &rpcError{Code: 3, Message: revert.Message, Data: revert.Data}
+		return res
+	}
+	if err != nil {
+		res.Error = &rpcError{Code: -32000, Message: err.Error()}
+		return res
+	}
+	res.Result = result
+	return res
+}
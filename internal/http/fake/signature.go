@@ -0,0 +1,31 @@
+package fake
+
+import (
+	"fmt"
+
+	ethcommon "github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/signer/core/apitypes"
+)
+
+// recoverTypedData returns the EIP-712 hash of data and the address that
+// produced signature over it, v being 27 or 28.
+func recoverTypedData(data apitypes.TypedData, signature string) ([]byte, ethcommon.Address, error) {
+	hash, _, err := apitypes.TypedDataAndHash(data)
+	if err != nil {
+		return nil, ethcommon.Address{}, fmt.Errorf("invalid typed data: %v", err)
+	}
+
+	sig, err := hexutil.Decode(signature)
+	if err != nil || len(sig) != 65 || sig[64] < 27 {
+		return nil, ethcommon.Address{}, fmt.Errorf("invalid signature %s", signature)
+	}
+	sig[64] -= 27
+
+	pub, err := crypto.SigToPub(hash, sig)
+	if err != nil {
+		return nil, ethcommon.Address{}, fmt.Errorf("invalid signature: %v", err)
+	}
+	return hash, crypto.PubkeyToAddress(*pub), nil
+}
@@ -0,0 +1,184 @@
+package fake
+
+import (
+	"fmt"
+	"math/big"
+	"net/http"
+	"strings"
+)
+
+// zeroXMux serves the 0x v1 swap, v2 permit2 and allowance holder swap and
+// gasless APIs.
+func (s *Server) zeroXMux() *http.ServeMux {
+	mux := newMux()
+	mux.HandleFunc("GET /swap/v1/price", serve(func(r *http.Request) (any, error) {
+		q := r.URL.Query()
+		return s.zeroX(q.Get("sellToken"), q.Get("buyToken"), q.Get("sellAmount"), q.Get("buyAmount"), false)
+	}))
+	mux.HandleFunc("GET /swap/v1/quote", serve(func(r *http.Request) (any, error) {
+		q := r.URL.Query()
+		return s.zeroX(q.Get("sellToken"), q.Get("buyToken"), q.Get("sellAmount"), q.Get("buyAmount"), true)
+	}))
+	for _, mode := range []string{"permit2", "allowance-holder"} {
+		for _, endpoint := range []string{"price", "quote"} {
+			mux.HandleFunc("GET /swap/"+mode+"/"+endpoint, serve(func(r *http.Request) (any, error) {
+				q := r.URL.Query()
+				return s.zeroXV2(r.URL.Path, q.Get("sellToken"), q.Get("buyToken"), q.Get("sellAmount"), q.Get("slippageBps"))
+			}))
+		}
+	}
+	for _, endpoint := range []string{"price", "quote"} {
+		mux.HandleFunc("GET /gasless/"+endpoint, serve(func(r *http.Request) (any, error) {
+			return s.zeroXGasless(r.URL.Path, r.URL.Query())
+		}))
+	}
+	mux.HandleFunc("POST /gasless/submit", serve(func(r *http.Request) (any, error) {
+		return s.zeroXGaslessSubmit(r)
+	}))
+	mux.HandleFunc("GET /gasless/status/{tradeHash}", serve(func(r *http.Request) (any, error) {
+		return s.zeroXGaslessStatus(r.PathValue("tradeHash"))
+	}))
+	return mux
+}
+
+func (s *Server) zeroX(sellToken, buyToken, sellAmount, buyAmount string, withTx bool) (map[string]any, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var sell, buy *big.Int
+	if sellAmount != "" {
+		amount, ok := new(big.Int).SetString(sellAmount, 10)
+		if !ok {
+			return nil, fmt.Errorf("invalid sellAmount %s", sellAmount)
+		}
+		sell, buy = amount, s.convert(amount, false)
+	} else {
+		amount, ok := new(big.Int).SetString(buyAmount, 10)
+		if !ok {
+			return nil, fmt.Errorf("invalid buyAmount %s", buyAmount)
+		}
+		sell, buy = s.convert(amount, true), amount
+	}
+
+	res := map[string]any{
+		"chainId":          137,
+		"price":            s.rate.FloatString(6),
+		"buyAmount":        buy.String(),
+		"sellAmount":       sell.String(),
+		"buyTokenAddress":  buyToken,
+		"sellTokenAddress": sellToken,
+		"allowanceTarget":  ExchangeAddress,
+		"gas":              fmt.Sprint(s.gas),
+		"estimatedGas":     fmt.Sprint(s.gas),
+		"gasPrice":         s.gasPrice.String(),
+		"value":            "0",
+	}
+	if withTx {
+		res["to"] = ExchangeAddress
+		res["data"] = SwapCallData
+		res["guaranteedPrice"] = s.rate.FloatString(6)
+	}
+	return res, nil
+}
+
+func (s *Server) zeroXV2(path, sellToken, buyToken, sellAmount, slippageBps string) (map[string]any, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	sell, ok := new(big.Int).SetString(sellAmount, 10)
+	if !ok {
+		return nil, fmt.Errorf("invalid sellAmount %s", sellAmount)
+	}
+	bps, ok := new(big.Int).SetString(slippageBps, 10)
+	if !ok {
+		bps = big.NewInt(100)
+	}
+
+	buy := s.convert(sell, false)
+	minBuy := new(big.Int).Mul(buy, new(big.Int).Sub(big.NewInt(10_000), bps))
+	minBuy.Div(minBuy, big.NewInt(10_000))
+
+	permit2 := strings.Contains(path, "/swap/permit2/")
+	spender := SettlerAddress
+	if permit2 {
+		spender = Permit2Address
+	}
+
+	res := map[string]any{
+		"allowanceTarget":    spender,
+		"blockNumber":        "58000000",
+		"buyAmount":          buy.String(),
+		"buyToken":           buyToken,
+		"gas":                fmt.Sprint(s.gas),
+		"gasPrice":           s.gasPrice.String(),
+		"liquidityAvailable": true,
+		"minBuyAmount":       minBuy.String(),
+		"sellAmount":         sell.String(),
+		"sellToken":          sellToken,
+		"totalNetworkFee":    new(big.Int).Mul(big.NewInt(s.gas), s.gasPrice).String(),
+		"issues": map[string]any{
+			"allowance":            map[string]any{"actual": "0", "spender": spender},
+			"balance":              nil,
+			"simulationIncomplete": false,
+			"invalidSourcesPassed": []string{},
+		},
+		"route": map[string]any{
+			"fills":  []map[string]any{{"from": sellToken, "to": buyToken, "source": "Uniswap_V3", "proportionBps": "10000"}},
+			"tokens": []map[string]any{},
+		},
+		"zid": "0x5f3ab2d1c4e8f6a7b9c0d1e2",
+	}
+
+	if !strings.HasSuffix(path, "/quote") {
+		return res, nil
+	}
+
+	delete(res, "gas")
+	delete(res, "gasPrice")
+	res["transaction"] = map[string]any{
+		"to":       SettlerAddress,
+		"data":     SwapCallData,
+		"gas":      fmt.Sprint(s.gas),
+		"gasPrice": s.gasPrice.String(),
+		"value":    "0",
+	}
+
+	if permit2 {
+		res["permit2"] = map[string]any{
+			"type": "Permit2",
+			"hash": "0x0d1b9f2c3a4e5f60718293a4b5c6d7e8f90a1b2c3d4e5f60718293a4b5c6d7e8",
+			"eip712": map[string]any{
+				"types": map[string]any{
+					"EIP712Domain": []map[string]string{
+						{"name": "name", "type": "string"},
+						{"name": "chainId", "type": "uint256"},
+						{"name": "verifyingContract", "type": "address"},
+					},
+					"PermitTransferFrom": []map[string]string{
+						{"name": "permitted", "type": "TokenPermissions"},
+						{"name": "spender", "type": "address"},
+						{"name": "nonce", "type": "uint256"},
+						{"name": "deadline", "type": "uint256"},
+					},
+					"TokenPermissions": []map[string]string{
+						{"name": "token", "type": "address"},
+						{"name": "amount", "type": "uint256"},
+					},
+				},
+				"domain": map[string]any{
+					"name":              "Permit2",
+					"chainId":           137,
+					"verifyingContract": Permit2Address,
+				},
+				"message": map[string]any{
+					"permitted": map[string]any{"token": sellToken, "amount": sell.String()},
+					"spender":   SettlerAddress,
+					"nonce":     "2241959297937691820908574931991586",
+					"deadline":  "1718110000",
+				},
+				"primaryType": "PermitTransferFrom",
+			},
+		}
+	}
+	return res, nil
+}
@@ -0,0 +1,438 @@
+package kyberswap
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"math/big"
+	uri "net/url"
+	"strconv"
+	"strings"
+
+	"github.com/onmetahq/go-evm/internal/http/common"
+	metahttp "github.com/onmetahq/meta-http/pkg/meta_http"
+)
+
+// DefaultChainSlugs maps chain ids to the chain slugs of the KyberSwap
+// aggregator API.
+var DefaultChainSlugs = map[uint64]string{
+	1:      "ethereum",
+	10:     "optimism",
+	56:     "bsc",
+	137:    "polygon",
+	250:    "fantom",
+	324:    "zksync",
+	1101:   "polygon-zkevm",
+	5000:   "mantle",
+	8453:   "base",
+	42161:  "arbitrum",
+	43114:  "avalanche",
+	59144:  "linea",
+	81457:  "blast",
+	534352: "scroll",
+}
+
+// KyberSwapOptions are the QuoteReq.Options only understood by KyberSwap.
+type KyberSwapOptions struct {
+	// SaveGas prefers routes with a lower gas cost over a higher output.
+	SaveGas bool
+	// ChargeFeeByInput takes FeeBps from the input instead of the output.
+	ChargeFeeByInput bool
+}
+
+func (KyberSwapOptions) Provider() string {
+	return "kyberswap"
+}
+
+type kyberSwap struct {
+	client       metahttp.Requests
+	chainSlugMap map[uint64]string
+	clientId     string
+}
+
+type Option func(*kyberSwap)
+
+// WithClientId sets the x-client-id header identifying the integrator.
+func WithClientId(clientId string) Option {
+	return func(o *kyberSwap) {
+		o.clientId = clientId
+	}
+}
+
+// NewKyberSwap returns a KyberSwap provider, client must be configured with
+// the aggregator API base url, e.g. https://aggregator-api.kyberswap.com.
+// chainSlugMap maps a chain id to its slug, DefaultChainSlugs when nil.
+func NewKyberSwap(client metahttp.Requests, chainSlugMap map[uint64]string, opts ...Option) *kyberSwap {
+	if chainSlugMap == nil {
+		chainSlugMap = DefaultChainSlugs
+	}
+
+	o := &kyberSwap{
+		client:       client,
+		chainSlugMap: chainSlugMap,
+	}
+
+	for _, opt := range opts {
+		opt(o)
+	}
+	return o
+}
+
+var _ common.Aggregator = (*kyberSwap)(nil)
+
+func (o *kyberSwap) FetchSupportedTokens(ctx context.Context, chainId uint64) ([]common.Token, error) {
+	return []common.Token{}, fmt.Errorf("operation token list is not supported by kyberswap, err: %w", common.ErrUnsupportedParameter)
+}
+
+func (o *kyberSwap) FetchExactInQuote(ctx context.Context, req common.QuoteReq) (common.QuoteRes, error) {
+	slippage, err := req.Slippage()
+	if err != nil {
+		return common.QuoteRes{}, err
+	}
+
+	res, err := o.FetchRoute(ctx, req)
+	if err != nil {
+		return common.QuoteRes{}, err
+	}
+	return parseKyberSwapQuote(req, res.RouteSummary, slippage)
+}
+
+// FetchExactOutQuote is not supported, KyberSwap only sells exact amounts.
+func (o *kyberSwap) FetchExactOutQuote(ctx context.Context, req common.QuoteReq) (common.QuoteRes, error) {
+	return common.QuoteRes{}, fmt.Errorf("operation exact out is not supported by kyberswap, err: %w", common.ErrUnsupportedParameter)
+}
+
+func (o *kyberSwap) FetchExactInSwapCallData(ctx context.Context, req common.QuoteReq) (common.SwapTx, error) {
+	route, err := o.FetchRoute(ctx, req)
+	if err != nil {
+		return common.SwapTx{}, err
+	}
+	return o.BuildRoute(ctx, req, route)
+}
+
+// FetchExactOutSwapCallData is not supported, KyberSwap only sells exact
+// amounts.
+func (o *kyberSwap) FetchExactOutSwapCallData(ctx context.Context, req common.QuoteReq) (common.SwapTx, error) {
+	return common.SwapTx{}, fmt.Errorf("operation exact out is not supported by kyberswap, err: %w", common.ErrUnsupportedParameter)
+}
+
+// FetchRoute returns the best route for req, its RouteSummary is passed
+// unchanged to BuildRoute.
+func (o *kyberSwap) FetchRoute(ctx context.Context, req common.QuoteReq) (KyberSwapRouteData, error) {
+	slug, ok := o.chainSlugMap[req.ChainId]
+	if !ok {
+		return KyberSwapRouteData{}, fmt.Errorf("unsupported chainId %d, err: %w", req.ChainId, common.ErrUnsupportedChain)
+	}
+
+	if req.FeeBps > 0 && req.Referrer == "" {
+		return KyberSwapRouteData{}, fmt.Errorf("kyberswap fee requires a referrer to receive it")
+	}
+
+	v := uri.Values{}
+	v.Add("tokenIn", req.Src)
+	v.Add("tokenOut", req.Dst)
+	v.Add("amountIn", req.Amount.String())
+	v.Add("gasInclude", "true")
+
+	if req.GasPrice != nil {
+		v.Add("gasPrice", req.GasPrice.String())
+	}
+
+	if len(req.IncludedSources) > 0 {
+		v.Add("includedSources", strings.Join(req.IncludedSources, ","))
+	}
+
+	if len(req.ExcludedSources) > 0 {
+		v.Add("excludedSources", strings.Join(req.ExcludedSources, ","))
+	}
+
+	opts, err := common.OptionsOf[KyberSwapOptions](req)
+	if err != nil {
+		return KyberSwapRouteData{}, err
+	}
+
+	if req.FeeBps > 0 {
+		chargeFeeBy := "currency_out"
+		if opts.ChargeFeeByInput {
+			chargeFeeBy = "currency_in"
+		}
+		v.Add("feeAmount", strconv.FormatUint(uint64(req.FeeBps), 10))
+		v.Add("isInBps", "true")
+		v.Add("chargeFeeBy", chargeFeeBy)
+		v.Add("feeReceiver", req.Referrer)
+	}
+
+	if opts.SaveGas {
+		v.Add("saveGas", "true")
+	}
+
+	var res KyberSwapRouteResponse
+	url := fmt.Sprintf("/%s/api/v1/routes?%s", slug, v.Encode())
+	_, err = o.client.Get(ctx, url, o.headers(), &res)
+	if err != nil {
+		return KyberSwapRouteData{}, fmt.Errorf("unable to fetch kyberswap route, err: %w", parseError(err))
+	}
+
+	if res.Code != 0 {
+		return KyberSwapRouteData{}, fmt.Errorf("unable to fetch kyberswap route, err: %w",
+			common.NewProviderError("kyberswap", 0, strconv.Itoa(res.Code), res.Message, nil))
+	}
+
+	var summary KyberSwapRouteSummary
+	if err := json.Unmarshal(res.Data.RouteSummary, &summary); err != nil {
+		return KyberSwapRouteData{}, fmt.Errorf("invalid route summary from kyberswap, err: %w", err)
+	}
+	summary.Raw = res.Data.RouteSummary
+
+	return KyberSwapRouteData{
+		RouteSummary:  summary,
+		RouterAddress: res.Data.RouterAddress,
+	}, nil
+}
+
+// BuildRoute builds the swap transaction of route, a route returned by
+// FetchRoute for the same req.
+func (o *kyberSwap) BuildRoute(ctx context.Context, req common.QuoteReq, route KyberSwapRouteData) (common.SwapTx, error) {
+	slippage, err := req.Slippage()
+	if err != nil {
+		return common.SwapTx{}, err
+	}
+
+	slug, ok := o.chainSlugMap[req.ChainId]
+	if !ok {
+		return common.SwapTx{}, fmt.Errorf("unsupported chainId %d, err: %w", req.ChainId, common.ErrUnsupportedChain)
+	}
+
+	recipient := req.From
+	if req.Receiver != "" {
+		recipient = req.Receiver
+	}
+
+	body := KyberSwapBuildReq{
+		RouteSummary:        route.RouteSummary.Raw,
+		Sender:              req.From,
+		Recipient:           recipient,
+		SlippageTolerance:   slippage,
+		SkipSimulateTx:      req.SkipValidation,
+		EnableGasEstimation: !req.SkipValidation,
+		Source:              o.clientId,
+	}
+
+	var res KyberSwapBuildResponse
+	url := fmt.Sprintf("/%s/api/v1/route/build", slug)
+	_, err = o.client.Post(ctx, url, o.headers(), body, &res)
+	if err != nil {
+		return common.SwapTx{}, fmt.Errorf("unable to build kyberswap route, err: %w", parseError(err))
+	}
+
+	if res.Code != 0 {
+		return common.SwapTx{}, fmt.Errorf("unable to build kyberswap route, err: %w",
+			common.NewProviderError("kyberswap", 0, strconv.Itoa(res.Code), res.Message, nil))
+	}
+	return parseKyberSwapBuild(req, route, res.Data, slippage)
+}
+
+func (o *kyberSwap) headers() map[string]string {
+	if o.clientId == "" {
+		return map[string]string{}
+	}
+	return map[string]string{
+		"x-client-id": o.clientId,
+	}
+}
+
+type KyberSwapErrorResponse struct {
+	Code      int    `json:"code"`
+	Message   string `json:"message"`
+	RequestId string `json:"requestId"`
+}
+
+// parseError classifies a failed KyberSwap call from its error payload, e.g.
+// {"code":4008,"message":"route not found","requestId":"..."}.
+func parseError(err error) error {
+	status, body, ok := common.StatusAndBody(err)
+	if !ok {
+		return common.NewProviderError("kyberswap", 0, "", "", err)
+	}
+
+	var payload KyberSwapErrorResponse
+	if json.Unmarshal(body, &payload) != nil || payload.Message == "" {
+		return common.NewProviderError("kyberswap", status, "", string(body), err)
+	}
+	return common.NewProviderError("kyberswap", status, strconv.Itoa(payload.Code), payload.Message, err)
+}
+
+type KyberSwapExtraFee struct {
+	FeeAmount   string `json:"feeAmount"`
+	ChargeFeeBy string `json:"chargeFeeBy"`
+	IsInBps     bool   `json:"isInBps"`
+	FeeReceiver string `json:"feeReceiver"`
+}
+
+type KyberSwapPool struct {
+	Pool       string `json:"pool"`
+	TokenIn    string `json:"tokenIn"`
+	TokenOut   string `json:"tokenOut"`
+	SwapAmount string `json:"swapAmount"`
+	AmountOut  string `json:"amountOut"`
+	Exchange   string `json:"exchange"`
+	PoolType   string `json:"poolType"`
+}
+
+type KyberSwapRouteSummary struct {
+	TokenIn      string            `json:"tokenIn"`
+	AmountIn     string            `json:"amountIn"`
+	AmountInUsd  string            `json:"amountInUsd"`
+	TokenOut     string            `json:"tokenOut"`
+	AmountOut    string            `json:"amountOut"`
+	AmountOutUsd string            `json:"amountOutUsd"`
+	Gas          string            `json:"gas"`
+	GasPrice     string            `json:"gasPrice"`
+	GasUsd       string            `json:"gasUsd"`
+	ExtraFee     KyberSwapExtraFee `json:"extraFee"`
+	Route        [][]KyberSwapPool `json:"route"`
+	// Raw is the routeSummary as returned by /routes, it is sent back
+	// unchanged to /route/build.
+	Raw json.RawMessage `json:"-"`
+}
+
+type KyberSwapRouteData struct {
+	RouteSummary  KyberSwapRouteSummary `json:"routeSummary"`
+	RouterAddress string                `json:"routerAddress"`
+}
+
+type KyberSwapRouteResponse struct {
+	Code    int    `json:"code"`
+	Message string `json:"message"`
+	Data    struct {
+		RouteSummary  json.RawMessage `json:"routeSummary"`
+		RouterAddress string          `json:"routerAddress"`
+	} `json:"data"`
+	RequestId string `json:"requestId"`
+}
+
+type KyberSwapBuildReq struct {
+	RouteSummary        json.RawMessage `json:"routeSummary"`
+	Sender              string          `json:"sender"`
+	Recipient           string          `json:"recipient"`
+	SlippageTolerance   uint32          `json:"slippageTolerance"`
+	SkipSimulateTx      bool            `json:"skipSimulateTx"`
+	EnableGasEstimation bool            `json:"enableGasEstimation"`
+	Source              string          `json:"source,omitempty"`
+}
+
+type KyberSwapBuildData struct {
+	AmountIn         string `json:"amountIn"`
+	AmountOut        string `json:"amountOut"`
+	Gas              string `json:"gas"`
+	Data             string `json:"data"`
+	RouterAddress    string `json:"routerAddress"`
+	TransactionValue string `json:"transactionValue"`
+}
+
+type KyberSwapBuildResponse struct {
+	Code      int                `json:"code"`
+	Message   string             `json:"message"`
+	Data      KyberSwapBuildData `json:"data"`
+	RequestId string             `json:"requestId"`
+}
+
+// parseRoutes converts the route of a summary, a list of paths made of
+// pools, into one hop per pool with the pool's share of the input.
+func parseRoutes(summary KyberSwapRouteSummary) []common.Route {
+	total, _ := common.ParseBigInt(summary.AmountIn)
+
+	routes := make([]common.Route, 0, len(summary.Route))
+	for _, path := range summary.Route {
+		hops := make([][]common.RoutePart, 0, len(path))
+		for i, pool := range path {
+			part := 100.0
+			if i == 0 && total != nil && total.Sign() > 0 {
+				if amount, ok := common.ParseBigInt(pool.SwapAmount); ok {
+					part, _ = new(big.Rat).SetFrac(new(big.Int).Mul(amount, big.NewInt(100)), total).Float64()
+				}
+			}
+			hops = append(hops, []common.RoutePart{{
+				Protocol:  pool.Exchange,
+				Part:      part,
+				FromToken: pool.TokenIn,
+				ToToken:   pool.TokenOut,
+			}})
+		}
+		routes = append(routes, common.Route{Hops: hops})
+	}
+	return routes
+}
+
+func parseKyberSwapQuote(req common.QuoteReq, summary KyberSwapRouteSummary, slippage uint32) (common.QuoteRes, error) {
+	outAmount, ok := common.ParseBigInt(summary.AmountOut)
+	if !ok {
+		return common.QuoteRes{}, fmt.Errorf("invalid out amount from kyberswap, amount: %v", summary.AmountOut)
+	}
+
+	gas, ok := common.ParseBigInt(summary.Gas)
+	if !ok {
+		return common.QuoteRes{}, fmt.Errorf("invalid gas from kyberswap, gas: %v", summary.Gas)
+	}
+
+	gasPrice, ok := common.ParseBigInt(summary.GasPrice)
+	if !ok {
+		return common.QuoteRes{}, fmt.Errorf("invalid gas price from kyberswap, gasPrice: %v", summary.GasPrice)
+	}
+
+	return common.QuoteRes{
+		ChainId:     req.ChainId,
+		Src:         req.Src,
+		Dst:         req.Dst,
+		FromAmount:  req.Amount,
+		ToAmount:    outAmount,
+		Gas:         gas,
+		GasPrice:    gasPrice,
+		MinToAmount: common.MinReceived(outAmount, slippage),
+		Routes:      parseRoutes(summary),
+	}, nil
+}
+
+func parseKyberSwapBuild(req common.QuoteReq, route KyberSwapRouteData, build KyberSwapBuildData, slippage uint32) (common.SwapTx, error) {
+	inAmount, ok := common.ParseBigInt(build.AmountIn)
+	if !ok {
+		return common.SwapTx{}, fmt.Errorf("invalid in amount from kyberswap, amount: %v", build.AmountIn)
+	}
+
+	outAmount, ok := common.ParseBigInt(build.AmountOut)
+	if !ok {
+		return common.SwapTx{}, fmt.Errorf("invalid out amount from kyberswap, amount: %v", build.AmountOut)
+	}
+
+	value, ok := common.ParseBigInt(build.TransactionValue)
+	if !ok {
+		return common.SwapTx{}, fmt.Errorf("invalid tx value from kyberswap, value: %v", build.TransactionValue)
+	}
+
+	gas, ok := common.ParseBigInt(build.Gas)
+	if !ok {
+		return common.SwapTx{}, fmt.Errorf("invalid gas from kyberswap, gas: %v", build.Gas)
+	}
+
+	gasPrice, ok := common.ParseBigInt(route.RouteSummary.GasPrice)
+	if !ok {
+		return common.SwapTx{}, fmt.Errorf("invalid gas price from kyberswap, gasPrice: %v", route.RouteSummary.GasPrice)
+	}
+
+	return common.SwapTx{
+		ChainId:         req.ChainId,
+		Src:             req.Src,
+		Dst:             req.Dst,
+		FromAmount:      inAmount,
+		ToAmount:        outAmount,
+		MinToAmount:     common.MinReceived(outAmount, slippage),
+		From:            req.From,
+		To:              build.RouterAddress,
+		Data:            build.Data,
+		Value:           value,
+		Gas:             gas,
+		GasPrice:        gasPrice,
+		AllowanceTarget: build.RouterAddress,
+		Routes:          parseRoutes(route.RouteSummary),
+	}, nil
+}
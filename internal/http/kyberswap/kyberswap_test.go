@@ -0,0 +1,113 @@
+package kyberswap
+
+import (
+	"context"
+	"errors"
+	"math/big"
+	"net/http"
+	"testing"
+
+	"github.com/onmetahq/go-evm/internal/http/common"
+	"github.com/onmetahq/go-evm/internal/http/fake"
+)
+
+const TOKENA = "0xeeeeeeeeeeeeeeeeeeeeeeeeeeeeeeeeeeeeeeee"
+const TOKENB = "0x2791bca1f2de4661ed88a30c99a7a9449aa84174"
+const FROM = "0x15Ba05723b04785C3E21157171810892A4FB795c"
+
+func TestFetchExactInQuote(t *testing.T) {
+	server := fake.NewServer()
+	defer server.Close()
+
+	kyberClient := NewKyberSwap(common.NewClient(server.URL+fake.KyberSwapPrefix, nil), nil)
+	req := common.QuoteReq{
+		ChainId:     137,
+		Src:         TOKENB,
+		Dst:         TOKENA,
+		Amount:      big.NewInt(1000000),
+		SlippageBps: common.Bps(50),
+		Options:     []common.ProviderOptions{KyberSwapOptions{SaveGas: true}},
+	}
+
+	res, err := kyberClient.FetchExactInQuote(context.Background(), req)
+	if err != nil {
+		t.Fatalf("quote err: %v", err)
+	}
+
+	if res.ToAmount.Cmp(big.NewInt(2000000)) != 0 || res.MinToAmount.Cmp(big.NewInt(1990000)) != 0 {
+		t.Fatalf("invalid amounts, out: %s, min: %s", res.ToAmount, res.MinToAmount)
+	}
+
+	if len(res.Routes) != 2 || res.Routes[0].Hops[0][0].Part != 60 || res.Routes[1].Hops[0][0].Protocol != "quickswap" {
+		t.Fatalf("invalid routes, routes: %+v", res.Routes)
+	}
+
+	if calls := server.Calls("/polygon/api/v1/routes"); calls != 1 {
+		t.Fatalf("expected the polygon slug, calls: %d", calls)
+	}
+}
+
+func TestFetchExactInSwapCallData(t *testing.T) {
+	server := fake.NewServer()
+	defer server.Close()
+
+	kyberClient := NewKyberSwap(common.NewClient(server.URL+fake.KyberSwapPrefix, nil), nil, WithClientId("go-evm"))
+	req := common.QuoteReq{
+		ChainId:         137,
+		Src:             TOKENB,
+		Dst:             TOKENA,
+		Amount:          big.NewInt(1000000),
+		From:            FROM,
+		Referrer:        FROM,
+		FeeBps:          10,
+		IncludedSources: []string{"uniswapv3", "quickswap"},
+	}
+
+	res, err := kyberClient.FetchExactInSwapCallData(context.Background(), req)
+	if err != nil {
+		t.Fatalf("swap err: %v", err)
+	}
+
+	if res.FromAmount.Cmp(req.Amount) != 0 || res.ToAmount.Cmp(big.NewInt(2000000)) != 0 {
+		t.Fatalf("invalid amounts, in: %s, out: %s", res.FromAmount, res.ToAmount)
+	}
+
+	if res.To != fake.KyberRouterAddress || res.AllowanceTarget != fake.KyberRouterAddress || res.Data != fake.SwapCallData {
+		t.Fatalf("invalid tx, res: %+v", res)
+	}
+
+	req.Referrer = ""
+	if _, err := kyberClient.FetchExactInSwapCallData(context.Background(), req); err == nil {
+		t.Fatalf("expected fee without referrer to fail")
+	}
+}
+
+func TestErrorPaths(t *testing.T) {
+	server := fake.NewServer()
+	defer server.Close()
+
+	kyberClient := NewKyberSwap(common.NewClient(server.URL+fake.KyberSwapPrefix, nil), map[uint64]string{137: "polygon"})
+	req := common.QuoteReq{
+		ChainId: 1,
+		Src:     TOKENB,
+		Dst:     TOKENA,
+		Amount:  big.NewInt(1000000),
+	}
+
+	_, err := kyberClient.FetchExactInQuote(context.Background(), req)
+	if !errors.Is(err, common.ErrUnsupportedChain) {
+		t.Fatalf("expected unsupported chain, err: %v", err)
+	}
+
+	req.ChainId = 137
+	server.Fail("/api/v1/routes", http.StatusBadRequest, `{"code":4008,"message":"route not found","requestId":"1"}`)
+	_, err = kyberClient.FetchExactInQuote(context.Background(), req)
+	if !errors.Is(err, common.ErrInsufficientLiquidity) || common.IsRetryable(err) {
+		t.Fatalf("expected insufficient liquidity, err: %v", err)
+	}
+
+	_, err = kyberClient.FetchExactOutQuote(context.Background(), req)
+	if !errors.Is(err, common.ErrUnsupportedParameter) {
+		t.Fatalf("expected exact out to be unsupported, err: %v", err)
+	}
+}
@@ -0,0 +1,391 @@
+package lifi
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"math/big"
+	uri "net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/onmetahq/go-evm/internal/http/common"
+	metahttp "github.com/onmetahq/meta-http/pkg/meta_http"
+)
+
+type lifi struct {
+	client      metahttp.Requests
+	credentials common.CredentialProvider
+	integrator  string
+}
+
+type Option func(*lifi)
+
+// WithCredentials sets the API key source, the LIFI_KEY environment
+// variable is read on every call by default. LI.FI works without a key at a
+// lower rate limit.
+func WithCredentials(credentials common.CredentialProvider) Option {
+	return func(o *lifi) {
+		o.credentials = credentials
+	}
+}
+
+// WithIntegrator sets the integrator name reported with every quote.
+func WithIntegrator(integrator string) Option {
+	return func(o *lifi) {
+		o.integrator = integrator
+	}
+}
+
+// NewLiFi returns a LI.FI provider, client must be configured with the LI.FI
+// API base url, e.g. https://li.quest/v1.
+func NewLiFi(client metahttp.Requests, opts ...Option) *lifi {
+	o := &lifi{
+		client:      client,
+		credentials: common.EnvCredentials("LIFI_KEY"),
+	}
+
+	for _, opt := range opts {
+		opt(o)
+	}
+	return o
+}
+
+var _ common.CrossChainAggregator = (*lifi)(nil)
+
+func (o *lifi) FetchCrossChainQuote(ctx context.Context, req common.CrossChainQuoteReq) (common.CrossChainQuoteRes, error) {
+	slippage, err := req.Slippage()
+	if err != nil {
+		return common.CrossChainQuoteRes{}, err
+	}
+
+	recipient := req.Recipient
+	if recipient == "" {
+		recipient = req.From
+	}
+
+	v := uri.Values{}
+	v.Add("fromChain", strconv.FormatUint(req.SrcChainId, 10))
+	v.Add("toChain", strconv.FormatUint(req.DstChainId, 10))
+	v.Add("fromToken", req.Src)
+	v.Add("toToken", req.Dst)
+	v.Add("fromAmount", req.Amount.String())
+	v.Add("fromAddress", req.From)
+	v.Add("toAddress", recipient)
+	v.Add("slippage", common.BpsFraction(slippage))
+
+	if len(req.AllowedBridges) > 0 {
+		v.Add("allowBridges", strings.Join(req.AllowedBridges, ","))
+	}
+
+	if len(req.DeniedBridges) > 0 {
+		v.Add("denyBridges", strings.Join(req.DeniedBridges, ","))
+	}
+
+	if o.integrator != "" {
+		v.Add("integrator", o.integrator)
+	}
+
+	headers, err := o.headers(ctx)
+	if err != nil {
+		return common.CrossChainQuoteRes{}, err
+	}
+
+	var res LiFiStep
+	_, err = o.client.Get(ctx, fmt.Sprintf("/quote?%s", v.Encode()), headers, &res)
+	if err != nil {
+		return common.CrossChainQuoteRes{}, fmt.Errorf("unable to fetch lifi quote, err: %w", parseError(err))
+	}
+	return parseLiFiQuote(req, res)
+}
+
+func (o *lifi) FetchTransferStatus(ctx context.Context, req common.CrossChainStatusReq) (common.CrossChainStatus, error) {
+	v := uri.Values{}
+	v.Add("txHash", req.TxHash)
+	if req.SrcChainId != 0 {
+		v.Add("fromChain", strconv.FormatUint(req.SrcChainId, 10))
+	}
+	if req.DstChainId != 0 {
+		v.Add("toChain", strconv.FormatUint(req.DstChainId, 10))
+	}
+	if req.Bridge != "" {
+		v.Add("bridge", req.Bridge)
+	}
+
+	headers, err := o.headers(ctx)
+	if err != nil {
+		return common.CrossChainStatus{}, err
+	}
+
+	var res LiFiStatusResponse
+	_, err = o.client.Get(ctx, fmt.Sprintf("/status?%s", v.Encode()), headers, &res)
+	if err != nil {
+		return common.CrossChainStatus{}, fmt.Errorf("unable to fetch lifi status, err: %w", parseError(err))
+	}
+	return parseLiFiStatus(res)
+}
+
+func (o *lifi) headers(ctx context.Context) (map[string]string, error) {
+	key, err := o.credentials.Credential(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("unable to load lifi credentials, err: %w", err)
+	}
+
+	if key == "" {
+		return map[string]string{}, nil
+	}
+	return map[string]string{
+		"x-lifi-api-key": key,
+	}, nil
+}
+
+type LiFiErrorResponse struct {
+	Message string `json:"message"`
+	Code    int    `json:"code"`
+}
+
+// parseError classifies a failed LI.FI call from its error payload, e.g.
+// {"message":"No available quotes for the requested transfer","code":1002}.
+func parseError(err error) error {
+	status, body, ok := common.StatusAndBody(err)
+	if !ok {
+		return common.NewProviderError("lifi", 0, "", "", err)
+	}
+
+	var payload LiFiErrorResponse
+	if json.Unmarshal(body, &payload) != nil || payload.Message == "" {
+		return common.NewProviderError("lifi", status, "", string(body), err)
+	}
+
+	return common.NewProviderError("lifi", status, strconv.Itoa(payload.Code), payload.Message, err)
+}
+
+type LiFiToken struct {
+	Address  string `json:"address"`
+	ChainId  uint64 `json:"chainId"`
+	Symbol   string `json:"symbol"`
+	Decimals int    `json:"decimals"`
+}
+
+type LiFiAction struct {
+	FromChainId uint64    `json:"fromChainId"`
+	ToChainId   uint64    `json:"toChainId"`
+	FromToken   LiFiToken `json:"fromToken"`
+	ToToken     LiFiToken `json:"toToken"`
+	FromAmount  string    `json:"fromAmount"`
+	FromAddress string    `json:"fromAddress"`
+	ToAddress   string    `json:"toAddress"`
+}
+
+type LiFiFeeCost struct {
+	Name     string    `json:"name"`
+	Token    LiFiToken `json:"token"`
+	Amount   string    `json:"amount"`
+	Included bool      `json:"included"`
+}
+
+type LiFiGasCost struct {
+	Type     string    `json:"type"`
+	Price    string    `json:"price"`
+	Estimate string    `json:"estimate"`
+	Limit    string    `json:"limit"`
+	Amount   string    `json:"amount"`
+	Token    LiFiToken `json:"token"`
+}
+
+// LiFiEstimate is the expected outcome of a step, ExecutionDuration is in
+// seconds.
+type LiFiEstimate struct {
+	Tool              string        `json:"tool"`
+	FromAmount        string        `json:"fromAmount"`
+	ToAmount          string        `json:"toAmount"`
+	ToAmountMin       string        `json:"toAmountMin"`
+	ApprovalAddress   string        `json:"approvalAddress"`
+	ExecutionDuration float64       `json:"executionDuration"`
+	FeeCosts          []LiFiFeeCost `json:"feeCosts"`
+	GasCosts          []LiFiGasCost `json:"gasCosts"`
+}
+
+// LiFiStep is a quote, IncludedSteps are the swaps and bridge transfers
+// executed by its single transaction.
+type LiFiStep struct {
+	Id            string       `json:"id"`
+	Type          string       `json:"type"`
+	Tool          string       `json:"tool"`
+	Action        LiFiAction   `json:"action"`
+	Estimate      LiFiEstimate `json:"estimate"`
+	IncludedSteps []LiFiStep   `json:"includedSteps"`
+	// TransactionRequest amounts are hex encoded.
+	TransactionRequest struct {
+		From     string `json:"from"`
+		To       string `json:"to"`
+		ChainId  uint64 `json:"chainId"`
+		Data     string `json:"data"`
+		Value    string `json:"value"`
+		GasPrice string `json:"gasPrice"`
+		GasLimit string `json:"gasLimit"`
+	} `json:"transactionRequest"`
+}
+
+type LiFiTransferInfo struct {
+	TxHash  string    `json:"txHash"`
+	ChainId uint64    `json:"chainId"`
+	Amount  string    `json:"amount"`
+	Token   LiFiToken `json:"token"`
+}
+
+type LiFiStatusResponse struct {
+	TransactionId    string           `json:"transactionId"`
+	Sending          LiFiTransferInfo `json:"sending"`
+	Receiving        LiFiTransferInfo `json:"receiving"`
+	Status           string           `json:"status"`
+	Substatus        string           `json:"substatus"`
+	SubstatusMessage string           `json:"substatusMessage"`
+	Tool             string           `json:"tool"`
+}
+
+func parseLiFiQuote(req common.CrossChainQuoteReq, step LiFiStep) (common.CrossChainQuoteRes, error) {
+	toAmount, ok := common.ParseBigInt(step.Estimate.ToAmount)
+	if !ok {
+		return common.CrossChainQuoteRes{}, fmt.Errorf("invalid out amount from lifi, amount: %v", step.Estimate.ToAmount)
+	}
+
+	minOut, ok := common.ParseBigInt(step.Estimate.ToAmountMin)
+	if !ok {
+		return common.CrossChainQuoteRes{}, fmt.Errorf("invalid min out amount from lifi, amount: %v", step.Estimate.ToAmountMin)
+	}
+
+	tx := step.TransactionRequest
+	value, ok := common.ParseBigInt(tx.Value)
+	if !ok {
+		return common.CrossChainQuoteRes{}, fmt.Errorf("invalid tx value from lifi, value: %v", tx.Value)
+	}
+
+	gasPrice, ok := common.ParseBigInt(tx.GasPrice)
+	if !ok {
+		return common.CrossChainQuoteRes{}, fmt.Errorf("invalid gas price from lifi, gasPrice: %v", tx.GasPrice)
+	}
+
+	gas, ok := common.ParseBigInt(tx.GasLimit)
+	if !ok {
+		return common.CrossChainQuoteRes{}, fmt.Errorf("invalid gas limit from lifi, gas: %v", tx.GasLimit)
+	}
+
+	// A quote is a single step wrapping its fee collection, swaps and bridge
+	// transfers, the bridge is the tool of the cross step. The top level
+	// estimate sums the fees of the included steps, so fees are only read
+	// from the top level when there are none.
+	bridge := step.Tool
+	included := step.IncludedSteps
+	if len(included) == 0 {
+		included = []LiFiStep{step}
+	}
+
+	var steps []common.CrossChainStep
+	var fees []common.CrossChainFee
+	for _, s := range included {
+		for _, fee := range s.Estimate.FeeCosts {
+			amount, ok := common.ParseBigInt(fee.Amount)
+			if !ok {
+				return common.CrossChainQuoteRes{}, fmt.Errorf("invalid fee amount from lifi, amount: %v", fee.Amount)
+			}
+			fees = append(fees, common.CrossChainFee{
+				Name:     fee.Name,
+				ChainId:  fee.Token.ChainId,
+				Token:    fee.Token.Address,
+				Amount:   amount,
+				Included: fee.Included,
+			})
+		}
+
+		from, ok := common.ParseBigInt(s.Estimate.FromAmount)
+		if !ok {
+			return common.CrossChainQuoteRes{}, fmt.Errorf("invalid step amount from lifi, amount: %v", s.Estimate.FromAmount)
+		}
+		to, ok := common.ParseBigInt(s.Estimate.ToAmount)
+		if !ok {
+			return common.CrossChainQuoteRes{}, fmt.Errorf("invalid step amount from lifi, amount: %v", s.Estimate.ToAmount)
+		}
+
+		if s.Type == "cross" {
+			bridge = s.Tool
+		}
+		steps = append(steps, common.CrossChainStep{
+			Type:       s.Type,
+			Tool:       s.Tool,
+			SrcChainId: s.Action.FromChainId,
+			DstChainId: s.Action.ToChainId,
+			Src:        s.Action.FromToken.Address,
+			Dst:        s.Action.ToToken.Address,
+			FromAmount: from,
+			ToAmount:   to,
+		})
+	}
+
+	return common.CrossChainQuoteRes{
+		Id:          step.Id,
+		SrcChainId:  req.SrcChainId,
+		DstChainId:  req.DstChainId,
+		Src:         req.Src,
+		Dst:         req.Dst,
+		FromAmount:  req.Amount,
+		ToAmount:    toAmount,
+		MinToAmount: minOut,
+		Bridge:      bridge,
+		Steps:       steps,
+		Duration:    time.Duration(step.Estimate.ExecutionDuration * float64(time.Second)),
+		Fees:        fees,
+		Tx: common.SwapTx{
+			ChainId:         req.SrcChainId,
+			Src:             req.Src,
+			Dst:             req.Dst,
+			FromAmount:      req.Amount,
+			ToAmount:        toAmount,
+			MinToAmount:     minOut,
+			From:            tx.From,
+			To:              tx.To,
+			Data:            tx.Data,
+			Value:           value,
+			Gas:             gas,
+			GasPrice:        gasPrice,
+			AllowanceTarget: step.Estimate.ApprovalAddress,
+		},
+	}, nil
+}
+
+// parseLiFiStatus maps the LI.FI status: NOT_FOUND is reported until the
+// source transaction is indexed so it is pending, DONE carries COMPLETED,
+// PARTIAL or REFUNDED as substatus.
+func parseLiFiStatus(res LiFiStatusResponse) (common.CrossChainStatus, error) {
+	var status common.TransferStatus
+	switch res.Status {
+	case "NOT_FOUND", "PENDING":
+		status = common.TransferPending
+	case "DONE":
+		status = common.TransferDone
+		if res.Substatus == "REFUNDED" {
+			status = common.TransferRefunded
+		}
+	default:
+		status = common.TransferFailed
+	}
+
+	var received *big.Int
+	if res.Receiving.Amount != "" {
+		var ok bool
+		if received, ok = common.ParseBigInt(res.Receiving.Amount); !ok {
+			return common.CrossChainStatus{}, fmt.Errorf("invalid received amount from lifi, amount: %v", res.Receiving.Amount)
+		}
+	}
+
+	return common.CrossChainStatus{
+		Status:         status,
+		SubStatus:      res.Substatus,
+		Bridge:         res.Tool,
+		SrcTxHash:      res.Sending.TxHash,
+		DstTxHash:      res.Receiving.TxHash,
+		ReceivedToken:  res.Receiving.Token.Address,
+		ReceivedAmount: received,
+	}, nil
+}
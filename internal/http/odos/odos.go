@@ -0,0 +1,478 @@
+package odos
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"math/big"
+	"strconv"
+	"strings"
+
+	"github.com/onmetahq/go-evm/internal/http/common"
+	metahttp "github.com/onmetahq/meta-http/pkg/meta_http"
+)
+
+// nativeToken is the address Odos uses for the chain's native token.
+const nativeToken = "0x0000000000000000000000000000000000000000"
+
+type odos struct {
+	client       metahttp.Requests
+	referralCode uint32
+}
+
+type Option func(*odos)
+
+// WithReferralCode sets the Odos referral code, the partner fee is the one
+// registered for the code.
+func WithReferralCode(code uint32) Option {
+	return func(o *odos) {
+		o.referralCode = code
+	}
+}
+
+// NewOdos returns an Odos provider, client must be configured with the Odos
+// API base url, e.g. https://api.odos.xyz.
+func NewOdos(client metahttp.Requests, opts ...Option) *odos {
+	o := &odos{
+		client: client,
+	}
+
+	for _, opt := range opts {
+		opt(o)
+	}
+	return o
+}
+
+var (
+	_ common.Aggregator      = (*odos)(nil)
+	_ common.MultiAggregator = (*odos)(nil)
+)
+
+func (o *odos) FetchSupportedTokens(ctx context.Context, chainId uint64) ([]common.Token, error) {
+	var res OdosTokensResponse
+	url := fmt.Sprintf("/info/tokens/%d", chainId)
+	_, err := o.client.Get(ctx, url, map[string]string{}, &res)
+	if err != nil {
+		return []common.Token{}, fmt.Errorf("unable to fetch all tokens from odos, err: %w", parseError(err))
+	}
+
+	var out []common.Token
+	for address, v := range res.TokenMap {
+		out = append(out, common.Token{
+			ChainId:  chainId,
+			Address:  fromOdosToken(address),
+			Symbol:   v.Symbol,
+			Name:     v.Name,
+			Decimals: v.Decimals,
+		})
+	}
+	return out, nil
+}
+
+func (o *odos) FetchExactInQuote(ctx context.Context, req common.QuoteReq) (common.QuoteRes, error) {
+	if req.FeeBps > 0 {
+		return common.QuoteRes{}, fmt.Errorf("odos fees are set by the referral code, err: %w", common.ErrUnsupportedParameter)
+	}
+
+	res, err := o.FetchMultiQuote(ctx, multiReq(req))
+	if err != nil {
+		return common.QuoteRes{}, err
+	}
+
+	return common.QuoteRes{
+		ChainId:     req.ChainId,
+		Src:         req.Src,
+		Dst:         req.Dst,
+		FromAmount:  req.Amount,
+		ToAmount:    res.Outputs[0].Amount,
+		Gas:         res.Gas,
+		GasPrice:    res.GasPrice,
+		MinToAmount: res.MinOutputs[0].Amount,
+	}, nil
+}
+
+// FetchExactOutQuote is not supported, Odos only sells exact amounts.
+func (o *odos) FetchExactOutQuote(ctx context.Context, req common.QuoteReq) (common.QuoteRes, error) {
+	return common.QuoteRes{}, fmt.Errorf("operation exact out is not supported by odos, err: %w", common.ErrUnsupportedParameter)
+}
+
+func (o *odos) FetchExactInSwapCallData(ctx context.Context, req common.QuoteReq) (common.SwapTx, error) {
+	if req.FeeBps > 0 {
+		return common.SwapTx{}, fmt.Errorf("odos fees are set by the referral code, err: %w", common.ErrUnsupportedParameter)
+	}
+
+	res, err := o.FetchMultiSwapCallData(ctx, multiReq(req))
+	if err != nil {
+		return common.SwapTx{}, err
+	}
+
+	return common.SwapTx{
+		ChainId:         req.ChainId,
+		Src:             req.Src,
+		Dst:             req.Dst,
+		FromAmount:      res.Inputs[0].Amount,
+		ToAmount:        res.Outputs[0].Amount,
+		MinToAmount:     res.MinOutputs[0].Amount,
+		From:            res.From,
+		To:              res.To,
+		Data:            res.Data,
+		Value:           res.Value,
+		Gas:             res.Gas,
+		GasPrice:        res.GasPrice,
+		AllowanceTarget: res.AllowanceTarget,
+	}, nil
+}
+
+// FetchExactOutSwapCallData is not supported, Odos only sells exact amounts.
+func (o *odos) FetchExactOutSwapCallData(ctx context.Context, req common.QuoteReq) (common.SwapTx, error) {
+	return common.SwapTx{}, fmt.Errorf("operation exact out is not supported by odos, err: %w", common.ErrUnsupportedParameter)
+}
+
+func (o *odos) FetchMultiQuote(ctx context.Context, req common.MultiQuoteReq) (common.MultiQuoteRes, error) {
+	slippage, err := req.Slippage()
+	if err != nil {
+		return common.MultiQuoteRes{}, err
+	}
+
+	res, err := o.FetchQuote(ctx, req)
+	if err != nil {
+		return common.MultiQuoteRes{}, err
+	}
+	return parseOdosQuote(req, res, slippage)
+}
+
+func (o *odos) FetchMultiSwapCallData(ctx context.Context, req common.MultiQuoteReq) (common.MultiSwapTx, error) {
+	slippage, err := req.Slippage()
+	if err != nil {
+		return common.MultiSwapTx{}, err
+	}
+
+	quote, err := o.FetchQuote(ctx, req)
+	if err != nil {
+		return common.MultiSwapTx{}, err
+	}
+
+	res, err := o.Assemble(ctx, req, quote.PathId)
+	if err != nil {
+		return common.MultiSwapTx{}, err
+	}
+	return parseOdosAssemble(req, res, slippage)
+}
+
+// FetchQuote returns the /sor/quote/v2 response for req, its PathId is
+// assembled into a transaction by Assemble.
+func (o *odos) FetchQuote(ctx context.Context, req common.MultiQuoteReq) (OdosQuoteResponse, error) {
+	slippage, err := req.Slippage()
+	if err != nil {
+		return OdosQuoteResponse{}, err
+	}
+
+	if err := req.Validate(); err != nil {
+		return OdosQuoteResponse{}, err
+	}
+
+	body := OdosQuoteReq{
+		ChainId:              req.ChainId,
+		UserAddr:             req.From,
+		SlippageLimitPercent: float64(slippage) / 100,
+		ReferralCode:         o.referralCode,
+		Compact:              true,
+		SourceWhitelist:      req.IncludedSources,
+		SourceBlacklist:      req.ExcludedSources,
+	}
+
+	for _, in := range req.Inputs {
+		body.InputTokens = append(body.InputTokens, OdosInputToken{
+			TokenAddress: toOdosToken(in.Token),
+			Amount:       in.Amount.String(),
+		})
+	}
+
+	for _, out := range req.Outputs {
+		body.OutputTokens = append(body.OutputTokens, OdosOutputToken{
+			TokenAddress: toOdosToken(out.Token),
+			Proportion:   out.Proportion,
+		})
+	}
+
+	if req.GasPrice != nil {
+		gwei, _ := new(big.Rat).SetFrac(req.GasPrice, big.NewInt(1e9)).Float64()
+		body.GasPrice = gwei
+	}
+
+	var res OdosQuoteResponse
+	_, err = o.client.Post(ctx, "/sor/quote/v2", map[string]string{}, body, &res)
+	if err != nil {
+		return OdosQuoteResponse{}, fmt.Errorf("unable to fetch odos quote, err: %w", parseError(err))
+	}
+	return res, nil
+}
+
+// Assemble builds the transaction of the quote pathId, it must be called
+// within the quote's validity, about a minute.
+func (o *odos) Assemble(ctx context.Context, req common.MultiQuoteReq, pathId string) (OdosAssembleResponse, error) {
+	body := OdosAssembleReq{
+		UserAddr: req.From,
+		PathId:   pathId,
+		Simulate: !req.SkipValidation,
+		Receiver: req.Receiver,
+	}
+
+	var res OdosAssembleResponse
+	_, err := o.client.Post(ctx, "/sor/assemble", map[string]string{}, body, &res)
+	if err != nil {
+		return OdosAssembleResponse{}, fmt.Errorf("unable to assemble odos transaction, err: %w", parseError(err))
+	}
+
+	if res.Simulation != nil && !res.Simulation.IsSuccess {
+		return OdosAssembleResponse{}, fmt.Errorf("odos simulation failed, err: %w",
+			common.NewProviderError("odos", 0, "", res.Simulation.SimulationError.ErrorMessage, nil))
+	}
+	return res, nil
+}
+
+func multiReq(req common.QuoteReq) common.MultiQuoteReq {
+	return common.MultiQuoteReq{
+		ChainId:         req.ChainId,
+		Inputs:          []common.TokenAmount{{Token: req.Src, Amount: req.Amount}},
+		Outputs:         []common.TokenProportion{{Token: req.Dst, Proportion: 1}},
+		From:            req.From,
+		Receiver:        req.Receiver,
+		SlippageBps:     req.SlippageBps,
+		SkipValidation:  req.SkipValidation,
+		GasPrice:        req.GasPrice,
+		IncludedSources: req.IncludedSources,
+		ExcludedSources: req.ExcludedSources,
+	}
+}
+
+func toOdosToken(token string) string {
+	if common.IsNativeToken(token) {
+		return nativeToken
+	}
+	return token
+}
+
+func fromOdosToken(token string) string {
+	if strings.EqualFold(token, nativeToken) {
+		return common.NativeToken
+	}
+	return token
+}
+
+type OdosErrorResponse struct {
+	Detail    string `json:"detail"`
+	TraceId   string `json:"traceId"`
+	ErrorCode int    `json:"errorCode"`
+}
+
+// parseError classifies a failed Odos call from its error payload, e.g.
+// {"detail":"No viable path found","traceId":"...","errorCode":2000}.
+func parseError(err error) error {
+	status, body, ok := common.StatusAndBody(err)
+	if !ok {
+		return common.NewProviderError("odos", 0, "", "", err)
+	}
+
+	var payload OdosErrorResponse
+	if json.Unmarshal(body, &payload) != nil || payload.Detail == "" {
+		return common.NewProviderError("odos", status, "", string(body), err)
+	}
+	return common.NewProviderError("odos", status, strconv.Itoa(payload.ErrorCode), payload.Detail, err)
+}
+
+type OdosToken struct {
+	Name     string `json:"name"`
+	Symbol   string `json:"symbol"`
+	Decimals int    `json:"decimals"`
+}
+
+type OdosTokensResponse struct {
+	TokenMap map[string]OdosToken `json:"tokenMap"`
+}
+
+type OdosInputToken struct {
+	TokenAddress string `json:"tokenAddress"`
+	Amount       string `json:"amount"`
+}
+
+type OdosOutputToken struct {
+	TokenAddress string  `json:"tokenAddress"`
+	Proportion   float64 `json:"proportion"`
+}
+
+type OdosQuoteReq struct {
+	ChainId              uint64            `json:"chainId"`
+	InputTokens          []OdosInputToken  `json:"inputTokens"`
+	OutputTokens         []OdosOutputToken `json:"outputTokens"`
+	UserAddr             string            `json:"userAddr"`
+	SlippageLimitPercent float64           `json:"slippageLimitPercent"`
+	ReferralCode         uint32            `json:"referralCode"`
+	Compact              bool              `json:"compact"`
+	GasPrice             float64           `json:"gasPrice,omitempty"`
+	SourceWhitelist      []string          `json:"sourceWhitelist,omitempty"`
+	SourceBlacklist      []string          `json:"sourceBlacklist,omitempty"`
+}
+
+type OdosQuoteResponse struct {
+	InTokens          []string  `json:"inTokens"`
+	OutTokens         []string  `json:"outTokens"`
+	InAmounts         []string  `json:"inAmounts"`
+	OutAmounts        []string  `json:"outAmounts"`
+	GasEstimate       float64   `json:"gasEstimate"`
+	DataGasEstimate   float64   `json:"dataGasEstimate"`
+	GweiPerGas        float64   `json:"gweiPerGas"`
+	GasEstimateValue  float64   `json:"gasEstimateValue"`
+	InValues          []float64 `json:"inValues"`
+	OutValues         []float64 `json:"outValues"`
+	NetOutValue       float64   `json:"netOutValue"`
+	PriceImpact       float64   `json:"priceImpact"`
+	PercentDiff       float64   `json:"percentDiff"`
+	PartnerFeePercent float64   `json:"partnerFeePercent"`
+	PathId            string    `json:"pathId"`
+	BlockNumber       uint64    `json:"blockNumber"`
+}
+
+type OdosAssembleReq struct {
+	UserAddr string `json:"userAddr"`
+	PathId   string `json:"pathId"`
+	Simulate bool   `json:"simulate"`
+	Receiver string `json:"receiver,omitempty"`
+}
+
+type OdosAssembleResponse struct {
+	BlockNumber      uint64           `json:"blockNumber"`
+	GasEstimate      float64          `json:"gasEstimate"`
+	GasEstimateValue float64          `json:"gasEstimateValue"`
+	InputTokens      []OdosInputToken `json:"inputTokens"`
+	OutputTokens     []OdosInputToken `json:"outputTokens"`
+	NetOutValue      float64          `json:"netOutValue"`
+	Transaction      struct {
+		Gas      int64  `json:"gas"`
+		GasPrice int64  `json:"gasPrice"`
+		Value    string `json:"value"`
+		To       string `json:"to"`
+		From     string `json:"from"`
+		Data     string `json:"data"`
+		Nonce    uint64 `json:"nonce"`
+		ChainId  uint64 `json:"chainId"`
+	} `json:"transaction"`
+	Simulation *struct {
+		IsSuccess       bool    `json:"isSuccess"`
+		GasEstimate     float64 `json:"gasEstimate"`
+		SimulationError struct {
+			Type         string `json:"type"`
+			ErrorMessage string `json:"errorMessage"`
+		} `json:"simulationError"`
+	} `json:"simulation"`
+}
+
+func parseAmounts(tokens, amounts []string) ([]common.TokenAmount, error) {
+	if len(tokens) != len(amounts) {
+		return nil, fmt.Errorf("invalid amounts from odos, tokens: %d, amounts: %d", len(tokens), len(amounts))
+	}
+
+	out := make([]common.TokenAmount, 0, len(tokens))
+	for i, token := range tokens {
+		amount, ok := common.ParseBigInt(amounts[i])
+		if !ok {
+			return nil, fmt.Errorf("invalid amount from odos, amount: %v", amounts[i])
+		}
+		out = append(out, common.TokenAmount{Token: fromOdosToken(token), Amount: amount})
+	}
+	return out, nil
+}
+
+func minOutputs(outputs []common.TokenAmount, slippage uint32) []common.TokenAmount {
+	out := make([]common.TokenAmount, 0, len(outputs))
+	for _, v := range outputs {
+		out = append(out, common.TokenAmount{Token: v.Token, Amount: common.MinReceived(v.Amount, slippage)})
+	}
+	return out
+}
+
+func parseOdosQuote(req common.MultiQuoteReq, quote OdosQuoteResponse, slippage uint32) (common.MultiQuoteRes, error) {
+	inputs, err := parseAmounts(quote.InTokens, quote.InAmounts)
+	if err != nil {
+		return common.MultiQuoteRes{}, err
+	}
+
+	outputs, err := parseAmounts(quote.OutTokens, quote.OutAmounts)
+	if err != nil {
+		return common.MultiQuoteRes{}, err
+	}
+
+	if len(inputs) != len(req.Inputs) {
+		return common.MultiQuoteRes{}, fmt.Errorf("invalid inputs from odos, want: %d, got: %d", len(req.Inputs), len(inputs))
+	}
+
+	if len(outputs) != len(req.Outputs) {
+		return common.MultiQuoteRes{}, fmt.Errorf("invalid outputs from odos, want: %d, got: %d", len(req.Outputs), len(outputs))
+	}
+
+	gasPrice, _ := new(big.Float).Mul(big.NewFloat(quote.GweiPerGas), big.NewFloat(1e9)).Int(nil)
+
+	return common.MultiQuoteRes{
+		ChainId:     req.ChainId,
+		Inputs:      inputs,
+		Outputs:     outputs,
+		MinOutputs:  minOutputs(outputs, slippage),
+		Gas:         big.NewInt(int64(quote.GasEstimate)),
+		GasPrice:    gasPrice,
+		PriceImpact: quote.PriceImpact,
+	}, nil
+}
+
+func parseOdosAssemble(req common.MultiQuoteReq, res OdosAssembleResponse, slippage uint32) (common.MultiSwapTx, error) {
+	var inTokens, inAmounts, outTokens, outAmounts []string
+	for _, v := range res.InputTokens {
+		inTokens, inAmounts = append(inTokens, v.TokenAddress), append(inAmounts, v.Amount)
+	}
+	for _, v := range res.OutputTokens {
+		outTokens, outAmounts = append(outTokens, v.TokenAddress), append(outAmounts, v.Amount)
+	}
+
+	inputs, err := parseAmounts(inTokens, inAmounts)
+	if err != nil {
+		return common.MultiSwapTx{}, err
+	}
+
+	outputs, err := parseAmounts(outTokens, outAmounts)
+	if err != nil {
+		return common.MultiSwapTx{}, err
+	}
+
+	if len(inputs) != len(req.Inputs) {
+		return common.MultiSwapTx{}, fmt.Errorf("invalid inputs from odos, want: %d, got: %d", len(req.Inputs), len(inputs))
+	}
+
+	if len(outputs) != len(req.Outputs) {
+		return common.MultiSwapTx{}, fmt.Errorf("invalid outputs from odos, want: %d, got: %d", len(req.Outputs), len(outputs))
+	}
+
+	value, ok := common.ParseBigInt(res.Transaction.Value)
+	if !ok {
+		return common.MultiSwapTx{}, fmt.Errorf("invalid tx value from odos, value: %v", res.Transaction.Value)
+	}
+
+	// The transaction gas is not estimated when the simulation is skipped,
+	// fall back to the path estimate.
+	gas := big.NewInt(res.Transaction.Gas)
+	if gas.Sign() <= 0 {
+		gas = big.NewInt(int64(res.GasEstimate))
+	}
+
+	return common.MultiSwapTx{
+		ChainId:         req.ChainId,
+		Inputs:          inputs,
+		Outputs:         outputs,
+		MinOutputs:      minOutputs(outputs, slippage),
+		From:            res.Transaction.From,
+		To:              res.Transaction.To,
+		Data:            res.Transaction.Data,
+		Value:           value,
+		Gas:             gas,
+		GasPrice:        big.NewInt(res.Transaction.GasPrice),
+		AllowanceTarget: res.Transaction.To,
+	}, nil
+}
@@ -0,0 +1,167 @@
+package odos
+
+import (
+	"context"
+	"errors"
+	"math/big"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/onmetahq/go-evm/internal/http/common"
+	"github.com/onmetahq/go-evm/internal/http/fake"
+)
+
+const (
+	USDC = "0x2791bca1f2de4661ed88a30c99a7a9449aa84174"
+	WETH = "0x7ceb23fd6bc0add59e62ac25578270cff1b9f619"
+	DAI  = "0x8f3cf7ad23cd3cadbd9735aff958023239c6a063"
+	FROM = "0x15Ba05723b04785C3E21157171810892A4FB795c"
+)
+
+func TestFetchMultiSwapCallData(t *testing.T) {
+	server := fake.NewServer()
+	defer server.Close()
+
+	odosClient := NewOdos(common.NewClient(server.URL+fake.OdosPrefix, nil), WithReferralCode(1))
+	req := common.MultiQuoteReq{
+		ChainId: 137,
+		Inputs: []common.TokenAmount{
+			{Token: WETH, Amount: big.NewInt(1000)},
+			{Token: DAI, Amount: big.NewInt(3000)},
+		},
+		Outputs:     []common.TokenProportion{{Token: USDC, Proportion: 1}},
+		From:        FROM,
+		SlippageBps: common.Bps(100),
+	}
+
+	res, err := odosClient.FetchMultiSwapCallData(context.Background(), req)
+	if err != nil {
+		t.Fatalf("swap err: %v", err)
+	}
+
+	if len(res.Inputs) != 2 || res.Inputs[1].Token != DAI || res.Inputs[1].Amount.Cmp(big.NewInt(3000)) != 0 {
+		t.Fatalf("invalid inputs, inputs: %+v", res.Inputs)
+	}
+
+	if len(res.Outputs) != 1 || res.Outputs[0].Amount.Cmp(big.NewInt(8000)) != 0 || res.MinOutputs[0].Amount.Cmp(big.NewInt(7920)) != 0 {
+		t.Fatalf("invalid outputs, outputs: %+v, min: %+v", res.Outputs, res.MinOutputs)
+	}
+
+	if res.To != fake.OdosRouterAddress || res.AllowanceTarget != fake.OdosRouterAddress || res.Data != fake.SwapCallData {
+		t.Fatalf("invalid tx, res: %+v", res)
+	}
+}
+
+func TestFetchMultiQuoteProportions(t *testing.T) {
+	server := fake.NewServer()
+	defer server.Close()
+
+	odosClient := NewOdos(common.NewClient(server.URL+fake.OdosPrefix, nil))
+	req := common.MultiQuoteReq{
+		ChainId: 137,
+		Inputs:  []common.TokenAmount{{Token: WETH, Amount: big.NewInt(5000)}},
+		Outputs: []common.TokenProportion{
+			{Token: USDC, Proportion: 0.8},
+			{Token: common.NativeToken, Proportion: 0.2},
+		},
+	}
+
+	res, err := odosClient.FetchMultiQuote(context.Background(), req)
+	if err != nil {
+		t.Fatalf("quote err: %v", err)
+	}
+
+	if len(res.Outputs) != 2 || res.Outputs[0].Amount.Cmp(big.NewInt(8000)) != 0 || res.Outputs[1].Amount.Cmp(big.NewInt(2000)) != 0 {
+		t.Fatalf("invalid outputs, outputs: %+v", res.Outputs)
+	}
+
+	if res.Outputs[1].Token != common.NativeToken {
+		t.Fatalf("expected native output, token: %s", res.Outputs[1].Token)
+	}
+
+	req.Outputs[1].Proportion = 0.3
+	if _, err := odosClient.FetchMultiQuote(context.Background(), req); err == nil {
+		t.Fatalf("expected proportions above 1 to fail")
+	}
+}
+
+func TestFetchExactInSwapCallData(t *testing.T) {
+	server := fake.NewServer()
+	defer server.Close()
+
+	odosClient := NewOdos(common.NewClient(server.URL+fake.OdosPrefix, nil))
+	req := common.QuoteReq{
+		ChainId:        137,
+		Src:            common.NativeToken,
+		Dst:            USDC,
+		Amount:         big.NewInt(1000000),
+		From:           FROM,
+		SkipValidation: true,
+	}
+
+	quote, err := odosClient.FetchExactInQuote(context.Background(), req)
+	if err != nil {
+		t.Fatalf("quote err: %v", err)
+	}
+
+	if quote.ToAmount.Cmp(big.NewInt(2000000)) != 0 || quote.GasPrice.Cmp(big.NewInt(30_000_000_000)) != 0 {
+		t.Fatalf("invalid quote, quote: %+v", quote)
+	}
+
+	res, err := odosClient.FetchExactInSwapCallData(context.Background(), req)
+	if err != nil {
+		t.Fatalf("swap err: %v", err)
+	}
+
+	if res.Src != common.NativeToken || res.FromAmount.Cmp(req.Amount) != 0 || res.ToAmount.Cmp(big.NewInt(2000000)) != 0 {
+		t.Fatalf("invalid swap, res: %+v", res)
+	}
+}
+
+func TestErrorPaths(t *testing.T) {
+	server := fake.NewServer()
+	defer server.Close()
+
+	odosClient := NewOdos(common.NewClient(server.URL+fake.OdosPrefix, nil))
+	req := common.QuoteReq{
+		ChainId: 137,
+		Src:     WETH,
+		Dst:     USDC,
+		Amount:  big.NewInt(1000000),
+	}
+
+	server.Fail("/sor/quote/v2", http.StatusBadRequest, `{"detail":"No viable path found","traceId":"1","errorCode":2000}`)
+	_, err := odosClient.FetchExactInQuote(context.Background(), req)
+	if !errors.Is(err, common.ErrInsufficientLiquidity) || common.IsRetryable(err) {
+		t.Fatalf("expected insufficient liquidity, err: %v", err)
+	}
+
+	if _, err := odosClient.FetchExactOutQuote(context.Background(), req); !errors.Is(err, common.ErrUnsupportedParameter) {
+		t.Fatalf("expected exact out to be unsupported, err: %v", err)
+	}
+
+	req.FeeBps = 10
+	if _, err := odosClient.FetchExactInSwapCallData(context.Background(), req); !errors.Is(err, common.ErrUnsupportedParameter) {
+		t.Fatalf("expected fees to be unsupported, err: %v", err)
+	}
+}
+
+func TestAssembleWithoutInputs(t *testing.T) {
+	server := fake.NewServer()
+	defer server.Close()
+
+	odosClient := NewOdos(common.NewClient(server.URL+fake.OdosPrefix, nil))
+	server.Fail("/sor/assemble", http.StatusOK, `{"inputTokens":[],"outputTokens":[{"tokenAddress":"`+USDC+`","amount":"2000000"}],"transaction":{"to":"`+fake.OdosRouterAddress+`","value":"0"}}`)
+
+	_, err := odosClient.FetchExactInSwapCallData(context.Background(), common.QuoteReq{
+		ChainId: 137,
+		Src:     WETH,
+		Dst:     USDC,
+		Amount:  big.NewInt(1000000),
+		From:    FROM,
+	})
+	if err == nil || !strings.Contains(err.Error(), "invalid inputs") {
+		t.Fatalf("expected an assemble without inputs to fail, err: %v", err)
+	}
+}
@@ -0,0 +1,412 @@
+package openocean
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"math/big"
+	"net/http"
+	uri "net/url"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/lmittmann/w3"
+	"github.com/onmetahq/go-evm/internal/gas"
+	"github.com/onmetahq/go-evm/internal/http/common"
+	metahttp "github.com/onmetahq/meta-http/pkg/meta_http"
+)
+
+type openOcean struct {
+	client metahttp.Requests
+	// oracle prices the gasPrice parameter OpenOcean requires when a request
+	// does not carry one.
+	oracle gas.Oracle
+
+	mu       sync.Mutex
+	decimals map[uint64]map[string]int
+}
+
+type Option func(*openOcean)
+
+// WithGasOracle prices requests without a GasPrice with oracle instead of
+// the eth_gasPrice of rpcs.
+func WithGasOracle(oracle gas.Oracle) Option {
+	return func(o *openOcean) {
+		o.oracle = oracle
+	}
+}
+
+// NewOpenOcean returns an OpenOcean provider, client must be configured with
+// the OpenOcean API base url, e.g. https://open-api.openocean.finance/v3.
+// rpcs maps a chain id to the node used to estimate the gas price.
+func NewOpenOcean(client metahttp.Requests, rpcs map[uint64]*w3.Client, opts ...Option) *openOcean {
+	o := &openOcean{
+		client:   client,
+		oracle:   gas.NodeOracle(rpcs),
+		decimals: map[uint64]map[string]int{},
+	}
+	for _, opt := range opts {
+		opt(o)
+	}
+	return o
+}
+
+var _ common.Aggregator = (*openOcean)(nil)
+
+func (o *openOcean) FetchSupportedTokens(ctx context.Context, chainId uint64) ([]common.Token, error) {
+	var res OpenOceanTokensResponse
+	url := fmt.Sprintf("/%d/tokenList", chainId)
+	_, err := o.client.Get(ctx, url, map[string]string{}, &res)
+	if err != nil {
+		return []common.Token{}, fmt.Errorf("unable to fetch all tokens from openocean, err: %w", parseError(err))
+	}
+
+	if res.Code != http.StatusOK {
+		return []common.Token{}, fmt.Errorf("unable to fetch all tokens from openocean, err: %w", res.err())
+	}
+
+	decimals := map[string]int{}
+	var out []common.Token
+	for _, v := range res.Data {
+		decimals[strings.ToLower(v.Address)] = v.Decimals
+		out = append(out, common.Token{
+			ChainId:  chainId,
+			Address:  v.Address,
+			Symbol:   v.Symbol,
+			Name:     v.Name,
+			Decimals: v.Decimals,
+			LogoURI:  v.Icon,
+		})
+	}
+
+	o.mu.Lock()
+	o.decimals[chainId] = decimals
+	o.mu.Unlock()
+	return out, nil
+}
+
+func (o *openOcean) FetchExactInQuote(ctx context.Context, req common.QuoteReq) (common.QuoteRes, error) {
+	slippage, err := req.Slippage()
+	if err != nil {
+		return common.QuoteRes{}, err
+	}
+
+	v, err := o.params(ctx, req, slippage)
+	if err != nil {
+		return common.QuoteRes{}, err
+	}
+
+	var res OpenOceanQuoteResponse
+	url := fmt.Sprintf("/%d/quote?%s", req.ChainId, v.Encode())
+	_, err = o.client.Get(ctx, url, map[string]string{}, &res)
+	if err != nil {
+		return common.QuoteRes{}, fmt.Errorf("unable to fetch openocean quote, err: %w", parseError(err))
+	}
+
+	if res.Code != http.StatusOK {
+		return common.QuoteRes{}, fmt.Errorf("unable to fetch openocean quote, err: %w", res.err())
+	}
+	return parseOpenOceanQuote(req, res.Data, v.Get("gasPrice"), slippage)
+}
+
+// FetchExactOutQuote is not supported, OpenOcean only sells exact amounts.
+func (o *openOcean) FetchExactOutQuote(ctx context.Context, req common.QuoteReq) (common.QuoteRes, error) {
+	return common.QuoteRes{}, fmt.Errorf("operation exact out is not supported by openocean, err: %w", common.ErrUnsupportedParameter)
+}
+
+func (o *openOcean) FetchExactInSwapCallData(ctx context.Context, req common.QuoteReq) (common.SwapTx, error) {
+	slippage, err := req.Slippage()
+	if err != nil {
+		return common.SwapTx{}, err
+	}
+
+	v, err := o.params(ctx, req, slippage)
+	if err != nil {
+		return common.SwapTx{}, err
+	}
+	v.Add("account", req.From)
+
+	if req.Receiver != "" {
+		v.Add("sender", req.From)
+		v.Set("account", req.Receiver)
+	}
+
+	var res OpenOceanSwapResponse
+	url := fmt.Sprintf("/%d/swap_quote?%s", req.ChainId, v.Encode())
+	_, err = o.client.Get(ctx, url, map[string]string{}, &res)
+	if err != nil {
+		return common.SwapTx{}, fmt.Errorf("unable to fetch openocean swap, err: %w", parseError(err))
+	}
+
+	if res.Code != http.StatusOK {
+		return common.SwapTx{}, fmt.Errorf("unable to fetch openocean swap, err: %w", res.err())
+	}
+	return parseOpenOceanSwap(req, res.Data, slippage)
+}
+
+// FetchExactOutSwapCallData is not supported, OpenOcean only sells exact
+// amounts.
+func (o *openOcean) FetchExactOutSwapCallData(ctx context.Context, req common.QuoteReq) (common.SwapTx, error) {
+	return common.SwapTx{}, fmt.Errorf("operation exact out is not supported by openocean, err: %w", common.ErrUnsupportedParameter)
+}
+
+// params builds the query shared by quote and swap_quote. OpenOcean takes
+// the amount in whole tokens and the gas price in gwei.
+func (o *openOcean) params(ctx context.Context, req common.QuoteReq, slippage uint32) (uri.Values, error) {
+	decimals, err := o.tokenDecimals(ctx, req.ChainId, req.Src, req.SrcDecimals)
+	if err != nil {
+		return nil, err
+	}
+
+	gasPrice, err := o.gasPrice(ctx, req)
+	if err != nil {
+		return nil, err
+	}
+
+	if req.FeeBps > 0 && req.Referrer == "" {
+		return nil, fmt.Errorf("openocean referrer fee requires a referrer to receive it")
+	}
+
+	v := uri.Values{}
+	v.Add("inTokenAddress", req.Src)
+	v.Add("outTokenAddress", req.Dst)
+	v.Add("amount", common.FormatUnits(req.Amount, decimals))
+	v.Add("gasPrice", common.FormatUnits(gasPrice, 9))
+	v.Add("slippage", common.BpsPercent(slippage))
+
+	if req.Referrer != "" {
+		v.Add("referrer", req.Referrer)
+	}
+
+	if req.FeeBps > 0 {
+		v.Add("referrerFee", common.BpsPercent(req.FeeBps))
+	}
+
+	if len(req.IncludedSources) > 0 {
+		v.Add("enabledDexIds", strings.Join(req.IncludedSources, ","))
+	}
+
+	if len(req.ExcludedSources) > 0 {
+		v.Add("disabledDexIds", strings.Join(req.ExcludedSources, ","))
+	}
+	return v, nil
+}
+
+// gasPrice returns the gas price of req or the one of the gas oracle,
+// OpenOcean rejects requests without it.
+func (o *openOcean) gasPrice(ctx context.Context, req common.QuoteReq) (*big.Int, error) {
+	if req.GasPrice != nil {
+		return req.GasPrice, nil
+	}
+
+	gasPrice, err := o.oracle.GasPrice(ctx, req.ChainId)
+	if err != nil {
+		return nil, fmt.Errorf("unable to estimate openocean gas price, err: %w", err)
+	}
+	return gasPrice, nil
+}
+
+// tokenDecimals returns known when set, 18 for the native token and the
+// decimals from the OpenOcean token list otherwise.
+func (o *openOcean) tokenDecimals(ctx context.Context, chainId uint64, token string, known int) (int, error) {
+	if known > 0 {
+		return known, nil
+	}
+
+	if common.IsNativeToken(token) {
+		return 18, nil
+	}
+
+	o.mu.Lock()
+	decimals, ok := o.decimals[chainId]
+	o.mu.Unlock()
+
+	if !ok {
+		if _, err := o.FetchSupportedTokens(ctx, chainId); err != nil {
+			return 0, err
+		}
+
+		o.mu.Lock()
+		decimals = o.decimals[chainId]
+		o.mu.Unlock()
+	}
+
+	d, ok := decimals[strings.ToLower(token)]
+	if !ok {
+		return 0, fmt.Errorf("unknown decimals of %s on openocean, err: %w", token, common.ErrUnsupportedToken)
+	}
+	return d, nil
+}
+
+// OpenOceanErrorResponse is returned with a 200 status as often as with an
+// error one, e.g. {"code":400,"error":"Invalid params","message":"..."}.
+type OpenOceanErrorResponse struct {
+	Code    int    `json:"code"`
+	Error   string `json:"error"`
+	Message string `json:"message"`
+}
+
+func (e OpenOceanErrorResponse) err() error {
+	return common.NewProviderError("openocean", e.Code, strconv.Itoa(e.Code), strings.TrimSpace(e.Error+" "+e.Message), nil)
+}
+
+func parseError(err error) error {
+	status, body, ok := common.StatusAndBody(err)
+	if !ok {
+		return common.NewProviderError("openocean", 0, "", "", err)
+	}
+
+	var payload OpenOceanErrorResponse
+	if json.Unmarshal(body, &payload) != nil || payload.Error == "" && payload.Message == "" {
+		return common.NewProviderError("openocean", status, "", string(body), err)
+	}
+	return common.NewProviderError("openocean", status, strconv.Itoa(payload.Code), strings.TrimSpace(payload.Error+" "+payload.Message), err)
+}
+
+type OpenOceanToken struct {
+	Address  string `json:"address"`
+	Decimals int    `json:"decimals"`
+	Symbol   string `json:"symbol"`
+	Name     string `json:"name"`
+	Icon     string `json:"icon"`
+}
+
+type OpenOceanTokensResponse struct {
+	OpenOceanErrorResponse
+	Data []OpenOceanToken `json:"data"`
+}
+
+type OpenOceanDex struct {
+	DexIndex   int    `json:"dexIndex"`
+	DexCode    string `json:"dexCode"`
+	SwapAmount string `json:"swapAmount"`
+}
+
+type OpenOceanQuote struct {
+	InToken      OpenOceanToken `json:"inToken"`
+	OutToken     OpenOceanToken `json:"outToken"`
+	InAmount     string         `json:"inAmount"`
+	OutAmount    string         `json:"outAmount"`
+	EstimatedGas json.Number    `json:"estimatedGas"`
+	Dexes        []OpenOceanDex `json:"dexes"`
+	PriceImpact  string         `json:"price_impact"`
+}
+
+type OpenOceanQuoteResponse struct {
+	OpenOceanErrorResponse
+	Data OpenOceanQuote `json:"data"`
+}
+
+type OpenOceanSwap struct {
+	InToken      OpenOceanToken `json:"inToken"`
+	OutToken     OpenOceanToken `json:"outToken"`
+	InAmount     string         `json:"inAmount"`
+	OutAmount    string         `json:"outAmount"`
+	MinOutAmount string         `json:"minOutAmount"`
+	EstimatedGas json.Number    `json:"estimatedGas"`
+	From         string         `json:"from"`
+	To           string         `json:"to"`
+	Value        string         `json:"value"`
+	GasPrice     string         `json:"gasPrice"`
+	Data         string         `json:"data"`
+	ChainId      uint64         `json:"chainId"`
+	PriceImpact  string         `json:"price_impact"`
+}
+
+type OpenOceanSwapResponse struct {
+	OpenOceanErrorResponse
+	Data OpenOceanSwap `json:"data"`
+}
+
+func parseOpenOceanQuote(req common.QuoteReq, quote OpenOceanQuote, gasPriceGwei string, slippage uint32) (common.QuoteRes, error) {
+	outAmount, ok := common.ParseBigInt(quote.OutAmount)
+	if !ok {
+		return common.QuoteRes{}, fmt.Errorf("invalid out amount from openocean, amount: %v", quote.OutAmount)
+	}
+
+	gas, ok := common.ParseBigInt(quote.EstimatedGas.String())
+	if !ok {
+		return common.QuoteRes{}, fmt.Errorf("invalid gas from openocean, gas: %v", quote.EstimatedGas)
+	}
+
+	gasPrice, err := common.ParseUnits(gasPriceGwei, 9)
+	if err != nil {
+		return common.QuoteRes{}, err
+	}
+
+	var routes []common.Route
+	if len(quote.Dexes) > 0 {
+		hop := make([]common.RoutePart, 0, len(quote.Dexes))
+		for _, dex := range quote.Dexes {
+			part := 0.0
+			if amount, ok := common.ParseBigInt(dex.SwapAmount); ok && req.Amount.Sign() > 0 {
+				part, _ = new(big.Rat).SetFrac(new(big.Int).Mul(amount, big.NewInt(100)), req.Amount).Float64()
+			}
+			hop = append(hop, common.RoutePart{Protocol: dex.DexCode, Part: part, FromToken: req.Src, ToToken: req.Dst})
+		}
+		routes = []common.Route{{Hops: [][]common.RoutePart{hop}}}
+	}
+
+	return common.QuoteRes{
+		ChainId:     req.ChainId,
+		Src:         req.Src,
+		Dst:         req.Dst,
+		FromAmount:  req.Amount,
+		ToAmount:    outAmount,
+		Gas:         gas,
+		GasPrice:    gasPrice,
+		MinToAmount: common.MinReceived(outAmount, slippage),
+		Routes:      routes,
+	}, nil
+}
+
+func parseOpenOceanSwap(req common.QuoteReq, swap OpenOceanSwap, slippage uint32) (common.SwapTx, error) {
+	inAmount, ok := common.ParseBigInt(swap.InAmount)
+	if !ok {
+		return common.SwapTx{}, fmt.Errorf("invalid in amount from openocean, amount: %v", swap.InAmount)
+	}
+
+	outAmount, ok := common.ParseBigInt(swap.OutAmount)
+	if !ok {
+		return common.SwapTx{}, fmt.Errorf("invalid out amount from openocean, amount: %v", swap.OutAmount)
+	}
+
+	minOut := common.MinReceived(outAmount, slippage)
+	if swap.MinOutAmount != "" {
+		if minOut, ok = common.ParseBigInt(swap.MinOutAmount); !ok {
+			return common.SwapTx{}, fmt.Errorf("invalid min out amount from openocean, amount: %v", swap.MinOutAmount)
+		}
+	}
+
+	value, ok := common.ParseBigInt(swap.Value)
+	if !ok {
+		return common.SwapTx{}, fmt.Errorf("invalid tx value from openocean, value: %v", swap.Value)
+	}
+
+	gas, ok := common.ParseBigInt(swap.EstimatedGas.String())
+	if !ok {
+		return common.SwapTx{}, fmt.Errorf("invalid gas from openocean, gas: %v", swap.EstimatedGas)
+	}
+
+	gasPrice, ok := common.ParseBigInt(swap.GasPrice)
+	if !ok {
+		return common.SwapTx{}, fmt.Errorf("invalid gas price from openocean, gasPrice: %v", swap.GasPrice)
+	}
+
+	return common.SwapTx{
+		ChainId:         req.ChainId,
+		Src:             req.Src,
+		Dst:             req.Dst,
+		FromAmount:      inAmount,
+		ToAmount:        outAmount,
+		MinToAmount:     minOut,
+		From:            req.From,
+		To:              swap.To,
+		Data:            swap.Data,
+		Value:           value,
+		Gas:             gas,
+		GasPrice:        gasPrice,
+		AllowanceTarget: swap.To,
+	}, nil
+}
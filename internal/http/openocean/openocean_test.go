@@ -0,0 +1,157 @@
+package openocean
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"math/big"
+	"testing"
+
+	"github.com/lmittmann/w3"
+	"github.com/onmetahq/go-evm/internal/gas"
+	"github.com/onmetahq/go-evm/internal/http/common"
+	"github.com/onmetahq/go-evm/internal/http/fake"
+)
+
+const TOKENA = "0xeeeeeeeeeeeeeeeeeeeeeeeeeeeeeeeeeeeeeeee"
+const TOKENB = "0x2791bca1f2de4661ed88a30c99a7a9449aa84174"
+const FROM = "0x15Ba05723b04785C3E21157171810892A4FB795c"
+
+// newNode returns a node pricing gas at 42 gwei.
+func newNode(t *testing.T) (*fake.RPC, *w3.Client) {
+	node := fake.NewRPC()
+	t.Cleanup(node.Close)
+
+	node.Handle("eth_chainId", func(params []json.RawMessage) (any, error) {
+		return "0x89", nil
+	})
+	node.Handle("eth_gasPrice", func(params []json.RawMessage) (any, error) {
+		return "0x9c7652400", nil
+	})
+
+	client, err := w3.Dial(node.URL)
+	if err != nil {
+		t.Fatalf("dial err: %v", err)
+	}
+	t.Cleanup(func() { client.Close() })
+	return node, client
+}
+
+func TestFetchExactInQuote(t *testing.T) {
+	server := fake.NewServer()
+	defer server.Close()
+	node, rpc := newNode(t)
+
+	oceanClient := NewOpenOcean(common.NewClient(server.URL+fake.OpenOceanPrefix+"/v3", nil), map[uint64]*w3.Client{137: rpc})
+	req := common.QuoteReq{
+		ChainId:     137,
+		Src:         TOKENB,
+		Dst:         TOKENA,
+		Amount:      big.NewInt(1500000),
+		SlippageBps: common.Bps(50),
+	}
+
+	res, err := oceanClient.FetchExactInQuote(context.Background(), req)
+	if err != nil {
+		t.Fatalf("quote err: %v", err)
+	}
+
+	if res.ToAmount.Cmp(big.NewInt(3000000)) != 0 || res.MinToAmount.Cmp(big.NewInt(2985000)) != 0 {
+		t.Fatalf("invalid amounts, out: %s, min: %s", res.ToAmount, res.MinToAmount)
+	}
+
+	if res.GasPrice.Cmp(big.NewInt(42_000_000_000)) != 0 || node.Calls("eth_gasPrice") != 1 {
+		t.Fatalf("expected the node gas price, gasPrice: %s", res.GasPrice)
+	}
+
+	if len(res.Routes) != 1 || res.Routes[0].Hops[0][0].Part != 100 {
+		t.Fatalf("invalid routes, routes: %+v", res.Routes)
+	}
+
+	if calls := server.Calls("/v3/137/tokenList"); calls != 1 {
+		t.Fatalf("expected decimals from the token list, calls: %d", calls)
+	}
+}
+
+func TestGasOracle(t *testing.T) {
+	server := fake.NewServer()
+	defer server.Close()
+
+	oracle := gas.FixedOracle(map[uint64]*big.Int{137: big.NewInt(55_000_000_000)}, nil)
+	oceanClient := NewOpenOcean(common.NewClient(server.URL+fake.OpenOceanPrefix+"/v3", nil), nil, WithGasOracle(oracle))
+	res, err := oceanClient.FetchExactInQuote(context.Background(), common.QuoteReq{
+		ChainId: 137,
+		Src:     TOKENB,
+		Dst:     TOKENA,
+		Amount:  big.NewInt(1500000),
+	})
+	if err != nil {
+		t.Fatalf("quote err: %v", err)
+	}
+
+	if res.GasPrice.Cmp(big.NewInt(55_000_000_000)) != 0 {
+		t.Fatalf("expected the oracle gas price, gasPrice: %s", res.GasPrice)
+	}
+}
+
+func TestFetchExactInSwapCallData(t *testing.T) {
+	server := fake.NewServer()
+	defer server.Close()
+	node, rpc := newNode(t)
+
+	oceanClient := NewOpenOcean(common.NewClient(server.URL+fake.OpenOceanPrefix+"/v3", nil), map[uint64]*w3.Client{137: rpc})
+	req := common.QuoteReq{
+		ChainId:  137,
+		Src:      TOKENA,
+		Dst:      TOKENB,
+		Amount:   big.NewInt(1e18),
+		From:     FROM,
+		GasPrice: big.NewInt(35_500_000_000),
+	}
+
+	res, err := oceanClient.FetchExactInSwapCallData(context.Background(), req)
+	if err != nil {
+		t.Fatalf("swap err: %v", err)
+	}
+
+	if res.FromAmount.Cmp(req.Amount) != 0 || res.ToAmount.Cmp(big.NewInt(2e18)) != 0 || res.MinToAmount.Cmp(big.NewInt(198e16)) != 0 {
+		t.Fatalf("invalid amounts, in: %s, out: %s, min: %s", res.FromAmount, res.ToAmount, res.MinToAmount)
+	}
+
+	if res.GasPrice.Cmp(req.GasPrice) != 0 || node.Calls("eth_gasPrice") != 0 {
+		t.Fatalf("expected the request gas price, gasPrice: %s", res.GasPrice)
+	}
+
+	if res.To != fake.OpenOceanExchangeAddress || res.AllowanceTarget != fake.OpenOceanExchangeAddress || res.Data != fake.SwapCallData {
+		t.Fatalf("invalid tx, res: %+v", res)
+	}
+}
+
+func TestErrorPaths(t *testing.T) {
+	server := fake.NewServer()
+	defer server.Close()
+
+	oceanClient := NewOpenOcean(common.NewClient(server.URL+fake.OpenOceanPrefix+"/v3", nil), nil)
+	req := common.QuoteReq{
+		ChainId: 137,
+		Src:     TOKENB,
+		Dst:     TOKENA,
+		Amount:  big.NewInt(1000000),
+	}
+
+	_, err := oceanClient.FetchExactInQuote(context.Background(), req)
+	if !errors.Is(err, common.ErrUnsupportedChain) {
+		t.Fatalf("expected missing rpc to fail, err: %v", err)
+	}
+
+	req.GasPrice = big.NewInt(30_000_000_000)
+	req.Src = "0x0000000000000000000000000000000000000001"
+	_, err = oceanClient.FetchExactInQuote(context.Background(), req)
+	if !errors.Is(err, common.ErrUnsupportedToken) {
+		t.Fatalf("expected unknown decimals to fail, err: %v", err)
+	}
+
+	if _, err := oceanClient.FetchExactOutSwapCallData(context.Background(), req); !errors.Is(err, common.ErrUnsupportedParameter) {
+		t.Fatalf("expected exact out to be unsupported, err: %v", err)
+	}
+}
@@ -0,0 +1,550 @@
+package paraswap
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"math/big"
+	uri "net/url"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/onmetahq/go-evm/internal/http/common"
+	metahttp "github.com/onmetahq/meta-http/pkg/meta_http"
+)
+
+// Side is the swap direction of a ParaSwap price route.
+type Side string
+
+const (
+	// SideSell sells an exact amount of the source token.
+	SideSell Side = "SELL"
+	// SideBuy buys an exact amount of the destination token.
+	SideBuy Side = "BUY"
+)
+
+// APIVersion is the Augustus version requested from /prices.
+const APIVersion = "6.2"
+
+type paraSwap struct {
+	client      metahttp.Requests
+	credentials common.CredentialProvider
+	partner     string
+
+	mu       sync.Mutex
+	decimals map[uint64]map[string]int
+}
+
+type Option func(*paraSwap)
+
+// WithCredentials sets the API key source, the PARASWAP_KEY environment
+// variable is read on every call by default and no key is sent when empty.
+func WithCredentials(credentials common.CredentialProvider) Option {
+	return func(o *paraSwap) {
+		o.credentials = credentials
+	}
+}
+
+// WithPartner sets the partner name sent with every price and transaction
+// request.
+func WithPartner(partner string) Option {
+	return func(o *paraSwap) {
+		o.partner = partner
+	}
+}
+
+// NewParaSwap returns a ParaSwap provider, client must be configured with the
+// ParaSwap API base url, e.g. https://api.paraswap.io.
+func NewParaSwap(client metahttp.Requests, opts ...Option) *paraSwap {
+	o := &paraSwap{
+		client:      client,
+		credentials: common.EnvCredentials("PARASWAP_KEY"),
+		decimals:    map[uint64]map[string]int{},
+	}
+
+	for _, opt := range opts {
+		opt(o)
+	}
+	return o
+}
+
+var _ common.Aggregator = (*paraSwap)(nil)
+
+func (o *paraSwap) FetchSupportedTokens(ctx context.Context, chainId uint64) ([]common.Token, error) {
+	var res ParaSwapTokens
+	url := fmt.Sprintf("/tokens/%d", chainId)
+	headers, err := o.headers(ctx)
+	if err != nil {
+		return []common.Token{}, err
+	}
+
+	_, err = o.client.Get(ctx, url, headers, &res)
+	if err != nil {
+		return []common.Token{}, fmt.Errorf("unable to fetch all tokens from paraswap, err: %w", parseError(err))
+	}
+
+	decimals := map[string]int{}
+	var out []common.Token
+	for _, v := range res.Tokens {
+		decimals[strings.ToLower(v.Address)] = v.Decimals
+		out = append(out, common.Token{
+			ChainId:  chainId,
+			Address:  v.Address,
+			Symbol:   v.Symbol,
+			Name:     v.Symbol,
+			Decimals: v.Decimals,
+			LogoURI:  v.Img,
+		})
+	}
+
+	o.mu.Lock()
+	o.decimals[chainId] = decimals
+	o.mu.Unlock()
+	return out, nil
+}
+
+func (o *paraSwap) FetchExactInQuote(ctx context.Context, req common.QuoteReq) (common.QuoteRes, error) {
+	return o.fetchQuote(ctx, req, SideSell)
+}
+
+func (o *paraSwap) FetchExactOutQuote(ctx context.Context, req common.QuoteReq) (common.QuoteRes, error) {
+	return o.fetchQuote(ctx, req, SideBuy)
+}
+
+func (o *paraSwap) FetchExactInSwapCallData(ctx context.Context, req common.QuoteReq) (common.SwapTx, error) {
+	route, err := o.FetchPriceRoute(ctx, req, SideSell)
+	if err != nil {
+		return common.SwapTx{}, err
+	}
+	return o.BuildTransaction(ctx, req, route)
+}
+
+func (o *paraSwap) FetchExactOutSwapCallData(ctx context.Context, req common.QuoteReq) (common.SwapTx, error) {
+	route, err := o.FetchPriceRoute(ctx, req, SideBuy)
+	if err != nil {
+		return common.SwapTx{}, err
+	}
+	return o.BuildTransaction(ctx, req, route)
+}
+
+func (o *paraSwap) fetchQuote(ctx context.Context, req common.QuoteReq, side Side) (common.QuoteRes, error) {
+	slippage, err := req.Slippage()
+	if err != nil {
+		return common.QuoteRes{}, err
+	}
+
+	route, err := o.FetchPriceRoute(ctx, req, side)
+	if err != nil {
+		return common.QuoteRes{}, err
+	}
+	return parseParaSwapQuote(req, route, slippage)
+}
+
+// FetchPriceRoute returns the /prices route for req, it is passed unchanged
+// to BuildTransaction so the transaction settles the quoted route.
+func (o *paraSwap) FetchPriceRoute(ctx context.Context, req common.QuoteReq, side Side) (ParaSwapPriceRoute, error) {
+	srcDecimals, err := o.tokenDecimals(ctx, req.ChainId, req.Src, req.SrcDecimals)
+	if err != nil {
+		return ParaSwapPriceRoute{}, err
+	}
+
+	dstDecimals, err := o.tokenDecimals(ctx, req.ChainId, req.Dst, req.DstDecimals)
+	if err != nil {
+		return ParaSwapPriceRoute{}, err
+	}
+
+	if req.FeeBps > 0 && req.Referrer == "" {
+		return ParaSwapPriceRoute{}, fmt.Errorf("paraswap partner fee requires a referrer to receive it")
+	}
+
+	v := uri.Values{}
+	v.Add("srcToken", req.Src)
+	v.Add("srcDecimals", strconv.Itoa(srcDecimals))
+	v.Add("destToken", req.Dst)
+	v.Add("destDecimals", strconv.Itoa(dstDecimals))
+	v.Add("amount", req.Amount.String())
+	v.Add("side", string(side))
+	v.Add("network", strconv.FormatUint(req.ChainId, 10))
+	v.Add("version", APIVersion)
+
+	if req.From != "" {
+		v.Add("userAddress", req.From)
+	}
+
+	if len(req.IncludedSources) > 0 {
+		v.Add("includeDEXS", strings.Join(req.IncludedSources, ","))
+	}
+
+	if len(req.ExcludedSources) > 0 {
+		v.Add("excludeDEXS", strings.Join(req.ExcludedSources, ","))
+	}
+
+	if o.partner != "" {
+		v.Add("partner", o.partner)
+	}
+
+	if req.FeeBps > 0 {
+		v.Add("partnerAddress", req.Referrer)
+		v.Add("partnerFeeBps", strconv.FormatUint(uint64(req.FeeBps), 10))
+	}
+
+	var res ParaSwapPriceResponse
+	url := fmt.Sprintf("/prices?%s", v.Encode())
+	headers, err := o.headers(ctx)
+	if err != nil {
+		return ParaSwapPriceRoute{}, err
+	}
+
+	_, err = o.client.Get(ctx, url, headers, &res)
+	if err != nil {
+		return ParaSwapPriceRoute{}, fmt.Errorf("unable to fetch paraswap price, err: %w", parseError(err))
+	}
+
+	var route ParaSwapPriceRoute
+	if err := json.Unmarshal(res.PriceRoute, &route); err != nil {
+		return ParaSwapPriceRoute{}, fmt.Errorf("invalid price route from paraswap, err: %w", err)
+	}
+	route.Raw = res.PriceRoute
+	return route, nil
+}
+
+// BuildTransaction builds the swap transaction settling route, a route
+// returned by FetchPriceRoute for the same req.
+func (o *paraSwap) BuildTransaction(ctx context.Context, req common.QuoteReq, route ParaSwapPriceRoute) (common.SwapTx, error) {
+	slippage, err := req.Slippage()
+	if err != nil {
+		return common.SwapTx{}, err
+	}
+
+	body := ParaSwapTransactionReq{
+		SrcToken:     route.SrcToken,
+		SrcDecimals:  route.SrcDecimals,
+		DestToken:    route.DestToken,
+		DestDecimals: route.DestDecimals,
+		Slippage:     slippage,
+		PriceRoute:   route.Raw,
+		UserAddress:  req.From,
+		TxOrigin:     req.From,
+		Receiver:     req.Receiver,
+		Partner:      o.partner,
+	}
+
+	if Side(route.Side) == SideBuy {
+		body.DestAmount = route.DestAmount
+	} else {
+		body.SrcAmount = route.SrcAmount
+	}
+
+	if req.FeeBps > 0 {
+		body.PartnerAddress = req.Referrer
+		body.PartnerFeeBps = req.FeeBps
+	}
+
+	v := uri.Values{}
+	if req.SkipValidation {
+		v.Add("ignoreChecks", "true")
+	}
+
+	if req.GasPrice != nil {
+		v.Add("gasPrice", req.GasPrice.String())
+	}
+
+	url := fmt.Sprintf("/transactions/%d", req.ChainId)
+	if query := v.Encode(); query != "" {
+		url = fmt.Sprintf("%s?%s", url, query)
+	}
+
+	var res ParaSwapTransactionResponse
+	headers, err := o.headers(ctx)
+	if err != nil {
+		return common.SwapTx{}, err
+	}
+
+	_, err = o.client.Post(ctx, url, headers, body, &res)
+	if err != nil {
+		return common.SwapTx{}, fmt.Errorf("unable to build paraswap transaction, err: %w", parseError(err))
+	}
+	return parseParaSwapTransaction(req, route, res, slippage)
+}
+
+// tokenDecimals returns known when set, 18 for the native token and the
+// decimals from the ParaSwap token list otherwise.
+func (o *paraSwap) tokenDecimals(ctx context.Context, chainId uint64, token string, known int) (int, error) {
+	if known > 0 {
+		return known, nil
+	}
+
+	if common.IsNativeToken(token) {
+		return 18, nil
+	}
+
+	o.mu.Lock()
+	decimals, ok := o.decimals[chainId]
+	o.mu.Unlock()
+
+	if !ok {
+		if _, err := o.FetchSupportedTokens(ctx, chainId); err != nil {
+			return 0, err
+		}
+
+		o.mu.Lock()
+		decimals = o.decimals[chainId]
+		o.mu.Unlock()
+	}
+
+	d, ok := decimals[strings.ToLower(token)]
+	if !ok {
+		return 0, fmt.Errorf("unknown decimals of %s on paraswap, err: %w", token, common.ErrUnsupportedToken)
+	}
+	return d, nil
+}
+
+func (o *paraSwap) headers(ctx context.Context) (map[string]string, error) {
+	key, err := o.credentials.Credential(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("unable to load paraswap credentials, err: %w", err)
+	}
+
+	if key == "" {
+		return map[string]string{}, nil
+	}
+	return map[string]string{
+		"X-API-KEY": key,
+	}, nil
+}
+
+type ParaSwapErrorResponse struct {
+	Error string `json:"error"`
+}
+
+// parseError classifies a failed ParaSwap call from its error payload, e.g.
+// {"error":"No routes found with enough liquidity"}.
+func parseError(err error) error {
+	status, body, ok := common.StatusAndBody(err)
+	if !ok {
+		return common.NewProviderError("paraswap", 0, "", "", err)
+	}
+
+	var payload ParaSwapErrorResponse
+	if json.Unmarshal(body, &payload) != nil || payload.Error == "" {
+		return common.NewProviderError("paraswap", status, "", string(body), err)
+	}
+	return common.NewProviderError("paraswap", status, "", payload.Error, err)
+}
+
+type ParaSwapToken struct {
+	Symbol   string `json:"symbol"`
+	Address  string `json:"address"`
+	Decimals int    `json:"decimals"`
+	Img      string `json:"img"`
+	Network  uint64 `json:"network"`
+}
+
+type ParaSwapTokens struct {
+	Tokens []ParaSwapToken `json:"tokens"`
+}
+
+type ParaSwapPriceResponse struct {
+	PriceRoute json.RawMessage `json:"priceRoute"`
+}
+
+type ParaSwapSwapExchange struct {
+	Exchange   string  `json:"exchange"`
+	SrcAmount  string  `json:"srcAmount"`
+	DestAmount string  `json:"destAmount"`
+	Percent    float64 `json:"percent"`
+}
+
+type ParaSwapSwap struct {
+	SrcToken      string                 `json:"srcToken"`
+	SrcDecimals   int                    `json:"srcDecimals"`
+	DestToken     string                 `json:"destToken"`
+	DestDecimals  int                    `json:"destDecimals"`
+	SwapExchanges []ParaSwapSwapExchange `json:"swapExchanges"`
+}
+
+type ParaSwapRoute struct {
+	Percent float64        `json:"percent"`
+	Swaps   []ParaSwapSwap `json:"swaps"`
+}
+
+type ParaSwapPriceRoute struct {
+	BlockNumber        uint64          `json:"blockNumber"`
+	Network            uint64          `json:"network"`
+	SrcToken           string          `json:"srcToken"`
+	SrcDecimals        int             `json:"srcDecimals"`
+	SrcAmount          string          `json:"srcAmount"`
+	DestToken          string          `json:"destToken"`
+	DestDecimals       int             `json:"destDecimals"`
+	DestAmount         string          `json:"destAmount"`
+	BestRoute          []ParaSwapRoute `json:"bestRoute"`
+	GasCost            string          `json:"gasCost"`
+	GasCostUSD         string          `json:"gasCostUSD"`
+	Side               string          `json:"side"`
+	Version            string          `json:"version"`
+	ContractAddress    string          `json:"contractAddress"`
+	TokenTransferProxy string          `json:"tokenTransferProxy"`
+	ContractMethod     string          `json:"contractMethod"`
+	Partner            string          `json:"partner"`
+	PartnerFee         float64         `json:"partnerFee"`
+	MaxImpactReached   bool            `json:"maxImpactReached"`
+	// Raw is the priceRoute as returned by /prices, it is sent back
+	// unchanged to /transactions.
+	Raw json.RawMessage `json:"-"`
+}
+
+type ParaSwapTransactionReq struct {
+	SrcToken       string          `json:"srcToken"`
+	SrcDecimals    int             `json:"srcDecimals"`
+	DestToken      string          `json:"destToken"`
+	DestDecimals   int             `json:"destDecimals"`
+	SrcAmount      string          `json:"srcAmount,omitempty"`
+	DestAmount     string          `json:"destAmount,omitempty"`
+	Slippage       uint32          `json:"slippage"`
+	PriceRoute     json.RawMessage `json:"priceRoute"`
+	UserAddress    string          `json:"userAddress"`
+	TxOrigin       string          `json:"txOrigin,omitempty"`
+	Receiver       string          `json:"receiver,omitempty"`
+	Partner        string          `json:"partner,omitempty"`
+	PartnerAddress string          `json:"partnerAddress,omitempty"`
+	PartnerFeeBps  uint32          `json:"partnerFeeBps,omitempty"`
+}
+
+type ParaSwapTransactionResponse struct {
+	From     string `json:"from"`
+	To       string `json:"to"`
+	Value    string `json:"value"`
+	Data     string `json:"data"`
+	GasPrice string `json:"gasPrice"`
+	Gas      string `json:"gas"`
+	ChainId  uint64 `json:"chainId"`
+}
+
+func parseRoutes(bestRoute []ParaSwapRoute) []common.Route {
+	routes := make([]common.Route, 0, len(bestRoute))
+	for _, route := range bestRoute {
+		hops := make([][]common.RoutePart, 0, len(route.Swaps))
+		for _, swap := range route.Swaps {
+			parts := make([]common.RoutePart, 0, len(swap.SwapExchanges))
+			for _, ex := range swap.SwapExchanges {
+				parts = append(parts, common.RoutePart{
+					Protocol:  ex.Exchange,
+					Part:      ex.Percent,
+					FromToken: swap.SrcToken,
+					ToToken:   swap.DestToken,
+				})
+			}
+			hops = append(hops, parts)
+		}
+		routes = append(routes, common.Route{Hops: hops})
+	}
+	return routes
+}
+
+func parseParaSwapQuote(req common.QuoteReq, route ParaSwapPriceRoute, slippage uint32) (common.QuoteRes, error) {
+	srcAmount, ok := common.ParseBigInt(route.SrcAmount)
+	if !ok {
+		return common.QuoteRes{}, fmt.Errorf("invalid src amount from paraswap, amount: %v", route.SrcAmount)
+	}
+
+	destAmount, ok := common.ParseBigInt(route.DestAmount)
+	if !ok {
+		return common.QuoteRes{}, fmt.Errorf("invalid dest amount from paraswap, amount: %v", route.DestAmount)
+	}
+
+	gas, ok := common.ParseBigInt(route.GasCost)
+	if !ok {
+		return common.QuoteRes{}, fmt.Errorf("invalid gas from paraswap, gas: %v", route.GasCost)
+	}
+
+	// Exact out quotes carry the sell amount in ToAmount, the buy amount is
+	// the requested one.
+	outAmount, minOut := destAmount, common.MinReceived(destAmount, slippage)
+	if Side(route.Side) == SideBuy {
+		outAmount, minOut = srcAmount, req.Amount
+	}
+
+	// /prices does not return a gas price, the router prices the quote with
+	// its own when zero.
+	gasPrice := big.NewInt(0)
+	if req.GasPrice != nil {
+		gasPrice = req.GasPrice
+	}
+
+	return common.QuoteRes{
+		ChainId:     req.ChainId,
+		Src:         req.Src,
+		Dst:         req.Dst,
+		FromAmount:  req.Amount,
+		ToAmount:    outAmount,
+		Gas:         gas,
+		GasPrice:    gasPrice,
+		MinToAmount: minOut,
+		Routes:      parseRoutes(route.BestRoute),
+	}, nil
+}
+
+func parseParaSwapTransaction(req common.QuoteReq, route ParaSwapPriceRoute, tx ParaSwapTransactionResponse, slippage uint32) (common.SwapTx, error) {
+	srcAmount, ok := common.ParseBigInt(route.SrcAmount)
+	if !ok {
+		return common.SwapTx{}, fmt.Errorf("invalid src amount from paraswap, amount: %v", route.SrcAmount)
+	}
+
+	destAmount, ok := common.ParseBigInt(route.DestAmount)
+	if !ok {
+		return common.SwapTx{}, fmt.Errorf("invalid dest amount from paraswap, amount: %v", route.DestAmount)
+	}
+
+	value, ok := common.ParseBigInt(tx.Value)
+	if !ok {
+		return common.SwapTx{}, fmt.Errorf("invalid tx value from paraswap, value: %v", tx.Value)
+	}
+
+	gasPrice, ok := common.ParseBigInt(tx.GasPrice)
+	if !ok {
+		return common.SwapTx{}, fmt.Errorf("invalid gas price from paraswap, gasPrice: %v", tx.GasPrice)
+	}
+
+	// /transactions omits gas when checks are skipped, fall back to the
+	// route estimate.
+	gasValue := tx.Gas
+	if gasValue == "" {
+		gasValue = route.GasCost
+	}
+	gas, ok := common.ParseBigInt(gasValue)
+	if !ok {
+		return common.SwapTx{}, fmt.Errorf("invalid gas from paraswap, gas: %v", gasValue)
+	}
+
+	// Exact out buys exactly destAmount and may pull up to the slippage
+	// more than srcAmount, exact in may receive up to the slippage less.
+	fromAmount, minOut := srcAmount, destAmount
+	if Side(route.Side) == SideBuy {
+		fromAmount = common.MaxSent(srcAmount, slippage)
+	} else {
+		minOut = common.MinReceived(destAmount, slippage)
+	}
+
+	spender := route.TokenTransferProxy
+	if spender == "" {
+		spender = route.ContractAddress
+	}
+
+	return common.SwapTx{
+		ChainId:         req.ChainId,
+		Src:             req.Src,
+		Dst:             req.Dst,
+		FromAmount:      fromAmount,
+		ToAmount:        destAmount,
+		MinToAmount:     minOut,
+		From:            req.From,
+		To:              tx.To,
+		Data:            tx.Data,
+		Value:           value,
+		Gas:             gas,
+		GasPrice:        gasPrice,
+		AllowanceTarget: spender,
+		Routes:          parseRoutes(route.BestRoute),
+	}, nil
+}
@@ -0,0 +1,164 @@
+package paraswap
+
+import (
+	"context"
+	"errors"
+	"math/big"
+	"net/http"
+	"testing"
+
+	"github.com/onmetahq/go-evm/internal/http/common"
+	"github.com/onmetahq/go-evm/internal/http/fake"
+)
+
+const TOKENA = "0xeeeeeeeeeeeeeeeeeeeeeeeeeeeeeeeeeeeeeeee"
+const TOKENB = "0x2791bca1f2de4661ed88a30c99a7a9449aa84174"
+const FROM = "0x15Ba05723b04785C3E21157171810892A4FB795c"
+
+func TestFetchExactInQuote(t *testing.T) {
+	server := fake.NewServer()
+	defer server.Close()
+
+	paraClient := NewParaSwap(common.NewClient(server.URL+fake.ParaSwapPrefix, nil))
+	req := common.QuoteReq{
+		ChainId:     137,
+		Src:         TOKENB,
+		Dst:         TOKENA,
+		Amount:      big.NewInt(1000000),
+		SlippageBps: common.Bps(50),
+	}
+
+	for i := 0; i < 2; i++ {
+		res, err := paraClient.FetchExactInQuote(context.Background(), req)
+		if err != nil {
+			t.Fatalf("quote err: %v", err)
+		}
+
+		if res.ToAmount.Cmp(big.NewInt(2000000)) != 0 || res.MinToAmount.Cmp(big.NewInt(1990000)) != 0 {
+			t.Fatalf("invalid amounts, out: %s, min: %s", res.ToAmount, res.MinToAmount)
+		}
+
+		if len(res.Routes) != 1 || res.Routes[0].Hops[0][0].Protocol != "UniswapV3" {
+			t.Fatalf("invalid routes, routes: %+v", res.Routes)
+		}
+	}
+
+	if calls := server.Calls("/tokens/137"); calls != 1 {
+		t.Fatalf("expected token decimals to be cached, calls: %d", calls)
+	}
+}
+
+func TestFetchExactOutSwapCallData(t *testing.T) {
+	server := fake.NewServer()
+	defer server.Close()
+
+	paraClient := NewParaSwap(common.NewClient(server.URL+fake.ParaSwapPrefix, nil))
+	req := common.QuoteReq{
+		ChainId:     137,
+		Src:         TOKENB,
+		Dst:         TOKENA,
+		SrcDecimals: 6,
+		Amount:      big.NewInt(2000000),
+		From:        FROM,
+	}
+
+	res, err := paraClient.FetchExactOutSwapCallData(context.Background(), req)
+	if err != nil {
+		t.Fatalf("swap err: %v", err)
+	}
+
+	// The swap may pull the 1000000 quoted input plus the default 1%
+	// slippage.
+	if res.FromAmount.Cmp(big.NewInt(1010000)) != 0 || res.ToAmount.Cmp(req.Amount) != 0 || res.MinToAmount.Cmp(req.Amount) != 0 {
+		t.Fatalf("invalid amounts, in: %s, out: %s, min: %s", res.FromAmount, res.ToAmount, res.MinToAmount)
+	}
+
+	if res.To != fake.AugustusAddress || res.AllowanceTarget != fake.AugustusAddress || res.Data != fake.SwapCallData {
+		t.Fatalf("invalid tx, res: %+v", res)
+	}
+
+	if res.Gas.Cmp(big.NewInt(150000)) != 0 {
+		t.Fatalf("invalid gas, gas: %s", res.Gas)
+	}
+
+	// 1000001 * 1.0033 is rounded up so approving FromAmount is enough.
+	req.Amount, req.SlippageBps = big.NewInt(2000002), common.Bps(33)
+	res, err = paraClient.FetchExactOutSwapCallData(context.Background(), req)
+	if err != nil {
+		t.Fatalf("swap err: %v", err)
+	}
+
+	if res.FromAmount.Cmp(big.NewInt(1003302)) != 0 {
+		t.Fatalf("invalid max input, in: %s", res.FromAmount)
+	}
+}
+
+func TestPriceRoutePassthrough(t *testing.T) {
+	server := fake.NewServer()
+	defer server.Close()
+
+	paraClient := NewParaSwap(common.NewClient(server.URL+fake.ParaSwapPrefix, nil), WithPartner("go-evm"))
+	req := common.QuoteReq{
+		ChainId:        137,
+		Src:            TOKENB,
+		Dst:            TOKENA,
+		SrcDecimals:    6,
+		Amount:         big.NewInt(1000000),
+		From:           FROM,
+		Referrer:       FROM,
+		FeeBps:         15,
+		SkipValidation: true,
+	}
+
+	route, err := paraClient.FetchPriceRoute(context.Background(), req, SideSell)
+	if err != nil {
+		t.Fatalf("price err: %v", err)
+	}
+
+	if route.Partner != "go-evm" || route.PartnerFee != 0.15 || len(route.Raw) == 0 {
+		t.Fatalf("invalid price route, route: %+v", route)
+	}
+
+	res, err := paraClient.BuildTransaction(context.Background(), req, route)
+	if err != nil {
+		t.Fatalf("build err: %v", err)
+	}
+
+	if res.ToAmount.Cmp(big.NewInt(2000000)) != 0 || res.Gas.Cmp(big.NewInt(150000)) != 0 {
+		t.Fatalf("invalid swap, res: %+v", res)
+	}
+
+	if calls := server.Calls("/prices"); calls != 1 {
+		t.Fatalf("expected a single price call, calls: %d", calls)
+	}
+
+	req.Referrer = ""
+	if _, err := paraClient.FetchPriceRoute(context.Background(), req, SideSell); err == nil {
+		t.Fatalf("expected fee without referrer to fail")
+	}
+}
+
+func TestErrorPaths(t *testing.T) {
+	server := fake.NewServer()
+	defer server.Close()
+
+	paraClient := NewParaSwap(common.NewClient(server.URL+fake.ParaSwapPrefix, nil))
+	req := common.QuoteReq{
+		ChainId: 137,
+		Src:     TOKENB,
+		Dst:     "0x0000000000000000000000000000000000000001",
+		Amount:  big.NewInt(1000000),
+	}
+
+	_, err := paraClient.FetchExactInQuote(context.Background(), req)
+	if !errors.Is(err, common.ErrUnsupportedToken) {
+		t.Fatalf("expected unsupported token, err: %v", err)
+	}
+
+	req.Dst = TOKENA
+	server.Fail("/prices", http.StatusBadRequest, `{"error":"No routes found with enough liquidity"}`)
+	_, err = paraClient.FetchExactInQuote(context.Background(), req)
+	if !errors.Is(err, common.ErrInsufficientLiquidity) || common.IsRetryable(err) {
+		t.Fatalf("expected insufficient liquidity, err: %v", err)
+	}
+}
@@ -0,0 +1,245 @@
+// Package recorder is an http.RoundTripper that records provider responses
+// into cassette files and replays them, so provider tests run offline.
+// Replaying only checks a client against the cassette it is given: the
+// cassettes written by hand rather than recorded are named
+// <name>.synthetic.json, they follow the documented responses and may
+// drift from what the live API returns.
+package recorder
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"sync"
+)
+
+type Mode int
+
+const (
+	// ModeReplay serves every request from the cassette and fails on
+	// requests that were not recorded.
+	ModeReplay Mode = iota
+	// ModeRecord sends requests to the network and stores the responses.
+	ModeRecord
+)
+
+// RecordEnv switches ModeFromEnv to ModeRecord when set to 1.
+const RecordEnv = "GO_EVM_RECORD"
+
+// ModeFromEnv returns ModeRecord when GO_EVM_RECORD=1, ModeReplay otherwise.
+func ModeFromEnv() Mode {
+	if os.Getenv(RecordEnv) == "1" {
+		return ModeRecord
+	}
+	return ModeReplay
+}
+
+// Interaction is a single recorded request and response. Request headers
+// are never stored so API keys do not end up in cassettes. JSON bodies are
+// kept readable in RequestBody and Body, any other body is stored base64
+// encoded in RawRequestBody and RawBody and replayed byte for byte.
+type Interaction struct {
+	Method         string          `json:"method"`
+	URL            string          `json:"url"`
+	RequestBody    json.RawMessage `json:"requestBody,omitempty"`
+	RawRequestBody []byte          `json:"rawRequestBody,omitempty"`
+	StatusCode     int             `json:"statusCode"`
+	ContentType    string          `json:"contentType,omitempty"`
+	Body           json.RawMessage `json:"body,omitempty"`
+	RawBody        []byte          `json:"rawBody,omitempty"`
+}
+
+type Cassette struct {
+	Interactions []Interaction `json:"interactions"`
+}
+
+type Recorder struct {
+	path string
+	mode Mode
+	next http.RoundTripper
+
+	mu       sync.Mutex
+	cassette Cassette
+	// replayed tracks the interactions already served so identical
+	// requests are answered in recording order.
+	replayed map[int]bool
+}
+
+// New loads the cassette at path in ModeReplay, in ModeRecord requests are
+// sent through next, http.DefaultTransport when nil, and Save writes them.
+func New(path string, mode Mode, next http.RoundTripper) (*Recorder, error) {
+	if next == nil {
+		next = http.DefaultTransport
+	}
+
+	r := &Recorder{
+		path:     path,
+		mode:     mode,
+		next:     next,
+		replayed: map[int]bool{},
+	}
+
+	if mode == ModeRecord {
+		return r, nil
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("unable to read cassette, err: %w", err)
+	}
+
+	if err := json.Unmarshal(data, &r.cassette); err != nil {
+		return nil, fmt.Errorf("unable to decode cassette %s, err: %w", path, err)
+	}
+	return r, nil
+}
+
+// Client returns an http.Client using the recorder as transport.
+func (r *Recorder) Client() *http.Client {
+	return &http.Client{Transport: r}
+}
+
+func (r *Recorder) RoundTrip(req *http.Request) (*http.Response, error) {
+	var reqBody []byte
+	if req.Body != nil {
+		var err error
+		reqBody, err = io.ReadAll(req.Body)
+		if err != nil {
+			return nil, err
+		}
+		req.Body.Close()
+		req.Body = io.NopCloser(bytes.NewReader(reqBody))
+	}
+
+	key := normalizeURL(req.URL)
+	if r.mode == ModeReplay {
+		return r.replay(req, key, reqBody)
+	}
+
+	res, err := r.next.RoundTrip(req)
+	if err != nil {
+		return nil, err
+	}
+	defer res.Body.Close()
+
+	body, err := io.ReadAll(res.Body)
+	if err != nil {
+		return nil, err
+	}
+
+	in := Interaction{
+		Method:      req.Method,
+		URL:         key,
+		StatusCode:  res.StatusCode,
+		ContentType: res.Header.Get("Content-Type"),
+	}
+	in.RequestBody, in.RawRequestBody = splitBody(reqBody)
+	in.Body, in.RawBody = splitBody(body)
+
+	r.mu.Lock()
+	r.cassette.Interactions = append(r.cassette.Interactions, in)
+	r.mu.Unlock()
+
+	return response(req, in, body), nil
+}
+
+// Save writes the recorded interactions, it is a no-op in ModeReplay.
+func (r *Recorder) Save() error {
+	if r.mode != ModeRecord {
+		return nil
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	data, err := json.MarshalIndent(r.cassette, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	if err := os.MkdirAll(filepath.Dir(r.path), 0o755); err != nil {
+		return err
+	}
+	return os.WriteFile(r.path, append(data, '\n'), 0o644)
+}
+
+func (r *Recorder) replay(req *http.Request, key string, reqBody []byte) (*http.Response, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for i, in := range r.cassette.Interactions {
+		if r.replayed[i] || in.Method != req.Method || in.URL != key {
+			continue
+		}
+		if len(in.RequestBody) > 0 && !jsonEqual(in.RequestBody, reqBody) {
+			continue
+		}
+		if len(in.RawRequestBody) > 0 && !bytes.Equal(in.RawRequestBody, reqBody) {
+			continue
+		}
+
+		r.replayed[i] = true
+		body := []byte(in.Body)
+		if in.RawBody != nil {
+			body = in.RawBody
+		}
+		return response(req, in, body), nil
+	}
+	return nil, fmt.Errorf("no recorded interaction for %s %s in %s", req.Method, key, r.path)
+}
+
+// normalizeURL sorts the query so recordings do not depend on parameter
+// order.
+func normalizeURL(u *url.URL) string {
+	c := *u
+	c.RawQuery = c.Query().Encode()
+	return c.String()
+}
+
+// response replays in with body, cassettes recorded without a content type
+// are JSON.
+func response(req *http.Request, in Interaction, body []byte) *http.Response {
+	contentType := in.ContentType
+	if contentType == "" {
+		contentType = "application/json"
+	}
+
+	return &http.Response{
+		StatusCode:    in.StatusCode,
+		Status:        fmt.Sprintf("%d %s", in.StatusCode, http.StatusText(in.StatusCode)),
+		Header:        http.Header{"Content-Type": []string{contentType}},
+		Body:          io.NopCloser(bytes.NewReader(body)),
+		ContentLength: int64(len(body)),
+		Request:       req,
+		Proto:         "HTTP/1.1",
+		ProtoMajor:    1,
+		ProtoMinor:    1,
+	}
+}
+
+// splitBody returns a JSON body as is to keep it readable in cassettes,
+// anything else is returned as raw bytes.
+func splitBody(body []byte) (json.RawMessage, []byte) {
+	if len(body) == 0 {
+		return nil, nil
+	}
+	if json.Valid(body) {
+		return body, nil
+	}
+	return nil, body
+}
+
+func jsonEqual(a, b []byte) bool {
+	var x, y any
+	if json.Unmarshal(a, &x) != nil || json.Unmarshal(b, &y) != nil {
+		return bytes.Equal(a, b)
+	}
+	ax, _ := json.Marshal(x)
+	by, _ := json.Marshal(y)
+	return bytes.Equal(ax, by)
+}
@@ -0,0 +1,100 @@
+package recorder
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestRecordAndReplay(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte(`{"toAmount":"42"}`))
+	}))
+	defer server.Close()
+
+	path := filepath.Join(t.TempDir(), "cassette.json")
+	rec, err := New(path, ModeRecord, nil)
+	if err != nil {
+		t.Fatalf("recorder err: %v", err)
+	}
+
+	if _, err := rec.Client().Get(server.URL + "/137/quote?src=a&dst=b"); err != nil {
+		t.Fatalf("record err: %v", err)
+	}
+	if err := rec.Save(); err != nil {
+		t.Fatalf("save err: %v", err)
+	}
+	server.Close()
+
+	replay, err := New(path, ModeReplay, nil)
+	if err != nil {
+		t.Fatalf("replay err: %v", err)
+	}
+
+	// Query order does not matter once recorded.
+	res, err := replay.Client().Get(server.URL + "/137/quote?dst=b&src=a")
+	if err != nil {
+		t.Fatalf("replay err: %v", err)
+	}
+	var body struct {
+		ToAmount string `json:"toAmount"`
+	}
+	if err := json.NewDecoder(res.Body).Decode(&body); err != nil || body.ToAmount != "42" {
+		t.Fatalf("invalid replayed body, body: %+v, err: %v", body, err)
+	}
+
+	if _, err := replay.Client().Get(server.URL + "/137/swap"); err == nil {
+		t.Fatalf("expected error for unrecorded request")
+	}
+}
+
+func TestReplayRawBody(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+		w.WriteHeader(http.StatusBadGateway)
+		_, _ = w.Write([]byte("upstream \"timeout\"\n"))
+	}))
+	defer server.Close()
+
+	path := filepath.Join(t.TempDir(), "cassette.json")
+	rec, err := New(path, ModeRecord, nil)
+	if err != nil {
+		t.Fatalf("recorder err: %v", err)
+	}
+
+	if _, err := rec.Client().Post(server.URL+"/order", "application/x-www-form-urlencoded", strings.NewReader("a=1&b=2")); err != nil {
+		t.Fatalf("record err: %v", err)
+	}
+	if err := rec.Save(); err != nil {
+		t.Fatalf("save err: %v", err)
+	}
+	server.Close()
+
+	replay, err := New(path, ModeReplay, nil)
+	if err != nil {
+		t.Fatalf("replay err: %v", err)
+	}
+
+	if _, err := replay.Client().Post(server.URL+"/order", "application/x-www-form-urlencoded", strings.NewReader("a=2&b=1")); err == nil {
+		t.Fatalf("expected error for a different request body")
+	}
+
+	res, err := replay.Client().Post(server.URL+"/order", "application/x-www-form-urlencoded", strings.NewReader("a=1&b=2"))
+	if err != nil {
+		t.Fatalf("replay err: %v", err)
+	}
+	defer res.Body.Close()
+
+	body, err := io.ReadAll(res.Body)
+	if err != nil || string(body) != "upstream \"timeout\"\n" {
+		t.Fatalf("expected the original bytes, body: %q, err: %v", body, err)
+	}
+
+	if res.StatusCode != http.StatusBadGateway || res.Header.Get("Content-Type") != "text/plain; charset=utf-8" {
+		t.Fatalf("invalid replayed response, status: %d, content type: %s", res.StatusCode, res.Header.Get("Content-Type"))
+	}
+}
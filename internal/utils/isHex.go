@@ -1,9 +0,0 @@
-package hex
-
-import "regexp"
-
-func WithOrWithout0xPrefix(data string) bool {
-	pattern := `^(0x)?[0-9a-fA-F]+$`
-	matched, _ := regexp.MatchString(pattern, data)
-	return matched
-}
@@ -0,0 +1,616 @@
+// Package aggregator exposes the DEX aggregator providers behind a single
+// Aggregator interface so callers can swap providers without type switches.
+package aggregator
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"fmt"
+	"math/big"
+	"net/http"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/signer/core/apitypes"
+	"github.com/lmittmann/w3"
+	"github.com/onmetahq/go-evm/internal/allowance"
+	"github.com/onmetahq/go-evm/internal/dex"
+	"github.com/onmetahq/go-evm/internal/gas"
+	zerox "github.com/onmetahq/go-evm/internal/http/0x"
+	oneinch "github.com/onmetahq/go-evm/internal/http/1inch"
+	"github.com/onmetahq/go-evm/internal/http/common"
+	"github.com/onmetahq/go-evm/internal/http/cow"
+	"github.com/onmetahq/go-evm/internal/http/kyberswap"
+	"github.com/onmetahq/go-evm/internal/http/lifi"
+	"github.com/onmetahq/go-evm/internal/http/odos"
+	"github.com/onmetahq/go-evm/internal/http/openocean"
+	"github.com/onmetahq/go-evm/internal/http/paraswap"
+	metahttp "github.com/onmetahq/meta-http/pkg/meta_http"
+)
+
+type (
+	Aggregator = common.Aggregator
+	QuoteReq   = common.QuoteReq
+	QuoteRes   = common.QuoteRes
+	SwapTx     = common.SwapTx
+	Token      = common.Token
+	Route      = common.Route
+	RoutePart  = common.RoutePart
+	ApproveTx  = common.ApproveTx
+	// ProviderOptions are set on QuoteReq.Options, e.g. OneInchOptions.
+	ProviderOptions = common.ProviderOptions
+)
+
+type (
+	MultiAggregator = common.MultiAggregator
+	MultiQuoteReq   = common.MultiQuoteReq
+	MultiQuoteRes   = common.MultiQuoteRes
+	MultiSwapTx     = common.MultiSwapTx
+	TokenAmount     = common.TokenAmount
+	TokenProportion = common.TokenProportion
+)
+
+type (
+	ProviderError = common.ProviderError
+	HTTPError     = common.HTTPError
+)
+
+var (
+	ErrInsufficientLiquidity = common.ErrInsufficientLiquidity
+	ErrRateLimited           = common.ErrRateLimited
+	ErrUnsupportedToken      = common.ErrUnsupportedToken
+	ErrUnsupportedChain      = common.ErrUnsupportedChain
+	ErrInsufficientAllowance = common.ErrInsufficientAllowance
+	ErrAuth                  = common.ErrAuth
+	ErrUnsupportedParameter  = common.ErrUnsupportedParameter
+)
+
+// IsRetryable reports whether the same request may succeed when retried.
+func IsRetryable(err error) bool {
+	return common.IsRetryable(err)
+}
+
+// NewHTTPClient returns a metahttp.Requests that keeps the body of failed
+// responses, so provider errors are classified from their error payloads.
+// httpClient may be nil.
+func NewHTTPClient(baseUrl string, httpClient *http.Client) metahttp.Requests {
+	return common.NewClient(baseUrl, httpClient)
+}
+
+const (
+	DefaultSlippageBps = common.DefaultSlippageBps
+	MaxSlippageBps     = common.MaxSlippageBps
+)
+
+// Bps returns a pointer to bps for the SlippageBps request fields, nil
+// selects DefaultSlippageBps.
+var Bps = common.Bps
+
+type (
+	CredentialProvider = common.CredentialProvider
+	KeyBudget          = common.KeyBudget
+)
+
+var (
+	StaticCredentials   = common.StaticCredentials
+	EnvCredentials      = common.EnvCredentials
+	FileCredentials     = common.FileCredentials
+	RotatingCredentials = common.RotatingCredentials
+)
+
+type (
+	OneInchOption  = oneinch.Option
+	ZeroXOption    = zerox.Option
+	ExactOutConfig = oneinch.ExactOutConfig
+	OneInchOptions = oneinch.OneInchOptions
+)
+
+type KyberSwapOptions = kyberswap.KyberSwapOptions
+
+var (
+	WithOneInchCredentials = oneinch.WithCredentials
+	WithZeroXCredentials   = zerox.WithCredentials
+	WithExactOutConfig     = oneinch.WithExactOutConfig
+	DefaultExactOutConfig  = oneinch.DefaultExactOutConfig
+)
+
+type (
+	OneInchFusionQuote  = oneinch.OneInchFusionQuote
+	OneInchFusionOrder  = oneinch.OneInchFusionOrder
+	OneInchFusionPreset = oneinch.OneInchFusionPreset
+
+	OneInchFusionPlusQuote     = oneinch.OneInchFusionPlusQuote
+	OneInchFusionPlusOrder     = oneinch.OneInchFusionPlusOrder
+	OneInchFusionPlusPreset    = oneinch.OneInchFusionPlusPreset
+	OneInchFusionPlusReadyFill = oneinch.OneInchFusionPlusReadyFill
+	OneInchTimeLocks           = oneinch.OneInchTimeLocks
+	OneInchEscrowVerifier      = oneinch.EscrowVerifier
+)
+
+const (
+	OneInchFusionPresetFast   = oneinch.FusionPresetFast
+	OneInchFusionPresetMedium = oneinch.FusionPresetMedium
+	OneInchFusionPresetSlow   = oneinch.FusionPresetSlow
+)
+
+// WithOneInchFusionClient enables gasless single chain Fusion orders, client
+// must be configured with the Fusion API base url, e.g.
+// https://api.1inch.dev/fusion.
+var WithOneInchFusionClient = oneinch.WithFusionClient
+
+// WithOneInchFusionPlusClient enables gasless cross chain Fusion+ orders,
+// client must be configured with the Fusion+ API base url, e.g.
+// https://api.1inch.dev/fusion-plus.
+var WithOneInchFusionPlusClient = oneinch.WithFusionPlusClient
+
+// WithOneInchEscrowVerifier checks the escrows of every ready Fusion+ fill
+// on chain before its secret is shared with the relayer.
+var WithOneInchEscrowVerifier = oneinch.WithEscrowVerifier
+
+// OneInch is the 1inch provider, on top of Aggregator it exposes the 1inch
+// approval endpoints and, with WithOneInchFusionClient and
+// WithOneInchFusionPlusClient, gasless Fusion and cross chain Fusion+
+// orders whose gas is paid by the resolver filling them.
+type OneInch interface {
+	Aggregator
+	OrderAggregator
+	FetchSpender(ctx context.Context, chainId uint64) (string, error)
+	FetchAllowance(ctx context.Context, chainId uint64, token, wallet string) (*big.Int, error)
+	FetchApproveTransaction(ctx context.Context, chainId uint64, token string, amount *big.Int) (ApproveTx, error)
+	FetchFusionQuote(ctx context.Context, req QuoteReq) (OneInchFusionQuote, error)
+	BuildFusionOrder(req QuoteReq, quote OneInchFusionQuote, preset string) (OneInchFusionOrder, error)
+	SubmitFusionOrder(ctx context.Context, chainId uint64, order OneInchFusionOrder, signature []byte) (string, error)
+	PlaceFusionPlusOrder(ctx context.Context, req CrossChainQuoteReq, signer Signer) (OneInchFusionPlusOrder, error)
+	FetchFusionPlusQuote(ctx context.Context, req CrossChainQuoteReq) (OneInchFusionPlusQuote, error)
+	BuildFusionPlusOrder(req CrossChainQuoteReq, quote OneInchFusionPlusQuote, preset string) (OneInchFusionPlusOrder, error)
+	SubmitFusionPlusOrder(ctx context.Context, order OneInchFusionPlusOrder, signature []byte) (string, error)
+	FetchReadyFusionPlusFills(ctx context.Context, orderHash string) ([]OneInchFusionPlusReadyFill, error)
+	SubmitFusionPlusSecret(ctx context.Context, orderHash, secret string) error
+	FetchFusionPlusOrder(ctx context.Context, orderHash string) (CrossChainStatus, error)
+	WaitForFusionPlusOrder(ctx context.Context, order OneInchFusionPlusOrder, interval time.Duration) (CrossChainStatus, error)
+}
+
+// OneInchFusionTypedData returns the EIP-712 payload of a Fusion order.
+func OneInchFusionTypedData(chainId uint64, order OneInchFusionOrder) apitypes.TypedData {
+	return oneinch.FusionTypedData(chainId, order.Order)
+}
+
+// OneInchFusionPlusTypedData returns the EIP-712 payload of a Fusion+
+// order, signed on its source chain.
+func OneInchFusionPlusTypedData(order OneInchFusionPlusOrder) apitypes.TypedData {
+	return oneinch.FusionTypedData(order.SrcChainId, order.Order)
+}
+
+// NewOneInch returns a 1inch provider, client must be configured with the
+// 1inch swap API base url, e.g. https://api.1inch.dev/swap/v5.2.
+func NewOneInch(client metahttp.Requests, opts ...OneInchOption) OneInch {
+	return oneinch.NewOneInch(client, opts...)
+}
+
+type (
+	ZeroXGaslessPriceResponse  = zerox.ZeroXGaslessPriceResponse
+	ZeroXGaslessQuoteResponse  = zerox.ZeroXGaslessQuoteResponse
+	ZeroXGaslessStatusResponse = zerox.ZeroXGaslessStatusResponse
+)
+
+// ZeroX is the 0x provider, on top of Aggregator it exposes the gasless API
+// whose relayer pays the gas out of the buy amount.
+type ZeroX interface {
+	Aggregator
+	OrderAggregator
+	FetchGaslessPrice(ctx context.Context, req QuoteReq) (ZeroXGaslessPriceResponse, error)
+	FetchGaslessQuote(ctx context.Context, req QuoteReq) (ZeroXGaslessQuoteResponse, error)
+	SubmitGasless(ctx context.Context, chainId uint64, quote ZeroXGaslessQuoteResponse, approvalSignature, tradeSignature []byte) (string, error)
+	FetchGaslessStatus(ctx context.Context, chainId uint64, tradeHash string) (ZeroXGaslessStatusResponse, error)
+}
+
+// NewZeroX returns a 0x provider, chainUrlMap maps a chain id to its 0x API
+// base url, e.g. 137 to https://polygon.api.0x.org.
+func NewZeroX(client metahttp.Requests, chainUrlMap map[uint64]string, opts ...ZeroXOption) ZeroX {
+	return zerox.NewZeroX(client, chainUrlMap, opts...)
+}
+
+type (
+	ZeroXV2Mode          = zerox.V2Mode
+	ZeroXV2PriceResponse = zerox.ZeroXV2PriceResponse
+	ZeroXV2QuoteResponse = zerox.ZeroXV2QuoteResponse
+	ZeroXV2Issues        = zerox.ZeroXV2Issues
+)
+
+const (
+	ZeroXV2Permit2         = zerox.V2Permit2
+	ZeroXV2AllowanceHolder = zerox.V2AllowanceHolder
+)
+
+// ZeroXV2 is the 0x v2 provider, on top of Aggregator it exposes the full
+// price and quote responses with their issues block and Permit2 payload.
+type ZeroXV2 interface {
+	Aggregator
+	FetchPriceV2(ctx context.Context, req QuoteReq) (ZeroXV2PriceResponse, error)
+	FetchQuoteV2(ctx context.Context, req QuoteReq) (ZeroXV2QuoteResponse, error)
+}
+
+// NewZeroXV2 returns a 0x v2 provider, baseUrl defaults to
+// https://api.0x.org when empty.
+func NewZeroXV2(client metahttp.Requests, baseUrl string, mode ZeroXV2Mode, opts ...ZeroXOption) ZeroXV2 {
+	return zerox.NewZeroXV2(client, baseUrl, mode, opts...)
+}
+
+// AppendPermit2Signature appends the signed Permit2 payload to the
+// transaction data of a ZeroXV2Permit2 quote.
+func AppendPermit2Signature(data string, signature []byte) (string, error) {
+	return zerox.AppendPermit2Signature(data, signature)
+}
+
+// ZeroXV2SwapTx returns the transaction of a v2 quote, signature is the
+// taker's signature of the Permit2 payload of ZeroXV2Permit2 quotes and nil
+// otherwise. ZeroXV2Permit2 providers do not return swap calldata without
+// it.
+func ZeroXV2SwapTx(req QuoteReq, quote ZeroXV2QuoteResponse, signature []byte) (SwapTx, error) {
+	return zerox.V2SwapTx(req, quote, signature)
+}
+
+type (
+	ParaSwapOption     = paraswap.Option
+	ParaSwapSide       = paraswap.Side
+	ParaSwapPriceRoute = paraswap.ParaSwapPriceRoute
+)
+
+const (
+	ParaSwapSell = paraswap.SideSell
+	ParaSwapBuy  = paraswap.SideBuy
+)
+
+var (
+	WithParaSwapCredentials = paraswap.WithCredentials
+	WithParaSwapPartner     = paraswap.WithPartner
+)
+
+// ParaSwap is the ParaSwap provider, on top of Aggregator it exposes the
+// price route so it can be built into a transaction without quoting again.
+type ParaSwap interface {
+	Aggregator
+	FetchPriceRoute(ctx context.Context, req QuoteReq, side ParaSwapSide) (ParaSwapPriceRoute, error)
+	BuildTransaction(ctx context.Context, req QuoteReq, route ParaSwapPriceRoute) (SwapTx, error)
+}
+
+// NewParaSwap returns a ParaSwap provider, client must be configured with
+// the ParaSwap API base url, e.g. https://api.paraswap.io.
+func NewParaSwap(client metahttp.Requests, opts ...ParaSwapOption) ParaSwap {
+	return paraswap.NewParaSwap(client, opts...)
+}
+
+type (
+	KyberSwapOption    = kyberswap.Option
+	KyberSwapRouteData = kyberswap.KyberSwapRouteData
+)
+
+var (
+	WithKyberSwapClientId      = kyberswap.WithClientId
+	DefaultKyberSwapChainSlugs = kyberswap.DefaultChainSlugs
+)
+
+// KyberSwap is the KyberSwap provider, on top of Aggregator it exposes the
+// route so it can be built into a transaction without quoting again.
+type KyberSwap interface {
+	Aggregator
+	FetchRoute(ctx context.Context, req QuoteReq) (KyberSwapRouteData, error)
+	BuildRoute(ctx context.Context, req QuoteReq, route KyberSwapRouteData) (SwapTx, error)
+}
+
+// NewKyberSwap returns a KyberSwap provider, client must be configured with
+// the aggregator API base url, e.g. https://aggregator-api.kyberswap.com.
+// chainSlugMap maps a chain id to its slug, e.g. 137 to polygon, the
+// default slugs are used when nil.
+func NewKyberSwap(client metahttp.Requests, chainSlugMap map[uint64]string, opts ...KyberSwapOption) KyberSwap {
+	return kyberswap.NewKyberSwap(client, chainSlugMap, opts...)
+}
+
+type OdosOption = odos.Option
+
+var WithOdosReferralCode = odos.WithReferralCode
+
+// Odos is the Odos provider, on top of Aggregator it swaps several inputs
+// into several outputs in a single transaction.
+type Odos interface {
+	Aggregator
+	MultiAggregator
+}
+
+// NewOdos returns an Odos provider, client must be configured with the Odos
+// API base url, e.g. https://api.odos.xyz.
+func NewOdos(client metahttp.Requests, opts ...OdosOption) Odos {
+	return odos.NewOdos(client, opts...)
+}
+
+// NewOpenOcean returns an OpenOcean provider, client must be configured with
+// the OpenOcean API base url, e.g. https://open-api.openocean.finance/v3.
+// rpcs maps a chain id to the node estimating the gas price OpenOcean
+// requires, it is only used for requests without a GasPrice.
+func NewOpenOcean(client metahttp.Requests, rpcs map[uint64]*w3.Client, opts ...OpenOceanOption) Aggregator {
+	return openocean.NewOpenOcean(client, rpcs, opts...)
+}
+
+type OpenOceanOption = openocean.Option
+
+var WithOpenOceanGasOracle = openocean.WithGasOracle
+
+type (
+	Signer          = common.Signer
+	Order           = common.Order
+	OrderStatus     = common.OrderStatus
+	OrderAggregator = common.OrderAggregator
+)
+
+const (
+	OrderPending         = common.OrderPending
+	OrderPartiallyFilled = common.OrderPartiallyFilled
+	OrderFilled          = common.OrderFilled
+	OrderExpired         = common.OrderExpired
+	OrderCancelled       = common.OrderCancelled
+	OrderFailed          = common.OrderFailed
+)
+
+// PrivateKeySigner signs order payloads with key in process.
+func PrivateKeySigner(key *ecdsa.PrivateKey) Signer {
+	return common.PrivateKeySigner(key)
+}
+
+// WaitForOrder polls the order every interval until it is filled, expired
+// or cancelled, or ctx is done.
+func WaitForOrder(ctx context.Context, aggregator OrderAggregator, chainId uint64, id string, interval time.Duration) (Order, error) {
+	return common.WaitForOrder(ctx, aggregator, chainId, id, interval)
+}
+
+type (
+	CowOption        = cow.Option
+	CowOrderKind     = cow.OrderKind
+	CowOrder         = cow.CowOrder
+	CowQuoteResponse = cow.CowQuoteResponse
+)
+
+const (
+	CowSell = cow.KindSell
+	CowBuy  = cow.KindBuy
+)
+
+var (
+	WithCowAppData         = cow.WithAppData
+	DefaultCowChainUrls    = cow.DefaultChainUrls
+	CowVaultRelayerAddress = cow.VaultRelayerAddress
+)
+
+// Cow is the CoW Protocol provider, orders are signed off-chain and settled
+// by solvers, the sell token must be approved to CowVaultRelayerAddress.
+type Cow interface {
+	OrderAggregator
+	PlaceExactOutOrder(ctx context.Context, req QuoteReq, signer Signer) (Order, error)
+	FetchQuote(ctx context.Context, req QuoteReq, kind CowOrderKind) (CowQuoteResponse, error)
+	BuildOrder(req QuoteReq, quote CowQuoteResponse) (CowOrder, error)
+	TypedData(chainId uint64, order CowOrder) apitypes.TypedData
+	SubmitOrder(ctx context.Context, req QuoteReq, order CowOrder, quote CowQuoteResponse, signature []byte) (string, error)
+}
+
+// NewCow returns a CoW Protocol provider, client may have an empty base url.
+// chainUrlMap maps a chain id to its orderbook API base url, e.g. 1 to
+// https://api.cow.fi/mainnet, the default urls are used when nil.
+func NewCow(client metahttp.Requests, chainUrlMap map[uint64]string, opts ...CowOption) Cow {
+	return cow.NewCow(client, chainUrlMap, opts...)
+}
+
+type (
+	CrossChainAggregator = common.CrossChainAggregator
+	CrossChainQuoteReq   = common.CrossChainQuoteReq
+	CrossChainQuoteRes   = common.CrossChainQuoteRes
+	CrossChainStep       = common.CrossChainStep
+	CrossChainFee        = common.CrossChainFee
+	CrossChainStatusReq  = common.CrossChainStatusReq
+	CrossChainStatus     = common.CrossChainStatus
+	TransferStatus       = common.TransferStatus
+)
+
+const (
+	TransferPending  = common.TransferPending
+	TransferDone     = common.TransferDone
+	TransferRefunded = common.TransferRefunded
+	TransferFailed   = common.TransferFailed
+)
+
+// WaitForTransfer polls a bridge transfer every interval until it is done,
+// refunded or failed, or ctx is done.
+func WaitForTransfer(ctx context.Context, aggregator CrossChainAggregator, req CrossChainStatusReq, interval time.Duration) (CrossChainStatus, error) {
+	return common.WaitForTransfer(ctx, aggregator, req, interval)
+}
+
+type LiFiOption = lifi.Option
+
+var (
+	WithLiFiCredentials = lifi.WithCredentials
+	WithLiFiIntegrator  = lifi.WithIntegrator
+)
+
+// NewLiFi returns a LI.FI provider, client must be configured with the LI.FI
+// API base url, e.g. https://li.quest/v1.
+func NewLiFi(client metahttp.Requests, opts ...LiFiOption) CrossChainAggregator {
+	return lifi.NewLiFi(client, opts...)
+}
+
+type (
+	UniswapOption     = dex.Option
+	UniswapDeployment = dex.UniswapDeployment
+	UniswapQuote      = dex.UniswapQuote
+	UniswapRoute      = dex.UniswapRoute
+	UniswapOptions    = dex.UniswapOptions
+	Permit2Permit     = dex.Permit2Permit
+)
+
+var (
+	WithUniswapDeadline       = dex.WithDeadline
+	WithUniswapGasOracle      = dex.WithGasOracle
+	DefaultUniswapDeployments = dex.DefaultUniswapDeployments
+	Permit2Address            = dex.Permit2Address
+)
+
+// Uniswap swaps through the Uniswap Universal Router from routes quoted on
+// chain, without an aggregator API. The source token must be approved to
+// Permit2Address, the router then pulls it through Permit2.
+type Uniswap interface {
+	Aggregator
+	Quote(ctx context.Context, req QuoteReq, exactOut bool) (UniswapQuote, error)
+	FetchPermit2Permit(ctx context.Context, chainId uint64, owner, token string, amount *big.Int, expiration time.Time) (Permit2Permit, error)
+}
+
+// NewUniswap returns a Uniswap provider, usable as a last resort when the
+// aggregator APIs are down. rpcs maps a chain id to the node quoting the
+// pools, deployments the chain id to its Uniswap contracts and defaults to
+// DefaultUniswapDeployments when nil.
+func NewUniswap(rpcs map[uint64]*w3.Client, deployments map[uint64]UniswapDeployment, opts ...UniswapOption) Uniswap {
+	return dex.NewUniswap(rpcs, deployments, opts...)
+}
+
+// Permit2TypedData returns the EIP-712 payload of a Permit2 permit to sign.
+func Permit2TypedData(chainId uint64, permit Permit2Permit) apitypes.TypedData {
+	return dex.Permit2TypedData(chainId, permit)
+}
+
+// SignPermit2Permit signs permit with signer, the token owner, ready to be
+// passed in QuoteReq.Options as UniswapOptions.
+func SignPermit2Permit(ctx context.Context, signer Signer, chainId uint64, permit Permit2Permit) (Permit2Permit, error) {
+	return dex.SignPermit2Permit(ctx, signer, chainId, permit)
+}
+
+type (
+	// GasOracle prices the gas of a chain as a gas price and as EIP-1559
+	// fees, see WithGasOracle, WithOpenOceanGasOracle and
+	// WithUniswapGasOracle.
+	GasOracle     = gas.Oracle
+	GasOracleFunc = gas.OracleFunc
+	GasCap        = gas.Cap
+	// GasFees is the maxFeePerGas and maxPriorityFeePerGas returned by
+	// GasOracle.Fees.
+	GasFees = gas.FeeEstimate
+)
+
+// NodeGasOracle returns eth_gasPrice of the node of the chain.
+func NodeGasOracle(rpcs map[uint64]*w3.Client) GasOracle {
+	return gas.NodeOracle(rpcs)
+}
+
+// FeeHistoryGasOracle returns the next base fee plus the priority fee paid
+// at percentile over the last blocks, eth_gasPrice on chains without
+// EIP-1559.
+func FeeHistoryGasOracle(rpcs map[uint64]*w3.Client, percentile float64, blocks uint64) GasOracle {
+	return gas.FeeHistoryOracle(rpcs, percentile, blocks)
+}
+
+// FixedGasOracle returns the gas price set for the chain in prices, other
+// chains are priced by fallback when not nil.
+func FixedGasOracle(prices map[uint64]*big.Int, fallback GasOracle) GasOracle {
+	return gas.FixedOracle(prices, fallback)
+}
+
+// MedianGasOracle returns the median price of oracles, e.g. one
+// NodeGasOracle per RPC provider.
+func MedianGasOracle(oracles ...GasOracle) GasOracle {
+	return gas.MedianOracle(oracles...)
+}
+
+// CappedGasOracle clamps the price of oracle to the cap of each chain.
+func CappedGasOracle(oracle GasOracle, caps map[uint64]GasCap) GasOracle {
+	return gas.CappedOracle(oracle, caps)
+}
+
+type (
+	CostReq    = gas.CostReq
+	Cost       = gas.Cost
+	OPStackFee = gas.OPStackFee
+)
+
+// EstimateCost returns the total cost in wei of a transaction, including the
+// L1 data fee on OP Stack chains and Arbitrum.
+func EstimateCost(ctx context.Context, client *w3.Client, req CostReq) (Cost, error) {
+	return gas.CostEstimate(ctx, client, req)
+}
+
+// EstimateSwapCost returns the total cost in wei of tx, see EstimateCost.
+func EstimateSwapCost(ctx context.Context, client *w3.Client, tx SwapTx) (Cost, error) {
+	data, err := hexutil.Decode(tx.Data)
+	if err != nil {
+		return Cost{}, fmt.Errorf("invalid swap calldata, err: %w", err)
+	}
+
+	req := CostReq{
+		ChainId:  tx.ChainId,
+		From:     tx.From,
+		To:       tx.To,
+		Data:     data,
+		Value:    tx.Value,
+		GasPrice: tx.GasPrice,
+	}
+	if tx.Gas != nil && tx.Gas.IsUint64() {
+		req.Gas = tx.Gas.Uint64()
+	}
+	return gas.CostEstimate(ctx, client, req)
+}
+
+type (
+	LimitReq    = gas.LimitReq
+	LimitOption = gas.LimitOption
+	// RevertError is returned by EstimateGasLimit when the transaction
+	// reverts.
+	RevertError = gas.RevertError
+)
+
+var (
+	WithGasBufferBps    = gas.WithBufferBps
+	WithGasBufferGas    = gas.WithBufferGas
+	WithGasLimitCaps    = gas.WithLimitCaps
+	WithStateOverrides  = gas.WithStateOverrides
+	WithBlock           = gas.WithBlock
+	BalanceOverride     = gas.BalanceOverride
+	ERC20Override       = gas.ERC20Override
+	ErrGasLimitAboveCap = gas.ErrLimitAboveCap
+)
+
+// EstimateGasLimit returns the gas limit of req at the block and state
+// overrides of opts, with their buffers and caps applied.
+func EstimateGasLimit(ctx context.Context, client *w3.Client, req LimitReq, opts ...LimitOption) (uint64, error) {
+	return gas.LimitEstimate(ctx, client, req, opts...)
+}
+
+// SimulateCall runs req with eth_call at the block and state overrides of
+// opts and returns its output, a revert fails with a *RevertError.
+func SimulateCall(ctx context.Context, client *w3.Client, req LimitReq, opts ...LimitOption) ([]byte, error) {
+	return gas.SimulateCall(ctx, client, req, opts...)
+}
+
+// WrappedNativeTokens maps chain ids to the ERC-20 wrapper of the native
+// token, e.g. WETH.
+var WrappedNativeTokens = common.WrappedNativeTokens
+
+// FormatUnits formats a raw amount as whole tokens, e.g. 1500000 with 6
+// decimals as "1.5".
+func FormatUnits(amount *big.Int, decimals int) string {
+	return common.FormatUnits(amount, decimals)
+}
+
+// ParseUnits parses whole tokens into a raw amount, e.g. "1.5" with 6
+// decimals as 1500000.
+func ParseUnits(value string, decimals int) (*big.Int, error) {
+	return common.ParseUnits(value, decimals)
+}
+
+type AllowanceReq = allowance.AllowanceReq
+
+// EnsureAllowance reads the on-chain allowance and returns the approve
+// transactions to send in order before the swap, nil when none are needed.
+// A nonzero allowance below the amount is reset to zero first, as USDT
+// requires. It works with the AllowanceTarget of any provider's SwapTx.
+func EnsureAllowance(ctx context.Context, client *w3.Client, req AllowanceReq) ([]ApproveTx, error) {
+	return allowance.EnsureAllowance(ctx, client, req)
+}
+
+// MaxUint256 returns the amount of an unlimited approval.
+func MaxUint256() *big.Int {
+	return common.MaxUint256()
+}
@@ -0,0 +1,164 @@
+package aggregator
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+)
+
+// FailoverReason records why a provider was skipped by Failover.
+type FailoverReason string
+
+const (
+	ReasonRateLimited           FailoverReason = "rate_limited"
+	ReasonUnsupportedChain      FailoverReason = "unsupported_chain"
+	ReasonInsufficientLiquidity FailoverReason = "insufficient_liquidity"
+	ReasonUnsupportedParameter  FailoverReason = "unsupported_parameter"
+	ReasonUnsupportedToken      FailoverReason = "unsupported_token"
+	ReasonInsufficientAllowance FailoverReason = "insufficient_allowance"
+	ReasonAuth                  FailoverReason = "auth"
+	ReasonTimeout               FailoverReason = "timeout"
+	ReasonError                 FailoverReason = "error"
+)
+
+type SkippedProvider struct {
+	Provider string
+	Reason   FailoverReason
+	Err      error
+}
+
+type FailoverResult struct {
+	// Provider is the name of the provider that built Tx.
+	Provider string
+	Tx       SwapTx
+	// Skipped lists the providers tried before Provider, in order.
+	Skipped []SkippedProvider
+}
+
+type FailoverOption func(*Failover)
+
+// WithFailoverOn restricts failing over to the reasons accepted by
+// shouldFailover, by default every reason fails over.
+func WithFailoverOn(shouldFailover func(FailoverReason) bool) FailoverOption {
+	return func(f *Failover) {
+		f.shouldFailover = shouldFailover
+	}
+}
+
+// Failover fetches swap calldata from the first provider of a priority
+// list that succeeds.
+type Failover struct {
+	providers      []Provider
+	shouldFailover func(FailoverReason) bool
+}
+
+// NewFailover returns a Failover trying providers in the given order.
+func NewFailover(providers []Provider, opts ...FailoverOption) *Failover {
+	f := &Failover{
+		providers:      providers,
+		shouldFailover: func(FailoverReason) bool { return true },
+	}
+
+	for _, opt := range opts {
+		opt(f)
+	}
+	return f
+}
+
+func (f *Failover) FetchExactInSwapCallData(ctx context.Context, req QuoteReq) (FailoverResult, error) {
+	return f.run(ctx, func(ctx context.Context, a Aggregator) (SwapTx, error) {
+		return a.FetchExactInSwapCallData(ctx, req)
+	})
+}
+
+func (f *Failover) FetchExactOutSwapCallData(ctx context.Context, req QuoteReq) (FailoverResult, error) {
+	return f.run(ctx, func(ctx context.Context, a Aggregator) (SwapTx, error) {
+		return a.FetchExactOutSwapCallData(ctx, req)
+	})
+}
+
+func (f *Failover) run(ctx context.Context, fetch func(context.Context, Aggregator) (SwapTx, error)) (FailoverResult, error) {
+	var res FailoverResult
+	if len(f.providers) == 0 {
+		return res, fmt.Errorf("no aggregator registered on failover")
+	}
+
+	for _, p := range f.providers {
+		tx, err := f.fetch(ctx, p, fetch)
+		if err == nil {
+			res.Provider = p.Name
+			res.Tx = tx
+			return res, nil
+		}
+
+		// The caller gave up, there is no point in trying the next provider.
+		if ctx.Err() != nil {
+			return res, fmt.Errorf("swap calldata cancelled, err: %w", ctx.Err())
+		}
+
+		reason := classifyFailure(err)
+		res.Skipped = append(res.Skipped, SkippedProvider{
+			Provider: p.Name,
+			Reason:   reason,
+			Err:      err,
+		})
+
+		if !f.shouldFailover(reason) {
+			return res, fmt.Errorf("%s failed without failover, reason: %s, err: %w", p.Name, reason, err)
+		}
+	}
+
+	errs := make([]error, 0, len(res.Skipped))
+	for _, s := range res.Skipped {
+		errs = append(errs, fmt.Errorf("%s (%s): %w", s.Provider, s.Reason, s.Err))
+	}
+	return res, fmt.Errorf("every aggregator failed to build swap calldata, err: %w", errors.Join(errs...))
+}
+
+func (f *Failover) fetch(ctx context.Context, p Provider, fetch func(context.Context, Aggregator) (SwapTx, error)) (SwapTx, error) {
+	timeout := p.Timeout
+	if timeout == 0 {
+		timeout = DefaultProviderTimeout
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	return fetch(ctx, p.Aggregator)
+}
+
+// classifyFailure maps err to a reason from the sentinel errors and the
+// ProviderError it wraps, anything else is ReasonError.
+func classifyFailure(err error) FailoverReason {
+	switch {
+	case errors.Is(err, context.DeadlineExceeded):
+		return ReasonTimeout
+	case errors.Is(err, ErrRateLimited):
+		return ReasonRateLimited
+	case errors.Is(err, ErrUnsupportedChain):
+		return ReasonUnsupportedChain
+	case errors.Is(err, ErrInsufficientLiquidity):
+		return ReasonInsufficientLiquidity
+	case errors.Is(err, ErrUnsupportedParameter):
+		return ReasonUnsupportedParameter
+	case errors.Is(err, ErrUnsupportedToken):
+		return ReasonUnsupportedToken
+	case errors.Is(err, ErrInsufficientAllowance):
+		return ReasonInsufficientAllowance
+	case errors.Is(err, ErrAuth):
+		return ReasonAuth
+	}
+
+	var providerErr *ProviderError
+	if errors.As(err, &providerErr) && providerErr.StatusCode == http.StatusRequestTimeout {
+		return ReasonTimeout
+	}
+
+	var netErr net.Error
+	if errors.As(err, &netErr) && netErr.Timeout() {
+		return ReasonTimeout
+	}
+	return ReasonError
+}
@@ -0,0 +1,90 @@
+package aggregator
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"net/http"
+	"testing"
+
+	"github.com/onmetahq/go-evm/internal/http/common"
+)
+
+func TestFailoverFetchExactInSwapCallData(t *testing.T) {
+	zeroX := &fakeAggregator{err: fmt.Errorf("unable to fetch 0x quote, err: %w", common.NewProviderError("0x", http.StatusTooManyRequests, "", "", nil))}
+	unsupported := &fakeAggregator{err: fmt.Errorf("unsupported chainId 56, err: %w", ErrUnsupportedChain)}
+	// Text alone is not classified.
+	untyped := &fakeAggregator{err: fmt.Errorf("insufficient liquidity")}
+	oneInch := &fakeAggregator{swap: SwapTx{Data: "0x12aa3caf"}}
+
+	failover := NewFailover([]Provider{
+		{Name: "0x", Aggregator: zeroX},
+		{Name: "unsupported", Aggregator: unsupported},
+		{Name: "untyped", Aggregator: untyped},
+		{Name: "1inch", Aggregator: oneInch},
+	})
+
+	res, err := failover.FetchExactInSwapCallData(context.Background(), QuoteReq{ChainId: 56})
+	if err != nil {
+		t.Fatalf("failover err: %v", err)
+	}
+
+	if res.Provider != "1inch" || res.Tx.Data != "0x12aa3caf" {
+		t.Fatalf("invalid provider, res: %+v", res)
+	}
+
+	if len(res.Skipped) != 3 ||
+		res.Skipped[0].Reason != ReasonRateLimited ||
+		res.Skipped[1].Reason != ReasonUnsupportedChain ||
+		res.Skipped[2].Reason != ReasonError {
+		t.Fatalf("invalid skipped providers, skipped: %+v", res.Skipped)
+	}
+}
+
+func TestFailoverStopsOnRejectedReason(t *testing.T) {
+	first := &fakeAggregator{err: fmt.Errorf("no route, err: %w", ErrInsufficientLiquidity)}
+	second := &fakeAggregator{swap: SwapTx{Data: "0x"}}
+
+	failover := NewFailover([]Provider{
+		{Name: "first", Aggregator: first},
+		{Name: "second", Aggregator: second},
+	}, WithFailoverOn(func(reason FailoverReason) bool {
+		return reason == ReasonRateLimited
+	}))
+
+	if _, err := failover.FetchExactInSwapCallData(context.Background(), QuoteReq{}); err == nil {
+		t.Fatalf("expected error without failover")
+	}
+
+	if second.calls != 0 {
+		t.Fatalf("second provider should not be called, calls: %d", second.calls)
+	}
+}
+
+func TestClassifyFailure(t *testing.T) {
+	tests := []struct {
+		name string
+		err  error
+		want FailoverReason
+	}{
+		{"deadline", fmt.Errorf("unable to fetch quote, err: %w", context.DeadlineExceeded), ReasonTimeout},
+		{"request timeout", common.NewProviderError("1inch", http.StatusRequestTimeout, "", "", nil), ReasonTimeout},
+		{"network timeout", &net.DNSError{IsTimeout: true}, ReasonTimeout},
+		{"rate limited", common.NewProviderError("0x", http.StatusTooManyRequests, "", "", nil), ReasonRateLimited},
+		{"unsupported chain", fmt.Errorf("unsupported chainId 56, err: %w", ErrUnsupportedChain), ReasonUnsupportedChain},
+		{"insufficient liquidity", common.NewProviderError("odos", http.StatusBadRequest, "", "No viable path found", nil), ReasonInsufficientLiquidity},
+		{"unsupported parameter", fmt.Errorf("receiver is not supported by 0x v1, err: %w", ErrUnsupportedParameter), ReasonUnsupportedParameter},
+		{"unsupported token", common.NewProviderError("1inch", http.StatusBadRequest, "", "token not supported", nil), ReasonUnsupportedToken},
+		{"insufficient allowance", common.NewProviderError("1inch", http.StatusBadRequest, "", "Not enough allowance", nil), ReasonInsufficientAllowance},
+		{"auth", common.NewProviderError("0x", http.StatusUnauthorized, "", "", nil), ReasonAuth},
+		{"untyped", fmt.Errorf("insufficient liquidity"), ReasonError},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := classifyFailure(tt.err); got != tt.want {
+				t.Fatalf("invalid reason, want: %s, got: %s", tt.want, got)
+			}
+		})
+	}
+}
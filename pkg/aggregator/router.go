@@ -0,0 +1,286 @@
+package aggregator
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math/big"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/onmetahq/go-evm/internal/http/common"
+)
+
+const DefaultProviderTimeout = 10 * time.Second
+
+// Provider is an aggregator registered on a Router under a unique name.
+type Provider struct {
+	Name       string
+	Aggregator Aggregator
+	// Timeout bounds a single call to the provider, DefaultProviderTimeout
+	// is used when zero.
+	Timeout time.Duration
+}
+
+// GasConverter converts a native token amount (wei) into the given token.
+type GasConverter interface {
+	ConvertNative(ctx context.Context, chainId uint64, token string, amount *big.Int) (*big.Int, error)
+}
+
+type RouterOption func(*Router)
+
+// WithGasConverter overrides how gas costs are converted into the
+// destination token, by default the router quotes native to destination
+// on its own providers.
+func WithGasConverter(converter GasConverter) RouterOption {
+	return func(r *Router) {
+		r.converter = converter
+	}
+}
+
+// WithGasPrice sets the gas price used for quotes that do not carry one,
+// e.g. 1inch quotes.
+func WithGasPrice(gasPrice func(ctx context.Context, chainId uint64) (*big.Int, error)) RouterOption {
+	return func(r *Router) {
+		r.gasPrice = gasPrice
+	}
+}
+
+// WithGasOracle sets the oracle pricing quotes that do not carry a gas
+// price, see WithGasPrice.
+func WithGasOracle(oracle GasOracle) RouterOption {
+	return WithGasPrice(oracle.GasPrice)
+}
+
+// Router fans quote requests out to every registered provider in parallel
+// and ranks the results by output net of gas cost, quotes whose gas cost
+// cannot be priced are ranked after the others.
+type Router struct {
+	providers []Provider
+	converter GasConverter
+	gasPrice  func(ctx context.Context, chainId uint64) (*big.Int, error)
+}
+
+func NewRouter(providers []Provider, opts ...RouterOption) *Router {
+	r := &Router{
+		providers: providers,
+	}
+	r.converter = &quoteConverter{router: r}
+
+	for _, opt := range opts {
+		opt(r)
+	}
+	return r
+}
+
+// RankedQuote is the result of a single provider, Err is set when the
+// provider failed and the quote fields are then empty.
+type RankedQuote struct {
+	Provider string
+	Quote    QuoteRes
+	// GasCost is Gas × GasPrice expressed in the destination token, nil
+	// when GasCostUnknown.
+	GasCost *big.Int
+	// GasCostUnknown is set when the quote has no gas or no gas price or
+	// conversion rate was found, the quote is then ranked last.
+	GasCostUnknown bool
+	// NetToAmount is ToAmount minus GasCost, used for ranking. It equals
+	// ToAmount when GasCostUnknown.
+	NetToAmount *big.Int
+	Duration    time.Duration
+	Err         error
+}
+
+type RouteResult struct {
+	// Best is the winning quote, nil when every provider failed.
+	Best *RankedQuote
+	// Quotes holds the successful quotes ordered from best to worst.
+	Quotes []RankedQuote
+	// Failures holds the providers that returned an error.
+	Failures []RankedQuote
+}
+
+// BestExactInQuote queries every provider for an exact in quote and returns
+// them ranked by net output after gas cost. Partial failures are reported
+// in RouteResult.Failures, an error is only returned when no provider
+// returned a quote.
+func (r *Router) BestExactInQuote(ctx context.Context, req QuoteReq) (RouteResult, error) {
+	if len(r.providers) == 0 {
+		return RouteResult{}, fmt.Errorf("no aggregator registered on router")
+	}
+
+	results := make([]RankedQuote, len(r.providers))
+	var wg sync.WaitGroup
+	for i, p := range r.providers {
+		wg.Add(1)
+		go func(i int, p Provider) {
+			defer wg.Done()
+			results[i] = r.fetch(ctx, p, req)
+		}(i, p)
+	}
+	wg.Wait()
+
+	var res RouteResult
+	for _, q := range results {
+		if q.Err != nil {
+			res.Failures = append(res.Failures, q)
+			continue
+		}
+		res.Quotes = append(res.Quotes, q)
+	}
+
+	if len(res.Quotes) == 0 {
+		errs := make([]error, 0, len(res.Failures))
+		for _, f := range res.Failures {
+			errs = append(errs, fmt.Errorf("%s: %w", f.Provider, f.Err))
+		}
+		return res, fmt.Errorf("no aggregator returned a quote, err: %w", errors.Join(errs...))
+	}
+
+	r.applyGasCost(ctx, req, res.Quotes)
+
+	sort.SliceStable(res.Quotes, func(i, j int) bool {
+		if res.Quotes[i].GasCostUnknown != res.Quotes[j].GasCostUnknown {
+			return !res.Quotes[i].GasCostUnknown
+		}
+		return res.Quotes[i].NetToAmount.Cmp(res.Quotes[j].NetToAmount) > 0
+	})
+	res.Best = &res.Quotes[0]
+
+	return res, nil
+}
+
+func (r *Router) fetch(ctx context.Context, p Provider, req QuoteReq) RankedQuote {
+	timeout := p.Timeout
+	if timeout == 0 {
+		timeout = DefaultProviderTimeout
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	start := time.Now()
+	quote, err := p.Aggregator.FetchExactInQuote(ctx, req)
+	out := RankedQuote{
+		Provider: p.Name,
+		Duration: time.Since(start),
+		Err:      err,
+	}
+	if err != nil {
+		return out
+	}
+	if quote.ToAmount == nil {
+		out.Err = fmt.Errorf("empty quote from %s", p.Name)
+		return out
+	}
+
+	out.Quote = quote
+	return out
+}
+
+// applyGasCost fills GasCost and NetToAmount. Quotes without a gas price
+// use the configured gas price or the highest one reported by the other
+// providers, GasCostUnknown is set when the cost cannot be priced.
+func (r *Router) applyGasCost(ctx context.Context, req QuoteReq, quotes []RankedQuote) {
+	fallback := r.fallbackGasPrice(ctx, req.ChainId, quotes)
+
+	costs := make([]*big.Int, len(quotes))
+	for i, q := range quotes {
+		gasPrice := q.Quote.GasPrice
+		if gasPrice == nil || gasPrice.Sign() == 0 {
+			gasPrice = fallback
+		}
+		if q.Quote.Gas == nil || gasPrice == nil {
+			continue
+		}
+		costs[i] = new(big.Int).Mul(q.Quote.Gas, gasPrice)
+	}
+
+	// Gas cost is linear in wei, so convert one unit of native token once
+	// and scale it for every quote.
+	var rate *big.Int
+	if !common.IsNativeToken(req.Dst) {
+		converted, err := r.converter.ConvertNative(ctx, req.ChainId, req.Dst, oneNative)
+		if err == nil {
+			rate = converted
+		}
+	}
+
+	for i := range quotes {
+		var cost *big.Int
+		switch {
+		case costs[i] == nil:
+		case common.IsNativeToken(req.Dst):
+			cost = costs[i]
+		case rate != nil:
+			cost = new(big.Int).Mul(costs[i], rate)
+			cost.Div(cost, oneNative)
+		}
+
+		if cost == nil {
+			quotes[i].GasCostUnknown = true
+			quotes[i].NetToAmount = new(big.Int).Set(quotes[i].Quote.ToAmount)
+			continue
+		}
+		quotes[i].GasCost = cost
+		quotes[i].NetToAmount = new(big.Int).Sub(quotes[i].Quote.ToAmount, cost)
+	}
+}
+
+func (r *Router) fallbackGasPrice(ctx context.Context, chainId uint64, quotes []RankedQuote) *big.Int {
+	if r.gasPrice != nil {
+		if gasPrice, err := r.gasPrice(ctx, chainId); err == nil {
+			return gasPrice
+		}
+	}
+
+	var highest *big.Int
+	for _, q := range quotes {
+		if q.Quote.GasPrice != nil && (highest == nil || q.Quote.GasPrice.Cmp(highest) > 0) {
+			highest = q.Quote.GasPrice
+		}
+	}
+	return highest
+}
+
+var oneNative = big.NewInt(1e18)
+
+// quoteConverter prices native token through the router's own providers,
+// queried in parallel, using the first provider that returns a quote.
+type quoteConverter struct {
+	router *Router
+}
+
+func (c *quoteConverter) ConvertNative(ctx context.Context, chainId uint64, token string, amount *big.Int) (*big.Int, error) {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	req := QuoteReq{
+		ChainId:        chainId,
+		Src:            common.NativeToken,
+		Dst:            token,
+		Amount:         amount,
+		SkipValidation: true,
+	}
+
+	// Buffered so the providers still running once a quote is returned do
+	// not block.
+	results := make(chan RankedQuote, len(c.router.providers))
+	for _, p := range c.router.providers {
+		go func(p Provider) {
+			results <- c.router.fetch(ctx, p, req)
+		}(p)
+	}
+
+	var errs []error
+	for range c.router.providers {
+		q := <-results
+		if q.Err == nil {
+			return q.Quote.ToAmount, nil
+		}
+		errs = append(errs, q.Err)
+	}
+	return nil, fmt.Errorf("unable to price native token in %s, err: %w", strings.ToLower(token), errors.Join(errs...))
+}
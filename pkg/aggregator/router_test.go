@@ -0,0 +1,178 @@
+package aggregator
+
+import (
+	"context"
+	"fmt"
+	"math/big"
+	"testing"
+	"time"
+
+	"github.com/onmetahq/go-evm/internal/http/common"
+)
+
+const TOKENA = "0xeeeeeeeeeeeeeeeeeeeeeeeeeeeeeeeeeeeeeeee"
+const TOKENB = "0x2791bca1f2de4661ed88a30c99a7a9449aa84174"
+
+type fakeAggregator struct {
+	quote QuoteRes
+	swap  SwapTx
+	err   error
+	delay time.Duration
+	calls int
+}
+
+func (f *fakeAggregator) FetchSupportedTokens(ctx context.Context, chainId uint64) ([]Token, error) {
+	return []Token{}, nil
+}
+
+func (f *fakeAggregator) FetchExactInQuote(ctx context.Context, req QuoteReq) (QuoteRes, error) {
+	f.calls++
+	select {
+	case <-time.After(f.delay):
+	case <-ctx.Done():
+		return QuoteRes{}, ctx.Err()
+	}
+	if f.err != nil {
+		return QuoteRes{}, f.err
+	}
+	// Price native token at 1e6 destination units for gas conversion.
+	if common.IsNativeToken(req.Src) {
+		return QuoteRes{ToAmount: big.NewInt(1e6)}, nil
+	}
+	return f.quote, nil
+}
+
+func (f *fakeAggregator) FetchExactOutQuote(ctx context.Context, req QuoteReq) (QuoteRes, error) {
+	return QuoteRes{}, fmt.Errorf("operation not supported")
+}
+
+func (f *fakeAggregator) FetchExactInSwapCallData(ctx context.Context, req QuoteReq) (SwapTx, error) {
+	f.calls++
+	if f.err != nil {
+		return SwapTx{}, f.err
+	}
+	return f.swap, nil
+}
+
+func (f *fakeAggregator) FetchExactOutSwapCallData(ctx context.Context, req QuoteReq) (SwapTx, error) {
+	return SwapTx{}, fmt.Errorf("operation not supported")
+}
+
+func TestRouterBestExactInQuote(t *testing.T) {
+	// cheap has a lower gross output but a much lower gas cost.
+	cheap := &fakeAggregator{quote: QuoteRes{
+		ToAmount: big.NewInt(1_000_000),
+		Gas:      big.NewInt(100_000),
+		GasPrice: big.NewInt(1e9),
+	}}
+	expensive := &fakeAggregator{quote: QuoteRes{
+		ToAmount: big.NewInt(1_000_050),
+		Gas:      big.NewInt(400_000),
+	}}
+	failing := &fakeAggregator{err: fmt.Errorf("rate limited")}
+	slow := &fakeAggregator{delay: time.Second}
+
+	router := NewRouter([]Provider{
+		{Name: "expensive", Aggregator: expensive},
+		{Name: "cheap", Aggregator: cheap},
+		{Name: "failing", Aggregator: failing},
+		{Name: "slow", Aggregator: slow, Timeout: 10 * time.Millisecond},
+	})
+
+	res, err := router.BestExactInQuote(context.Background(), QuoteReq{
+		ChainId: 137,
+		Src:     TOKENB,
+		Dst:     "0xc2132d05d31c914a87c6611c10748aeb04b58e8f",
+		Amount:  big.NewInt(1_000_000),
+	})
+	if err != nil {
+		t.Fatalf("route err: %v", err)
+	}
+
+	if res.Best.Provider != "cheap" {
+		t.Fatalf("invalid winner, provider: %s", res.Best.Provider)
+	}
+
+	// 100k gas at 1 gwei is 1e14 wei, priced at 1e6 per 1e18 wei.
+	if res.Best.GasCost.Cmp(big.NewInt(100)) != 0 {
+		t.Fatalf("invalid gas cost, cost: %s", res.Best.GasCost)
+	}
+
+	if len(res.Quotes) != 2 || len(res.Failures) != 2 {
+		t.Fatalf("invalid result, quotes: %d, failures: %d", len(res.Quotes), len(res.Failures))
+	}
+}
+
+func TestRouterUnknownGasCostRanksLast(t *testing.T) {
+	// unpriced reports no gas, it must not outrank priced on its gross output.
+	priced := &fakeAggregator{quote: QuoteRes{
+		ToAmount: big.NewInt(1_000_000),
+		Gas:      big.NewInt(100_000),
+		GasPrice: big.NewInt(1e9),
+	}}
+	unpriced := &fakeAggregator{quote: QuoteRes{
+		ToAmount: big.NewInt(1_000_050),
+	}}
+
+	router := NewRouter([]Provider{
+		{Name: "unpriced", Aggregator: unpriced},
+		{Name: "priced", Aggregator: priced},
+	})
+
+	res, err := router.BestExactInQuote(context.Background(), QuoteReq{
+		ChainId: 137,
+		Src:     TOKENB,
+		Dst:     "0xc2132d05d31c914a87c6611c10748aeb04b58e8f",
+		Amount:  big.NewInt(1_000_000),
+	})
+	if err != nil {
+		t.Fatalf("route err: %v", err)
+	}
+
+	if res.Best.Provider != "priced" || res.Best.GasCostUnknown {
+		t.Fatalf("invalid winner, provider: %s", res.Best.Provider)
+	}
+
+	last := res.Quotes[1]
+	if last.Provider != "unpriced" || !last.GasCostUnknown || last.GasCost != nil || last.NetToAmount.Cmp(last.Quote.ToAmount) != 0 {
+		t.Fatalf("expected the unpriced quote last and marked, quote: %+v", last)
+	}
+}
+
+func TestQuoteConverterParallel(t *testing.T) {
+	router := NewRouter([]Provider{
+		{Name: "slow", Aggregator: &fakeAggregator{delay: time.Second}},
+		{Name: "fast", Aggregator: &fakeAggregator{}},
+	})
+
+	start := time.Now()
+	amount, err := router.converter.ConvertNative(context.Background(), 137, TOKENB, oneNative)
+	if err != nil || amount.Cmp(big.NewInt(1e6)) != 0 {
+		t.Fatalf("invalid conversion, amount: %s, err: %v", amount, err)
+	}
+
+	if elapsed := time.Since(start); elapsed > 500*time.Millisecond {
+		t.Fatalf("expected the fast provider to answer first, elapsed: %v", elapsed)
+	}
+}
+
+func TestRouterAllProvidersFail(t *testing.T) {
+	router := NewRouter([]Provider{
+		{Name: "a", Aggregator: &fakeAggregator{err: fmt.Errorf("down")}},
+		{Name: "b", Aggregator: &fakeAggregator{err: fmt.Errorf("down")}},
+	})
+
+	res, err := router.BestExactInQuote(context.Background(), QuoteReq{
+		ChainId: 137,
+		Src:     TOKENB,
+		Dst:     TOKENA,
+		Amount:  big.NewInt(1_000_000),
+	})
+	if err == nil {
+		t.Fatalf("expected error when every provider fails")
+	}
+
+	if res.Best != nil || len(res.Failures) != 2 {
+		t.Fatalf("invalid result, res: %+v", res)
+	}
+}